@@ -0,0 +1,421 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/auth"
+	acpjwt "github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/oidc"
+	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
+	"github.com/traefik/hub-agent-kubernetes/pkg/logger"
+	"github.com/urfave/cli/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+type acpCmd struct{}
+
+func newACPCmd() acpCmd {
+	return acpCmd{}
+}
+
+func (c acpCmd) build() *cli.Command {
+	return &cli.Command{
+		Name:  "acp",
+		Usage: "Access control policy tools",
+		Subcommands: []*cli.Command{
+			newACPTestCmd().build(),
+		},
+	}
+}
+
+type acpTestCmd struct {
+	flags []cli.Flag
+}
+
+func newACPTestCmd() acpTestCmd {
+	flgs := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "policy",
+			Usage:    "Path of the AccessControlPolicy or NamespacedAccessControlPolicy manifest to evaluate",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "token",
+			Usage: "JWT sent as a bearer token",
+		},
+		&cli.StringFlag{
+			Name:  "user",
+			Usage: "Basic authentication credentials, formatted as user:password",
+		},
+		&cli.StringFlag{
+			Name:  "method",
+			Usage: "Method of the request",
+			Value: http.MethodGet,
+		},
+		&cli.StringFlag{
+			Name:  "url",
+			Usage: "URL of the request",
+			Value: "http://localhost/",
+		},
+		&cli.StringSliceFlag{
+			Name:  "header",
+			Usage: "Header of the request, formatted as 'Name: value'",
+		},
+		&cli.StringFlag{
+			Name:  "jwks-file",
+			Usage: "Path of a local JWKs file replacing the JWKs of the policy",
+		},
+		&cli.BoolFlag{
+			Name:  "allow-remote-jwks",
+			Usage: "Allow fetching the remote JWKs of the policy",
+		},
+		&cli.StringFlag{
+			Name:  "signing-secret",
+			Usage: "JWT signing secret replacing the one of the policy",
+		},
+		&cli.StringFlag{
+			Name:  "public-key-file",
+			Usage: "Path of a PEM-encoded public key replacing the one of the policy",
+		},
+		&cli.StringFlag{
+			Name:  "client-secret",
+			Usage: "OIDC client secret replacing the one the policy references",
+		},
+		&cli.StringFlag{
+			Name:  "oidc-key",
+			Usage: "Key the OIDC sessions are encrypted with. A random one is used if not set",
+		},
+		&cli.StringFlag{
+			Name:  "oidc-discovery-file",
+			Usage: "Path of the OpenID provider discovery document of the policy issuer, used instead of the one the issuer serves",
+		},
+		&cli.BoolFlag{
+			Name:  "allow-remote-oidc-discovery",
+			Usage: "Allow fetching the discovery document of the policy OIDC issuer",
+		},
+	}
+
+	return acpTestCmd{
+		flags: append(globalFlags(), flgs...),
+	}
+}
+
+func (c acpTestCmd) build() *cli.Command {
+	return &cli.Command{
+		Name:   "test",
+		Usage:  "Evaluates a request against an access control policy, offline",
+		Flags:  c.flags,
+		Action: c.run,
+	}
+}
+
+func (c acpTestCmd) run(cliCtx *cli.Context) error {
+	logger.Setup(cliCtx.String(flagLogLevel), cliCtx.String(flagLogFormat))
+
+	name, cfg, err := loadPolicy(cliCtx.String("policy"))
+	if err != nil {
+		return err
+	}
+
+	if err = c.overrideConfig(cliCtx, cfg); err != nil {
+		return err
+	}
+
+	req, err := buildEvaluationRequest(cliCtx, name)
+	if err != nil {
+		return err
+	}
+
+	evaluation, err := auth.Evaluate(cliCtx.Context, name, cfg, req)
+	if err != nil {
+		return fmt.Errorf("evaluate request: %w", err)
+	}
+
+	return printEvaluation(cliCtx.App.Writer, name, cfg, cliCtx.String("token"), evaluation)
+}
+
+// overrideConfig replaces the values the policy can't resolve offline with the ones given through flags.
+func (c acpTestCmd) overrideConfig(cliCtx *cli.Context, cfg *acp.Config) error {
+	switch {
+	case cfg.JWT != nil:
+		return overrideJWTConfig(cliCtx, cfg.JWT)
+	case cfg.OIDC != nil:
+		return overrideOIDCConfig(cliCtx, cfg.OIDC)
+	case cfg.OIDCGoogle != nil:
+		return overrideOIDCConfig(cliCtx, &cfg.OIDCGoogle.Config)
+	default:
+		return nil
+	}
+}
+
+func overrideJWTConfig(cliCtx *cli.Context, cfg *acpjwt.Config) error {
+	if secret := cliCtx.String("signing-secret"); secret != "" {
+		cfg.SigningSecret = secret
+		cfg.SigningSecretRef = nil
+	}
+
+	if path := cliCtx.String("public-key-file"); path != "" {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read public key: %w", err)
+		}
+
+		cfg.PublicKey = string(key)
+		cfg.PublicKeyRef = nil
+	}
+
+	if path := cliCtx.String("jwks-file"); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("read JWKs file: %w", err)
+		}
+
+		cfg.JWKsFile = acpjwt.FileOrContent(path)
+		cfg.JWKsURL = ""
+		cfg.JWKsRef = nil
+	}
+
+	if cfg.JWKsURL != "" && !cliCtx.Bool("allow-remote-jwks") {
+		return errors.New("the policy uses a remote JWKs: use --jwks-file or --allow-remote-jwks")
+	}
+
+	for _, r := range []struct {
+		flag string
+		ref  *acpjwt.ValueReference
+	}{
+		{flag: "signing-secret", ref: cfg.SigningSecretRef},
+		{flag: "public-key-file", ref: cfg.PublicKeyRef},
+		{flag: "jwks-file", ref: cfg.JWKsRef},
+	} {
+		if r.ref != nil {
+			return fmt.Errorf("the policy references %s/%s, which can't be resolved offline: use --%s", r.ref.Namespace, r.ref.Name, r.flag)
+		}
+	}
+
+	return nil
+}
+
+func overrideOIDCConfig(cliCtx *cli.Context, cfg *oidc.Config) error {
+	cfg.ClientSecret = cliCtx.String("client-secret")
+	if cfg.ClientSecret == "" {
+		return errors.New("the client secret of the policy can't be resolved offline: use --client-secret")
+	}
+
+	cfg.Key = cliCtx.String("oidc-key")
+	if cfg.Key == "" {
+		key := make([]byte, 16)
+		if _, err := rand.Read(key); err != nil {
+			return fmt.Errorf("generate OIDC key: %w", err)
+		}
+		cfg.Key = hex.EncodeToString(key)
+	}
+
+	if path := cliCtx.String("oidc-discovery-file"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read OIDC discovery document: %w", err)
+		}
+
+		var discovery oidc.Discovery
+		if err = json.Unmarshal(b, &discovery); err != nil {
+			return fmt.Errorf("decode OIDC discovery document: %w", err)
+		}
+		cfg.Discovery = &discovery
+
+		return nil
+	}
+
+	if !cliCtx.Bool("allow-remote-oidc-discovery") {
+		return errors.New("the policy discovers its OIDC provider remotely: use --oidc-discovery-file or --allow-remote-oidc-discovery")
+	}
+
+	return nil
+}
+
+// loadPolicy reads the policy manifest at the given path and returns its canonical name and configuration.
+func loadPolicy(path string) (string, *acp.Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("read policy: %w", err)
+	}
+
+	var typeMeta metav1.TypeMeta
+	if err = yaml.Unmarshal(b, &typeMeta); err != nil {
+		return "", nil, fmt.Errorf("decode policy: %w", err)
+	}
+
+	switch typeMeta.Kind {
+	case "AccessControlPolicy":
+		var policy hubv1alpha1.AccessControlPolicy
+		if err = yaml.UnmarshalStrict(b, &policy); err != nil {
+			return "", nil, fmt.Errorf("decode policy: %w", err)
+		}
+
+		return policy.Name, acp.ConfigFromPolicy(&policy), nil
+
+	case "NamespacedAccessControlPolicy":
+		var policy hubv1alpha1.NamespacedAccessControlPolicy
+		if err = yaml.UnmarshalStrict(b, &policy); err != nil {
+			return "", nil, fmt.Errorf("decode policy: %w", err)
+		}
+
+		return acp.CanonicalName(policy.Name, policy.Namespace), acp.ConfigFromNamespacedPolicy(&policy), nil
+
+	default:
+		return "", nil, fmt.Errorf("unsupported kind %q", typeMeta.Kind)
+	}
+}
+
+// buildEvaluationRequest builds the request the reverse proxy would send to the auth server for the request described
+// by the flags.
+func buildEvaluationRequest(cliCtx *cli.Context, name string) (*http.Request, error) {
+	u, err := url.Parse(cliCtx.String("url"))
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(cliCtx.Context, http.MethodGet, "http://localhost"+acp.AuthServerPath(name), http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+
+	for _, header := range cliCtx.StringSlice("header") {
+		key, value, ok := strings.Cut(header, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q: must be formatted as 'Name: value'", header)
+		}
+		req.Header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+
+	if token := cliCtx.String("token"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	if user := cliCtx.String("user"); user != "" {
+		username, password, _ := strings.Cut(user, ":")
+		req.SetBasicAuth(username, password)
+	}
+
+	req.Header.Set("X-Forwarded-Method", cliCtx.String("method"))
+	req.Header.Set("X-Forwarded-Proto", u.Scheme)
+	req.Header.Set("X-Forwarded-Host", u.Host)
+	req.Header.Set("X-Forwarded-Uri", u.RequestURI())
+	req.Header.Set("X-Forwarded-For", "127.0.0.1")
+
+	return req, nil
+}
+
+func printEvaluation(w io.Writer, name string, cfg *acp.Config, token string, evaluation *auth.Evaluation) error {
+	p := &printer{w: w}
+
+	p.printf("Policy:    %s\n", name)
+	if cfg.EnforcementMode == acp.EnforcementModeAudit {
+		p.printf("Mode:      audit, requests are let through whatever the decision\n")
+	}
+	p.printf("Decision:  %s (%d)\n", evaluation.Result, evaluation.StatusCode)
+	if evaluation.Reason != "" {
+		p.printf("Reason:    %s\n", evaluation.Reason)
+	}
+	if evaluation.Subject != "" {
+		p.printf("Subject:   %s\n", evaluation.Subject)
+	}
+	if evaluation.Email != "" {
+		p.printf("Email:     %s\n", evaluation.Email)
+	}
+	if len(evaluation.Groups) > 0 {
+		p.printf("Groups:    %s\n", strings.Join(evaluation.Groups, ", "))
+	}
+
+	if claims := claimsExpression(cfg); claims != "" {
+		p.printf("Claims:    %s\n", claims)
+	}
+
+	if token != "" {
+		tokenClaims := jwt.MapClaims{}
+		if _, _, err := new(jwt.Parser).ParseUnverified(token, tokenClaims); err == nil {
+			b, err := json.MarshalIndent(tokenClaims, "  ", "  ")
+			if err != nil {
+				return fmt.Errorf("encode token claims: %w", err)
+			}
+			p.printf("Token claims:\n  %s\n", b)
+		}
+	}
+
+	if len(evaluation.Header) > 0 {
+		if evaluation.Result == "allowed" {
+			p.printf("Forwarded headers:\n")
+		} else {
+			p.printf("Response headers:\n")
+		}
+
+		names := make([]string, 0, len(evaluation.Header))
+		for name := range evaluation.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			for _, value := range evaluation.Header[name] {
+				p.printf("  %s: %s\n", name, value)
+			}
+		}
+	}
+
+	return p.err
+}
+
+func claimsExpression(cfg *acp.Config) string {
+	switch {
+	case cfg.JWT != nil:
+		return cfg.JWT.Claims
+	case cfg.OIDC != nil:
+		return cfg.OIDC.Claims
+	case cfg.OIDCGoogle != nil:
+		return cfg.OIDCGoogle.Claims
+	default:
+		return ""
+	}
+}
+
+// printer writes formatted output, keeping the first error met.
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	if p.err != nil {
+		return
+	}
+
+	_, p.err = fmt.Fprintf(p.w, format, args...)
+}
@@ -19,27 +19,41 @@ package main
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	stdlog "log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/audit"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/auth"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/identity"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
 	hubclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/clientset/versioned"
 	hubinformer "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/informers/externalversions"
 	"github.com/traefik/hub-agent-kubernetes/pkg/kube"
 	"github.com/traefik/hub-agent-kubernetes/pkg/logger"
 	"github.com/traefik/hub-agent-kubernetes/pkg/version"
 	"github.com/urfave/cli/v2"
+	corev1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
+const identityKeySecretName = "hub-identity-key"
+
 type authServerCmd struct {
 	flags []cli.Flag
 }
@@ -52,6 +66,80 @@ func newAuthServerCmd() authServerCmd {
 			EnvVars: []string{"AUTH_SERVER_LISTEN_ADDR"},
 			Value:   "0.0.0.0:80",
 		},
+		&cli.StringFlag{
+			Name:    "metrics-addr",
+			Usage:   "Address on which the auth server exposes its Prometheus metrics",
+			EnvVars: []string{"AUTH_SERVER_METRICS_ADDR"},
+			Value:   "0.0.0.0:9090",
+		},
+		&cli.StringFlag{
+			Name:    "identity-token-issuer",
+			Usage:   "Issuer of the identity tokens minted for upstream services",
+			EnvVars: []string{"AUTH_SERVER_IDENTITY_TOKEN_ISSUER"},
+			Value:   "hub-agent",
+		},
+		&cli.DurationFlag{
+			Name:    "identity-token-ttl",
+			Usage:   "Lifetime of the identity tokens minted for upstream services",
+			EnvVars: []string{"AUTH_SERVER_IDENTITY_TOKEN_TTL"},
+			Value:   time.Minute,
+		},
+		&cli.StringSliceFlag{
+			Name:    "audit-sinks",
+			Usage:   "Sinks audit events are written to, among stdout, file and webhook. Auditing is disabled if none is set",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_SINKS"},
+		},
+		&cli.Float64Flag{
+			Name:    "audit-sample-rate",
+			Usage:   "Ratio, between 0 and 1, of allowed requests to audit. Denied requests are always audited",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_SAMPLE_RATE"},
+			Value:   1,
+		},
+		&cli.StringSliceFlag{
+			Name:    "audit-trusted-proxies",
+			Usage:   "IPs and CIDRs of the proxies in front of the reverse proxy, skipped when reading the client IP of audit events from the X-Forwarded-For header",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_TRUSTED_PROXIES"},
+		},
+		&cli.StringSliceFlag{
+			Name:    "audit-redact-fields",
+			Usage:   "Audit event fields to redact, among subject, email, groups, method, host, uri, clientIp and reason",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_REDACT_FIELDS"},
+		},
+		&cli.StringFlag{
+			Name:    "audit-file-path",
+			Usage:   "Path of the file audit events are written to by the file sink",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_FILE_PATH"},
+			Value:   "/var/log/hub/audit.log",
+		},
+		&cli.Int64Flag{
+			Name:    "audit-file-max-size",
+			Usage:   "Size, in megabytes, above which the audit file is rotated",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_FILE_MAX_SIZE"},
+			Value:   100,
+		},
+		&cli.IntFlag{
+			Name:    "audit-file-max-backups",
+			Usage:   "Number of rotated audit files to keep",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_FILE_MAX_BACKUPS"},
+			Value:   3,
+		},
+		&cli.StringFlag{
+			Name:    "audit-webhook-url",
+			Usage:   "URL batches of audit events are posted to by the webhook sink",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_WEBHOOK_URL"},
+		},
+		&cli.IntFlag{
+			Name:    "audit-webhook-batch-size",
+			Usage:   "Maximum number of audit events sent in a single webhook request",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_WEBHOOK_BATCH_SIZE"},
+			Value:   100,
+		},
+		&cli.DurationFlag{
+			Name:    "audit-webhook-flush-interval",
+			Usage:   "Interval at which pending audit events are sent to the webhook",
+			EnvVars: []string{"AUTH_SERVER_AUDIT_WEBHOOK_FLUSH_INTERVAL"},
+			Value:   5 * time.Second,
+		},
 	}
 
 	flgs = append(flgs, globalFlags()...)
@@ -95,11 +183,39 @@ func (c authServerCmd) run(cliCtx *cli.Context) error {
 		return fmt.Errorf("read key: %w", err)
 	}
 
+	identityKey, err := readIdentityKey(cliCtx, kubeClientSet)
+	if err != nil {
+		return fmt.Errorf("read identity key: %w", err)
+	}
+
+	minter, err := identity.NewMinter(identityKey, cliCtx.String("identity-token-issuer"), cliCtx.Duration("identity-token-ttl"))
+	if err != nil {
+		return fmt.Errorf("create identity token minter: %w", err)
+	}
+
+	auditor, err := newAuditor(cliCtx)
+	if err != nil {
+		return fmt.Errorf("create auditor: %w", err)
+	}
+
+	statusUpdater := auth.NewStatusUpdater(hubClientSet)
+	go statusUpdater.Run(cliCtx.Context)
+
+	// Among the auth server replicas, only the elected one writes policy statuses.
+	if err = runLeaderElection(cliCtx.Context, kubeClientSet, "hub-auth-server-status", statusUpdater); err != nil {
+		return fmt.Errorf("run status leader election: %w", err)
+	}
+
+	kubeInformer := informers.NewSharedInformerFactory(kubeClientSet, 5*time.Minute)
+	secrets := kubeInformer.Core().V1().Secrets()
+	configMaps := kubeInformer.Core().V1().ConfigMaps()
+
 	switcher := auth.NewHandlerSwitcher()
-	acpWatcher := auth.NewWatcher(switcher, key)
+	acpWatcher := auth.NewWatcher(switcher, key, minter, auditor, statusUpdater, secrets.Lister(), configMaps.Lister())
 
 	hubInformer := hubinformer.NewSharedInformerFactory(hubClientSet, 5*time.Minute)
 	hubInformer.Hub().V1alpha1().AccessControlPolicies().Informer().AddEventHandler(acpWatcher)
+	hubInformer.Hub().V1alpha1().NamespacedAccessControlPolicies().Informer().AddEventHandler(acpWatcher)
 	hubInformer.Start(cliCtx.Context.Done())
 
 	for t, ok := range hubInformer.WaitForCacheSync(cliCtx.Context.Done()) {
@@ -108,8 +224,8 @@ func (c authServerCmd) run(cliCtx *cli.Context) error {
 		}
 	}
 
-	kubeInformer := informers.NewSharedInformerFactory(kubeClientSet, 5*time.Minute)
-	kubeInformer.Core().V1().Secrets().Informer().AddEventHandler(acpWatcher)
+	secrets.Informer().AddEventHandler(acpWatcher)
+	configMaps.Informer().AddEventHandler(acpWatcher)
 	kubeInformer.Start(cliCtx.Context.Done())
 
 	for t, ok := range kubeInformer.WaitForCacheSync(cliCtx.Context.Done()) {
@@ -131,6 +247,7 @@ func (c authServerCmd) run(cliCtx *cli.Context) error {
 		rw.WriteHeader(http.StatusOK)
 	}))
 
+	mux.Handle(identity.JWKSPath, minter)
 	mux.Handle("/", switcher)
 
 	server := &http.Server{
@@ -150,11 +267,40 @@ func (c authServerCmd) run(cliCtx *cli.Context) error {
 		close(srvDone)
 	}()
 
+	metricsAddr := cliCtx.String("metrics-addr")
+
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metrics.Handler())
+
+	metricsServer := &http.Server{
+		Addr:              metricsAddr,
+		Handler:           metricsMux,
+		ErrorLog:          stdlog.New(log.Logger.Level(zerolog.DebugLevel), "", 0),
+		ReadHeaderTimeout: 2 * time.Second,
+	}
+
+	metricsSrvDone := make(chan struct{})
+
+	go func() {
+		log.Info().Str("addr", metricsAddr).Msg("Starting auth server metrics")
+		if metricsErr := metricsServer.ListenAndServe(); !errors.Is(metricsErr, http.ErrServerClosed) {
+			log.Err(metricsErr).Msg("Unable to listen and serve metrics requests")
+		}
+		close(metricsSrvDone)
+	}()
+
 	select {
 	case <-cliCtx.Context.Done():
 		gracefulCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
 
+		if err = metricsServer.Shutdown(gracefulCtx); err != nil {
+			log.Error().Err(err).Msg("Failed to shutdown auth server metrics gracefully")
+			if err = metricsServer.Close(); err != nil {
+				return fmt.Errorf("close auth server metrics: %w", err)
+			}
+		}
+
 		if err = server.Shutdown(gracefulCtx); err != nil {
 			log.Error().Err(err).Msg("Failed to shutdown auth server gracefully")
 			if err = server.Close(); err != nil {
@@ -163,6 +309,8 @@ func (c authServerCmd) run(cliCtx *cli.Context) error {
 		}
 	case <-srvDone:
 		return errors.New("auth server stopped")
+	case <-metricsSrvDone:
+		return errors.New("auth server metrics stopped")
 	}
 
 	return nil
@@ -184,3 +332,104 @@ func readKey(cliCtx *cli.Context, client clientset.Interface) (string, error) {
 
 	return fmt.Sprintf("%x", sha256.Sum256(key))[:32], nil
 }
+
+// newAuditor returns the audit logger configured by the CLI flags, or nil if auditing is disabled.
+func newAuditor(cliCtx *cli.Context) (*audit.Logger, error) {
+	sinkNames := cliCtx.StringSlice("audit-sinks")
+	if len(sinkNames) == 0 {
+		return nil, nil
+	}
+
+	var sinks []audit.Sink
+	for _, name := range sinkNames {
+		switch name {
+		case "stdout":
+			sinks = append(sinks, audit.NewWriterSink(os.Stdout))
+
+		case "file":
+			maxSize := cliCtx.Int64("audit-file-max-size") * 1024 * 1024
+			sink, err := audit.NewFileSink(cliCtx.String("audit-file-path"), maxSize, cliCtx.Int("audit-file-max-backups"))
+			if err != nil {
+				return nil, fmt.Errorf("create file sink: %w", err)
+			}
+			sinks = append(sinks, sink)
+
+		case "webhook":
+			client := &http.Client{Timeout: 10 * time.Second}
+			sink, err := audit.NewWebhookSink(client, cliCtx.String("audit-webhook-url"),
+				cliCtx.Int("audit-webhook-batch-size"), cliCtx.Duration("audit-webhook-flush-interval"))
+			if err != nil {
+				return nil, fmt.Errorf("create webhook sink: %w", err)
+			}
+			go sink.Run(cliCtx.Context)
+
+			sinks = append(sinks, sink)
+
+		default:
+			return nil, fmt.Errorf("unknown audit sink %q", name)
+		}
+	}
+
+	return audit.NewLogger(audit.Config{
+		SampleRate:     cliCtx.Float64("audit-sample-rate"),
+		RedactFields:   cliCtx.StringSlice("audit-redact-fields"),
+		TrustedProxies: cliCtx.StringSlice("audit-trusted-proxies"),
+	}, sinks...)
+}
+
+// readIdentityKey reads the key used to sign identity tokens. It is stored in a Secret, shared by all auth server
+// replicas, which is created on first use.
+func readIdentityKey(cliCtx *cli.Context, client clientset.Interface) (*ecdsa.PrivateKey, error) {
+	ctx, cancel := context.WithTimeout(cliCtx.Context, 5*time.Second)
+	defer cancel()
+
+	secrets := client.CoreV1().Secrets(currentNamespace())
+
+	secret, err := secrets.Get(ctx, identityKeySecretName, metav1.GetOptions{})
+	if kerror.IsNotFound(err) {
+		secret, err = createIdentityKeySecret(ctx, secrets)
+		if kerror.IsAlreadyExists(err) {
+			// Another replica created the secret in the meantime.
+			secret, err = secrets.Get(ctx, identityKeySecretName, metav1.GetOptions{})
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get secret: %w", err)
+	}
+
+	block, _ := pem.Decode(secret.Data["key"])
+	if block == nil {
+		return nil, errors.New("key not found")
+	}
+
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse key: %w", err)
+	}
+
+	return key, nil
+}
+
+func createIdentityKeySecret(ctx context.Context, secrets typedcorev1.SecretInterface) (*corev1.Secret, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal key: %w", err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: identityKeySecretName,
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"key": pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}),
+		},
+	}
+
+	return secrets.Create(ctx, secret, metav1.CreateOptions{})
+}
@@ -47,3 +47,20 @@ func TestReadKey(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "5e78863ed1ffb9fc66b1d61634b126bf", key)
 }
+
+func TestReadIdentityKey(t *testing.T) {
+	cliCtx := &cli.Context{Context: context.Background()}
+	client := kubemock.NewSimpleClientset()
+
+	key, err := readIdentityKey(cliCtx, client)
+	require.NoError(t, err)
+
+	secret, err := client.CoreV1().Secrets("default").Get(context.Background(), identityKeySecretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotEmpty(t, secret.Data["key"])
+
+	// The key is read back from the existing Secret.
+	got, err := readIdentityKey(cliCtx, client)
+	require.NoError(t, err)
+	require.True(t, key.Equal(got))
+}
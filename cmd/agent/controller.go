@@ -27,6 +27,7 @@ import (
 	"github.com/ettle/strcase"
 	"github.com/rs/zerolog/log"
 	traefikclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned"
+	traefikioclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned"
 	"github.com/traefik/hub-agent-kubernetes/pkg/heartbeat"
 	"github.com/traefik/hub-agent-kubernetes/pkg/kube"
 	"github.com/traefik/hub-agent-kubernetes/pkg/logger"
@@ -123,6 +124,11 @@ func (c controllerCmd) run(cliCtx *cli.Context) error {
 		return fmt.Errorf("create Traefik client set: %w", err)
 	}
 
+	traefikIOClientSet, err := traefikioclientset.NewForConfig(kubeCfg)
+	if err != nil {
+		return fmt.Errorf("create Traefik (traefik.io) client set: %w", err)
+	}
+
 	platformClient, err := platform.NewClient(platformURL, token)
 	if err != nil {
 		return fmt.Errorf("build platform client: %w", err)
@@ -137,7 +143,7 @@ func (c controllerCmd) run(cliCtx *cli.Context) error {
 		return fmt.Errorf("setup agent: %w", err)
 	}
 
-	topoFetcher, err := state.NewFetcher(cliCtx.Context, kubeClient, traefikClientSet)
+	topoFetcher, err := state.NewFetcher(cliCtx.Context, kubeClient, traefikClientSet, traefikIOClientSet)
 	if err != nil {
 		return err
 	}
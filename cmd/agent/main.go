@@ -48,9 +48,11 @@ func run() error {
 		Version: version.String(),
 		Commands: []*cli.Command{
 			newControllerCmd().build(),
+			newACPCmd().build(),
 			newAuthServerCmd().build(),
 			newRefreshConfigCmd().build(),
 			newTunnelCmd().build(),
+			newValidateCmd().build(),
 			newVersionCmd().build(),
 		},
 	}
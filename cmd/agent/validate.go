@@ -0,0 +1,112 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/traefik/hub-agent-kubernetes/pkg/logger"
+	"github.com/traefik/hub-agent-kubernetes/pkg/validation"
+	"github.com/urfave/cli/v2"
+)
+
+type validateCmd struct {
+	flags []cli.Flag
+}
+
+func newValidateCmd() validateCmd {
+	flgs := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "Format of the report (text, json or junit)",
+			Value: validation.FormatText,
+		},
+		&cli.StringSliceFlag{
+			Name:  "ingress-class",
+			Usage: "IngressClass existing in the cluster, formatted as name=controller",
+		},
+		&cli.StringFlag{
+			Name:  "default-ingress-class",
+			Usage: "Name of the default IngressClass among the ones given with --ingress-class",
+		},
+	}
+
+	return validateCmd{
+		flags: append(globalFlags(), flgs...),
+	}
+}
+
+func (c validateCmd) build() *cli.Command {
+	return &cli.Command{
+		Name:      "validate",
+		Usage:     "Validates the Hub resources, and the resources using them, of a directory of manifests, offline",
+		ArgsUsage: "[directory]",
+		Flags:     c.flags,
+		Action:    c.run,
+	}
+}
+
+func (c validateCmd) run(cliCtx *cli.Context) error {
+	logger.Setup(cliCtx.String(flagLogLevel), cliCtx.String(flagLogFormat))
+
+	dir := cliCtx.Args().First()
+	if dir == "" {
+		dir = "."
+	}
+
+	opts, err := validationOptions(cliCtx)
+	if err != nil {
+		return err
+	}
+
+	results, err := validation.Validate(dir, opts)
+	if err != nil {
+		return err
+	}
+
+	if err = validation.WriteReport(cliCtx.App.Writer, cliCtx.String("format"), results); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	if failures := validation.Failures(results); failures > 0 {
+		return fmt.Errorf("%d resource(s) failed validation", failures)
+	}
+
+	return nil
+}
+
+func validationOptions(cliCtx *cli.Context) (validation.Options, error) {
+	var opts validation.Options
+
+	defaultClass := cliCtx.String("default-ingress-class")
+	for _, class := range cliCtx.StringSlice("ingress-class") {
+		name, controller, ok := strings.Cut(class, "=")
+		if !ok || name == "" || controller == "" {
+			return validation.Options{}, fmt.Errorf("invalid ingress class %q: must be formatted as name=controller", class)
+		}
+
+		opts.IngressClasses = append(opts.IngressClasses, validation.IngressClass{
+			Name:       name,
+			Controller: controller,
+			Default:    name == defaultClass,
+		})
+	}
+
+	return opts, nil
+}
@@ -19,6 +19,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	stdlog "log"
@@ -35,22 +36,32 @@ import (
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/ingclass"
+	admissionmetrics "github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/metrics"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/nsdefault"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/reviewer"
 	hubclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/clientset/versioned"
 	hubinformer "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/informers/externalversions"
 	traefikclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned"
+	traefikioclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned"
 	"github.com/traefik/hub-agent-kubernetes/pkg/edgeingress"
 	edgeadmission "github.com/traefik/hub-agent-kubernetes/pkg/edgeingress/admission"
 	"github.com/traefik/hub-agent-kubernetes/pkg/kube"
 	"github.com/traefik/hub-agent-kubernetes/pkg/kubevers"
 	"github.com/traefik/hub-agent-kubernetes/pkg/platform"
+	"github.com/traefik/hub-agent-kubernetes/pkg/webhookcert"
 	"github.com/urfave/cli/v2"
+	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -58,6 +69,16 @@ const (
 	flagACPServerCertificate    = "acp-server.cert"
 	flagACPServerKey            = "acp-server.key"
 	flagACPServerAuthServerAddr = "acp-server.auth-server-addr"
+	flagACPServerMetricsAddr    = "acp-server.metrics-addr"
+	flagACPServerSelfSignedCert = "acp-server.self-signed-cert"
+	flagACPServerCertSecret     = "acp-server.cert-secret"
+	flagACPServerServiceName    = "acp-server.service-name"
+	flagACPReconcileInterval    = "acp-server.reconcile-interval"
+	flagACPReconcileSelfHeal    = "acp-server.reconcile-self-heal"
+	flagACPServerOTLPEndpoint   = "acp-server.otlp-endpoint"
+	flagKongAuthPlugin          = "acp-server.kong-auth-plugin"
+	flagKongAuthPluginAddr      = "acp-server.kong-auth-plugin-address-field"
+	flagKongAuthPluginHeaders   = "acp-server.kong-auth-plugin-headers-field"
 	flagIngressClassName        = "ingress-class-name"
 	flagTraefikEntryPoint       = "traefik.entryPoint"
 )
@@ -82,12 +103,68 @@ func acpFlags() []cli.Flag {
 			EnvVars: []string{strcase.ToSNAKE(flagACPServerKey)},
 			Value:   "/var/run/hub-agent-kubernetes/key.pem",
 		},
+		&cli.BoolFlag{
+			Name:    flagACPServerSelfSignedCert,
+			Usage:   "Generate and rotate a self-signed certificate for the ACP server, and inject its CA bundle into the webhook configurations, instead of using the given certificate and key",
+			EnvVars: []string{strcase.ToSNAKE(flagACPServerSelfSignedCert)},
+		},
+		&cli.StringFlag{
+			Name:    flagACPServerCertSecret,
+			Usage:   "Name of the Secret storing the self-signed certificate of the ACP server",
+			EnvVars: []string{strcase.ToSNAKE(flagACPServerCertSecret)},
+			Value:   "hub-agent-cert",
+		},
+		&cli.StringFlag{
+			Name:    flagACPServerServiceName,
+			Usage:   "Name of the Service exposing the ACP server, which the self-signed certificate is issued for",
+			EnvVars: []string{strcase.ToSNAKE(flagACPServerServiceName)},
+			Value:   "hub-agent",
+		},
 		&cli.StringFlag{
 			Name:    flagACPServerAuthServerAddr,
 			Usage:   "Address the ACP server can reach the auth server on",
 			EnvVars: []string{strcase.ToSNAKE(flagACPServerAuthServerAddr)},
 			Value:   "http://hub-agent-auth-server.hub.svc.cluster.local",
 		},
+		&cli.StringFlag{
+			Name:    flagACPServerMetricsAddr,
+			Usage:   "Address on which the ACP server exposes its Prometheus metrics",
+			EnvVars: []string{strcase.ToSNAKE(flagACPServerMetricsAddr)},
+			Value:   "0.0.0.0:9090",
+		},
+		&cli.DurationFlag{
+			Name:    flagACPReconcileInterval,
+			Usage:   "Interval at which the ACP wiring of ingresses and IngressRoutes is checked for drifts",
+			EnvVars: []string{strcase.ToSNAKE(flagACPReconcileInterval)},
+			Value:   5 * time.Minute,
+		},
+		&cli.BoolFlag{
+			Name:    flagACPReconcileSelfHeal,
+			Usage:   "Repair the drifting ACP wiring of ingresses and IngressRoutes instead of only reporting it",
+			EnvVars: []string{strcase.ToSNAKE(flagACPReconcileSelfHeal)},
+		},
+		&cli.StringFlag{
+			Name:    flagACPServerOTLPEndpoint,
+			Usage:   "URL of the OTLP/HTTP collector receiving the admission request traces, e.g. http://otel-collector:4318. Tracing is disabled unless it is set",
+			EnvVars: []string{strcase.ToSNAKE(flagACPServerOTLPEndpoint)},
+		},
+		&cli.StringFlag{
+			Name:    flagKongAuthPlugin,
+			Usage:   "Name of the Kong plugin delegating authentication to the auth server. ACPs can't be enforced on Kong ingresses unless it is set",
+			EnvVars: []string{strcase.ToSNAKE(flagKongAuthPlugin)},
+		},
+		&cli.StringFlag{
+			Name:    flagKongAuthPluginAddr,
+			Usage:   "Kong plugin configuration field set to the auth server URL of the ACP",
+			EnvVars: []string{strcase.ToSNAKE(flagKongAuthPluginAddr)},
+			Value:   "address",
+		},
+		&cli.StringFlag{
+			Name:    flagKongAuthPluginHeaders,
+			Usage:   "Kong plugin configuration field set to the auth server response headers to forward, if any",
+			EnvVars: []string{strcase.ToSNAKE(flagKongAuthPluginHeaders)},
+			Value:   "auth_response_headers",
+		},
 		&cli.StringFlag{
 			Name:    flagIngressClassName,
 			Usage:   "The ingress class name used for ingresses managed by Hub",
@@ -108,15 +185,43 @@ func webhookAdmission(ctx context.Context, cliCtx *cli.Context, platformClient *
 		certFile       = cliCtx.String(flagACPServerCertificate)
 		keyFile        = cliCtx.String(flagACPServerKey)
 		authServerAddr = cliCtx.String(flagACPServerAuthServerAddr)
+		metricsAddr    = cliCtx.String(flagACPServerMetricsAddr)
+		reconcilerCfg  = admission.ReconcilerConfig{
+			Interval: cliCtx.Duration(flagACPReconcileInterval),
+			SelfHeal: cliCtx.Bool(flagACPReconcileSelfHeal),
+		}
+		kongPluginCfg = reviewer.KongPluginConfig{
+			Name:                 cliCtx.String(flagKongAuthPlugin),
+			AddressField:         cliCtx.String(flagKongAuthPluginAddr),
+			ResponseHeadersField: cliCtx.String(flagKongAuthPluginHeaders),
+		}
 	)
 
 	if _, err := url.Parse(authServerAddr); err != nil {
 		return fmt.Errorf("invalid auth server address: %w", err)
 	}
 
+	if kongPluginCfg.Name != "" && kongPluginCfg.AddressField == "" {
+		return fmt.Errorf("missing %s: required along with %s", flagKongAuthPluginAddr, flagKongAuthPlugin)
+	}
+
+	// Admission spans, and the platform requests they lead to, are exported only if a collector is configured.
+	shutdownTracing, err := setupTracing(ctx, cliCtx.String(flagACPServerOTLPEndpoint))
+	if err != nil {
+		return fmt.Errorf("setup tracing: %w", err)
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if shutdownErr := shutdownTracing(shutdownCtx); shutdownErr != nil {
+			log.Error().Err(shutdownErr).Msg("Unable to flush admission traces")
+		}
+	}()
+
 	ingressClassName := cliCtx.String(flagIngressClassName)
 	traefikEntryPoint := cliCtx.String(flagTraefikEntryPoint)
-	acpAdmission, edgeIngressAdmission, err := setupAdmissionHandlers(ctx, platformClient, authServerAddr, ingressClassName, traefikEntryPoint)
+	acpAdmission, edgeIngressAdmission, err := setupAdmissionHandlers(ctx, platformClient, authServerAddr, ingressClassName, traefikEntryPoint, reconcilerCfg, kongPluginCfg)
 	if err != nil {
 		return fmt.Errorf("create admission handler: %w", err)
 	}
@@ -124,9 +229,9 @@ func webhookAdmission(ctx context.Context, cliCtx *cli.Context, platformClient *
 	webAdmissionACP := admission.NewACPHandler(platformClient)
 
 	router := chi.NewRouter()
-	router.Handle("/edge-ingress", edgeIngressAdmission)
-	router.Handle("/ingress", acpAdmission)
-	router.Handle("/acp", webAdmissionACP)
+	router.Handle("/edge-ingress", admissionmetrics.InstrumentHandler("edge-ingress", edgeIngressAdmission))
+	router.Handle("/ingress", admissionmetrics.InstrumentHandler("ingress", acpAdmission))
+	router.Handle("/acp", admissionmetrics.InstrumentHandler("acp", webAdmissionACP))
 
 	server := &http.Server{
 		Addr:              listenAddr,
@@ -134,6 +239,23 @@ func webhookAdmission(ctx context.Context, cliCtx *cli.Context, platformClient *
 		ErrorLog:          stdlog.New(log.Logger.Level(zerolog.DebugLevel), "", 0),
 		ReadHeaderTimeout: 2 * time.Second,
 	}
+
+	if cliCtx.Bool(flagACPServerSelfSignedCert) {
+		certMgr, err := setupWebhookCertManager(ctx, cliCtx)
+		if err != nil {
+			return fmt.Errorf("setup webhook certificate manager: %w", err)
+		}
+
+		go certMgr.Run(ctx)
+
+		// Certificates are served by the manager, which reloads them on renewal.
+		server.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certMgr.GetCertificate,
+		}
+		certFile, keyFile = "", ""
+	}
+
 	srvDone := make(chan struct{})
 
 	go func() {
@@ -144,11 +266,37 @@ func webhookAdmission(ctx context.Context, cliCtx *cli.Context, platformClient *
 		close(srvDone)
 	}()
 
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", admissionmetrics.Handler())
+
+	metricsServer := &http.Server{
+		Addr:              metricsAddr,
+		Handler:           metricsMux,
+		ErrorLog:          stdlog.New(log.Logger.Level(zerolog.DebugLevel), "", 0),
+		ReadHeaderTimeout: 2 * time.Second,
+	}
+	metricsSrvDone := make(chan struct{})
+
+	go func() {
+		log.Info().Str("addr", metricsAddr).Msg("Starting admission server metrics")
+		if metricsErr := metricsServer.ListenAndServe(); !errors.Is(metricsErr, http.ErrServerClosed) {
+			log.Err(metricsErr).Msg("Unable to listen and serve metrics requests")
+		}
+		close(metricsSrvDone)
+	}()
+
 	select {
 	case <-ctx.Done():
 		gracefulCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
 
+		if err = metricsServer.Shutdown(gracefulCtx); err != nil {
+			log.Error().Err(err).Msg("Failed to shutdown admission server metrics gracefully")
+			if err = metricsServer.Close(); err != nil {
+				return fmt.Errorf("close admission server metrics: %w", err)
+			}
+		}
+
 		if err = server.Shutdown(gracefulCtx); err != nil {
 			log.Error().Err(err).Msg("Failed to shutdown admission server gracefully")
 			if err = server.Close(); err != nil {
@@ -158,12 +306,41 @@ func webhookAdmission(ctx context.Context, cliCtx *cli.Context, platformClient *
 		log.Info().Msg("Successfully shutdown admission server")
 	case <-srvDone:
 		return errors.New("admission server stopped")
+	case <-metricsSrvDone:
+		return errors.New("admission server metrics stopped")
 	}
 
 	return nil
 }
 
-func setupAdmissionHandlers(ctx context.Context, platformClient *platform.Client, authServerAddr, ingressClassName, traefikEntryPoint string) (acpHdl, edgeIngressHdl http.Handler, err error) {
+func setupWebhookCertManager(ctx context.Context, cliCtx *cli.Context) (*webhookcert.Manager, error) {
+	config, err := kube.InClusterConfigWithRetrier(2)
+	if err != nil {
+		return nil, fmt.Errorf("create Kubernetes in-cluster configuration: %w", err)
+	}
+
+	clientSet, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("create Kubernetes client set: %w", err)
+	}
+
+	certMgr := webhookcert.NewManager(clientSet, webhookcert.Config{
+		Namespace:    currentNamespace(),
+		SecretName:   cliCtx.String(flagACPServerCertSecret),
+		ServiceName:  cliCtx.String(flagACPServerServiceName),
+		Validity:     365 * 24 * time.Hour,
+		RenewBefore:  30 * 24 * time.Hour,
+		SyncInterval: time.Hour,
+	})
+
+	if err = certMgr.Setup(ctx); err != nil {
+		return nil, err
+	}
+
+	return certMgr, nil
+}
+
+func setupAdmissionHandlers(ctx context.Context, platformClient *platform.Client, authServerAddr, ingressClassName, traefikEntryPoint string, reconcilerCfg admission.ReconcilerConfig, kongPluginCfg reviewer.KongPluginConfig) (acpHdl, edgeIngressHdl http.Handler, err error) {
 	config, err := kube.InClusterConfigWithRetrier(2)
 	if err != nil {
 		return nil, nil, fmt.Errorf("create Kubernetes in-cluster configuration: %w", err)
@@ -194,20 +371,49 @@ func setupAdmissionHandlers(ctx context.Context, platformClient *platform.Client
 	kubeInformer := informers.NewSharedInformerFactory(clientSet, 5*time.Minute)
 	hubInformer := hubinformer.NewSharedInformerFactory(hubClientSet, 5*time.Minute)
 
-	ingressUpdater := admission.NewIngressUpdater(kubeInformer, clientSet, kubeVers.GitVersion)
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create Kubernetes dynamic client: %w", err)
+	}
+
+	ingressUpdater := admission.NewIngressUpdater(kubeInformer, clientSet, dynClient, kubeVers.GitVersion)
 
 	go ingressUpdater.Run(ctx)
 
-	acpEventHandler := admission.NewEventHandler(ingressUpdater)
+	traefikClientSet, err := traefikclientset.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create Traefik client set: %w", err)
+	}
+
+	traefikIOClientSet, err := traefikioclientset.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create Traefik (traefik.io) client set: %w", err)
+	}
+
+	traefikGroup, err := kube.TraefikAPIGroup(clientSet.Discovery())
+	if err != nil {
+		return nil, nil, fmt.Errorf("detect Traefik API group: %w", err)
+	}
+	log.Info().Str("group", traefikGroup).Msg("Detected Traefik API group")
+
+	traefikMiddlewares := kube.TraefikMiddlewares(traefikGroup, traefikClientSet, traefikIOClientSet)
+
+	mdlwrCollector := admission.NewMiddlewareCollector(kubeInformer, dynClient, traefikMiddlewares, authServerAddr, kubeVers.GitVersion, 10*time.Minute)
+
+	go mdlwrCollector.Run(ctx)
+
+	acpEventHandler := admission.NewEventHandler(ingressUpdater, mdlwrCollector)
 	ingClassWatcher := ingclass.NewWatcher()
+	nsDefaultWatcher := nsdefault.NewWatcher(ingressUpdater)
 
-	err = startKubeInformer(ctx, kubeVers.GitVersion, kubeInformer, ingClassWatcher)
+	err = startKubeInformer(ctx, kubeVers.GitVersion, kubeInformer, ingClassWatcher, nsDefaultWatcher)
 	if err != nil {
 		return nil, nil, fmt.Errorf("start kube informer: %w", err)
 	}
 
 	hubInformer.Hub().V1alpha1().IngressClasses().Informer().AddEventHandler(ingClassWatcher)
 	hubInformer.Hub().V1alpha1().AccessControlPolicies().Informer().AddEventHandler(acpEventHandler)
+	hubInformer.Hub().V1alpha1().NamespacedAccessControlPolicies().Informer().AddEventHandler(acpEventHandler)
 	hubInformer.Hub().V1alpha1().EdgeIngresses().Informer()
 
 	hubInformer.Start(ctx.Done())
@@ -223,11 +429,6 @@ func setupAdmissionHandlers(ctx context.Context, platformClient *platform.Client
 		acpWatcher.Run(ctx)
 	}()
 
-	traefikClientSet, err := traefikclientset.NewForConfig(config)
-	if err != nil {
-		return nil, nil, fmt.Errorf("create Traefik client set: %w", err)
-	}
-
 	watcherCfg := edgeingress.WatcherConfig{
 		IngressClassName:        ingressClassName,
 		TraefikEntryPoint:       traefikEntryPoint,
@@ -236,7 +437,7 @@ func setupAdmissionHandlers(ctx context.Context, platformClient *platform.Client
 		CertRetryInterval:       time.Minute,
 		CertSyncInterval:        time.Hour,
 	}
-	edgeIngressWatcher, err := edgeingress.NewWatcher(platformClient, hubClientSet, clientSet, traefikClientSet.TraefikV1alpha1(), hubInformer, watcherCfg)
+	edgeIngressWatcher, err := edgeingress.NewWatcher(platformClient, hubClientSet, clientSet, traefikMiddlewares, hubInformer, watcherCfg)
 	if err != nil {
 		return nil, nil, fmt.Errorf("create edge ingress watcher: %w", err)
 	}
@@ -246,23 +447,52 @@ func setupAdmissionHandlers(ctx context.Context, platformClient *platform.Client
 
 	polGetter := reviewer.NewPolGetter(hubInformer)
 
-	fwdAuthMdlwrs := reviewer.NewFwdAuthMiddlewares(authServerAddr, polGetter, traefikClientSet.TraefikV1alpha1())
+	fwdAuthMdlwrs := reviewer.NewFwdAuthMiddlewares(authServerAddr, polGetter, traefikMiddlewares)
+	kongPlugins := reviewer.NewKongPlugins(authServerAddr, polGetter, dynClient, kongPluginCfg)
 
 	reviewers := []admission.Reviewer{
-		reviewer.NewNginxIngress(authServerAddr, ingClassWatcher, polGetter),
-		reviewer.NewTraefikIngress(ingClassWatcher, fwdAuthMdlwrs),
+		reviewer.NewNginxIngress(authServerAddr, ingClassWatcher, polGetter, nsDefaultWatcher),
+		reviewer.NewHAProxyIngress(authServerAddr, ingClassWatcher, polGetter, nsDefaultWatcher),
+		reviewer.NewKongIngress(ingClassWatcher, kongPlugins, nsDefaultWatcher),
+		reviewer.NewTraefikIngress(ingClassWatcher, fwdAuthMdlwrs, nsDefaultWatcher),
+		reviewer.NewTraefikIngressRoute(fwdAuthMdlwrs, nsDefaultWatcher),
+		reviewer.NewGatewayHTTPRoute(fwdAuthMdlwrs, traefikGroup, dynClient),
+	}
+
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientSet.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "hub-agent"})
+
+	dynInformer := dynamicinformer.NewDynamicSharedInformerFactory(dynClient, 5*time.Minute)
+	reconciler := admission.NewReconciler(reviewers, kubeInformer, dynInformer, dynClient, recorder, kubeVers.GitVersion, traefikGroup, reconcilerCfg)
+
+	dynInformer.Start(ctx.Done())
+
+	for t, ok := range dynInformer.WaitForCacheSync(ctx.Done()) {
+		if !ok {
+			return nil, nil, fmt.Errorf("wait for dynamic informer cache sync: %s: %w", t, ctx.Err())
+		}
+	}
+
+	go reconciler.Run(ctx)
+
+	// Among the admission webhook replicas, only the elected one reconciles resources and collects orphaned middlewares.
+	if err = runLeaderElection(ctx, clientSet, "hub-acp-reconciler", reconciler, mdlwrCollector); err != nil {
+		return nil, nil, fmt.Errorf("run reconciler leader election: %w", err)
 	}
 
 	return admission.NewHandler(reviewers), edgeadmission.NewHandler(platformClient), nil
 }
 
-func startKubeInformer(ctx context.Context, kubeVers string, kubeInformer informers.SharedInformerFactory, ingClassEventHandler cache.ResourceEventHandler) error {
+func startKubeInformer(ctx context.Context, kubeVers string, kubeInformer informers.SharedInformerFactory, ingClassEventHandler, nsEventHandler cache.ResourceEventHandler) error {
 	if kubevers.SupportsNetV1IngressClasses(kubeVers) {
 		kubeInformer.Networking().V1().IngressClasses().Informer().AddEventHandler(ingClassEventHandler)
 	} else if kubevers.SupportsNetV1Beta1IngressClasses(kubeVers) {
 		kubeInformer.Networking().V1beta1().IngressClasses().Informer().AddEventHandler(ingClassEventHandler)
 	}
 
+	kubeInformer.Core().V1().Namespaces().Informer().AddEventHandler(nsEventHandler)
+
 	if kubevers.SupportsNetV1Ingresses(kubeVers) {
 		kubeInformer.Networking().V1().Ingresses().Informer()
 	} else {
@@ -22,6 +22,7 @@ import (
 	"reflect"
 
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
 	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
 )
 
@@ -30,21 +31,30 @@ type Updatable interface {
 	Update(polName string)
 }
 
-// EventHandler watches ACP resources and calls its set Updatable when they are modified.
+// Collectable represents an object collecting the resources generated for deleted ACPs.
+type Collectable interface {
+	Collect(polName string)
+}
+
+// EventHandler watches ACP resources and calls its set Updatable when they are modified, and its set Collectable when
+// they are deleted.
 type EventHandler struct {
-	listener Updatable
+	listener  Updatable
+	collector Collectable
 }
 
-// NewEventHandler returns a new event handler meant to listen for ACP changes. It calls the given Updatable when an ACP is modified.
-func NewEventHandler(listener Updatable) *EventHandler {
+// NewEventHandler returns a new event handler meant to listen for ACP changes. It calls the given Updatable when an ACP is modified,
+// and the given Collectable when an ACP is deleted.
+func NewEventHandler(listener Updatable, collector Collectable) *EventHandler {
 	return &EventHandler{
-		listener: listener,
+		listener:  listener,
+		collector: collector,
 	}
 }
 
 // OnAdd implements Kubernetes cache.ResourceEventHandler so it can be used as an informer event handler.
 func (w *EventHandler) OnAdd(obj interface{}) {
-	v, ok := obj.(*hubv1alpha1.AccessControlPolicy)
+	canonicalName, _, ok := policyOf(obj)
 	if !ok {
 		log.Error().
 			Str("component", "acp_watcher").
@@ -53,12 +63,12 @@ func (w *EventHandler) OnAdd(obj interface{}) {
 		return
 	}
 
-	w.listener.Update(v.ObjectMeta.Name)
+	w.listener.Update(canonicalName)
 }
 
 // OnUpdate implements Kubernetes cache.ResourceEventHandler so it can be used as an informer event handler.
 func (w *EventHandler) OnUpdate(oldObj, newObj interface{}) {
-	newACP, ok := newObj.(*hubv1alpha1.AccessControlPolicy)
+	canonicalName, newSpec, ok := policyOf(newObj)
 	if !ok {
 		log.Error().
 			Str("component", "acp_watcher").
@@ -67,7 +77,7 @@ func (w *EventHandler) OnUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
-	oldACP, ok := oldObj.(*hubv1alpha1.AccessControlPolicy)
+	_, oldSpec, ok := policyOf(oldObj)
 	if !ok {
 		log.Error().
 			Str("component", "acp_watcher").
@@ -76,16 +86,16 @@ func (w *EventHandler) OnUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
-	if !headersChanged(oldACP.Spec, newACP.Spec) {
+	if !headersChanged(oldSpec, newSpec) {
 		return
 	}
 
-	w.listener.Update(newACP.ObjectMeta.Name)
+	w.listener.Update(canonicalName)
 }
 
 // OnDelete implements Kubernetes cache.ResourceEventHandler so it can be used as an informer event handler.
 func (w *EventHandler) OnDelete(obj interface{}) {
-	v, ok := obj.(*hubv1alpha1.AccessControlPolicy)
+	canonicalName, _, ok := policyOf(obj)
 	if !ok {
 		log.Error().
 			Str("component", "acp_watcher").
@@ -94,7 +104,21 @@ func (w *EventHandler) OnDelete(obj interface{}) {
 		return
 	}
 
-	w.listener.Update(v.ObjectMeta.Name)
+	w.listener.Update(canonicalName)
+	w.collector.Collect(canonicalName)
+}
+
+// policyOf returns the canonical name and the spec of the given policy, which is either an AccessControlPolicy or a
+// NamespacedAccessControlPolicy.
+func policyOf(obj interface{}) (string, hubv1alpha1.AccessControlPolicySpec, bool) {
+	switch v := obj.(type) {
+	case *hubv1alpha1.AccessControlPolicy:
+		return v.Name, v.Spec, true
+	case *hubv1alpha1.NamespacedAccessControlPolicy:
+		return acp.CanonicalName(v.Name, v.Namespace), v.Spec, true
+	default:
+		return "", hubv1alpha1.AccessControlPolicySpec{}, false
+	}
 }
 
 func headersChanged(oldCfg, newCfg hubv1alpha1.AccessControlPolicySpec) bool {
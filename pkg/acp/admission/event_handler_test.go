@@ -34,6 +34,14 @@ func (f *fakeUpdater) Update(polName string) {
 	f.policies = append(f.policies, polName)
 }
 
+type fakeCollector struct {
+	policies []string
+}
+
+func (f *fakeCollector) Collect(polName string) {
+	f.policies = append(f.policies, polName)
+}
+
 func createPolicy(uid, name string, sah bool) *hubv1alpha1.AccessControlPolicy {
 	return &hubv1alpha1.AccessControlPolicy{
 		ObjectMeta: metav1.ObjectMeta{UID: ktypes.UID(uid), Name: name},
@@ -49,7 +57,9 @@ func createPolicy(uid, name string, sah bool) *hubv1alpha1.AccessControlPolicy {
 func TestEventHandler_OnAdd(t *testing.T) {
 	updater := fakeUpdater{}
 
-	handler := NewEventHandler(&updater)
+	collector := fakeCollector{}
+
+	handler := NewEventHandler(&updater, &collector)
 
 	handler.OnAdd(createPolicy("1", "my-policy-1", false))
 	handler.OnAdd(createPolicy("2", "my-policy-2", false))
@@ -62,7 +72,9 @@ func TestEventHandler_OnAdd(t *testing.T) {
 func TestEventHandler_OnDelete(t *testing.T) {
 	updater := fakeUpdater{}
 
-	handler := NewEventHandler(&updater)
+	collector := fakeCollector{}
+
+	handler := NewEventHandler(&updater, &collector)
 
 	handler.OnDelete(createPolicy("1", "my-policy-1", false))
 	handler.OnDelete(createPolicy("2", "my-policy-2", false))
@@ -70,12 +82,15 @@ func TestEventHandler_OnDelete(t *testing.T) {
 	expected := []string{"my-policy-1", "my-policy-2"}
 
 	assert.Equal(t, expected, updater.policies)
+	assert.Equal(t, expected, collector.policies)
 }
 
 func TestEventHandler_OnUpdate(t *testing.T) {
 	updater := fakeUpdater{}
 
-	handler := NewEventHandler(&updater)
+	collector := fakeCollector{}
+
+	handler := NewEventHandler(&updater, &collector)
 
 	handler.OnUpdate(
 		createPolicy("1", "my-policy-1", false),
@@ -91,3 +106,30 @@ func TestEventHandler_OnUpdate(t *testing.T) {
 
 	assert.Equal(t, expected, updater.policies)
 }
+
+func TestEventHandler_namespacedPolicies(t *testing.T) {
+	updater := fakeUpdater{}
+
+	collector := fakeCollector{}
+
+	handler := NewEventHandler(&updater, &collector)
+
+	createNamespacedPolicy := func(sah bool) *hubv1alpha1.NamespacedAccessControlPolicy {
+		policy := createPolicy("1", "my-policy", sah)
+
+		return &hubv1alpha1.NamespacedAccessControlPolicy{
+			ObjectMeta: metav1.ObjectMeta{UID: policy.UID, Name: policy.Name, Namespace: "my-ns"},
+			Spec:       policy.Spec,
+		}
+	}
+
+	handler.OnAdd(createNamespacedPolicy(false))
+	handler.OnUpdate(createNamespacedPolicy(false), createNamespacedPolicy(false))
+	handler.OnUpdate(createNamespacedPolicy(false), createNamespacedPolicy(true))
+	handler.OnDelete(createNamespacedPolicy(true))
+
+	expected := []string{"my-policy@my-ns", "my-policy@my-ns", "my-policy@my-ns"}
+
+	assert.Equal(t, expected, updater.policies)
+	assert.Equal(t, []string{"my-policy@my-ns"}, collector.policies)
+}
@@ -43,6 +43,8 @@ const annotationDefaultIngressClass = "ingressclass.kubernetes.io/is-default-cla
 const (
 	ControllerTypeNginxCommunity = "k8s.io/ingress-nginx"
 	ControllerTypeTraefik        = "traefik.io/ingress-controller"
+	ControllerTypeHAProxy        = "haproxy-ingress.github.io/controller"
+	ControllerTypeKong           = "ingress-controllers.konghq.com/kong"
 )
 
 // Watcher watches for IngressClass resources, maintaining a local cache of these resources,
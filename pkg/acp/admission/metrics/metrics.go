@@ -0,0 +1,202 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package metrics holds the Prometheus metrics of the admission webhook.
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	admv1 "k8s.io/api/admission/v1"
+)
+
+// Admission review outcomes.
+const (
+	OutcomeAllowed  = "allowed"
+	OutcomePatched  = "patched"
+	OutcomeWarned   = "warned"
+	OutcomeRejected = "rejected"
+	OutcomeError    = "error"
+)
+
+// ReviewerNone is the reviewer label value of admission requests which weren't handled by a reviewer.
+const ReviewerNone = "none"
+
+const namespace = "hub_admission"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	drifts = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "acp_drifts_total",
+		Help:      "Number of resources found with an ACP wiring drifting from the expected one, by resource kind.",
+	}, []string{"kind"})
+
+	driftedResources = promauto.With(registry).NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "acp_drifted_resources",
+		Help:      "Number of resources currently having an ACP wiring drifting from the expected one, by resource kind.",
+	}, []string{"kind"})
+
+	driftRepairs = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "acp_drift_repairs_total",
+		Help:      "Number of ACP wiring drifts repaired, by resource kind and result.",
+	}, []string{"kind", "result"})
+
+	requests = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Number of admission requests, by endpoint, reviewer, operation and outcome.",
+	}, []string{"endpoint", "reviewer", "operation", "outcome"})
+
+	requestDuration = promauto.With(registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to review admission requests, by endpoint and operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "operation"})
+
+	platformRequestDuration = promauto.With(registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "platform_request_duration_seconds",
+		Help:      "Time taken by the platform to answer the requests made while reviewing admission requests, by method and result.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "result"})
+)
+
+// Handler returns the HTTP handler exposing the admission webhook metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// IncDrifts records a resource of the given kind found with a drifting ACP wiring.
+func IncDrifts(kind string) {
+	drifts.WithLabelValues(kind).Inc()
+}
+
+// SetDriftedResources records the number of resources of the given kind currently having a drifting ACP wiring.
+func SetDriftedResources(kind string, n int) {
+	driftedResources.WithLabelValues(kind).Set(float64(n))
+}
+
+// IncDriftRepairs records an attempt to repair the drifting ACP wiring of a resource of the given kind.
+func IncDriftRepairs(kind string, err error) {
+	res := "success"
+	if err != nil {
+		res = "failure"
+	}
+
+	driftRepairs.WithLabelValues(kind, res).Inc()
+}
+
+// InstrumentHandler returns a handler recording the admission reviews the given handler makes for the given endpoint.
+// The operation and outcome of a review are read from the AdmissionReview the handler responds with.
+func InstrumentHandler(endpoint string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		rev := &review{reviewer: ReviewerNone}
+		recorder := &responseRecorder{ResponseWriter: rw}
+		next.ServeHTTP(recorder, req.WithContext(context.WithValue(req.Context(), reviewKey{}, rev)))
+
+		operation, outcome := reviewResult(recorder.body.Bytes())
+
+		requestDuration.WithLabelValues(endpoint, operation).Observe(time.Since(start).Seconds())
+		requests.WithLabelValues(endpoint, rev.reviewer, operation, outcome).Inc()
+	})
+}
+
+// SetReviewer records the reviewer handling the admission request bound to the given context.
+// It is a no-op if the request isn't going through an instrumented handler.
+func SetReviewer(ctx context.Context, reviewer interface{}) {
+	rev, ok := ctx.Value(reviewKey{}).(*review)
+	if !ok {
+		return
+	}
+
+	rev.reviewer = ReviewerName(reviewer)
+}
+
+// ReviewerName returns the name of the given reviewer, that is, the name of its type.
+func ReviewerName(reviewer interface{}) string {
+	t := reflect.TypeOf(reviewer)
+	if t == nil {
+		return ReviewerNone
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Name()
+}
+
+// ObservePlatformRequest records the duration of a request made to the platform by the given method.
+func ObservePlatformRequest(method string, start time.Time, err error) {
+	res := "success"
+	if err != nil {
+		res = "failure"
+	}
+
+	platformRequestDuration.WithLabelValues(method, res).Observe(time.Since(start).Seconds())
+}
+
+type reviewKey struct{}
+
+type review struct {
+	reviewer string
+}
+
+// reviewResult returns the operation and the outcome of the given encoded AdmissionReview.
+func reviewResult(body []byte) (operation, outcome string) {
+	var ar admv1.AdmissionReview
+	if err := json.Unmarshal(body, &ar); err != nil || ar.Request == nil || ar.Response == nil {
+		return "UNKNOWN", OutcomeError
+	}
+
+	switch {
+	case !ar.Response.Allowed:
+		return string(ar.Request.Operation), OutcomeRejected
+	case ar.Response.Patch != nil:
+		return string(ar.Request.Operation), OutcomePatched
+	case len(ar.Response.Warnings) > 0:
+		return string(ar.Request.Operation), OutcomeWarned
+	default:
+		return string(ar.Request.Operation), OutcomeAllowed
+	}
+}
+
+type responseRecorder struct {
+	http.ResponseWriter
+
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admv1 "k8s.io/api/admission/v1"
+)
+
+func TestIncDriftRepairs(t *testing.T) {
+	IncDriftRepairs("repairs", nil)
+	IncDriftRepairs("repairs", nil)
+	IncDriftRepairs("repairs", errors.New("boom"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(driftRepairs.WithLabelValues("repairs", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(driftRepairs.WithLabelValues("repairs", "failure")))
+}
+
+func TestHandler(t *testing.T) {
+	IncDrifts("handler")
+	SetDriftedResources("handler", 3)
+
+	rw := httptest.NewRecorder()
+	Handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), `hub_admission_acp_drifts_total{kind="handler"} 1`)
+	assert.Contains(t, rw.Body.String(), `hub_admission_acp_drifted_resources{kind="handler"} 3`)
+}
+
+type fakeReviewer struct{}
+
+func TestInstrumentHandler(t *testing.T) {
+	tests := []struct {
+		desc         string
+		reviewer     interface{}
+		response     *admv1.AdmissionResponse
+		wantReviewer string
+		wantOp       string
+		wantOutcome  string
+	}{
+		{
+			desc:         "allowed",
+			reviewer:     &fakeReviewer{},
+			response:     &admv1.AdmissionResponse{Allowed: true},
+			wantReviewer: "fakeReviewer",
+			wantOp:       "CREATE",
+			wantOutcome:  OutcomeAllowed,
+		},
+		{
+			desc:         "patched",
+			reviewer:     fakeReviewer{},
+			response:     &admv1.AdmissionResponse{Allowed: true, Patch: []byte("[]"), Warnings: []string{"warning"}},
+			wantReviewer: "fakeReviewer",
+			wantOp:       "CREATE",
+			wantOutcome:  OutcomePatched,
+		},
+		{
+			desc:         "warned",
+			response:     &admv1.AdmissionResponse{Allowed: true, Warnings: []string{"warning"}},
+			wantReviewer: ReviewerNone,
+			wantOp:       "CREATE",
+			wantOutcome:  OutcomeWarned,
+		},
+		{
+			desc:         "rejected",
+			response:     &admv1.AdmissionResponse{Allowed: false},
+			wantReviewer: ReviewerNone,
+			wantOp:       "CREATE",
+			wantOutcome:  OutcomeRejected,
+		},
+		{
+			desc:         "not an admission review",
+			wantReviewer: ReviewerNone,
+			wantOp:       "UNKNOWN",
+			wantOutcome:  OutcomeError,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			next := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				if test.reviewer != nil {
+					SetReviewer(req.Context(), test.reviewer)
+				}
+
+				if test.response == nil {
+					http.Error(rw, "boom", http.StatusUnprocessableEntity)
+					return
+				}
+
+				err := json.NewEncoder(rw).Encode(admv1.AdmissionReview{
+					Request:  &admv1.AdmissionRequest{Operation: admv1.Create},
+					Response: test.response,
+				})
+				require.NoError(t, err)
+			})
+
+			rw := httptest.NewRecorder()
+			InstrumentHandler(test.desc, next).ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/", nil))
+
+			assert.Equal(t, 1.0, testutil.ToFloat64(requests.WithLabelValues(test.desc, test.wantReviewer, test.wantOp, test.wantOutcome)))
+
+			rw = httptest.NewRecorder()
+			Handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+			wantDuration := fmt.Sprintf(`hub_admission_request_duration_seconds_count{endpoint=%q,operation=%q} 1`, test.desc, test.wantOp)
+			assert.Contains(t, rw.Body.String(), wantDuration)
+		})
+	}
+}
+
+func TestSetReviewer_notInstrumented(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	assert.NotPanics(t, func() { SetReviewer(req.Context(), fakeReviewer{}) })
+}
+
+func TestObservePlatformRequest(t *testing.T) {
+	ObservePlatformRequest("ObservePlatformRequest", time.Now(), nil)
+	ObservePlatformRequest("ObservePlatformRequest", time.Now(), errors.New("boom"))
+
+	rw := httptest.NewRecorder()
+	Handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, rw.Body.String(), `hub_admission_platform_request_duration_seconds_count{method="ObservePlatformRequest",result="success"} 1`)
+	assert.Contains(t, rw.Body.String(), `hub_admission_platform_request_duration_seconds_count{method="ObservePlatformRequest",result="failure"} 1`)
+}
@@ -0,0 +1,483 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package admission
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/reviewer"
+	traefikclient "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/typed/traefik/v1alpha1"
+	"github.com/traefik/hub-agent-kubernetes/pkg/kubevers"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+)
+
+// middlewareGracePeriod is the minimum age of the middlewares to collect. Middlewares are created by the admission
+// webhook before the resources referencing them are persisted, so younger ones may not be referenced yet.
+const middlewareGracePeriod = time.Minute
+
+// collectQueueSize is the number of deleted ACPs whose middlewares can be waiting to be collected.
+const collectQueueSize = 100
+
+// MiddlewareCollector deletes the forwardAuth middlewares generated for ACPs which are no longer referenced by any
+// ingress, IngressRoute or HTTPRoute, as well as the KongPlugins no longer referenced by any Kong ingress.
+// Middlewares and KongPlugins still referenced are kept, even if their ACP has been deleted.
+// Only the replica elected as leader collects them, see SetLeader.
+type MiddlewareCollector struct {
+	informer       informers.SharedInformerFactory
+	dynClient      dynamic.Interface
+	middlewares    traefikclient.MiddlewaresGetter
+	authServerAddr string
+
+	interval time.Duration
+	delay    time.Duration
+
+	polNameCh chan string
+	electedCh chan struct{}
+
+	mu     sync.Mutex
+	leader bool
+
+	supportsNetV1Ingresses bool
+
+	now func() time.Time
+}
+
+// NewMiddlewareCollector returns a new MiddlewareCollector, collecting orphaned middlewares every interval. The given
+// client manages the Middlewares of the API group served by the cluster, see kube.TraefikMiddlewares. The given auth
+// server address is the one forwardAuth middlewares are generated with, see reviewer.FwdAuthMiddlewares.
+func NewMiddlewareCollector(informer informers.SharedInformerFactory, dynClient dynamic.Interface, middlewares traefikclient.MiddlewaresGetter, authServerAddr, kubeVersion string, interval time.Duration) *MiddlewareCollector {
+	return &MiddlewareCollector{
+		informer:               informer,
+		dynClient:              dynClient,
+		middlewares:            middlewares,
+		authServerAddr:         authServerAddr,
+		interval:               interval,
+		delay:                  middlewareGracePeriod,
+		polNameCh:              make(chan string, collectQueueSize),
+		electedCh:              make(chan struct{}, 1),
+		supportsNetV1Ingresses: kubevers.SupportsNetV1Ingresses(kubeVersion),
+		now:                    time.Now,
+	}
+}
+
+// Run runs the MiddlewareCollector control loop, collecting orphaned middlewares periodically and when ACPs are
+// deleted, while this replica is the leader. Middlewares generated before they were labeled are labeled once elected,
+// so they can be collected as well.
+func (c *MiddlewareCollector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.electedCh:
+			if err := c.labelLegacyMiddlewares(ctx); err != nil {
+				log.Error().Err(err).Msg("Unable to label legacy ForwardAuth middlewares")
+			}
+
+		case <-ticker.C:
+			if !c.isLeader() {
+				continue
+			}
+
+			if err := c.collect(ctx, ""); err != nil {
+				log.Error().Err(err).Msg("Unable to collect orphaned ForwardAuth middlewares")
+			}
+
+		case polName := <-c.polNameCh:
+			// Leave some time to the IngressUpdater to update the resources referencing the deleted policy.
+			go func(polName string) {
+				select {
+				case <-time.After(c.delay):
+				case <-ctx.Done():
+					return
+				}
+
+				if !c.isLeader() {
+					return
+				}
+
+				if err := c.collect(ctx, polName); err != nil {
+					log.Error().Err(err).Str("acp_name", polName).Msg("Unable to collect orphaned ForwardAuth middlewares")
+				}
+			}(polName)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// SetLeader sets whether this replica is the leader. Replicas all watch ACP deletions, only the leader collects the
+// middlewares so they don't race deleting them.
+func (c *MiddlewareCollector) SetLeader(leader bool) {
+	c.mu.Lock()
+	c.leader = leader
+	c.mu.Unlock()
+
+	if leader {
+		select {
+		case c.electedCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *MiddlewareCollector) isLeader() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.leader
+}
+
+// Collect notifies the MiddlewareCollector control loop that it should collect the middlewares generated for the
+// given ACP, as it has been deleted. It doesn't block: if too many collections are pending, the middlewares are left
+// to the periodic collection. It does nothing if this replica is not the leader.
+func (c *MiddlewareCollector) Collect(polName string) {
+	if !c.isLeader() {
+		return
+	}
+
+	select {
+	case c.polNameCh <- polName:
+	default:
+		log.Warn().Str("acp_name", polName).Msg("Too many pending collections, orphaned ForwardAuth middlewares will be collected periodically")
+	}
+}
+
+// labelLegacyMiddlewares labels the forwardAuth middlewares generated for ACPs before they were labeled. They are
+// recognized by their name prefix and their forwardAuth address, pointing at the auth server.
+func (c *MiddlewareCollector) labelLegacyMiddlewares(ctx context.Context) error {
+	mdlwrs, err := c.middlewares.Middlewares(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: "!" + reviewer.LabelManagedBy,
+	})
+	if err != nil {
+		return fmt.Errorf("list middlewares: %w", err)
+	}
+
+	for _, mdlwr := range mdlwrs.Items {
+		mdlwr := mdlwr
+		if !strings.HasPrefix(mdlwr.Name, "zz-") || mdlwr.Spec.ForwardAuth == nil {
+			continue
+		}
+
+		path := strings.TrimPrefix(mdlwr.Spec.ForwardAuth.Address, c.authServerAddr)
+		if path == mdlwr.Spec.ForwardAuth.Address || !strings.HasPrefix(path, "/") {
+			continue
+		}
+
+		logger := log.With().
+			Str("middleware_name", mdlwr.Name).
+			Str("middleware_namespace", mdlwr.Namespace).
+			Logger()
+
+		mdlwr.Labels = reviewer.MiddlewareLabels(mdlwr.Labels, acp.CanonicalNameFromAuthServerPath(path))
+
+		_, err = c.middlewares.Middlewares(mdlwr.Namespace).Update(ctx, &mdlwr, metav1.UpdateOptions{FieldManager: "hub-auth"})
+		if err != nil {
+			logger.Error().Err(err).Msg("Unable to label legacy ForwardAuth middleware")
+			continue
+		}
+
+		logger.Debug().Msg("Legacy ForwardAuth middleware labeled")
+	}
+
+	return nil
+}
+
+// collect deletes the orphaned middlewares and KongPlugins generated for the policy with the given canonical name, or
+// for all policies if the name is empty.
+func (c *MiddlewareCollector) collect(ctx context.Context, canonicalPolName string) error {
+	if err := c.collectMiddlewares(ctx, canonicalPolName); err != nil {
+		return err
+	}
+
+	if err := c.collectKongPlugins(ctx, canonicalPolName); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *MiddlewareCollector) collectMiddlewares(ctx context.Context, canonicalPolName string) error {
+	mdlwrs, err := c.middlewares.Middlewares(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: reviewer.MiddlewareSelector(canonicalPolName),
+	})
+	if err != nil {
+		return fmt.Errorf("list middlewares: %w", err)
+	}
+	if len(mdlwrs.Items) == 0 {
+		return nil
+	}
+
+	refs, err := c.referencedMiddlewares(ctx)
+	if err != nil {
+		return fmt.Errorf("list referenced middlewares: %w", err)
+	}
+
+	for _, mdlwr := range mdlwrs.Items {
+		if _, ok := refs[middlewareRef(mdlwr.Namespace, mdlwr.Name)]; ok {
+			continue
+		}
+
+		if c.now().Sub(mdlwr.CreationTimestamp.Time) < middlewareGracePeriod {
+			continue
+		}
+
+		logger := log.With().
+			Str("middleware_name", mdlwr.Name).
+			Str("middleware_namespace", mdlwr.Namespace).
+			Logger()
+
+		err = c.middlewares.Middlewares(mdlwr.Namespace).Delete(ctx, mdlwr.Name, metav1.DeleteOptions{})
+		if err != nil && !kerror.IsNotFound(err) {
+			logger.Error().Err(err).Msg("Unable to delete orphaned ForwardAuth middleware")
+			continue
+		}
+
+		logger.Info().Msg("Orphaned ForwardAuth middleware deleted")
+	}
+
+	return nil
+}
+
+func (c *MiddlewareCollector) collectKongPlugins(ctx context.Context, canonicalPolName string) error {
+	plugins, err := c.dynClient.Resource(reviewer.KongPluginGVR).List(ctx, metav1.ListOptions{
+		LabelSelector: reviewer.MiddlewareSelector(canonicalPolName),
+	})
+	if err != nil {
+		// The KongPlugin CRD is only installed along with the Kong Ingress Controller.
+		if kerror.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("list KongPlugins: %w", err)
+	}
+	if len(plugins.Items) == 0 {
+		return nil
+	}
+
+	refs, err := c.referencedKongPlugins()
+	if err != nil {
+		return fmt.Errorf("list referenced KongPlugins: %w", err)
+	}
+
+	for _, plugin := range plugins.Items {
+		if _, ok := refs[plugin.GetNamespace()+"/"+plugin.GetName()]; ok {
+			continue
+		}
+
+		if c.now().Sub(plugin.GetCreationTimestamp().Time) < middlewareGracePeriod {
+			continue
+		}
+
+		logger := log.With().
+			Str("kong_plugin_name", plugin.GetName()).
+			Str("kong_plugin_namespace", plugin.GetNamespace()).
+			Logger()
+
+		err = c.dynClient.Resource(reviewer.KongPluginGVR).Namespace(plugin.GetNamespace()).Delete(ctx, plugin.GetName(), metav1.DeleteOptions{})
+		if err != nil && !kerror.IsNotFound(err) {
+			logger.Error().Err(err).Msg("Unable to delete orphaned KongPlugin")
+			continue
+		}
+
+		logger.Info().Msg("Orphaned KongPlugin deleted")
+	}
+
+	return nil
+}
+
+// referencedKongPlugins returns the KongPlugins referenced by ingresses, as "namespace/name".
+func (c *MiddlewareCollector) referencedKongPlugins() (map[string]struct{}, error) {
+	ingAnnotations, err := c.ingressAnnotations()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]struct{})
+	for namespace, annotations := range ingAnnotations {
+		for _, anno := range annotations {
+			for _, name := range strings.Split(anno[reviewer.AnnotationKongPlugins], ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					refs[namespace+"/"+name] = struct{}{}
+				}
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+// referencedMiddlewares returns the middlewares referenced by ingresses, IngressRoutes and HTTPRoutes, see
+// middlewareRef.
+func (c *MiddlewareCollector) referencedMiddlewares(ctx context.Context) (map[string]struct{}, error) {
+	refs := make(map[string]struct{})
+
+	if err := c.addIngressRefs(refs); err != nil {
+		return nil, err
+	}
+
+	if err := c.addIngressRouteRefs(ctx, refs); err != nil {
+		return nil, err
+	}
+
+	if err := c.addHTTPRouteRefs(ctx, refs); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+func (c *MiddlewareCollector) addIngressRefs(refs map[string]struct{}) error {
+	ingAnnotations, err := c.ingressAnnotations()
+	if err != nil {
+		return err
+	}
+
+	for _, annotations := range ingAnnotations {
+		for _, anno := range annotations {
+			for _, ref := range strings.Split(anno[reviewer.AnnotationTraefikMiddlewares], ",") {
+				if ref = strings.TrimSpace(ref); ref != "" {
+					refs[ref] = struct{}{}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ingressAnnotations returns the annotations of the ingresses, by namespace.
+func (c *MiddlewareCollector) ingressAnnotations() (map[string][]map[string]string, error) {
+	annotations := make(map[string][]map[string]string)
+	if c.supportsNetV1Ingresses {
+		ingList, err := c.informer.Networking().V1().Ingresses().Lister().List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("list ingresses: %w", err)
+		}
+		for _, ing := range ingList {
+			annotations[ing.Namespace] = append(annotations[ing.Namespace], ing.Annotations)
+		}
+	} else {
+		ingList, err := c.informer.Networking().V1beta1().Ingresses().Lister().List(labels.Everything())
+		if err != nil {
+			return nil, fmt.Errorf("list legacy ingresses: %w", err)
+		}
+		for _, ing := range ingList {
+			annotations[ing.Namespace] = append(annotations[ing.Namespace], ing.Annotations)
+		}
+	}
+
+	return annotations, nil
+}
+
+func (c *MiddlewareCollector) addIngressRouteRefs(ctx context.Context, refs map[string]struct{}) error {
+	for _, group := range ingressRouteGroups {
+		gvr := schema.GroupVersionResource{Group: group, Version: "v1alpha1", Resource: "ingressroutes"}
+
+		ingRoutes, err := c.dynClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if kerror.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("list %s IngressRoutes: %w", group, err)
+		}
+
+		for _, ingRoute := range ingRoutes.Items {
+			routes, _, _ := unstructured.NestedSlice(ingRoute.Object, "spec", "routes")
+			for _, route := range routes {
+				r, ok := route.(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				mdlwrs, _, _ := unstructured.NestedSlice(r, "middlewares")
+				for _, mdlwr := range mdlwrs {
+					m, ok := mdlwr.(map[string]interface{})
+					if !ok {
+						continue
+					}
+
+					name, _ := m["name"].(string)
+					namespace, _ := m["namespace"].(string)
+					if namespace == "" {
+						namespace = ingRoute.GetNamespace()
+					}
+
+					refs[middlewareRef(namespace, strings.TrimSuffix(name, "@kubernetescrd"))] = struct{}{}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *MiddlewareCollector) addHTTPRouteRefs(ctx context.Context, refs map[string]struct{}) error {
+	_, routes, err := listHTTPRoutes(ctx, c.dynClient)
+	if err != nil {
+		return fmt.Errorf("list HTTPRoutes: %w", err)
+	}
+	if routes == nil {
+		return nil
+	}
+
+	for _, route := range routes.Items {
+		rules, _, _ := unstructured.NestedSlice(route.Object, "spec", "rules")
+		for _, rule := range rules {
+			r, ok := rule.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			filters, _, _ := unstructured.NestedSlice(r, "filters")
+			for _, filter := range filters {
+				f, ok := filter.(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				if kind, _, _ := unstructured.NestedString(f, "extensionRef", "kind"); kind != "Middleware" {
+					continue
+				}
+
+				name, _, _ := unstructured.NestedString(f, "extensionRef", "name")
+				refs[middlewareRef(route.GetNamespace(), name)] = struct{}{}
+			}
+		}
+	}
+
+	return nil
+}
+
+// middlewareRef returns the reference to the middleware with the given namespace and name, as used by Traefik
+// ingresses.
+func middlewareRef(namespace, name string) string {
+	return fmt.Sprintf("%s-%s@kubernetescrd", namespace, name)
+}
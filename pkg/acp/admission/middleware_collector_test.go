@@ -0,0 +1,382 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package admission
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMiddlewareCollector_collect(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	newMiddleware := func(name, namespace, polName, polNamespace string, age time.Duration) *traefikv1alpha1.Middleware {
+		mdlwr := &traefikv1alpha1.Middleware{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         namespace,
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+		}
+		if polName != "" {
+			mdlwr.Labels = map[string]string{
+				"app.kubernetes.io/managed-by":         "traefik-hub",
+				"hub.traefik.io/access-control-policy": polName,
+			}
+			if polNamespace != "" {
+				mdlwr.Labels["hub.traefik.io/access-control-policy-namespace"] = polNamespace
+			}
+		}
+		return mdlwr
+	}
+
+	tests := []struct {
+		desc        string
+		polName     string
+		wantDeleted []string
+	}{
+		{
+			desc:        "all policies",
+			wantDeleted: []string{"my-ns/zz-orphaned", "my-ns/zz--my-ns.orphaned", "other-ns/zz-orphaned"},
+		},
+		{
+			desc:        "cluster-wide policy",
+			polName:     "orphaned",
+			wantDeleted: []string{"my-ns/zz-orphaned", "other-ns/zz-orphaned"},
+		},
+		{
+			desc:        "namespaced policy",
+			polName:     "orphaned@my-ns",
+			wantDeleted: []string{"my-ns/zz--my-ns.orphaned"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			traefikClientSet := traefikkubemock.NewSimpleClientset(
+				newMiddleware("zz-ingress", "my-ns", "ingress", "", time.Hour),
+				newMiddleware("zz-ingress-route", "my-ns", "ingress-route", "", time.Hour),
+				newMiddleware("zz-ingress-route-other-ns", "other-ns", "ingress-route", "", time.Hour),
+				newMiddleware("zz-http-route", "my-ns", "http-route", "", time.Hour),
+				newMiddleware("zz-orphaned", "my-ns", "orphaned", "", time.Hour),
+				newMiddleware("zz-orphaned", "other-ns", "orphaned", "", time.Hour),
+				newMiddleware("zz--my-ns.orphaned", "my-ns", "orphaned", "my-ns", time.Hour),
+				newMiddleware("zz-recent", "my-ns", "recent", "", time.Second),
+				newMiddleware("custom", "my-ns", "", "", time.Hour),
+			)
+
+			kubeInformer := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0)
+			err := kubeInformer.Networking().V1().Ingresses().Informer().GetIndexer().Add(&netv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ingress",
+					Namespace: "my-ns",
+					Annotations: map[string]string{
+						"traefik.ingress.kubernetes.io/router.middlewares": "custom@kubernetescrd, my-ns-zz-ingress@kubernetescrd",
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			ingRoute := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "traefik.containo.us/v1alpha1",
+				"kind":       "IngressRoute",
+				"metadata":   map[string]interface{}{"name": "ingress-route", "namespace": "my-ns"},
+				"spec": map[string]interface{}{
+					"routes": []interface{}{
+						map[string]interface{}{
+							"match": "PathPrefix(`/`)",
+							"middlewares": []interface{}{
+								map[string]interface{}{"name": "zz-ingress-route"},
+								map[string]interface{}{"name": "zz-ingress-route-other-ns", "namespace": "other-ns"},
+							},
+						},
+					},
+				},
+			}}
+			httpRoute := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "gateway.networking.k8s.io/v1",
+				"kind":       "HTTPRoute",
+				"metadata":   map[string]interface{}{"name": "http-route", "namespace": "my-ns"},
+				"spec": map[string]interface{}{
+					"rules": []interface{}{
+						map[string]interface{}{
+							"filters": []interface{}{
+								map[string]interface{}{
+									"type": "ExtensionRef",
+									"extensionRef": map[string]interface{}{
+										"group": "traefik.containo.us",
+										"kind":  "Middleware",
+										"name":  "zz-http-route",
+									},
+								},
+							},
+						},
+					},
+				},
+			}}
+
+			listKinds := map[schema.GroupVersionResource]string{
+				{Group: "traefik.io", Version: "v1alpha1", Resource: "ingressroutes"}:          "IngressRouteList",
+				{Group: "traefik.containo.us", Version: "v1alpha1", Resource: "ingressroutes"}: "IngressRouteList",
+				{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}:    "HTTPRouteList",
+				{Group: "configuration.konghq.com", Version: "v1", Resource: "kongplugins"}:    "KongPluginList",
+			}
+			dynClient := dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, ingRoute, httpRoute)
+
+			c := NewMiddlewareCollector(kubeInformer, dynClient, traefikClientSet.TraefikV1alpha1(), "http://auth-server", "v1.22", time.Minute)
+			c.now = func() time.Time { return now }
+
+			err = c.collect(context.Background(), test.polName)
+			require.NoError(t, err)
+
+			mdlwrs, err := traefikClientSet.TraefikV1alpha1().Middlewares(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err)
+
+			all := []string{
+				"my-ns/custom",
+				"my-ns/zz--my-ns.orphaned",
+				"my-ns/zz-http-route",
+				"my-ns/zz-ingress",
+				"my-ns/zz-ingress-route",
+				"my-ns/zz-orphaned",
+				"my-ns/zz-recent",
+				"other-ns/zz-ingress-route-other-ns",
+				"other-ns/zz-orphaned",
+			}
+			var want []string
+			for _, name := range all {
+				if !containsString(test.wantDeleted, name) {
+					want = append(want, name)
+				}
+			}
+
+			var got []string
+			for _, mdlwr := range mdlwrs.Items {
+				got = append(got, mdlwr.Namespace+"/"+mdlwr.Name)
+			}
+			sort.Strings(got)
+
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestMiddlewareCollector_collectKongPlugins(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	newPlugin := func(name, namespace, polName string, age time.Duration) runtime.Object {
+		plugin := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "configuration.konghq.com/v1",
+			"kind":       "KongPlugin",
+			"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+			"plugin":     "external-auth",
+		}}
+		plugin.SetCreationTimestamp(metav1.NewTime(now.Add(-age)))
+		if polName != "" {
+			plugin.SetLabels(map[string]string{
+				"app.kubernetes.io/managed-by":         "traefik-hub",
+				"hub.traefik.io/access-control-policy": polName,
+			})
+		}
+		return plugin
+	}
+
+	kubeInformer := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0)
+	err := kubeInformer.Networking().V1().Ingresses().Informer().GetIndexer().Add(&netv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "ingress",
+			Namespace: "my-ns",
+			Annotations: map[string]string{
+				"konghq.com/plugins": "custom, zz-ingress",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	listKinds := map[schema.GroupVersionResource]string{
+		{Group: "traefik.io", Version: "v1alpha1", Resource: "ingressroutes"}:          "IngressRouteList",
+		{Group: "traefik.containo.us", Version: "v1alpha1", Resource: "ingressroutes"}: "IngressRouteList",
+		{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}:    "HTTPRouteList",
+		{Group: "configuration.konghq.com", Version: "v1", Resource: "kongplugins"}:    "KongPluginList",
+	}
+	dynClient := dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		newPlugin("zz-ingress", "my-ns", "ingress", time.Hour),
+		newPlugin("zz-ingress", "other-ns", "ingress", time.Hour),
+		newPlugin("zz-recent", "my-ns", "recent", time.Second),
+		newPlugin("custom", "my-ns", "", time.Hour),
+		newPlugin("unused", "my-ns", "", time.Hour),
+	)
+
+	c := NewMiddlewareCollector(kubeInformer, dynClient, traefikkubemock.NewSimpleClientset().TraefikV1alpha1(), "http://auth-server", "v1.22", time.Minute)
+	c.now = func() time.Time { return now }
+
+	err = c.collect(context.Background(), "")
+	require.NoError(t, err)
+
+	plugins, err := dynClient.Resource(schema.GroupVersionResource{Group: "configuration.konghq.com", Version: "v1", Resource: "kongplugins"}).
+		List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+
+	var got []string
+	for _, plugin := range plugins.Items {
+		got = append(got, plugin.GetNamespace()+"/"+plugin.GetName())
+	}
+	sort.Strings(got)
+
+	assert.Equal(t, []string{"my-ns/custom", "my-ns/unused", "my-ns/zz-ingress", "my-ns/zz-recent"}, got)
+}
+
+func TestMiddlewareCollector_labelLegacyMiddlewares(t *testing.T) {
+	newMiddleware := func(name, address string, lbls map[string]string) *traefikv1alpha1.Middleware {
+		return &traefikv1alpha1.Middleware{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "my-ns", Labels: lbls},
+			Spec: traefikv1alpha1.MiddlewareSpec{
+				ForwardAuth: &traefikv1alpha1.ForwardAuth{Address: address},
+			},
+		}
+	}
+
+	traefikClientSet := traefikkubemock.NewSimpleClientset(
+		newMiddleware("zz-legacy", "http://auth-server/legacy", map[string]string{"app": "whoami"}),
+		newMiddleware("zz--my-ns.legacy", "http://auth-server/my-ns/legacy", nil),
+		newMiddleware("zz-other-server", "http://other-server/legacy", nil),
+		newMiddleware("custom", "http://auth-server/legacy", nil),
+		newMiddleware("zz-labeled", "http://auth-server/other", map[string]string{
+			"app.kubernetes.io/managed-by":         "traefik-hub",
+			"hub.traefik.io/access-control-policy": "labeled",
+		}),
+	)
+
+	kubeInformer := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0)
+	c := NewMiddlewareCollector(kubeInformer, nil, traefikClientSet.TraefikV1alpha1(), "http://auth-server", "v1.22", time.Minute)
+
+	err := c.labelLegacyMiddlewares(context.Background())
+	require.NoError(t, err)
+
+	mdlwrs, err := traefikClientSet.TraefikV1alpha1().Middlewares(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+
+	got := make(map[string]map[string]string)
+	for _, mdlwr := range mdlwrs.Items {
+		got[mdlwr.Name] = mdlwr.Labels
+	}
+
+	assert.Equal(t, map[string]map[string]string{
+		"zz-legacy": {
+			"app":                                  "whoami",
+			"app.kubernetes.io/managed-by":         "traefik-hub",
+			"hub.traefik.io/access-control-policy": "legacy",
+		},
+		"zz--my-ns.legacy": {
+			"app.kubernetes.io/managed-by":                   "traefik-hub",
+			"hub.traefik.io/access-control-policy":           "legacy",
+			"hub.traefik.io/access-control-policy-namespace": "my-ns",
+		},
+		"zz-other-server": nil,
+		"custom":          nil,
+		"zz-labeled": {
+			"app.kubernetes.io/managed-by":         "traefik-hub",
+			"hub.traefik.io/access-control-policy": "labeled",
+		},
+	}, got)
+}
+
+func TestMiddlewareCollector_RunOnlyAsLeader(t *testing.T) {
+	traefikClientSet := traefikkubemock.NewSimpleClientset(&traefikv1alpha1.Middleware{
+		ObjectMeta: metav1.ObjectMeta{Name: "zz-legacy", Namespace: "my-ns"},
+		Spec: traefikv1alpha1.MiddlewareSpec{
+			ForwardAuth: &traefikv1alpha1.ForwardAuth{Address: "http://auth-server/legacy"},
+		},
+	})
+
+	kubeInformer := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0)
+	c := NewMiddlewareCollector(kubeInformer, nil, traefikClientSet.TraefikV1alpha1(), "http://auth-server", "v1.22", time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go c.Run(ctx)
+
+	// Followers ignore ACP deletions, the leader collects their middlewares.
+	c.Collect("my-policy")
+	assert.Empty(t, c.polNameCh)
+
+	time.Sleep(50 * time.Millisecond)
+
+	mdlwr, err := traefikClientSet.TraefikV1alpha1().Middlewares("my-ns").Get(ctx, "zz-legacy", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, mdlwr.Labels)
+
+	c.SetLeader(true)
+
+	assert.Eventually(t, func() bool {
+		mdlwr, err = traefikClientSet.TraefikV1alpha1().Middlewares("my-ns").Get(ctx, "zz-legacy", metav1.GetOptions{})
+		return err == nil && mdlwr.Labels["hub.traefik.io/access-control-policy"] == "legacy"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMiddlewareCollector_CollectDoesNotBlock(t *testing.T) {
+	kubeInformer := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0)
+	c := NewMiddlewareCollector(kubeInformer, nil, nil, "http://auth-server", "v1.22", time.Minute)
+	c.SetLeader(true)
+
+	done := make(chan struct{})
+	go func() {
+		// The control loop is not running, collections pile up.
+		for i := 0; i <= collectQueueSize; i++ {
+			c.Collect("my-policy")
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.Fail(t, "Collect blocked")
+	}
+
+	assert.Len(t, c.polNameCh, collectQueueSize)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package nsdefault
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/reviewer"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Listener is notified when the default ACP of a namespace changes.
+type Listener interface {
+	UpdateNamespace(namespace string)
+}
+
+// Watcher watches for Namespace resources, maintaining a local cache of their default ACP, see
+// reviewer.AnnotationDefaultHubAuth. It notifies its listener when the default ACP of a namespace is set or changes.
+// Added namespaces are not notified: they are either newly created, hence have no resources yet, or listed when the
+// informer starts, in which case notifying them would update all their resources on every start. Default ACPs changed
+// while the agent was not running are caught up by the admission.Reconciler instead.
+type Watcher struct {
+	mu       sync.RWMutex
+	defaults map[string]string
+
+	listener Listener
+}
+
+// NewWatcher creates a new Watcher to track the default ACPs of namespaces.
+func NewWatcher(listener Listener) *Watcher {
+	return &Watcher{
+		defaults: make(map[string]string),
+		listener: listener,
+	}
+}
+
+// OnAdd implements Kubernetes cache.ResourceEventHandler so it can be used as an informer event handler.
+func (w *Watcher) OnAdd(obj interface{}) {
+	w.upsert(obj, false)
+}
+
+// OnUpdate implements Kubernetes cache.ResourceEventHandler so it can be used as an informer event handler.
+func (w *Watcher) OnUpdate(_, newObj interface{}) {
+	w.upsert(newObj, true)
+}
+
+// OnDelete implements Kubernetes cache.ResourceEventHandler so it can be used as an informer event handler.
+func (w *Watcher) OnDelete(obj interface{}) {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		log.Error().
+			Str("component", "namespace_default_acp_watcher").
+			Str("type", fmt.Sprintf("%T", obj)).
+			Msg("Received delete event of unknown type")
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.defaults, ns.Name)
+}
+
+func (w *Watcher) upsert(obj interface{}, notify bool) {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		log.Error().
+			Str("component", "namespace_default_acp_watcher").
+			Str("type", fmt.Sprintf("%T", obj)).
+			Msg("Received upsert event of unknown type")
+		return
+	}
+
+	polName := ns.Annotations[reviewer.AnnotationDefaultHubAuth]
+
+	w.mu.Lock()
+	prevPolName := w.defaults[ns.Name]
+	if prevPolName == polName {
+		w.mu.Unlock()
+		return
+	}
+	if polName == "" {
+		delete(w.defaults, ns.Name)
+	} else {
+		w.defaults[ns.Name] = polName
+	}
+	w.mu.Unlock()
+
+	if !notify {
+		return
+	}
+
+	log.Debug().
+		Str("namespace", ns.Name).
+		Str("acp_name", polName).
+		Str("prev_acp_name", prevPolName).
+		Msg("Default ACP of namespace changed")
+
+	w.listener.UpdateNamespace(ns.Name)
+}
+
+// DefaultPolicy returns the name of the default ACP of the given namespace, or an empty name if it has none.
+// The ACPs previously applied by default are recorded on the resources they protect, see
+// reviewer.AnnotationAppliedDefaultHubAuth.
+func (w *Watcher) DefaultPolicy(namespace string) string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.defaults[namespace]
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package nsdefault
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type listenerMock struct {
+	namespaces []string
+}
+
+func (l *listenerMock) UpdateNamespace(namespace string) {
+	l.namespaces = append(l.namespaces, namespace)
+}
+
+func newNamespace(name, polName string) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if polName != "" {
+		ns.Annotations = map[string]string{"hub.traefik.io/default-access-control-policy": polName}
+	}
+
+	return ns
+}
+
+func TestWatcher_DefaultPolicy(t *testing.T) {
+	listener := &listenerMock{}
+	watcher := NewWatcher(listener)
+
+	watcher.OnAdd(newNamespace("no-default", ""))
+	watcher.OnAdd(newNamespace("my-ns", "my-policy"))
+
+	assert.Equal(t, "", watcher.DefaultPolicy("no-default"))
+	assert.Equal(t, "my-policy", watcher.DefaultPolicy("my-ns"))
+
+	// Updating the namespace without changing its default ACP must not notify the listener.
+	watcher.OnUpdate(newNamespace("my-ns", "my-policy"), newNamespace("my-ns", "my-policy"))
+	watcher.OnUpdate(newNamespace("my-ns", "my-policy"), newNamespace("my-ns", "other-policy"))
+
+	assert.Equal(t, "other-policy", watcher.DefaultPolicy("my-ns"))
+
+	watcher.OnUpdate(newNamespace("my-ns", "other-policy"), newNamespace("my-ns", ""))
+
+	assert.Equal(t, "", watcher.DefaultPolicy("my-ns"))
+
+	watcher.OnAdd(newNamespace("my-ns", "my-policy"))
+	watcher.OnDelete(newNamespace("my-ns", "my-policy"))
+
+	assert.Equal(t, "", watcher.DefaultPolicy("my-ns"))
+
+	assert.Equal(t, []string{"my-ns", "my-ns"}, listener.namespaces)
+}
+
+func TestWatcher_startDoesNotNotify(t *testing.T) {
+	listener := &listenerMock{}
+	watcher := NewWatcher(listener)
+
+	// Initial list of the informer.
+	watcher.OnAdd(newNamespace("my-ns", "my-policy"))
+	watcher.OnAdd(newNamespace("other-ns", "other-policy"))
+
+	// Resync.
+	watcher.OnUpdate(newNamespace("my-ns", "my-policy"), newNamespace("my-ns", "my-policy"))
+
+	assert.Equal(t, "my-policy", watcher.DefaultPolicy("my-ns"))
+	assert.Equal(t, "other-policy", watcher.DefaultPolicy("other-ns"))
+	assert.Empty(t, listener.namespaces)
+}
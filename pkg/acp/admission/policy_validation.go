@@ -0,0 +1,193 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package admission
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/basicauth"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/deny"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt/expr"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/oidc"
+	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
+)
+
+// Placeholders standing for the values the auth server reads from Secrets and ConfigMaps, which are not resolved
+// when validating a policy.
+const (
+	placeholderSecret = "placeholder"
+	placeholderKey    = "0123456789abcdef0123456789abcdef"
+)
+
+// probeTimeout is the maximum duration allowed to reach the remote endpoints of a policy.
+const probeTimeout = 3 * time.Second
+
+// invalidPolicyError is returned when an access control policy cannot be built by the auth server.
+type invalidPolicyError struct {
+	Kind   string
+	Name   string
+	Errors []acp.FieldError
+}
+
+func (e invalidPolicyError) Error() string {
+	details := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		details = append(details, err.Error())
+	}
+
+	return fmt.Sprintf("invalid %s %q: %s", e.Kind, e.Name, strings.Join(details, "; "))
+}
+
+// validatePolicy makes sure the given policy can be built by the auth server, using the same constructors.
+// It returns warnings for the issues which don't prevent the policy from being built, such as an unreachable issuer.
+func (h ACPHandler) validatePolicy(ctx context.Context, policy *hubv1alpha1.AccessControlPolicy) ([]string, error) {
+	if errs := acp.ValidateSpec(&policy.Spec); len(errs) > 0 {
+		return nil, invalidPolicyError{Kind: "AccessControlPolicy", Name: policy.Name, Errors: errs}
+	}
+
+	cfg := acp.ConfigFromPolicy(policy)
+	if err := compilePolicy(policy.Name, cfg); err != nil {
+		return nil, invalidPolicyError{Kind: "AccessControlPolicy", Name: policy.Name, Errors: []acp.FieldError{*err}}
+	}
+
+	return h.probePolicy(ctx, cfg), nil
+}
+
+// validateNamespacedPolicy makes sure the given namespaced policy can be built by the auth server, and doesn't reach
+// beyond its namespace. Its remote endpoints are not probed: namespaced policies may be written by users who must not
+// be able to make the webhook call arbitrary endpoints.
+func validateNamespacedPolicy(policy *hubv1alpha1.NamespacedAccessControlPolicy) error {
+	name := acp.CanonicalName(policy.Name, policy.Namespace)
+
+	if errs := acp.ValidateNamespacedSpec(&policy.Spec, policy.Namespace); len(errs) > 0 {
+		return invalidPolicyError{Kind: "NamespacedAccessControlPolicy", Name: name, Errors: errs}
+	}
+
+	if err := compilePolicy(name, acp.ConfigFromNamespacedPolicy(policy)); err != nil {
+		return invalidPolicyError{Kind: "NamespacedAccessControlPolicy", Name: name, Errors: []acp.FieldError{*err}}
+	}
+
+	return nil
+}
+
+// compilePolicy builds the handler of the given policy configuration without reaching the network.
+func compilePolicy(name string, cfg *acp.Config) *acp.FieldError {
+	switch {
+	case cfg.JWT != nil:
+		// Referenced values are resolved by the auth server. A placeholder secret lets the rest of the
+		// configuration be compiled.
+		if cfg.JWT.SigningSecretRef != nil || (cfg.JWT.SigningSecret == "" && (cfg.JWT.PublicKeyRef != nil || cfg.JWT.JWKsRef != nil)) {
+			cfg.JWT.SigningSecret = placeholderSecret
+			cfg.JWT.SigningSecretBase64Encoded = false
+		}
+
+		if _, err := jwt.NewHandler(cfg.JWT, name); err != nil {
+			return &acp.FieldError{Field: "spec.jwt", Detail: err.Error()}
+		}
+
+	case cfg.BasicAuth != nil:
+		if _, err := basicauth.NewHandler(cfg.BasicAuth, name); err != nil {
+			return &acp.FieldError{Field: "spec.basicAuth", Detail: err.Error()}
+		}
+
+	case cfg.OIDC != nil:
+		if err := compileOIDC(cfg.OIDC); err != nil {
+			return &acp.FieldError{Field: "spec.oidc", Detail: err.Error()}
+		}
+
+	case cfg.OIDCGoogle != nil:
+		if err := compileOIDC(&cfg.OIDCGoogle.Config); err != nil {
+			return &acp.FieldError{Field: "spec.oidcGoogle", Detail: err.Error()}
+		}
+	}
+
+	return nil
+}
+
+// compileOIDC performs the checks oidc.NewHandler does before discovering the provider.
+func compileOIDC(cfg *oidc.Config) error {
+	// The client secret and the key are read from a Secret by the auth server.
+	cfg.ClientSecret = placeholderSecret
+	cfg.Key = placeholderKey
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("validate configuration: %w", err)
+	}
+
+	if cfg.Claims != "" {
+		if _, err := expr.Parse(cfg.Claims); err != nil {
+			return fmt.Errorf("unable to make predicate: %w", err)
+		}
+	}
+
+	if cfg.DenyResponse != nil {
+		if _, err := deny.NewResponder(cfg.DenyResponse); err != nil {
+			return fmt.Errorf("new deny responder: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// probePolicy checks the remote endpoints the given policy configuration depends on are reachable.
+func (h ACPHandler) probePolicy(ctx context.Context, cfg *acp.Config) []string {
+	var warnings []string
+
+	switch {
+	case cfg.JWT != nil && cfg.JWT.JWKsURL != "" && !strings.HasPrefix(cfg.JWT.JWKsURL, "/"):
+		if err := h.probe(ctx, cfg.JWT.JWKsURL); err != nil {
+			warnings = append(warnings, fmt.Sprintf("spec.jwt.jwksUrl: unable to reach %q: %v", cfg.JWT.JWKsURL, err))
+		}
+
+	case cfg.OIDC != nil:
+		discoveryURL := strings.TrimSuffix(cfg.OIDC.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := h.probe(ctx, discoveryURL); err != nil {
+			warnings = append(warnings, fmt.Sprintf("spec.oidc.issuer: unable to reach issuer %q: %v", cfg.OIDC.Issuer, err))
+		}
+	}
+
+	return warnings
+}
+
+func (h ACPHandler) probe(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,473 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/metrics"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/reviewer"
+	"github.com/traefik/hub-agent-kubernetes/pkg/kubevers"
+	admv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Reasons of the events recorded on resources whose ACP wiring drifted.
+const (
+	EventReasonACPDrift         = "ACPDrift"
+	EventReasonACPDriftRepaired = "ACPDriftRepaired"
+)
+
+// Kinds of the resources reconciled by the Reconciler.
+const (
+	kindIngress      = "Ingress"
+	kindIngressRoute = "IngressRoute"
+)
+
+// ReconcilerConfig holds the Reconciler configuration.
+type ReconcilerConfig struct {
+	// Interval is the interval at which all resources are reconciled, in addition to informer events.
+	Interval time.Duration
+	// SelfHeal enables patching the resources whose ACP wiring drifted. Drifts are only reported otherwise.
+	SelfHeal bool
+}
+
+// resourceKey identifies a resource reconciled by the Reconciler.
+type resourceKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// reconciledResource describes a kind of resource reconciled by the Reconciler.
+type reconciledResource struct {
+	gvr schema.GroupVersionResource
+	gvk metav1.GroupVersionKind
+}
+
+// Reconciler continuously checks the ACP wiring of ingresses and IngressRoutes, which is otherwise only applied at
+// admission time. It reviews them again, periodically and on informer events, the same way the admission webhook does:
+// a non-empty patch means the wiring drifted from the expected one, for instance because it has been edited while the
+// webhook was down. Drifts are reported through events and metrics, and optionally repaired by applying the patch.
+// When repairing drifts, reviewers keep the generated ForwardAuth middlewares up to date, like the admission webhook
+// does. Otherwise, reviews are dry runs, see reviewer.WithDryRun.
+// As every replica would report and repair the same drifts, only the one elected as leader reconciles resources.
+type Reconciler struct {
+	reviewers []Reviewer
+	informer  informers.SharedInformerFactory
+	dynClient dynamic.Interface
+	recorder  record.EventRecorder
+	cfg       ReconcilerConfig
+
+	resources map[string]reconciledResource
+	ingRoutes cache.GenericLister
+	queue     workqueue.Interface
+
+	supportsNetV1Ingresses bool
+
+	mu      sync.Mutex
+	leader  bool
+	drifted map[resourceKey]struct{}
+}
+
+// NewReconciler returns a new Reconciler reviewing resources with the given reviewers. IngressRoutes are reconciled
+// only if the given Traefik API group is not empty, see kube.TraefikAPIGroup.
+func NewReconciler(reviewers []Reviewer, informer informers.SharedInformerFactory, dynInformer dynamicinformer.DynamicSharedInformerFactory,
+	dynClient dynamic.Interface, recorder record.EventRecorder, kubeVersion, traefikGroup string, cfg ReconcilerConfig,
+) *Reconciler {
+	r := &Reconciler{
+		reviewers:              reviewers,
+		informer:               informer,
+		dynClient:              dynClient,
+		recorder:               recorder,
+		cfg:                    cfg,
+		resources:              make(map[string]reconciledResource),
+		queue:                  workqueue.New(),
+		supportsNetV1Ingresses: kubevers.SupportsNetV1Ingresses(kubeVersion),
+		drifted:                make(map[resourceKey]struct{}),
+	}
+
+	ingVersion := "v1beta1"
+	if r.supportsNetV1Ingresses {
+		ingVersion = "v1"
+	}
+	r.resources[kindIngress] = reconciledResource{
+		gvr: schema.GroupVersionResource{Group: "networking.k8s.io", Version: ingVersion, Resource: "ingresses"},
+		gvk: metav1.GroupVersionKind{Group: "networking.k8s.io", Version: ingVersion, Kind: kindIngress},
+	}
+
+	if r.supportsNetV1Ingresses {
+		informer.Networking().V1().Ingresses().Informer().AddEventHandler(r.eventHandler(kindIngress))
+	} else {
+		informer.Networking().V1beta1().Ingresses().Informer().AddEventHandler(r.eventHandler(kindIngress))
+	}
+
+	if traefikGroup != "" {
+		ingRoute := reconciledResource{
+			gvr: schema.GroupVersionResource{Group: traefikGroup, Version: "v1alpha1", Resource: "ingressroutes"},
+			gvk: metav1.GroupVersionKind{Group: traefikGroup, Version: "v1alpha1", Kind: kindIngressRoute},
+		}
+		r.resources[kindIngressRoute] = ingRoute
+
+		genericInformer := dynInformer.ForResource(ingRoute.gvr)
+		genericInformer.Informer().AddEventHandler(r.eventHandler(kindIngressRoute))
+		r.ingRoutes = genericInformer.Lister()
+	}
+
+	return r
+}
+
+// Run runs the Reconciler control loop, reconciling all resources every interval and the ones informers notify
+// about in between.
+func (r *Reconciler) Run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		r.queue.ShutDown()
+	}()
+
+	go func() {
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.enqueueAll()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for r.processNext(ctx) {
+	}
+}
+
+// SetLeader sets whether this replica is the leader, hence reconciles resources.
+func (r *Reconciler) SetLeader(leader bool) {
+	r.mu.Lock()
+	r.leader = leader
+	if !leader {
+		// Drifts are reported by the leader only.
+		for key := range r.drifted {
+			delete(r.drifted, key)
+			r.updateDriftedResources(key.kind)
+		}
+	}
+	r.mu.Unlock()
+
+	if leader {
+		r.enqueueAll()
+	}
+}
+
+func (r *Reconciler) isLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.leader
+}
+
+func (r *Reconciler) processNext(ctx context.Context) bool {
+	item, shutdown := r.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer r.queue.Done(item)
+
+	key := item.(resourceKey)
+	if err := r.reconcile(ctx, key); err != nil {
+		log.Error().Err(err).
+			Str("kind", key.kind).
+			Str("name", key.name).
+			Str("namespace", key.namespace).
+			Msg("Unable to reconcile ACP wiring")
+	}
+
+	return true
+}
+
+// eventHandler returns the informer event handler enqueuing the resources of the given kind.
+func (r *Reconciler) eventHandler(kind string) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			r.enqueue(kind, obj)
+		},
+		UpdateFunc: func(_, newObj interface{}) {
+			r.enqueue(kind, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return
+			}
+
+			r.setInSync(resourceKey{kind: kind, namespace: accessor.GetNamespace(), name: accessor.GetName()})
+		},
+	}
+}
+
+func (r *Reconciler) enqueue(kind string, obj interface{}) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		log.Error().Err(err).Str("type", fmt.Sprintf("%T", obj)).Msg("Received event of unknown type")
+		return
+	}
+
+	r.queue.Add(resourceKey{kind: kind, namespace: accessor.GetNamespace(), name: accessor.GetName()})
+}
+
+func (r *Reconciler) enqueueAll() {
+	objs, err := r.listIngresses()
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to list ingresses to reconcile")
+	}
+	for _, obj := range objs {
+		r.enqueue(kindIngress, obj)
+	}
+
+	if r.ingRoutes == nil {
+		return
+	}
+
+	objs, err = r.ingRoutes.List(labels.Everything())
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to list IngressRoutes to reconcile")
+	}
+	for _, obj := range objs {
+		r.enqueue(kindIngressRoute, obj)
+	}
+}
+
+func (r *Reconciler) listIngresses() ([]runtime.Object, error) {
+	var objs []runtime.Object
+	if r.supportsNetV1Ingresses {
+		ings, err := r.informer.Networking().V1().Ingresses().Lister().List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, ing := range ings {
+			objs = append(objs, ing)
+		}
+
+		return objs, nil
+	}
+
+	ings, err := r.informer.Networking().V1beta1().Ingresses().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, ing := range ings {
+		objs = append(objs, ing)
+	}
+
+	return objs, nil
+}
+
+func (r *Reconciler) getResource(key resourceKey) (runtime.Object, error) {
+	switch key.kind {
+	case kindIngress:
+		if r.supportsNetV1Ingresses {
+			return r.informer.Networking().V1().Ingresses().Lister().Ingresses(key.namespace).Get(key.name)
+		}
+		return r.informer.Networking().V1beta1().Ingresses().Lister().Ingresses(key.namespace).Get(key.name)
+	case kindIngressRoute:
+		return r.ingRoutes.ByNamespace(key.namespace).Get(key.name)
+	default:
+		return nil, fmt.Errorf("unsupported kind %q", key.kind)
+	}
+}
+
+// reconcile reviews the resource with the given key and reports, and optionally repairs, its drifting ACP wiring.
+func (r *Reconciler) reconcile(ctx context.Context, key resourceKey) error {
+	if !r.isLeader() {
+		return nil
+	}
+
+	obj, err := r.getResource(key)
+	if err != nil {
+		if kerror.IsNotFound(err) {
+			r.setInSync(key)
+			return nil
+		}
+		return fmt.Errorf("get resource: %w", err)
+	}
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	if !r.usesACP(accessor.GetNamespace(), accessor.GetAnnotations()) {
+		r.setInSync(key)
+		return nil
+	}
+
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("marshal resource: %w", err)
+	}
+
+	resource := r.resources[key.kind]
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			UID:       ktypes.UID("reconcile-" + string(accessor.GetUID())),
+			Kind:      resource.gvk,
+			Name:      key.name,
+			Namespace: key.namespace,
+			Operation: admv1.Update,
+			Object:    runtime.RawExtension{Raw: raw},
+			OldObject: runtime.RawExtension{Raw: raw},
+		},
+	}
+
+	rev, err := findReviewer(r.reviewers, ar)
+	if err != nil {
+		return fmt.Errorf("find reviewer: %w", err)
+	}
+	if rev == nil {
+		// Resources handled by unsupported ingress controllers are rejected at admission time when using ACPs.
+		r.setInSync(key)
+		return nil
+	}
+
+	reviewCtx := ctx
+	if !r.cfg.SelfHeal {
+		reviewCtx = reviewer.WithDryRun(ctx)
+	}
+
+	patches, err := rev.Review(reviewCtx, ar)
+	if err != nil {
+		return fmt.Errorf("review: %w", err)
+	}
+	if len(patches) == 0 {
+		r.setInSync(key)
+		return nil
+	}
+
+	logger := log.With().
+		Str("kind", key.kind).
+		Str("name", key.name).
+		Str("namespace", key.namespace).
+		Logger()
+
+	metrics.IncDrifts(key.kind)
+
+	if !r.cfg.SelfHeal {
+		logger.Warn().Msg("ACP wiring drifted from the expected one")
+		r.recorder.Event(obj, corev1.EventTypeWarning, EventReasonACPDrift, "ACP wiring drifted from the expected one")
+		r.setDrifted(key)
+		return nil
+	}
+
+	err = r.repair(ctx, resource.gvr, key, accessor.GetResourceVersion(), patches)
+	metrics.IncDriftRepairs(key.kind, err)
+	if err != nil {
+		r.recorder.Event(obj, corev1.EventTypeWarning, EventReasonACPDrift, "ACP wiring drifted from the expected one and couldn't be repaired")
+		r.setDrifted(key)
+		return fmt.Errorf("repair: %w", err)
+	}
+
+	logger.Info().Msg("Drifting ACP wiring repaired")
+	r.recorder.Event(obj, corev1.EventTypeNormal, EventReasonACPDriftRepaired, "Drifting ACP wiring repaired")
+	r.setInSync(key)
+
+	return nil
+}
+
+// repair applies the given patches to the resource with the given key, provided it is still at the given version: the
+// patches were computed out of this version and could revert concurrent changes otherwise. Resources changed in the
+// meantime are reconciled again on the related informer event.
+func (r *Reconciler) repair(ctx context.Context, gvr schema.GroupVersionResource, key resourceKey, resourceVersion string, patches []map[string]interface{}) error {
+	precondition := map[string]interface{}{
+		"op":    "test",
+		"path":  "/metadata/resourceVersion",
+		"value": resourceVersion,
+	}
+
+	b, err := json.Marshal(append([]map[string]interface{}{precondition}, patches...))
+	if err != nil {
+		return fmt.Errorf("serialize patch: %w", err)
+	}
+
+	_, err = r.dynClient.Resource(gvr).Namespace(key.namespace).Patch(ctx, key.name, ktypes.JSONPatchType, b, metav1.PatchOptions{FieldManager: "hub-auth"})
+	return err
+}
+
+// usesACP reports whether a resource of the given namespace, with the given annotations, is or has been wired to an
+// ACP, either explicitly or as the default ACP of its namespace.
+func (r *Reconciler) usesACP(namespace string, anno map[string]string) bool {
+	if anno[reviewer.AnnotationHubAuth] != "" || anno[reviewer.AnnotationHubRouteAuth] != "" || anno[reviewer.AnnotationAppliedDefaultHubAuth] != "" {
+		return true
+	}
+
+	return usesDefaultPolicy(namespace, anno) && namespaceDefaultPolicy(r.informer, namespace) != ""
+}
+
+func (r *Reconciler) setDrifted(key resourceKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.drifted[key] = struct{}{}
+	r.updateDriftedResources(key.kind)
+}
+
+func (r *Reconciler) setInSync(key resourceKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.drifted[key]; !ok {
+		return
+	}
+
+	delete(r.drifted, key)
+	r.updateDriftedResources(key.kind)
+}
+
+func (r *Reconciler) updateDriftedResources(kind string) {
+	var n int
+	for key := range r.drifted {
+		if key.kind == kind {
+			n++
+		}
+	}
+
+	metrics.SetDriftedResources(kind, n)
+}
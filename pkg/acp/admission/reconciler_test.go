@@ -0,0 +1,164 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package admission
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestReconciler_reconcile(t *testing.T) {
+	patch := []map[string]interface{}{{
+		"op":    "replace",
+		"path":  "/metadata/annotations",
+		"value": map[string]string{"hub.traefik.io/access-control-policy": "my-policy"},
+	}}
+
+	tests := []struct {
+		desc            string
+		anno            map[string]string
+		notLeader       bool
+		selfHeal        bool
+		resourceVersion string
+		patch           []map[string]interface{}
+		wantEvent       string
+		wantPatched     bool
+		wantDrifted     bool
+		wantErr         bool
+	}{
+		{
+			desc: "resource not using ACPs",
+		},
+		{
+			desc:      "not leader",
+			anno:      map[string]string{"hub.traefik.io/access-control-policy": "my-policy"},
+			notLeader: true,
+		},
+		{
+			desc: "no drift",
+			anno: map[string]string{"hub.traefik.io/access-control-policy": "my-policy"},
+		},
+		{
+			desc:        "drift reported",
+			anno:        map[string]string{"hub.traefik.io/access-control-policy": "my-policy"},
+			patch:       patch,
+			wantEvent:   "Warning ACPDrift ACP wiring drifted from the expected one",
+			wantDrifted: true,
+		},
+		{
+			desc:        "drift repaired",
+			anno:        map[string]string{"hub.traefik.io/access-control-policy": "my-policy"},
+			selfHeal:    true,
+			patch:       patch,
+			wantEvent:   "Normal ACPDriftRepaired Drifting ACP wiring repaired",
+			wantPatched: true,
+		},
+		{
+			desc:            "drift not repaired on concurrent change",
+			anno:            map[string]string{"hub.traefik.io/access-control-policy": "my-policy"},
+			selfHeal:        true,
+			resourceVersion: "2",
+			patch:           patch,
+			wantEvent:       "Warning ACPDrift ACP wiring drifted from the expected one and couldn't be repaired",
+			wantPatched:     true,
+			wantDrifted:     true,
+			wantErr:         true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			ing := &netv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-ingress", Namespace: "my-ns", ResourceVersion: "1", Annotations: test.anno},
+			}
+
+			kubeInformer := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0)
+			err := kubeInformer.Networking().V1().Ingresses().Informer().GetIndexer().Add(ing)
+			require.NoError(t, err)
+
+			resourceVersion := "1"
+			if test.resourceVersion != "" {
+				resourceVersion = test.resourceVersion
+			}
+
+			dynClient := dynfake.NewSimpleDynamicClient(runtime.NewScheme(), &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "networking.k8s.io/v1",
+				"kind":       "Ingress",
+				"metadata":   map[string]interface{}{"name": "my-ingress", "namespace": "my-ns", "resourceVersion": resourceVersion},
+			}})
+			dynInformer := dynamicinformer.NewDynamicSharedInformerFactory(dynClient, 0)
+
+			rev := newReviewerMock(t)
+			if test.anno != nil && !test.notLeader {
+				rev.OnCanReviewRaw(mock.Anything).TypedReturns(true, nil).Once()
+				rev.OnReviewRaw(mock.Anything).TypedReturns(test.patch, nil).Once()
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			r := NewReconciler([]Reviewer{rev}, kubeInformer, dynInformer, dynClient, recorder, "v1.22", "", ReconcilerConfig{
+				Interval: time.Minute,
+				SelfHeal: test.selfHeal,
+			})
+			r.leader = !test.notLeader
+
+			err = r.reconcile(context.Background(), resourceKey{kind: kindIngress, namespace: "my-ns", name: "my-ingress"})
+			if test.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			var patched bool
+			for _, action := range dynClient.Actions() {
+				if _, ok := action.(ktesting.PatchAction); ok {
+					patched = true
+				}
+			}
+			assert.Equal(t, test.wantPatched, patched)
+
+			if test.wantEvent == "" {
+				assert.Empty(t, recorder.Events)
+			} else {
+				require.Len(t, recorder.Events, 1)
+				assert.Equal(t, test.wantEvent, <-recorder.Events)
+			}
+
+			_, drifted := r.drifted[resourceKey{kind: kindIngress, namespace: "my-ns", name: "my-ingress"}]
+			assert.Equal(t, test.wantDrifted, drifted)
+		})
+	}
+}
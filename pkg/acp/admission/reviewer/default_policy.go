@@ -0,0 +1,101 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+// AnnotationDefaultHubAuth is the annotation to add to a Namespace in order to protect the Ingresses and IngressRoutes
+// of the namespace that don't reference an ACP.
+const AnnotationDefaultHubAuth = "hub.traefik.io/default-access-control-policy"
+
+// AnnotationAppliedDefaultHubAuth is the annotation reviewers set on the Ingresses and IngressRoutes they protect with
+// the default ACP of their namespace, to know which settings to clear once this default changes.
+const AnnotationAppliedDefaultHubAuth = "hub.traefik.io/applied-default-access-control-policy"
+
+// AnnotationHubAuthOptOut is the annotation to set to "true" on an Ingress or an IngressRoute in order to opt it out
+// of the default ACP of its namespace.
+const AnnotationHubAuthOptOut = "hub.traefik.io/default-access-control-policy-opt-out"
+
+// DefaultPolicies gives access to the default ACPs of namespaces, see AnnotationDefaultHubAuth.
+type DefaultPolicies interface {
+	// DefaultPolicy returns the name of the default ACP of the given namespace, if any.
+	DefaultPolicy(namespace string) string
+}
+
+// ingressPolicies returns the names of the ACPs protecting the previous and the current versions of an Ingress, given
+// their annotations. An Ingress that doesn't reference an ACP is protected by the default ACP of its namespace, unless
+// it's opted out of it. The returned default is the value the AnnotationAppliedDefaultHubAuth annotation of the
+// Ingress must have.
+func ingressPolicies(defaults DefaultPolicies, namespace string, oldAnno, anno map[string]string) (prevPolName, polName, defaultPolName string) {
+	prevPolName = oldAnno[AnnotationHubAuth]
+	if prevPolName == "" {
+		prevPolName = oldAnno[AnnotationAppliedDefaultHubAuth]
+	}
+
+	polName = anno[AnnotationHubAuth]
+	if polName == "" {
+		defaultPolName = defaultPolicy(defaults, namespace, anno)
+		polName = defaultPolName
+	}
+
+	return prevPolName, polName, defaultPolName
+}
+
+// defaultPolicy returns the default ACP of the given namespace, unless a resource with the given annotations is opted
+// out of it.
+func defaultPolicy(defaults DefaultPolicies, namespace string, anno map[string]string) string {
+	if defaults == nil || anno[AnnotationHubAuthOptOut] == "true" {
+		return ""
+	}
+
+	return defaults.DefaultPolicy(namespace)
+}
+
+// setAppliedDefaultPolicyAnnotation sets the AnnotationAppliedDefaultHubAuth annotation to the given ACP name, removing
+// it if empty. It returns the annotations, created if the resource had none.
+func setAppliedDefaultPolicyAnnotation(anno map[string]string, defaultPolName string) map[string]string {
+	if defaultPolName == "" {
+		delete(anno, AnnotationAppliedDefaultHubAuth)
+		return anno
+	}
+
+	if anno == nil {
+		anno = make(map[string]string)
+	}
+	anno[AnnotationAppliedDefaultHubAuth] = defaultPolName
+
+	return anno
+}
+
+// annotationsPatch returns the patch setting the given annotations on a resource. The annotations are added if the
+// resource didn't have any, as they can't be replaced.
+func annotationsPatch(hadAnnotations bool, anno map[string]string) []map[string]interface{} {
+	return []map[string]interface{}{annotationsPatchOp(hadAnnotations, anno)}
+}
+
+// annotationsPatchOp returns the patch operation setting the given annotations on a resource, see annotationsPatch.
+func annotationsPatchOp(hadAnnotations bool, anno map[string]string) map[string]interface{} {
+	op := "replace"
+	if !hadAnnotations {
+		op = "add"
+	}
+
+	return map[string]interface{}{
+		"op":    op,
+		"path":  "/metadata/annotations",
+		"value": anno,
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
+	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
+	admv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestTraefikIngress_ReviewDefaultPolicy(t *testing.T) {
+	tests := []struct {
+		desc           string
+		currentDefault string
+		oldIngAnno     map[string]string
+		ingAnno        map[string]string
+		wantOp         string
+		wantPatch      map[string]string
+		noPatch        bool
+	}{
+		{
+			desc:           "applies the default policy of the namespace",
+			currentDefault: "default-policy",
+			wantOp:         "add",
+			wantPatch: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "test-zz-default-policy@kubernetescrd",
+			},
+		},
+		{
+			desc:           "switches to the new default policy of the namespace",
+			currentDefault: "default-policy",
+			oldIngAnno: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "old-default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "custom@kubernetescrd,test-zz-old-default-policy@kubernetescrd",
+			},
+			ingAnno: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "old-default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "custom@kubernetescrd,test-zz-old-default-policy@kubernetescrd",
+			},
+			wantOp: "replace",
+			wantPatch: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "custom@kubernetescrd,test-zz-default-policy@kubernetescrd",
+			},
+		},
+		{
+			desc: "removes the default policy once the namespace has none",
+			oldIngAnno: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "test-zz-default-policy@kubernetescrd",
+			},
+			ingAnno: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "test-zz-default-policy@kubernetescrd",
+			},
+			wantOp:    "replace",
+			wantPatch: map[string]string{},
+		},
+		{
+			desc:           "removes the default policy of opted out ingresses",
+			currentDefault: "default-policy",
+			oldIngAnno: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "test-zz-default-policy@kubernetescrd",
+			},
+			ingAnno: map[string]string{
+				"hub.traefik.io/default-access-control-policy-opt-out": "true",
+				"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "test-zz-default-policy@kubernetescrd",
+			},
+			wantOp: "replace",
+			wantPatch: map[string]string{
+				"hub.traefik.io/default-access-control-policy-opt-out": "true",
+			},
+		},
+		{
+			desc:           "returns no patch if the default policy is already applied",
+			currentDefault: "default-policy",
+			oldIngAnno: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "test-zz-default-policy@kubernetescrd",
+			},
+			ingAnno: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+				"traefik.ingress.kubernetes.io/router.middlewares":     "test-zz-default-policy@kubernetescrd",
+			},
+			noPatch: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			traefikClientSet := traefikkubemock.NewSimpleClientset()
+
+			policies := newPolicyGetterMock(t).
+				OnGetConfig("default-policy", "test").TypedReturns("default-policy", &acp.Config{JWT: &jwt.Config{}}, nil).Maybe().
+				Parent
+			defaults := newDefaultPoliciesMock(t).
+				OnDefaultPolicy("test").TypedReturns(test.currentDefault).Maybe().
+				Parent
+
+			rev := NewTraefikIngress(newIngressClassesMock(t), NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1()), defaults)
+
+			ar := admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					Object:    runtime.RawExtension{Raw: marshalIngress(t, test.ingAnno)},
+					OldObject: runtime.RawExtension{Raw: marshalIngress(t, test.oldIngAnno)},
+				},
+			}
+
+			patch, err := rev.Review(context.Background(), ar)
+			require.NoError(t, err)
+
+			if test.noPatch {
+				assert.Nil(t, patch)
+				return
+			}
+			require.NotNil(t, patch)
+
+			assert.Equal(t, test.wantOp, patch[0]["op"])
+			assert.Equal(t, "/metadata/annotations", patch[0]["path"])
+			assert.Equal(t, test.wantPatch, patch[0]["value"])
+		})
+	}
+}
+
+func TestTraefikIngress_ReviewDefaultPolicyOverridden(t *testing.T) {
+	traefikClientSet := traefikkubemock.NewSimpleClientset()
+
+	policies := newPolicyGetterMock(t).
+		OnGetConfig("my-policy", "test").TypedReturns("my-policy", &acp.Config{JWT: &jwt.Config{}}, nil).Once().
+		Parent
+
+	// The default policy of the namespace is not looked up when the Ingress references its own.
+	rev := NewTraefikIngress(newIngressClassesMock(t), NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1()), newDefaultPoliciesMock(t))
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object: runtime.RawExtension{Raw: marshalIngress(t, map[string]string{AnnotationHubAuth: "my-policy"})},
+		},
+	}
+
+	patch, err := rev.Review(context.Background(), ar)
+	require.NoError(t, err)
+	require.NotNil(t, patch)
+
+	assert.Equal(t, map[string]string{
+		AnnotationHubAuth: "my-policy",
+		"traefik.ingress.kubernetes.io/router.middlewares": "test-zz-my-policy@kubernetescrd",
+	}, patch[0]["value"])
+}
+
+func TestNginxIngress_ReviewDefaultPolicy(t *testing.T) {
+	policies := newPolicyGetterMock(t).
+		OnGetConfig("default-policy", "test").TypedReturns("default-policy", &acp.Config{JWT: &jwt.Config{}}, nil).Once().
+		Parent
+	defaults := newDefaultPoliciesMock(t).
+		OnDefaultPolicy("test").TypedReturns("default-policy").Once().
+		Parent
+
+	rev := NewNginxIngress("http://hub-agent.default.svc.cluster.local", nil, policies, defaults)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object: runtime.RawExtension{Raw: marshalIngress(t, nil)},
+		},
+	}
+
+	patch, err := rev.Review(context.Background(), ar)
+	require.NoError(t, err)
+	require.NotNil(t, patch)
+
+	assert.Equal(t, "add", patch[0]["op"])
+	assert.Equal(t, map[string]string{
+		"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+		"nginx.ingress.kubernetes.io/auth-url":                 "http://hub-agent.default.svc.cluster.local/default-policy",
+	}, patch[0]["value"])
+}
+
+func TestTraefikIngressRoute_ReviewDefaultPolicy(t *testing.T) {
+	traefikClientSet := traefikkubemock.NewSimpleClientset()
+
+	policies := newPolicyGetterMock(t).
+		OnGetConfig("default-policy", "test").TypedReturns("default-policy", &acp.Config{JWT: &jwt.Config{}}, nil).Once().
+		Parent
+	defaults := newDefaultPoliciesMock(t).
+		OnDefaultPolicy("test").TypedReturns("default-policy").
+		Parent
+
+	rev := NewTraefikIngressRoute(NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1()), defaults)
+
+	ing := traefikv1alpha1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "test",
+			Annotations: map[string]string{
+				"hub.traefik.io/route-access-control-policies":         `{"1": ""}`,
+				"hub.traefik.io/applied-default-access-control-policy": "old-default-policy",
+			},
+		},
+		Spec: traefikv1alpha1.IngressRouteSpec{
+			Routes: []traefikv1alpha1.Route{
+				{
+					Match: "PathPrefix(`/`)",
+					Middlewares: []traefikv1alpha1.MiddlewareRef{
+						{Name: "zz-old-default-policy", Namespace: "test"},
+					},
+				},
+				{
+					Match: "PathPrefix(`/public`)",
+					Middlewares: []traefikv1alpha1.MiddlewareRef{
+						{Name: "zz-old-default-policy", Namespace: "test"},
+					},
+				},
+			},
+		},
+	}
+	b, err := json.Marshal(ing)
+	require.NoError(t, err)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object:    runtime.RawExtension{Raw: b},
+			OldObject: runtime.RawExtension{Raw: b},
+		},
+	}
+
+	patch, err := rev.Review(context.Background(), ar)
+	require.NoError(t, err)
+	require.Len(t, patch, 2)
+
+	assert.Equal(t, "/spec/routes", patch[0]["path"])
+	assert.Equal(t, []traefikv1alpha1.Route{
+		{
+			Match: "PathPrefix(`/`)",
+			Middlewares: []traefikv1alpha1.MiddlewareRef{
+				{Name: "zz-default-policy", Namespace: "test"},
+			},
+		},
+		{
+			Match:       "PathPrefix(`/public`)",
+			Middlewares: []traefikv1alpha1.MiddlewareRef{},
+		},
+	}, patch[0]["value"])
+
+	assert.Equal(t, "replace", patch[1]["op"])
+	assert.Equal(t, "/metadata/annotations", patch[1]["path"])
+	assert.Equal(t, map[string]string{
+		"hub.traefik.io/route-access-control-policies":         `{"1": ""}`,
+		"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+	}, patch[1]["value"])
+}
+
+func TestTraefikIngressRoute_ReviewDefaultPolicyRemoved(t *testing.T) {
+	defaults := newDefaultPoliciesMock(t).
+		OnDefaultPolicy("test").TypedReturns("").
+		Parent
+
+	rev := NewTraefikIngressRoute(NewFwdAuthMiddlewares("", nil, nil), defaults)
+
+	ing := traefikv1alpha1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "test",
+			Annotations: map[string]string{
+				"hub.traefik.io/applied-default-access-control-policy": "default-policy",
+			},
+		},
+		Spec: traefikv1alpha1.IngressRouteSpec{
+			Routes: []traefikv1alpha1.Route{
+				{
+					Match: "PathPrefix(`/`)",
+					Middlewares: []traefikv1alpha1.MiddlewareRef{
+						{Name: "custom", Namespace: "test"},
+						{Name: "zz-default-policy", Namespace: "test"},
+					},
+				},
+			},
+		},
+	}
+	b, err := json.Marshal(ing)
+	require.NoError(t, err)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object:    runtime.RawExtension{Raw: b},
+			OldObject: runtime.RawExtension{Raw: b},
+		},
+	}
+
+	patch, err := rev.Review(context.Background(), ar)
+	require.NoError(t, err)
+	require.Len(t, patch, 2)
+
+	assert.Equal(t, []traefikv1alpha1.Route{
+		{
+			Match: "PathPrefix(`/`)",
+			Middlewares: []traefikv1alpha1.MiddlewareRef{
+				{Name: "custom", Namespace: "test"},
+			},
+		},
+	}, patch[0]["value"])
+	assert.Equal(t, map[string]string{}, patch[1]["value"])
+}
@@ -0,0 +1,345 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+	admv1 "k8s.io/api/admission/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Filter of HTTPRoute rules referencing Traefik middlewares.
+const (
+	filterTypeExtensionRef = "ExtensionRef"
+	middlewareKind         = "Middleware"
+)
+
+// gatewayGroup is the API group of the Gateway API resources.
+const gatewayGroup = "gateway.networking.k8s.io"
+
+// traefikGatewayController is the controller name of the GatewayClasses handled by Traefik.
+const traefikGatewayController = "traefik.io/gateway-controller"
+
+// gatewayVersions are the Gateway API versions Gateways and GatewayClasses are looked up with, by order of preference.
+var gatewayVersions = []string{"v1", "v1beta1", "v1alpha2"}
+
+// GatewayHTTPRoute is a reviewer that can handle Gateway API HTTPRoute resources.
+// ACPs are enforced through an ExtensionRef filter referencing a Traefik forwardAuth middleware, which requires
+// Traefik to have the Kubernetes Gateway provider enabled. Therefore, only HTTPRoutes whose parent Gateways are all
+// handled by Traefik can be protected by an ACP.
+type GatewayHTTPRoute struct {
+	fwdAuthMiddlewares FwdAuthMiddlewares
+	middlewareGroup    string
+	client             dynamic.Interface
+}
+
+// NewGatewayHTTPRoute returns a Gateway API HTTPRoute reviewer. The given group is the API group of the Traefik
+// middlewares referenced by the HTTPRoutes, which defaults to traefik.containo.us. The given client is used to
+// resolve the controller of the Gateways the HTTPRoutes are attached to.
+func NewGatewayHTTPRoute(fwdAuthMiddlewares FwdAuthMiddlewares, middlewareGroup string, client dynamic.Interface) *GatewayHTTPRoute {
+	if middlewareGroup == "" {
+		middlewareGroup = traefikv1alpha1.GroupName
+	}
+
+	return &GatewayHTTPRoute{
+		fwdAuthMiddlewares: fwdAuthMiddlewares,
+		middlewareGroup:    middlewareGroup,
+		client:             client,
+	}
+}
+
+// httpRoute is the subset of a Gateway API HTTPRoute this reviewer needs. Rules are kept unstructured to patch them
+// without losing the fields of the Gateway API versions this reviewer doesn't know about.
+type httpRoute struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec struct {
+		ParentRefs []parentRef              `json:"parentRefs,omitempty"`
+		Rules      []map[string]interface{} `json:"rules,omitempty"`
+	} `json:"spec"`
+}
+
+// parentRef is a reference from an HTTPRoute to the resource it is attached to, usually a Gateway.
+type parentRef struct {
+	Group     *string `json:"group,omitempty"`
+	Kind      *string `json:"kind,omitempty"`
+	Namespace *string `json:"namespace,omitempty"`
+	Name      string  `json:"name"`
+}
+
+// CanReview returns whether this reviewer can handle the given admission review request.
+func (r GatewayHTTPRoute) CanReview(ar admv1.AdmissionReview) (bool, error) {
+	resource := ar.Request.Kind
+
+	// Check resource type. Only continue if it's an HTTPRoute resource.
+	return isGatewayHTTPRoute(resource), nil
+}
+
+// Review reviews the given admission review request and optionally returns the required patch.
+func (r GatewayHTTPRoute) Review(ctx context.Context, ar admv1.AdmissionReview) ([]map[string]interface{}, error) {
+	logger := log.Ctx(ctx).With().Str("reviewer", "GatewayHTTPRoute").Logger()
+	ctx = logger.WithContext(ctx)
+
+	logger.Info().Msg("Reviewing HTTPRoute resource")
+
+	if ar.Request.Operation == admv1.Delete {
+		log.Ctx(ctx).Info().Msg("Deleting HTTPRoute resource")
+		return nil, nil
+	}
+
+	route, oldRoute, err := parseRawHTTPRoutes(ar.Request.Object.Raw, ar.Request.OldObject.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse raw objects: %w", err)
+	}
+
+	prevPolName := oldRoute.Annotations[AnnotationHubAuth]
+	polName := route.Annotations[AnnotationHubAuth]
+	if prevPolName == "" && polName == "" {
+		logger.Debug().Msg("No ACP defined")
+		return nil, nil
+	}
+
+	var updated bool
+	if prevPolName != "" {
+		logger.Debug().Str("prev_acp_name", prevPolName).Msg("Clearing previous ACP settings")
+
+		// The previous policy may have been either the namespaced or the cluster-wide one.
+		updated = clearExtensionRefs(route.Spec.Rules, middlewareNames(prevPolName, route.Namespace))
+	}
+
+	var mdlwrName string
+	if polName != "" {
+		if err = r.checkParentGateways(ctx, route); err != nil {
+			return nil, err
+		}
+
+		mdlwrName, err = r.fwdAuthMiddlewares.Setup(ctx, polName, route.Namespace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !addExtensionRef(route.Spec.Rules, r.middlewareGroup, mdlwrName) && !updated {
+		logger.Debug().Str("acp_name", polName).Msg("No patch required")
+		return nil, nil
+	}
+
+	logger.Info().Str("acp_name", polName).Msg("Patching resource")
+
+	return []map[string]interface{}{{
+		"op":    "replace",
+		"path":  "/spec/rules",
+		"value": route.Spec.Rules,
+	}}, nil
+}
+
+// checkParentGateways makes sure the given HTTPRoute is attached to Gateways, all handled by Traefik. Other Gateway
+// controllers don't know about Traefik middlewares and would serve the route unprotected, or not at all.
+func (r GatewayHTTPRoute) checkParentGateways(ctx context.Context, route httpRoute) error {
+	if len(route.Spec.ParentRefs) == 0 {
+		return fmt.Errorf("HTTPRoute %s/%s is not attached to any Gateway: ACPs can only be enforced on Traefik Gateways", route.Namespace, route.Name)
+	}
+
+	for _, ref := range route.Spec.ParentRefs {
+		if (ref.Group != nil && *ref.Group != gatewayGroup) || (ref.Kind != nil && *ref.Kind != "Gateway") {
+			return fmt.Errorf("HTTPRoute %s/%s has a parent which is not a Gateway: ACPs can only be enforced on Traefik Gateways", route.Namespace, route.Name)
+		}
+
+		namespace := route.Namespace
+		if ref.Namespace != nil && *ref.Namespace != "" {
+			namespace = *ref.Namespace
+		}
+
+		controller, err := r.gatewayController(ctx, namespace, ref.Name)
+		if err != nil {
+			return fmt.Errorf("resolve controller of Gateway %s/%s: %w", namespace, ref.Name, err)
+		}
+
+		if controller != traefikGatewayController {
+			return fmt.Errorf("HTTPRoute parent Gateway %s/%s is not handled by Traefik (controller %q): ACPs can only be enforced on Traefik Gateways", namespace, ref.Name, controller)
+		}
+	}
+
+	return nil
+}
+
+// gatewayController returns the controller name of the GatewayClass of the given Gateway.
+func (r GatewayHTTPRoute) gatewayController(ctx context.Context, namespace, name string) (string, error) {
+	gateway, err := r.getGatewayResource(ctx, "gateways", namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	className, _, err := unstructured.NestedString(gateway.Object, "spec", "gatewayClassName")
+	if err != nil {
+		return "", fmt.Errorf("read gatewayClassName: %w", err)
+	}
+	if className == "" {
+		return "", errors.New("missing gatewayClassName")
+	}
+
+	class, err := r.getGatewayResource(ctx, "gatewayclasses", "", className)
+	if err != nil {
+		return "", fmt.Errorf("get GatewayClass %q: %w", className, err)
+	}
+
+	controller, _, err := unstructured.NestedString(class.Object, "spec", "controllerName")
+	if err != nil {
+		return "", fmt.Errorf("read controllerName of GatewayClass %q: %w", className, err)
+	}
+
+	return controller, nil
+}
+
+// getGatewayResource gets the given Gateway API resource using the first Gateway API version it is found with.
+func (r GatewayHTTPRoute) getGatewayResource(ctx context.Context, resource, namespace, name string) (*unstructured.Unstructured, error) {
+	var err error
+	for _, version := range gatewayVersions {
+		gvr := schema.GroupVersionResource{Group: gatewayGroup, Version: version, Resource: resource}
+
+		var obj *unstructured.Unstructured
+		obj, err = r.client.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if kerror.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		return obj, nil
+	}
+
+	return nil, err
+}
+
+// addExtensionRef adds an ExtensionRef filter referencing the middleware with the given group and name to the rules
+// missing it.
+func addExtensionRef(rules []map[string]interface{}, group, name string) (updated bool) {
+	if name == "" {
+		return false
+	}
+
+	for _, rule := range rules {
+		filters, _ := rule["filters"].([]interface{})
+
+		var found bool
+		for _, filter := range filters {
+			if refGroup, refName, ok := middlewareExtensionRef(filter); ok && refGroup == group && refName == name {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		rule["filters"] = append(filters, map[string]interface{}{
+			"type": filterTypeExtensionRef,
+			"extensionRef": map[string]interface{}{
+				"group": group,
+				"kind":  middlewareKind,
+				"name":  name,
+			},
+		})
+		updated = true
+	}
+
+	return updated
+}
+
+// clearExtensionRefs removes the ExtensionRef filters referencing the middlewares with the given names, whatever the
+// Traefik API group they belong to.
+func clearExtensionRefs(rules []map[string]interface{}, names []string) (updated bool) {
+	for _, rule := range rules {
+		filters, _ := rule["filters"].([]interface{})
+		if len(filters) == 0 {
+			continue
+		}
+
+		var kept []interface{}
+		for _, filter := range filters {
+			if _, refName, ok := middlewareExtensionRef(filter); ok && containsString(names, refName) {
+				updated = true
+				continue
+			}
+			kept = append(kept, filter)
+		}
+
+		if len(kept) == 0 {
+			delete(rule, "filters")
+			continue
+		}
+		rule["filters"] = kept
+	}
+
+	return updated
+}
+
+// middlewareExtensionRef returns the group and name of the Traefik middleware referenced by the given filter, if it is
+// an ExtensionRef filter referencing one.
+func middlewareExtensionRef(filter interface{}) (group, name string, ok bool) {
+	f, ok := filter.(map[string]interface{})
+	if !ok || f["type"] != filterTypeExtensionRef {
+		return "", "", false
+	}
+
+	ref, ok := f["extensionRef"].(map[string]interface{})
+	if !ok || ref["kind"] != middlewareKind {
+		return "", "", false
+	}
+
+	group, _ = ref["group"].(string)
+	name, _ = ref["name"].(string)
+
+	return group, name, isTraefikGroup(group)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseRawHTTPRoutes parses raw HTTPRoutes from admission requests.
+func parseRawHTTPRoutes(newRaw, oldRaw []byte) (newRoute, oldRoute httpRoute, err error) {
+	if err = json.Unmarshal(newRaw, &newRoute); err != nil {
+		return httpRoute{}, httpRoute{}, fmt.Errorf("unmarshal reviewed HTTPRoute: %w", err)
+	}
+
+	if oldRaw != nil {
+		if err = json.Unmarshal(oldRaw, &oldRoute); err != nil {
+			return httpRoute{}, httpRoute{}, fmt.Errorf("unmarshal reviewed old HTTPRoute: %w", err)
+		}
+	}
+
+	return newRoute, oldRoute, nil
+}
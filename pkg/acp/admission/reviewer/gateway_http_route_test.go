@@ -0,0 +1,372 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
+	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
+	admv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestGatewayHTTPRoute_CanReviewChecksKind(t *testing.T) {
+	tests := []struct {
+		desc      string
+		kind      metav1.GroupVersionKind
+		canReview bool
+	}{
+		{
+			desc: "can review gateway.networking.k8s.io v1 HTTPRoute",
+			kind: metav1.GroupVersionKind{
+				Group:   "gateway.networking.k8s.io",
+				Version: "v1",
+				Kind:    "HTTPRoute",
+			},
+			canReview: true,
+		},
+		{
+			desc: "can review gateway.networking.k8s.io v1beta1 HTTPRoute",
+			kind: metav1.GroupVersionKind{
+				Group:   "gateway.networking.k8s.io",
+				Version: "v1beta1",
+				Kind:    "HTTPRoute",
+			},
+			canReview: true,
+		},
+		{
+			desc: "can review gateway.networking.k8s.io v1alpha2 HTTPRoute",
+			kind: metav1.GroupVersionKind{
+				Group:   "gateway.networking.k8s.io",
+				Version: "v1alpha2",
+				Kind:    "HTTPRoute",
+			},
+			canReview: true,
+		},
+		{
+			desc: "can't review invalid gateway.networking.k8s.io HTTPRoute version",
+			kind: metav1.GroupVersionKind{
+				Group:   "gateway.networking.k8s.io",
+				Version: "invalid",
+				Kind:    "HTTPRoute",
+			},
+		},
+		{
+			desc: "can't review gateway.networking.k8s.io TCPRoute",
+			kind: metav1.GroupVersionKind{
+				Group:   "gateway.networking.k8s.io",
+				Version: "v1alpha2",
+				Kind:    "TCPRoute",
+			},
+		},
+		{
+			desc: "can't review Ingresses",
+			kind: metav1.GroupVersionKind{
+				Group:   "networking.k8s.io",
+				Version: "v1",
+				Kind:    "Ingress",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rev := NewGatewayHTTPRoute(NewFwdAuthMiddlewares("", nil, nil), "", nil)
+
+			ar := admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					Kind: test.kind,
+					Object: runtime.RawExtension{
+						Raw: []byte("{}"),
+					},
+				},
+			}
+
+			ok, err := rev.CanReview(ar)
+			require.NoError(t, err)
+			assert.Equal(t, test.canReview, ok)
+		})
+	}
+}
+
+func TestGatewayHTTPRoute_Review(t *testing.T) {
+	tests := []struct {
+		desc            string
+		middlewareGroup string
+		oldRoute        string
+		route           string
+		wantPatch       string
+	}{
+		{
+			desc: "add authentication to every rule",
+			route: `{
+				"metadata": {"name": "whoami", "namespace": "test", "annotations": {"hub.traefik.io/access-control-policy": "my-policy"}},
+				"spec": {
+					"parentRefs": [{"name": "traefik-gateway"}],
+					"rules": [
+						{"matches": [{"path": {"type": "PathPrefix", "value": "/"}}], "backendRefs": [{"name": "whoami", "port": 80}]},
+						{"filters": [{"type": "RequestHeaderModifier", "requestHeaderModifier": {"add": [{"name": "X-Foo", "value": "bar"}]}}]}
+					]
+				}
+			}`,
+			wantPatch: `[
+				{
+					"matches": [{"path": {"type": "PathPrefix", "value": "/"}}],
+					"backendRefs": [{"name": "whoami", "port": 80}],
+					"filters": [{"type": "ExtensionRef", "extensionRef": {"group": "traefik.containo.us", "kind": "Middleware", "name": "zz--test.my-policy"}}]
+				},
+				{
+					"filters": [
+						{"type": "RequestHeaderModifier", "requestHeaderModifier": {"add": [{"name": "X-Foo", "value": "bar"}]}},
+						{"type": "ExtensionRef", "extensionRef": {"group": "traefik.containo.us", "kind": "Middleware", "name": "zz--test.my-policy"}}
+					]
+				}
+			]`,
+		},
+		{
+			desc: "replace the previous policy",
+			oldRoute: `{
+				"metadata": {"name": "whoami", "namespace": "test", "annotations": {"hub.traefik.io/access-control-policy": "my-old-policy"}},
+				"spec": {"parentRefs": [{"name": "traefik-gateway"}], "rules": [{"filters": [{"type": "ExtensionRef", "extensionRef": {"group": "traefik.containo.us", "kind": "Middleware", "name": "zz-my-old-policy"}}]}]}
+			}`,
+			route: `{
+				"metadata": {"name": "whoami", "namespace": "test", "annotations": {"hub.traefik.io/access-control-policy": "my-policy"}},
+				"spec": {"parentRefs": [{"name": "traefik-gateway"}], "rules": [{"filters": [{"type": "ExtensionRef", "extensionRef": {"group": "traefik.containo.us", "kind": "Middleware", "name": "zz-my-old-policy"}}]}]}
+			}`,
+			wantPatch: `[
+				{"filters": [{"type": "ExtensionRef", "extensionRef": {"group": "traefik.containo.us", "kind": "Middleware", "name": "zz--test.my-policy"}}]}
+			]`,
+		},
+		{
+			desc:            "migrate to the traefik.io group",
+			middlewareGroup: "traefik.io",
+			oldRoute: `{
+				"metadata": {"name": "whoami", "namespace": "test", "annotations": {"hub.traefik.io/access-control-policy": "my-policy"}},
+				"spec": {"parentRefs": [{"name": "traefik-gateway"}], "rules": [{"filters": [{"type": "ExtensionRef", "extensionRef": {"group": "traefik.containo.us", "kind": "Middleware", "name": "zz--test.my-policy"}}]}]}
+			}`,
+			route: `{
+				"metadata": {"name": "whoami", "namespace": "test", "annotations": {"hub.traefik.io/access-control-policy": "my-policy"}},
+				"spec": {"parentRefs": [{"name": "traefik-gateway"}], "rules": [{"filters": [{"type": "ExtensionRef", "extensionRef": {"group": "traefik.containo.us", "kind": "Middleware", "name": "zz--test.my-policy"}}]}]}
+			}`,
+			wantPatch: `[
+				{"filters": [{"type": "ExtensionRef", "extensionRef": {"group": "traefik.io", "kind": "Middleware", "name": "zz--test.my-policy"}}]}
+			]`,
+		},
+		{
+			desc: "remove authentication",
+			oldRoute: `{
+				"metadata": {"name": "whoami", "namespace": "test", "annotations": {"hub.traefik.io/access-control-policy": "my-policy"}},
+				"spec": {"parentRefs": [{"name": "traefik-gateway"}], "rules": [{"filters": [{"type": "ExtensionRef", "extensionRef": {"group": "traefik.containo.us", "kind": "Middleware", "name": "zz--test.my-policy"}}]}]}
+			}`,
+			route: `{
+				"metadata": {"name": "whoami", "namespace": "test"},
+				"spec": {"parentRefs": [{"name": "traefik-gateway"}], "rules": [{"filters": [{"type": "ExtensionRef", "extensionRef": {"group": "traefik.containo.us", "kind": "Middleware", "name": "zz--test.my-policy"}}]}]}
+			}`,
+			wantPatch: `[{}]`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			traefikClientSet := traefikkubemock.NewSimpleClientset()
+
+			policies := newPolicyGetterMock(t)
+			policies.OnGetConfig("my-policy", "test").TypedReturns("my-policy@test", &acp.Config{JWT: &jwt.Config{}}, nil).Maybe()
+
+			rev := NewGatewayHTTPRoute(NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1()), test.middlewareGroup, newGatewayClient(t))
+
+			ar := admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					Operation: admv1.Update,
+					Object: runtime.RawExtension{
+						Raw: []byte(test.route),
+					},
+				},
+			}
+			if test.oldRoute != "" {
+				ar.Request.OldObject = runtime.RawExtension{Raw: []byte(test.oldRoute)}
+			}
+
+			patch, err := rev.Review(context.Background(), ar)
+			require.NoError(t, err)
+			require.NotNil(t, patch)
+
+			assert.Equal(t, "replace", patch[0]["op"])
+			assert.Equal(t, "/spec/rules", patch[0]["path"])
+
+			b, err := json.Marshal(patch[0]["value"])
+			require.NoError(t, err)
+			assert.JSONEq(t, test.wantPatch, string(b))
+		})
+	}
+}
+
+func TestGatewayHTTPRoute_ReviewCreatesMiddleware(t *testing.T) {
+	traefikClientSet := traefikkubemock.NewSimpleClientset()
+
+	policies := newPolicyGetterMock(t)
+	policies.OnGetConfig("my-policy", "test").TypedReturns("my-policy", &acp.Config{JWT: &jwt.Config{
+		ForwardHeaders: map[string]string{"X-User": "sub"},
+	}}, nil).Once()
+
+	rev := NewGatewayHTTPRoute(NewFwdAuthMiddlewares("http://auth-server", policies, traefikClientSet.TraefikV1alpha1()), "", newGatewayClient(t))
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Operation: admv1.Create,
+			Object: runtime.RawExtension{
+				Raw: []byte(`{
+					"metadata": {"name": "whoami", "namespace": "test", "annotations": {"hub.traefik.io/access-control-policy": "my-policy"}},
+					"spec": {"parentRefs": [{"name": "traefik-gateway"}], "rules": [{}]}
+				}`),
+			},
+		},
+	}
+
+	patch, err := rev.Review(context.Background(), ar)
+	require.NoError(t, err)
+	require.NotNil(t, patch)
+
+	m, err := traefikClientSet.TraefikV1alpha1().Middlewares("test").Get(context.Background(), "zz-my-policy", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://auth-server/my-policy", m.Spec.ForwardAuth.Address)
+	assert.Equal(t, []string{"X-User"}, m.Spec.ForwardAuth.AuthResponseHeaders)
+}
+
+func TestGatewayHTTPRoute_ReviewRejectsNonTraefikGateways(t *testing.T) {
+	tests := []struct {
+		desc       string
+		parentRefs string
+		wantErr    string
+	}{
+		{
+			desc:       "no parent",
+			parentRefs: `[]`,
+			wantErr:    "HTTPRoute test/whoami is not attached to any Gateway: ACPs can only be enforced on Traefik Gateways",
+		},
+		{
+			desc:       "Gateway handled by another controller",
+			parentRefs: `[{"name": "traefik-gateway"}, {"name": "other-gateway", "namespace": "infra"}]`,
+			wantErr:    `HTTPRoute parent Gateway infra/other-gateway is not handled by Traefik (controller "example.com/gateway-controller"): ACPs can only be enforced on Traefik Gateways`,
+		},
+		{
+			desc:       "unknown Gateway",
+			parentRefs: `[{"name": "unknown-gateway"}]`,
+			wantErr:    "resolve controller of Gateway test/unknown-gateway",
+		},
+		{
+			desc:       "parent which is not a Gateway",
+			parentRefs: `[{"group": "example.com", "kind": "Mesh", "name": "mesh"}]`,
+			wantErr:    "HTTPRoute test/whoami has a parent which is not a Gateway: ACPs can only be enforced on Traefik Gateways",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			traefikClientSet := traefikkubemock.NewSimpleClientset()
+			rev := NewGatewayHTTPRoute(NewFwdAuthMiddlewares("", newPolicyGetterMock(t), traefikClientSet.TraefikV1alpha1()), "", newGatewayClient(t))
+
+			ar := admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					Operation: admv1.Create,
+					Object: runtime.RawExtension{
+						Raw: []byte(`{
+							"metadata": {"name": "whoami", "namespace": "test", "annotations": {"hub.traefik.io/access-control-policy": "my-policy"}},
+							"spec": {"parentRefs": ` + test.parentRefs + `, "rules": [{}]}
+						}`),
+					},
+				},
+			}
+
+			patch, err := rev.Review(context.Background(), ar)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.wantErr)
+			assert.Nil(t, patch)
+
+			mdlwrs, err := traefikClientSet.TraefikV1alpha1().Middlewares("test").List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, mdlwrs.Items)
+		})
+	}
+}
+
+func TestGatewayHTTPRoute_ReviewWithoutPolicy(t *testing.T) {
+	rev := NewGatewayHTTPRoute(NewFwdAuthMiddlewares("", nil, nil), "", nil)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Operation: admv1.Create,
+			Object: runtime.RawExtension{
+				Raw: []byte(`{"metadata": {"name": "whoami", "namespace": "test"}, "spec": {"parentRefs": [{"name": "traefik-gateway"}], "rules": [{}]}}`),
+			},
+		},
+	}
+
+	patch, err := rev.Review(context.Background(), ar)
+	require.NoError(t, err)
+	assert.Nil(t, patch)
+}
+
+// newGatewayClient returns a dynamic client serving the "test/traefik-gateway" Gateway, handled by Traefik, and the
+// "infra/other-gateway" Gateway, handled by another controller.
+func newGatewayClient(t *testing.T) *dynfake.FakeDynamicClient {
+	t.Helper()
+
+	client := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
+
+	// Objects are created with their resource, as the fake client would guess "gatewaies" out of the Gateway kind.
+	create := func(version, resource, kind, namespace, name string, spec map[string]interface{}) {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "gateway.networking.k8s.io/" + version,
+			"kind":       kind,
+			"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+			"spec":       spec,
+		}}
+
+		gvr := schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: version, Resource: resource}
+		_, err := client.Resource(gvr).Namespace(namespace).Create(context.Background(), obj, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	create("v1beta1", "gatewayclasses", "GatewayClass", "", "traefik", map[string]interface{}{"controllerName": "traefik.io/gateway-controller"})
+	create("v1beta1", "gatewayclasses", "GatewayClass", "", "other", map[string]interface{}{"controllerName": "example.com/gateway-controller"})
+	create("v1beta1", "gateways", "Gateway", "test", "traefik-gateway", map[string]interface{}{"gatewayClassName": "traefik"})
+	create("v1alpha2", "gateways", "Gateway", "infra", "other-gateway", map[string]interface{}{"gatewayClassName": "other"})
+
+	return client
+}
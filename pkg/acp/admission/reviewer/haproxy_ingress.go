@@ -0,0 +1,256 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/ingclass"
+	admv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// haproxyTechControllerPrefix prefixes the controller name of the HAProxy Kubernetes Ingress Controller.
+const haproxyTechControllerPrefix = "haproxy.org/ingress-controller"
+
+// HAProxy Ingress annotations managed by the reviewer.
+const (
+	haproxyAuthURL            = "haproxy-ingress.github.io/auth-url"
+	haproxyAuthHeadersSucceed = "haproxy-ingress.github.io/auth-headers-succeed"
+	haproxyAuthHeadersFail    = "haproxy-ingress.github.io/auth-headers-fail"
+	haproxyConfigBackend      = "haproxy-ingress.github.io/config-backend"
+)
+
+// HAProxyIngress is a reviewer that handles Ingress resources of HAProxy Ingress (haproxy-ingress.github.io), whose
+// external authentication annotations are used to enforce ACPs. The HAProxy Kubernetes Ingress Controller from
+// HAProxy Technologies (haproxy.org) has no such annotations: Ingresses it handles are rejected if they have an ACP.
+type HAProxyIngress struct {
+	agentAddress    string
+	ingressClasses  IngressClasses
+	policies        PolicyGetter
+	defaultPolicies DefaultPolicies
+}
+
+// NewHAProxyIngress returns an HAProxy ingress reviewer.
+func NewHAProxyIngress(authServerAddr string, ingClasses IngressClasses, policies PolicyGetter, defaultPolicies DefaultPolicies) *HAProxyIngress {
+	return &HAProxyIngress{
+		agentAddress:    authServerAddr,
+		ingressClasses:  ingClasses,
+		policies:        policies,
+		defaultPolicies: defaultPolicies,
+	}
+}
+
+// CanReview returns whether this reviewer can handle the given admission review request.
+func (r HAProxyIngress) CanReview(ar admv1.AdmissionReview) (bool, error) {
+	resource := ar.Request.Kind
+
+	// Check resource type. Only continue if it's a legacy Ingress (<1.18) or an Ingress resource.
+	if !isNetV1Ingress(resource) && !isNetV1Beta1Ingress(resource) && !isExtV1Beta1Ingress(resource) {
+		return false, nil
+	}
+
+	obj := ar.Request.Object.Raw
+	if ar.Request.Operation == admv1.Delete {
+		obj = ar.Request.OldObject.Raw
+	}
+
+	ingClassName, ingClassAnno, err := parseIngressClass(obj)
+	if err != nil {
+		return false, fmt.Errorf("parse raw ingress class: %w", err)
+	}
+
+	var ctrlr string
+	switch {
+	case ingClassName != "":
+		ctrlr, err = r.ingressClasses.GetController(ingClassName)
+		if err != nil {
+			return false, fmt.Errorf("get ingress class controller from ingress class name: %w", err)
+		}
+
+	case ingClassAnno == defaultAnnotationHAProxy:
+		// Both HAProxy controllers default to this annotation value. Rely on the IngressClass named after it
+		// to tell them apart if there is one, otherwise assume HAProxy Ingress.
+		ctrlr, err = r.ingressClasses.GetController(ingClassAnno)
+		if err != nil {
+			ctrlr = ingclass.ControllerTypeHAProxy
+		}
+
+	case ingClassAnno != "":
+		// Don't return an error if it's the default value of another reviewer,
+		// just say we can't review it.
+		if isDefaultIngressClassValue(ingClassAnno) {
+			return false, nil
+		}
+
+		ctrlr, err = r.ingressClasses.GetController(ingClassAnno)
+		if err != nil {
+			return false, fmt.Errorf("get ingress class controller from annotation: %w", err)
+		}
+
+	default:
+		ctrlr, err = r.ingressClasses.GetDefaultController()
+		if err != nil {
+			return false, fmt.Errorf("get default ingress class controller: %w", err)
+		}
+	}
+
+	if isHAProxyTech(ctrlr) {
+		var hasACP bool
+		hasACP, err = hasACPAnnotation(obj)
+		if err != nil {
+			return false, fmt.Errorf("parse raw ingress annotations: %w", err)
+		}
+		if hasACP {
+			return false, fmt.Errorf("ingress controller %q does not support ACPs, only HAProxy Ingress (%s) does", ctrlr, ingclass.ControllerTypeHAProxy)
+		}
+	}
+
+	return isHAProxy(ctrlr), nil
+}
+
+// Review reviews the given admission review request and optionally returns the required patch.
+func (r HAProxyIngress) Review(ctx context.Context, ar admv1.AdmissionReview) ([]map[string]interface{}, error) {
+	l := log.Ctx(ctx).With().Str("reviewer", "HAProxyIngress").Logger()
+	ctx = l.WithContext(ctx)
+
+	log.Ctx(ctx).Info().Msg("Reviewing Ingress resource")
+
+	if ar.Request.Operation == admv1.Delete {
+		log.Ctx(ctx).Info().Msg("Deleting Ingress resource")
+		return nil, nil
+	}
+
+	ing, oldIng, err := parseRawIngresses(ar.Request.Object.Raw, ar.Request.OldObject.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse raw objects: %w", err)
+	}
+
+	prevPolName, polName, defaultPolName := ingressPolicies(r.defaultPolicies, ing.Metadata.Namespace, oldIng.Metadata.Annotations, ing.Metadata.Annotations)
+
+	if prevPolName == "" && polName == "" {
+		log.Ctx(ctx).Debug().Msg("No ACP defined")
+		return nil, nil
+	}
+
+	// Start from a set of empty annotations so the ones previously generated get removed
+	// if the ACP annotation is removed or no longer requires them.
+	haproxyAnno := map[string]string{
+		haproxyAuthURL:                  "",
+		haproxyAuthHeadersSucceed:       "",
+		haproxyAuthHeadersFail:          "",
+		haproxyConfigBackend:            "",
+		AnnotationAppliedDefaultHubAuth: defaultPolName,
+	}
+	if polName == "" {
+		log.Ctx(ctx).Debug().Msg("No ACP annotation found")
+	} else {
+		log.Ctx(ctx).Debug().Str("acp_name", polName).Msg("ACP annotation is present")
+
+		var (
+			canonicalPolName string
+			polCfg           *acp.Config
+			anno             map[string]string
+		)
+		canonicalPolName, polCfg, err = r.policies.GetConfig(polName, ing.Metadata.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		anno, err = genHAProxyAnnotations(canonicalPolName, polCfg, r.agentAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range anno {
+			haproxyAnno[k] = v
+		}
+	}
+	haproxyAnno[haproxyConfigBackend] = mergeSnippet(ing.Metadata.Annotations[haproxyConfigBackend], haproxyAnno[haproxyConfigBackend])
+
+	if noPatchRequired(ing.Metadata.Annotations, haproxyAnno) {
+		log.Ctx(ctx).Debug().Str("acp_name", polName).Msg("No patch required")
+		return nil, nil
+	}
+
+	hadAnnotations := ing.Metadata.Annotations != nil
+	if !hadAnnotations {
+		ing.Metadata.Annotations = make(map[string]string)
+	}
+	setAnnotations(ing.Metadata.Annotations, haproxyAnno)
+
+	log.Ctx(ctx).Info().Str("acp_name", polName).Msg("Patching resource")
+
+	return annotationsPatch(hadAnnotations, ing.Metadata.Annotations), nil
+}
+
+// genHAProxyAnnotations generates the HAProxy Ingress external authentication annotations for the given policy.
+// HAProxy Ingress relays redirections returned by the auth server to the client, which is what drives the OIDC
+// flow: the auth server redirects to the provider, then handles its callback on the redirect path. This requires
+// the auth server to know the original request, hence the X-Forwarded headers set by the backend snippet, and the
+// state and session cookies to be returned to the client.
+func genHAProxyAnnotations(canonicalPolName string, polCfg *acp.Config, agentAddr string) (map[string]string, error) {
+	headerToFwd, err := headerToForward(polCfg)
+	if err != nil {
+		return nil, fmt.Errorf("get header to forward: %w", err)
+	}
+
+	anno := map[string]string{
+		haproxyAuthURL:            agentAddr + acp.AuthServerPath(canonicalPolName),
+		haproxyAuthHeadersSucceed: strings.Join(headerToFwd, ","),
+	}
+
+	if polCfg.OIDC == nil {
+		return anno, nil
+	}
+
+	anno[haproxyAuthHeadersFail] = "Location,Set-Cookie"
+	anno[haproxyConfigBackend] = wrapHubSnippet(`
+http-request set-header X-Forwarded-Uri %[url]
+http-request set-header X-Forwarded-Host %[req.hdr(host)]
+http-request set-header X-Forwarded-Proto %[ssl_fc,iif(https,http)]
+http-request set-header X-Forwarded-Method %[method]`)
+
+	return anno, nil
+}
+
+func isHAProxy(ctrlr string) bool {
+	return ctrlr == ingclass.ControllerTypeHAProxy
+}
+
+// isHAProxyTech returns whether the given controller is the HAProxy Kubernetes Ingress Controller, whose controller
+// name is suffixed with its ingress class.
+func isHAProxyTech(ctrlr string) bool {
+	return strings.HasPrefix(ctrlr, haproxyTechControllerPrefix+"/")
+}
+
+func hasACPAnnotation(obj []byte) (bool, error) {
+	var ing struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}
+	if err := json.Unmarshal(obj, &ing); err != nil {
+		return false, err
+	}
+
+	return ing.Metadata.Annotations[AnnotationHubAuth] != "", nil
+}
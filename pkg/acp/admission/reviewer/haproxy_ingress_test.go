@@ -0,0 +1,324 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/ingclass"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/basicauth"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/oidc"
+	admv1 "k8s.io/api/admission/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestHAProxyIngress_CanReviewChecksIngressClass(t *testing.T) {
+	tests := []struct {
+		desc              string
+		kind              metav1.GroupVersionKind
+		annotation        string
+		spec              string
+		acp               string
+		haproxyClass      string
+		defaultController string
+		canReview         bool
+		wantErr           string
+	}{
+		{
+			desc:              "can review a valid resource",
+			defaultController: ingclass.ControllerTypeHAProxy,
+			canReview:         true,
+		},
+		{
+			desc: "can't review non Ingress resources",
+			kind: metav1.GroupVersionKind{
+				Group:   "networking.k8s.io",
+				Version: "v1",
+				Kind:    "NetworkPolicy",
+			},
+			defaultController: ingclass.ControllerTypeHAProxy,
+			canReview:         false,
+		},
+		{
+			desc:              "can't review if the default controller is not of the correct type",
+			defaultController: ingclass.ControllerTypeNginxCommunity,
+			canReview:         false,
+		},
+		{
+			desc:              "can review if using the haproxy annotation",
+			annotation:        "haproxy",
+			defaultController: ingclass.ControllerTypeNginxCommunity,
+			canReview:         true,
+		},
+		{
+			desc:              "can't review if using another annotation",
+			annotation:        "nginx",
+			defaultController: ingclass.ControllerTypeHAProxy,
+			canReview:         false,
+		},
+		{
+			desc:              "can review if using a custom ingress class with haproxy value (spec)",
+			spec:              "custom-haproxy-ingress-class",
+			defaultController: ingclass.ControllerTypeNginxCommunity,
+			canReview:         true,
+		},
+		{
+			desc:              "can't review if using the HAProxy Kubernetes Ingress Controller",
+			spec:              "haproxy-tech",
+			defaultController: ingclass.ControllerTypeHAProxy,
+			canReview:         false,
+		},
+		{
+			desc:              "can't review if the haproxy annotation refers to the HAProxy Kubernetes Ingress Controller",
+			annotation:        "haproxy",
+			haproxyClass:      "haproxy.org/ingress-controller/haproxy",
+			defaultController: ingclass.ControllerTypeHAProxy,
+			canReview:         false,
+		},
+		{
+			desc:              "rejects ACPs on the HAProxy Kubernetes Ingress Controller",
+			spec:              "haproxy-tech",
+			acp:               "my-policy",
+			defaultController: ingclass.ControllerTypeHAProxy,
+			wantErr:           `ingress controller "haproxy.org/ingress-controller/haproxy" does not support ACPs, only HAProxy Ingress (haproxy-ingress.github.io/controller) does`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			var haproxyClassErr error
+			if test.haproxyClass == "" {
+				haproxyClassErr = errors.New("not found")
+			}
+
+			i := newIngressClassesMock(t).
+				OnGetController("custom-haproxy-ingress-class").TypedReturns(ingclass.ControllerTypeHAProxy, nil).Maybe().
+				OnGetController("haproxy").TypedReturns(test.haproxyClass, haproxyClassErr).Maybe().
+				OnGetController("haproxy-tech").TypedReturns("haproxy.org/ingress-controller/haproxy", nil).Maybe().
+				OnGetDefaultController().TypedReturns(test.defaultController, nil).Maybe().
+				Parent
+
+			review := NewHAProxyIngress("", i, nil, nil)
+
+			ing := netv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"kubernetes.io/ingress.class": test.annotation,
+						AnnotationHubAuth:             test.acp,
+					},
+				},
+				Spec: netv1.IngressSpec{
+					IngressClassName: &test.spec,
+				},
+			}
+
+			b, err := json.Marshal(ing)
+			require.NoError(t, err)
+
+			kind := test.kind
+			if kind.Kind == "" {
+				kind = metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}
+			}
+
+			ar := admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					Kind: kind,
+					Object: runtime.RawExtension{
+						Raw: b,
+					},
+				},
+			}
+
+			ok, err := review.CanReview(ar)
+			if test.wantErr != "" {
+				assert.EqualError(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.canReview, ok)
+		})
+	}
+}
+
+func TestHAProxyIngress_Review(t *testing.T) {
+	tests := []struct {
+		desc            string
+		config          acp.Config
+		prevAnnotations map[string]string
+		ingAnnotations  map[string]string
+		wantPatch       map[string]string
+		noPatch         bool
+	}{
+		{
+			desc: "adds authentication if ACP annotation is set",
+			config: acp.Config{
+				BasicAuth: &basicauth.Config{
+					ForwardUsernameHeader: "User",
+				},
+			},
+			ingAnnotations: map[string]string{
+				"hub.traefik.io/access-control-policy": "my-policy",
+				"custom-annotation":                    "foobar",
+			},
+			wantPatch: map[string]string{
+				"hub.traefik.io/access-control-policy":           "my-policy",
+				"haproxy-ingress.github.io/auth-url":             "http://hub-agent.default.svc.cluster.local/my-policy",
+				"haproxy-ingress.github.io/auth-headers-succeed": "User",
+				"custom-annotation":                              "foobar",
+			},
+		},
+		{
+			desc: "adds authentication with the OIDC redirect flow",
+			config: acp.Config{
+				OIDC: &oidc.Config{
+					RedirectURL: "https://example.com/callback",
+				},
+			},
+			ingAnnotations: map[string]string{
+				"hub.traefik.io/access-control-policy":     "my-policy",
+				"haproxy-ingress.github.io/config-backend": "# Stuff before.",
+			},
+			wantPatch: map[string]string{
+				"hub.traefik.io/access-control-policy":           "my-policy",
+				"haproxy-ingress.github.io/auth-url":             "http://hub-agent.default.svc.cluster.local/my-policy",
+				"haproxy-ingress.github.io/auth-headers-succeed": "Authorization,Cookie",
+				"haproxy-ingress.github.io/auth-headers-fail":    "Location,Set-Cookie",
+				"haproxy-ingress.github.io/config-backend":       "# Stuff before.\n##hub-snippet-start\nhttp-request set-header X-Forwarded-Uri %[url]\nhttp-request set-header X-Forwarded-Host %[req.hdr(host)]\nhttp-request set-header X-Forwarded-Proto %[ssl_fc,iif(https,http)]\nhttp-request set-header X-Forwarded-Method %[method]\n##hub-snippet-end",
+			},
+		},
+		{
+			desc: "removes authentication if ACP annotation is removed",
+			prevAnnotations: map[string]string{
+				"hub.traefik.io/access-control-policy": "my-policy",
+			},
+			ingAnnotations: map[string]string{
+				"custom-annotation":                              "foobar",
+				"haproxy-ingress.github.io/auth-url":             "http://hub-agent.default.svc.cluster.local/my-policy",
+				"haproxy-ingress.github.io/auth-headers-succeed": "Authorization,Cookie",
+				"haproxy-ingress.github.io/auth-headers-fail":    "Location,Set-Cookie",
+				"haproxy-ingress.github.io/config-backend":       "# Stuff before.\n##hub-snippet-start\nhttp-request set-header X-Forwarded-Uri %[url]\n##hub-snippet-end",
+			},
+			wantPatch: map[string]string{
+				"custom-annotation":                        "foobar",
+				"haproxy-ingress.github.io/config-backend": "# Stuff before.\n",
+			},
+		},
+		{
+			desc: "returns no patch if annotations are already correct",
+			config: acp.Config{
+				BasicAuth: &basicauth.Config{
+					StripAuthorizationHeader: true,
+				},
+			},
+			ingAnnotations: map[string]string{
+				"hub.traefik.io/access-control-policy":           "my-policy",
+				"haproxy-ingress.github.io/auth-url":             "http://hub-agent.default.svc.cluster.local/my-policy",
+				"haproxy-ingress.github.io/auth-headers-succeed": "Authorization",
+			},
+			noPatch: true,
+		},
+		{
+			desc:    "no previous ACP and no current ACP returns an empty patch",
+			noPatch: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			policyGetter := newPolicyGetterMock(t).
+				OnGetConfig(mock.Anything, "test").TypedReturns("my-policy", &test.config, nil).Maybe().
+				Parent
+			rev := NewHAProxyIngress("http://hub-agent.default.svc.cluster.local", nil, policyGetter, nil)
+
+			ing := struct {
+				Metadata metav1.ObjectMeta `json:"metadata"`
+			}{
+				Metadata: metav1.ObjectMeta{
+					Name:        "name",
+					Namespace:   "test",
+					Annotations: test.ingAnnotations,
+				},
+			}
+			b, err := json.Marshal(ing)
+			require.NoError(t, err)
+
+			oldIng := struct {
+				Metadata metav1.ObjectMeta `json:"metadata"`
+			}{
+				Metadata: metav1.ObjectMeta{
+					Name:        "name",
+					Namespace:   "test",
+					Annotations: test.prevAnnotations,
+				},
+			}
+			oldB, err := json.Marshal(oldIng)
+			require.NoError(t, err)
+
+			ar := admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					Object: runtime.RawExtension{
+						Raw: b,
+					},
+					OldObject: runtime.RawExtension{
+						Raw: oldB,
+					},
+				},
+			}
+
+			patch, err := rev.Review(context.Background(), ar)
+			require.NoError(t, err)
+
+			if test.noPatch {
+				assert.Nil(t, patch)
+				return
+			}
+			require.NotNil(t, patch)
+
+			assert.Equal(t, "replace", patch[0]["op"])
+			assert.Equal(t, "/metadata/annotations", patch[0]["path"])
+			assert.Equal(t, test.wantPatch, patch[0]["value"].(map[string]string))
+		})
+	}
+}
+
+func TestGenHAProxyAnnotations_namespacedPolicy(t *testing.T) {
+	polCfg := &acp.Config{
+		BasicAuth: &basicauth.Config{},
+	}
+
+	anno, err := genHAProxyAnnotations("my-policy@my-ns", polCfg, "http://hub-agent.default.svc.cluster.local")
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://hub-agent.default.svc.cluster.local/my-ns/my-policy", anno[haproxyAuthURL])
+	assert.Empty(t, anno[haproxyAuthHeadersFail])
+}
@@ -29,10 +29,18 @@ import (
 // AnnotationHubAuth is the annotation to add to an Ingress resource in order to enable Hub authentication.
 const AnnotationHubAuth = "hub.traefik.io/access-control-policy"
 
+// AnnotationHubRouteAuth is the annotation to add to an IngressRoute resource in order to protect its routes with
+// different ACPs. Its value is a JSON object mapping routes, by index or match expression, to the name of the ACP
+// protecting them, an empty name leaving the route unprotected. Routes it doesn't map are protected by the ACP of the
+// AnnotationHubAuth annotation, if any.
+const AnnotationHubRouteAuth = "hub.traefik.io/route-access-control-policies"
+
 // Ingress controller default annotations.
 const (
 	defaultAnnotationNginx   = "nginx"
 	defaultAnnotationTraefik = "traefik"
+	defaultAnnotationHAProxy = "haproxy"
+	defaultAnnotationKong    = "kong"
 )
 
 // ingress is a generic form of netv1, netv1beta1 and extv1 ingress resources.
@@ -122,14 +130,45 @@ func headerToForward(cfg *acp.Config) ([]string, error) {
 		return nil, errors.New("unsupported ACP type")
 	}
 
+	if cfg.IdentityToken != nil {
+		headerToFwd = append(headerToFwd, cfg.IdentityToken.Header)
+	}
+
+	if cfg.EnforcementMode == acp.EnforcementModeAudit && cfg.WouldDenyHeader != "" {
+		headerToFwd = append(headerToFwd, cfg.WouldDenyHeader)
+	}
+
 	return headerToFwd, nil
 }
 
 func isDefaultIngressClassValue(value string) bool {
 	switch value {
-	case defaultAnnotationTraefik, defaultAnnotationNginx:
+	case defaultAnnotationTraefik, defaultAnnotationNginx, defaultAnnotationHAProxy, defaultAnnotationKong:
 		return true
 	default:
 		return false
 	}
 }
+
+// noPatchRequired reports whether the given annotations already hold the controller specific ones.
+func noPatchRequired(anno, ctrlrAnno map[string]string) bool {
+	for k, v := range ctrlrAnno {
+		if anno[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// setAnnotations sets the controller specific annotations on the given ones, removing those with an empty value.
+func setAnnotations(anno, ctrlrAnno map[string]string) {
+	for k, v := range ctrlrAnno {
+		if v == "" {
+			delete(anno, k)
+			continue
+		}
+
+		anno[k] = v
+	}
+}
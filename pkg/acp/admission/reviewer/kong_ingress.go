@@ -0,0 +1,173 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/ingclass"
+	admv1 "k8s.io/api/admission/v1"
+)
+
+// AnnotationKongPlugins is the annotation listing the KongPlugins applied to a Kong ingress.
+const AnnotationKongPlugins = "konghq.com/plugins"
+
+// KongIngress is a reviewer that handles Kong Ingress resources.
+// Note that this reviewer requires the KongPlugin CRD to be defined in the cluster,
+// and Kong to have the plugin configured in KongPlugins installed, see KongPluginConfig.
+type KongIngress struct {
+	ingressClasses  IngressClasses
+	kongPlugins     KongPlugins
+	defaultPolicies DefaultPolicies
+}
+
+// NewKongIngress returns a Kong ingress reviewer.
+func NewKongIngress(ingClasses IngressClasses, kongPlugins KongPlugins, defaultPolicies DefaultPolicies) *KongIngress {
+	return &KongIngress{
+		ingressClasses:  ingClasses,
+		kongPlugins:     kongPlugins,
+		defaultPolicies: defaultPolicies,
+	}
+}
+
+// CanReview returns whether this reviewer can handle the given admission review request.
+func (r KongIngress) CanReview(ar admv1.AdmissionReview) (bool, error) {
+	resource := ar.Request.Kind
+
+	// Check resource type. Only continue if it's a legacy Ingress (<1.18) or an Ingress resource.
+	if !isNetV1Ingress(resource) && !isNetV1Beta1Ingress(resource) && !isExtV1Beta1Ingress(resource) {
+		return false, nil
+	}
+
+	obj := ar.Request.Object.Raw
+	if ar.Request.Operation == admv1.Delete {
+		obj = ar.Request.OldObject.Raw
+	}
+
+	ingClassName, ingClassAnno, err := parseIngressClass(obj)
+	if err != nil {
+		return false, fmt.Errorf("parse raw ingress class: %w", err)
+	}
+
+	if ingClassName != "" {
+		var ctrlr string
+		ctrlr, err = r.ingressClasses.GetController(ingClassName)
+		if err != nil {
+			return false, fmt.Errorf("get ingress class controller from ingress class name: %w", err)
+		}
+
+		return isKong(ctrlr), nil
+	}
+
+	if ingClassAnno != "" {
+		if ingClassAnno == defaultAnnotationKong {
+			return true, nil
+		}
+
+		// Don't return an error if it's the default value of another reviewer,
+		// just say we can't review it.
+		if isDefaultIngressClassValue(ingClassAnno) {
+			return false, nil
+		}
+
+		var ctrlr string
+		ctrlr, err = r.ingressClasses.GetController(ingClassAnno)
+		if err != nil {
+			return false, fmt.Errorf("get ingress class controller from annotation: %w", err)
+		}
+
+		return isKong(ctrlr), nil
+	}
+
+	defaultCtrlr, err := r.ingressClasses.GetDefaultController()
+	if err != nil {
+		return false, fmt.Errorf("get default ingress class controller: %w", err)
+	}
+
+	return isKong(defaultCtrlr), nil
+}
+
+// Review reviews the given admission review request and optionally returns the required patch.
+func (r KongIngress) Review(ctx context.Context, ar admv1.AdmissionReview) ([]map[string]interface{}, error) {
+	l := log.Ctx(ctx).With().Str("reviewer", "KongIngress").Logger()
+	ctx = l.WithContext(ctx)
+
+	log.Ctx(ctx).Info().Msg("Reviewing Ingress resource")
+
+	if ar.Request.Operation == admv1.Delete {
+		log.Ctx(ctx).Info().Msg("Deleting Ingress resource")
+		return nil, nil
+	}
+
+	ing, oldIng, err := parseRawIngresses(ar.Request.Object.Raw, ar.Request.OldObject.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse raw objects: %w", err)
+	}
+
+	prevPolName, polName, defaultPolName := ingressPolicies(r.defaultPolicies, ing.Metadata.Namespace, oldIng.Metadata.Annotations, ing.Metadata.Annotations)
+
+	if prevPolName == "" && polName == "" {
+		log.Ctx(ctx).Debug().Msg("No ACP defined")
+		return nil, nil
+	}
+
+	plugins := ing.Metadata.Annotations[AnnotationKongPlugins]
+
+	if prevPolName != "" {
+		log.Ctx(ctx).Debug().Str("prev_acp_name", prevPolName).Msg("Clearing previous ACP settings")
+
+		// The previous policy may have been either the namespaced or the cluster-wide one.
+		for _, name := range middlewareNames(prevPolName, ing.Metadata.Namespace) {
+			plugins = removeMiddleware(plugins, name)
+		}
+	}
+
+	if polName != "" {
+		var pluginName string
+		pluginName, err = r.kongPlugins.Setup(ctx, polName, ing.Metadata.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		plugins = appendMiddleware(plugins, pluginName)
+	}
+
+	if ing.Metadata.Annotations[AnnotationKongPlugins] == plugins && ing.Metadata.Annotations[AnnotationAppliedDefaultHubAuth] == defaultPolName {
+		log.Ctx(ctx).Debug().Str("acp_name", polName).Msg("No patch required")
+		return nil, nil
+	}
+
+	hadAnnotations := ing.Metadata.Annotations != nil
+	ing.Metadata.Annotations = setAppliedDefaultPolicyAnnotation(ing.Metadata.Annotations, defaultPolName)
+
+	if plugins != "" {
+		ing.Metadata.Annotations[AnnotationKongPlugins] = plugins
+	} else {
+		delete(ing.Metadata.Annotations, AnnotationKongPlugins)
+	}
+
+	log.Ctx(ctx).Info().Str("acp_name", polName).Msg("Patching resource")
+
+	return annotationsPatch(hadAnnotations, ing.Metadata.Annotations), nil
+}
+
+func isKong(ctrlr string) bool {
+	return ctrlr == ingclass.ControllerTypeKong
+}
@@ -0,0 +1,313 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/ingclass"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/basicauth"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
+	admv1 "k8s.io/api/admission/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestKongIngress_CanReviewChecksIngressClass(t *testing.T) {
+	tests := []struct {
+		desc              string
+		annotation        string
+		spec              string
+		defaultController string
+		canReview         bool
+	}{
+		{
+			desc:              "can review a valid resource",
+			defaultController: ingclass.ControllerTypeKong,
+			canReview:         true,
+		},
+		{
+			desc:              "can't review if the default controller is not of the correct type",
+			defaultController: ingclass.ControllerTypeTraefik,
+			canReview:         false,
+		},
+		{
+			desc:              "can review if using the kong annotation",
+			annotation:        "kong",
+			defaultController: ingclass.ControllerTypeTraefik,
+			canReview:         true,
+		},
+		{
+			desc:              "can't review if using another annotation",
+			annotation:        "traefik",
+			defaultController: ingclass.ControllerTypeKong,
+			canReview:         false,
+		},
+		{
+			desc:              "can review if using a custom ingress class with kong value (spec)",
+			spec:              "custom-kong-ingress-class",
+			defaultController: ingclass.ControllerTypeTraefik,
+			canReview:         true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			i := newIngressClassesMock(t).
+				OnGetController("custom-kong-ingress-class").TypedReturns(ingclass.ControllerTypeKong, nil).Maybe().
+				OnGetDefaultController().TypedReturns(test.defaultController, nil).Maybe().
+				Parent
+
+			review := NewKongIngress(i, KongPlugins{}, nil)
+
+			ing := netv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"kubernetes.io/ingress.class": test.annotation,
+					},
+				},
+				Spec: netv1.IngressSpec{
+					IngressClassName: &test.spec,
+				},
+			}
+
+			b, err := json.Marshal(ing)
+			require.NoError(t, err)
+
+			ar := admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					Kind: metav1.GroupVersionKind{
+						Group:   "networking.k8s.io",
+						Version: "v1",
+						Kind:    "Ingress",
+					},
+					Object: runtime.RawExtension{
+						Raw: b,
+					},
+				},
+			}
+
+			ok, err := review.CanReview(ar)
+			require.NoError(t, err)
+			assert.Equal(t, test.canReview, ok)
+		})
+	}
+}
+
+func TestKongIngress_Review(t *testing.T) {
+	kongPluginCfg := KongPluginConfig{
+		Name:                 "external-auth",
+		AddressField:         "url",
+		ResponseHeadersField: "response_headers",
+	}
+
+	outdatedPlugin := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "configuration.konghq.com/v1",
+		"kind":       "KongPlugin",
+		"metadata": map[string]interface{}{
+			"name":      "zz--test.my-policy",
+			"namespace": "test",
+		},
+		"plugin": "external-auth",
+		"config": map[string]interface{}{
+			"address":               "http://hub-agent-auth-server/outdated",
+			"auth_response_headers": []interface{}{},
+		},
+	}}
+
+	tests := []struct {
+		desc            string
+		config          *acp.Config
+		existingPlugins []runtime.Object
+		oldIngAnno      map[string]string
+		ingAnno         map[string]string
+		wantPatch       map[string]string
+		wantHeaders     []interface{}
+	}{
+		{
+			desc: "add JWT authentication",
+			config: &acp.Config{JWT: &jwt.Config{
+				ForwardHeaders: map[string]string{
+					"fwdHeader": "claim",
+				},
+			}},
+			ingAnno: map[string]string{
+				AnnotationHubAuth:    "my-policy",
+				"konghq.com/plugins": "custom-plugin",
+			},
+			wantPatch: map[string]string{
+				AnnotationHubAuth:    "my-policy",
+				"konghq.com/plugins": "custom-plugin,zz--test.my-policy",
+			},
+			wantHeaders: []interface{}{"fwdHeader"},
+		},
+		{
+			desc: "replace the plugin of the previous policy",
+			config: &acp.Config{BasicAuth: &basicauth.Config{
+				StripAuthorizationHeader: true,
+				ForwardUsernameHeader:    "User",
+			}},
+			oldIngAnno: map[string]string{
+				AnnotationHubAuth:    "my-old-policy",
+				"konghq.com/plugins": "zz-my-old-policy,custom-plugin",
+			},
+			ingAnno: map[string]string{
+				AnnotationHubAuth:    "my-policy",
+				"konghq.com/plugins": "zz-my-old-policy,custom-plugin",
+			},
+			wantPatch: map[string]string{
+				AnnotationHubAuth:    "my-policy",
+				"konghq.com/plugins": "custom-plugin,zz--test.my-policy",
+			},
+			wantHeaders: []interface{}{"User", "Authorization"},
+		},
+		{
+			desc: "update an outdated plugin",
+			config: &acp.Config{BasicAuth: &basicauth.Config{
+				StripAuthorizationHeader: true,
+			}},
+			existingPlugins: []runtime.Object{outdatedPlugin},
+			ingAnno: map[string]string{
+				AnnotationHubAuth: "my-policy",
+			},
+			wantPatch: map[string]string{
+				AnnotationHubAuth:    "my-policy",
+				"konghq.com/plugins": "zz--test.my-policy",
+			},
+			wantHeaders: []interface{}{"Authorization"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := dynfake.NewSimpleDynamicClient(runtime.NewScheme(), test.existingPlugins...)
+
+			policies := newPolicyGetterMock(t)
+			policies.OnGetConfig("my-policy", "test").TypedReturns("my-policy@test", test.config, nil).Once()
+
+			rev := NewKongIngress(newIngressClassesMock(t), NewKongPlugins("http://hub-agent-auth-server", policies, client, kongPluginCfg), nil)
+
+			ar := admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					Object: runtime.RawExtension{
+						Raw: marshalIngress(t, test.ingAnno),
+					},
+					OldObject: runtime.RawExtension{
+						Raw: marshalIngress(t, test.oldIngAnno),
+					},
+				},
+			}
+
+			patch, err := rev.Review(context.Background(), ar)
+			require.NoError(t, err)
+			require.NotNil(t, patch)
+
+			assert.Equal(t, "replace", patch[0]["op"])
+			assert.Equal(t, "/metadata/annotations", patch[0]["path"])
+			assert.Equal(t, test.wantPatch, patch[0]["value"].(map[string]string))
+
+			plugin, err := client.Resource(KongPluginGVR).Namespace("test").
+				Get(context.Background(), "zz--test.my-policy", metav1.GetOptions{})
+			require.NoError(t, err)
+
+			assert.Equal(t, "external-auth", plugin.Object["plugin"])
+			assert.Equal(t, map[string]interface{}{
+				"url":              "http://hub-agent-auth-server/test/my-policy",
+				"response_headers": test.wantHeaders,
+			}, plugin.Object["config"])
+			assert.Equal(t, map[string]string{
+				"app.kubernetes.io/managed-by":                   "traefik-hub",
+				"hub.traefik.io/access-control-policy":           "my-policy",
+				"hub.traefik.io/access-control-policy-namespace": "test",
+			}, plugin.GetLabels())
+		})
+	}
+}
+
+func TestKongIngress_ReviewWithoutPluginConfigured(t *testing.T) {
+	client := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
+	rev := NewKongIngress(newIngressClassesMock(t), NewKongPlugins("http://hub-agent-auth-server", newPolicyGetterMock(t), client, KongPluginConfig{}), nil)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object: runtime.RawExtension{
+				Raw: marshalIngress(t, map[string]string{AnnotationHubAuth: "my-policy"}),
+			},
+		},
+	}
+
+	_, err := rev.Review(context.Background(), ar)
+	assert.EqualError(t, err, "no Kong plugin is configured to enforce ACPs")
+}
+
+func TestKongIngress_ReviewRemovesPlugin(t *testing.T) {
+	rev := NewKongIngress(newIngressClassesMock(t), KongPlugins{}, nil)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object: runtime.RawExtension{
+				Raw: marshalIngress(t, map[string]string{"konghq.com/plugins": "zz--test.my-policy"}),
+			},
+			OldObject: runtime.RawExtension{
+				Raw: marshalIngress(t, map[string]string{
+					AnnotationHubAuth:    "my-policy",
+					"konghq.com/plugins": "zz--test.my-policy",
+				}),
+			},
+		},
+	}
+
+	patch, err := rev.Review(context.Background(), ar)
+	require.NoError(t, err)
+	require.NotNil(t, patch)
+
+	assert.Equal(t, map[string]string{}, patch[0]["value"].(map[string]string))
+}
+
+func marshalIngress(t *testing.T, anno map[string]string) []byte {
+	t.Helper()
+
+	ing := struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}{
+		Metadata: metav1.ObjectMeta{
+			Name:        "name",
+			Namespace:   "test",
+			Annotations: anno,
+		},
+	}
+
+	b, err := json.Marshal(ing)
+	require.NoError(t, err)
+
+	return b
+}
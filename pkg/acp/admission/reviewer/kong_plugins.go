@@ -0,0 +1,188 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// KongPluginGVR is the resource of the KongPlugin CRD, installed along with the Kong Ingress Controller.
+var KongPluginGVR = schema.GroupVersionResource{Group: "configuration.konghq.com", Version: "v1", Resource: "kongplugins"}
+
+// KongPluginConfig describes the Kong plugin ACPs are enforced with. Kong doesn't bundle any plugin delegating the
+// authentication of requests to an external server, so it must be a custom or third-party plugin installed in Kong.
+type KongPluginConfig struct {
+	// Name is the name of the plugin. ACPs can't be enforced on Kong ingresses if it is empty.
+	Name string
+	// AddressField is the plugin configuration field set to the auth server URL of the policy.
+	AddressField string
+	// ResponseHeadersField is the plugin configuration field set to the auth server response headers to forward to
+	// the upstream service. They are not configured if it is empty.
+	ResponseHeadersField string
+}
+
+// KongPlugins manages the KongPlugins configuring Kong's authentication plugin for ACPs.
+// Kong Ingress Controller has no generated client in this repository, KongPlugins are handled through the dynamic client.
+// KongPlugins are labeled the same way forwardAuth middlewares are, so the ones no longer referenced can be collected.
+type KongPlugins struct {
+	agentAddress string
+	policies     PolicyGetter
+	client       dynamic.Interface
+	plugin       KongPluginConfig
+}
+
+// NewKongPlugins returns a new KongPlugins, configuring the given Kong plugin.
+func NewKongPlugins(agentAddr string, policies PolicyGetter, client dynamic.Interface, plugin KongPluginConfig) KongPlugins {
+	return KongPlugins{
+		agentAddress: agentAddr,
+		policies:     policies,
+		client:       client,
+		plugin:       plugin,
+	}
+}
+
+// Setup first checks if there is already a KongPlugin for this policy.
+// If one is found, it makes sure it has the correct configuration and if it's not the case, it updates it.
+// If no KongPlugin is found, a new one is created for this policy.
+// The given policy name resolves within the given namespace, see PolicyGetter.
+// KongPlugins are labeled with the policy they are generated for; deleting the ones no longer referenced is done
+// elsewhere, see admission.MiddlewareCollector.
+// In dry runs, the KongPlugin name is returned without creating nor updating the KongPlugin, see WithDryRun.
+func (p KongPlugins) Setup(ctx context.Context, polName, namespace string) (string, error) {
+	logger := log.Ctx(ctx).With().
+		Str("acp_name", polName).
+		Logger()
+	ctx = logger.WithContext(ctx)
+
+	logger.Debug().Msg("Setting up KongPlugin")
+
+	if p.plugin.Name == "" {
+		return "", errors.New("no Kong plugin is configured to enforce ACPs")
+	}
+
+	canonicalPolName, acpCfg, err := p.policies.GetConfig(polName, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	// KongPlugins are named after the policy the same way forwardAuth middlewares are.
+	name := middlewareName(canonicalPolName)
+	if isDryRun(ctx) {
+		return name, nil
+	}
+
+	if err = p.setupPlugin(ctx, name, namespace, canonicalPolName, acpCfg); err != nil {
+		return "", fmt.Errorf("setup KongPlugin: %w", err)
+	}
+
+	return name, nil
+}
+
+func (p KongPlugins) setupPlugin(ctx context.Context, name, namespace, canonicalPolName string, cfg *acp.Config) error {
+	logger := log.Ctx(ctx).With().Str("kong_plugin_name", name).Logger()
+
+	pluginCfg, err := p.newPluginConfig(canonicalPolName, cfg)
+	if err != nil {
+		return fmt.Errorf("new plugin config: %w", err)
+	}
+
+	plugins := p.client.Resource(KongPluginGVR).Namespace(namespace)
+
+	currentPlugin, err := plugins.Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !kerror.IsNotFound(err) {
+		return err
+	}
+
+	if kerror.IsNotFound(err) {
+		logger.Debug().Msg("No KongPlugin found, creating a new one")
+
+		plugin := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": KongPluginGVR.GroupVersion().String(),
+			"kind":       "KongPlugin",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"plugin": p.plugin.Name,
+			"config": pluginCfg,
+		}}
+		plugin.SetLabels(MiddlewareLabels(nil, canonicalPolName))
+
+		if _, err = plugins.Create(ctx, plugin, metav1.CreateOptions{FieldManager: "hub-auth"}); err != nil {
+			return fmt.Errorf("create KongPlugin: %w", err)
+		}
+
+		return nil
+	}
+
+	newLabels := MiddlewareLabels(currentPlugin.GetLabels(), canonicalPolName)
+
+	if currentPlugin.Object["plugin"] == p.plugin.Name && reflect.DeepEqual(currentPlugin.Object["config"], pluginCfg) &&
+		reflect.DeepEqual(currentPlugin.GetLabels(), newLabels) {
+		logger.Debug().Msg("Existing KongPlugin is up do date")
+		return nil
+	}
+
+	logger.Debug().Msg("Existing KongPlugin is outdated, updating it")
+
+	currentPlugin.Object["plugin"] = p.plugin.Name
+	currentPlugin.Object["config"] = pluginCfg
+	currentPlugin.SetLabels(newLabels)
+
+	if _, err = plugins.Update(ctx, currentPlugin, metav1.UpdateOptions{FieldManager: "hub-auth"}); err != nil {
+		return fmt.Errorf("update KongPlugin: %w", err)
+	}
+
+	return nil
+}
+
+// newPluginConfig returns the plugin configuration for the given policy, mapped onto the fields of the configured
+// plugin. Its values mirror the ones of Traefik's forwardAuth middleware, see FwdAuthMiddlewares.
+func (p KongPlugins) newPluginConfig(canonicalPolName string, cfg *acp.Config) (map[string]interface{}, error) {
+	headerToFwd, err := headerToForward(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pluginCfg := map[string]interface{}{
+		p.plugin.AddressField: p.agentAddress + acp.AuthServerPath(canonicalPolName),
+	}
+
+	if p.plugin.ResponseHeadersField != "" {
+		// Values are kept unstructured so they can be compared with the ones of existing KongPlugins.
+		authResponseHeaders := make([]interface{}, 0, len(headerToFwd))
+		for _, header := range headerToFwd {
+			authResponseHeaders = append(authResponseHeaders, header)
+		}
+
+		pluginCfg[p.plugin.ResponseHeadersField] = authResponseHeaders
+	}
+
+	return pluginCfg, nil
+}
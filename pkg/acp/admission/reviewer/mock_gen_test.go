@@ -230,25 +230,26 @@ func newPolicyGetterMock(tb testing.TB) *policyGetterMock {
 	return m
 }
 
-func (_m *policyGetterMock) GetConfig(canonicalName string) (*acp.Config, error) {
-	_ret := _m.Called(canonicalName)
+func (_m *policyGetterMock) GetConfig(name string, namespace string) (string, *acp.Config, error) {
+	_ret := _m.Called(name, namespace)
 
-	if _rf, ok := _ret.Get(0).(func(string) (*acp.Config, error)); ok {
-		return _rf(canonicalName)
+	if _rf, ok := _ret.Get(0).(func(string, string) (string, *acp.Config, error)); ok {
+		return _rf(name, namespace)
 	}
 
-	_ra0, _ := _ret.Get(0).(*acp.Config)
-	_rb1 := _ret.Error(1)
+	_ra0 := _ret.String(0)
+	_rb1, _ := _ret.Get(1).(*acp.Config)
+	_rc2 := _ret.Error(2)
 
-	return _ra0, _rb1
+	return _ra0, _rb1, _rc2
 }
 
-func (_m *policyGetterMock) OnGetConfig(canonicalName string) *policyGetterGetConfigCall {
-	return &policyGetterGetConfigCall{Call: _m.Mock.On("GetConfig", canonicalName), Parent: _m}
+func (_m *policyGetterMock) OnGetConfig(name string, namespace string) *policyGetterGetConfigCall {
+	return &policyGetterGetConfigCall{Call: _m.Mock.On("GetConfig", name, namespace), Parent: _m}
 }
 
-func (_m *policyGetterMock) OnGetConfigRaw(canonicalName interface{}) *policyGetterGetConfigCall {
-	return &policyGetterGetConfigCall{Call: _m.Mock.On("GetConfig", canonicalName), Parent: _m}
+func (_m *policyGetterMock) OnGetConfigRaw(name interface{}, namespace interface{}) *policyGetterGetConfigCall {
+	return &policyGetterGetConfigCall{Call: _m.Mock.On("GetConfig", name, namespace), Parent: _m}
 }
 
 type policyGetterGetConfigCall struct {
@@ -296,28 +297,135 @@ func (_c *policyGetterGetConfigCall) Maybe() *policyGetterGetConfigCall {
 	return _c
 }
 
-func (_c *policyGetterGetConfigCall) TypedReturns(a *acp.Config, b error) *policyGetterGetConfigCall {
-	_c.Call = _c.Return(a, b)
+func (_c *policyGetterGetConfigCall) TypedReturns(a string, b *acp.Config, c error) *policyGetterGetConfigCall {
+	_c.Call = _c.Return(a, b, c)
+	return _c
+}
+
+func (_c *policyGetterGetConfigCall) ReturnsFn(fn func(string, string) (string, *acp.Config, error)) *policyGetterGetConfigCall {
+	_c.Call = _c.Return(fn)
+	return _c
+}
+
+func (_c *policyGetterGetConfigCall) TypedRun(fn func(string, string)) *policyGetterGetConfigCall {
+	_c.Call = _c.Call.Run(func(args mock.Arguments) {
+		_name := args.String(0)
+		_namespace := args.String(1)
+		fn(_name, _namespace)
+	})
+	return _c
+}
+
+func (_c *policyGetterGetConfigCall) OnGetConfig(name string, namespace string) *policyGetterGetConfigCall {
+	return _c.Parent.OnGetConfig(name, namespace)
+}
+
+func (_c *policyGetterGetConfigCall) OnGetConfigRaw(name interface{}, namespace interface{}) *policyGetterGetConfigCall {
+	return _c.Parent.OnGetConfigRaw(name, namespace)
+}
+
+// defaultPoliciesMock mock of DefaultPolicies.
+type defaultPoliciesMock struct{ mock.Mock }
+
+// newDefaultPoliciesMock creates a new defaultPoliciesMock.
+func newDefaultPoliciesMock(tb testing.TB) *defaultPoliciesMock {
+	tb.Helper()
+
+	m := &defaultPoliciesMock{}
+	m.Mock.Test(tb)
+
+	tb.Cleanup(func() { m.AssertExpectations(tb) })
+
+	return m
+}
+
+func (_m *defaultPoliciesMock) DefaultPolicy(namespace string) string {
+	_ret := _m.Called(namespace)
+
+	if _rf, ok := _ret.Get(0).(func(string) string); ok {
+		return _rf(namespace)
+	}
+
+	_ra0 := _ret.String(0)
+
+	return _ra0
+}
+
+func (_m *defaultPoliciesMock) OnDefaultPolicy(namespace string) *defaultPoliciesDefaultPolicyCall {
+	return &defaultPoliciesDefaultPolicyCall{Call: _m.Mock.On("DefaultPolicy", namespace), Parent: _m}
+}
+
+func (_m *defaultPoliciesMock) OnDefaultPolicyRaw(namespace interface{}) *defaultPoliciesDefaultPolicyCall {
+	return &defaultPoliciesDefaultPolicyCall{Call: _m.Mock.On("DefaultPolicy", namespace), Parent: _m}
+}
+
+type defaultPoliciesDefaultPolicyCall struct {
+	*mock.Call
+	Parent *defaultPoliciesMock
+}
+
+func (_c *defaultPoliciesDefaultPolicyCall) Panic(msg string) *defaultPoliciesDefaultPolicyCall {
+	_c.Call = _c.Call.Panic(msg)
+	return _c
+}
+
+func (_c *defaultPoliciesDefaultPolicyCall) Once() *defaultPoliciesDefaultPolicyCall {
+	_c.Call = _c.Call.Once()
+	return _c
+}
+
+func (_c *defaultPoliciesDefaultPolicyCall) Twice() *defaultPoliciesDefaultPolicyCall {
+	_c.Call = _c.Call.Twice()
+	return _c
+}
+
+func (_c *defaultPoliciesDefaultPolicyCall) Times(i int) *defaultPoliciesDefaultPolicyCall {
+	_c.Call = _c.Call.Times(i)
+	return _c
+}
+
+func (_c *defaultPoliciesDefaultPolicyCall) WaitUntil(w <-chan time.Time) *defaultPoliciesDefaultPolicyCall {
+	_c.Call = _c.Call.WaitUntil(w)
+	return _c
+}
+
+func (_c *defaultPoliciesDefaultPolicyCall) After(d time.Duration) *defaultPoliciesDefaultPolicyCall {
+	_c.Call = _c.Call.After(d)
+	return _c
+}
+
+func (_c *defaultPoliciesDefaultPolicyCall) Run(fn func(args mock.Arguments)) *defaultPoliciesDefaultPolicyCall {
+	_c.Call = _c.Call.Run(fn)
+	return _c
+}
+
+func (_c *defaultPoliciesDefaultPolicyCall) Maybe() *defaultPoliciesDefaultPolicyCall {
+	_c.Call = _c.Call.Maybe()
+	return _c
+}
+
+func (_c *defaultPoliciesDefaultPolicyCall) TypedReturns(a string) *defaultPoliciesDefaultPolicyCall {
+	_c.Call = _c.Return(a)
 	return _c
 }
 
-func (_c *policyGetterGetConfigCall) ReturnsFn(fn func(string) (*acp.Config, error)) *policyGetterGetConfigCall {
+func (_c *defaultPoliciesDefaultPolicyCall) ReturnsFn(fn func(string) string) *defaultPoliciesDefaultPolicyCall {
 	_c.Call = _c.Return(fn)
 	return _c
 }
 
-func (_c *policyGetterGetConfigCall) TypedRun(fn func(string)) *policyGetterGetConfigCall {
+func (_c *defaultPoliciesDefaultPolicyCall) TypedRun(fn func(string)) *defaultPoliciesDefaultPolicyCall {
 	_c.Call = _c.Call.Run(func(args mock.Arguments) {
-		_canonicalName := args.String(0)
-		fn(_canonicalName)
+		_namespace := args.String(0)
+		fn(_namespace)
 	})
 	return _c
 }
 
-func (_c *policyGetterGetConfigCall) OnGetConfig(canonicalName string) *policyGetterGetConfigCall {
-	return _c.Parent.OnGetConfig(canonicalName)
+func (_c *defaultPoliciesDefaultPolicyCall) OnDefaultPolicy(namespace string) *defaultPoliciesDefaultPolicyCall {
+	return _c.Parent.OnDefaultPolicy(namespace)
 }
 
-func (_c *policyGetterGetConfigCall) OnGetConfigRaw(canonicalName interface{}) *policyGetterGetConfigCall {
-	return _c.Parent.OnGetConfigRaw(canonicalName)
+func (_c *defaultPoliciesDefaultPolicyCall) OnDefaultPolicyRaw(namespace interface{}) *defaultPoliciesDefaultPolicyCall {
+	return _c.Parent.OnDefaultPolicyRaw(namespace)
 }
@@ -19,3 +19,4 @@ package reviewer
 
 // mocktail:IngressClasses
 // mocktail:PolicyGetter
+// mocktail:DefaultPolicies
@@ -29,17 +29,19 @@ import (
 
 // NginxIngress is a reviewer that handles Nginx Ingress resources.
 type NginxIngress struct {
-	agentAddress   string
-	ingressClasses IngressClasses
-	policies       PolicyGetter
+	agentAddress    string
+	ingressClasses  IngressClasses
+	policies        PolicyGetter
+	defaultPolicies DefaultPolicies
 }
 
 // NewNginxIngress returns an Nginx ingress reviewer.
-func NewNginxIngress(authServerAddr string, ingClasses IngressClasses, policies PolicyGetter) *NginxIngress {
+func NewNginxIngress(authServerAddr string, ingClasses IngressClasses, policies PolicyGetter, defaultPolicies DefaultPolicies) *NginxIngress {
 	return &NginxIngress{
-		agentAddress:   authServerAddr,
-		ingressClasses: ingClasses,
-		policies:       policies,
+		agentAddress:    authServerAddr,
+		ingressClasses:  ingClasses,
+		policies:        policies,
+		defaultPolicies: defaultPolicies,
 	}
 }
 
@@ -101,7 +103,7 @@ func (r NginxIngress) CanReview(ar admv1.AdmissionReview) (bool, error) {
 }
 
 // Review reviews the given admission review request and optionally returns the required patch.
-func (r NginxIngress) Review(ctx context.Context, ar admv1.AdmissionReview) (map[string]interface{}, error) {
+func (r NginxIngress) Review(ctx context.Context, ar admv1.AdmissionReview) ([]map[string]interface{}, error) {
 	l := log.Ctx(ctx).With().Str("reviewer", "NginxIngress").Logger()
 	ctx = l.WithContext(ctx)
 
@@ -117,8 +119,7 @@ func (r NginxIngress) Review(ctx context.Context, ar admv1.AdmissionReview) (map
 		return nil, fmt.Errorf("parse raw objects: %w", err)
 	}
 
-	prevPolName := oldIng.Metadata.Annotations[AnnotationHubAuth]
-	polName := ing.Metadata.Annotations[AnnotationHubAuth]
+	prevPolName, polName, defaultPolName := ingressPolicies(r.defaultPolicies, ing.Metadata.Namespace, oldIng.Metadata.Annotations, ing.Metadata.Annotations)
 
 	if prevPolName == "" && polName == "" {
 		log.Ctx(ctx).Debug().Msg("No ACP defined")
@@ -131,54 +132,37 @@ func (r NginxIngress) Review(ctx context.Context, ar admv1.AdmissionReview) (map
 	} else {
 		log.Ctx(ctx).Debug().Str("acp_name", polName).Msg("ACP annotation is present")
 
-		var polCfg *acp.Config
-		polCfg, err = r.policies.GetConfig(polName)
+		var (
+			canonicalPolName string
+			polCfg           *acp.Config
+		)
+		canonicalPolName, polCfg, err = r.policies.GetConfig(polName, ing.Metadata.Namespace)
 		if err != nil {
 			return nil, err
 		}
 
-		nginxAnno, err = genNginxAnnotations(polName, polCfg, r.agentAddress)
+		nginxAnno, err = genNginxAnnotations(canonicalPolName, polCfg, r.agentAddress)
 		if err != nil {
 			return nil, err
 		}
 	}
 	nginxAnno = mergeSnippets(nginxAnno, ing.Metadata.Annotations)
+	nginxAnno[AnnotationAppliedDefaultHubAuth] = defaultPolName
 
-	if noNginxPatchRequired(ing.Metadata.Annotations, nginxAnno) {
+	if noPatchRequired(ing.Metadata.Annotations, nginxAnno) {
 		log.Ctx(ctx).Debug().Str("acp_name", polName).Msg("No patch required")
 		return nil, nil
 	}
 
-	setNginxAnnotations(ing.Metadata.Annotations, nginxAnno)
-
-	log.Ctx(ctx).Info().Str("acp_name", polName).Msg("Patching resource")
-
-	return map[string]interface{}{
-		"op":    "replace",
-		"path":  "/metadata/annotations",
-		"value": ing.Metadata.Annotations,
-	}, nil
-}
-
-func noNginxPatchRequired(anno, nginxAnno map[string]string) bool {
-	for k, v := range nginxAnno {
-		if anno[k] != v {
-			return false
-		}
+	hadAnnotations := ing.Metadata.Annotations != nil
+	if !hadAnnotations {
+		ing.Metadata.Annotations = make(map[string]string)
 	}
+	setAnnotations(ing.Metadata.Annotations, nginxAnno)
 
-	return true
-}
-
-func setNginxAnnotations(anno, nginxAnno map[string]string) {
-	for k, v := range nginxAnno {
-		if v == "" {
-			delete(anno, k)
-			continue
-		}
+	log.Ctx(ctx).Info().Str("acp_name", polName).Msg("Patching resource")
 
-		anno[k] = v
-	}
+	return annotationsPatch(hadAnnotations, ing.Metadata.Annotations), nil
 }
 
 func isNginx(ctrlr string) bool {
@@ -37,7 +37,7 @@ const (
 	serverSnippet        = "nginx.ingress.kubernetes.io/server-snippet"
 )
 
-func genNginxAnnotations(polName string, polCfg *acp.Config, agentAddr string) (map[string]string, error) {
+func genNginxAnnotations(canonicalPolName string, polCfg *acp.Config, agentAddr string) (map[string]string, error) {
 	headerToFwd, err := headerToForward(polCfg)
 	if err != nil {
 		return nil, fmt.Errorf("get header to forward: %w", err)
@@ -47,12 +47,12 @@ func genNginxAnnotations(polName string, polCfg *acp.Config, agentAddr string) (
 
 	if polCfg.OIDC == nil {
 		return map[string]string{
-			authURL:              fmt.Sprintf("%s/%s", agentAddr, polName),
+			authURL:              agentAddr + acp.AuthServerPath(canonicalPolName),
 			configurationSnippet: wrapHubSnippet(locSnip),
 		}, nil
 	}
 
-	redirectPath, err := redirectPath(polCfg)
+	redirectPaths, err := redirectPaths(polCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -63,19 +63,54 @@ proxy_set_header X-Forwarded-Uri $request_uri;
 proxy_set_header X-Forwarded-Host $host;
 proxy_set_header X-Forwarded-Proto $scheme;
 proxy_set_header X-Forwarded-Method $request_method;`
-	authServerURL := fmt.Sprintf("%s/%s", agentAddr, polName)
+	authServerURL := agentAddr + acp.AuthServerPath(canonicalPolName)
+
+	// The server snippet is added to the server block of every host of the Ingress. Declaring a location for each
+	// redirect path makes the provider callback reach the auth server whatever the host it was configured for.
+	var callbackLocations []string
+	for _, redirectPath := range redirectPaths {
+		callbackLocations = append(callbackLocations, fmt.Sprintf("location %s { proxy_pass %s; %s}", redirectPath, authServerURL, headers))
+	}
 
 	return map[string]string{
 		authURL:              authServerURL,
 		authSignin:           "$url_redirect",
 		authSnippet:          wrapHubSnippet(headers),
 		configurationSnippet: wrapHubSnippet(locSnip + " auth_request_set $url_redirect $upstream_http_url_redirect;"),
-		serverSnippet:        wrapHubSnippet(fmt.Sprintf("location %s { proxy_pass %s; %s}", redirectPath, authServerURL, headers)),
+		serverSnippet:        wrapHubSnippet(strings.Join(callbackLocations, "\n")),
 	}, nil
 }
 
-func redirectPath(polCfg *acp.Config) (string, error) {
-	u, err := url.Parse(polCfg.OIDC.RedirectURL)
+// redirectPaths returns the distinct redirect paths of the given OIDC policy, including host specific ones.
+func redirectPaths(polCfg *acp.Config) ([]string, error) {
+	redirectURLs := []string{polCfg.OIDC.RedirectURL}
+	for _, host := range polCfg.OIDC.Hosts {
+		if host.RedirectURL != "" {
+			redirectURLs = append(redirectURLs, host.RedirectURL)
+		}
+	}
+
+	var paths []string
+	seen := make(map[string]struct{})
+	for _, redirectURL := range redirectURLs {
+		path, err := redirectPath(redirectURL)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+func redirectPath(redirectURL string) (string, error) {
+	u, err := url.Parse(redirectURL)
 	if err != nil {
 		return "", fmt.Errorf("parse redirect url: %w", err)
 	}
@@ -124,7 +124,7 @@ func TestNginxIngress_CanReviewChecksKind(t *testing.T) {
 			ic := newIngressClassesMock(t).
 				OnGetDefaultController().TypedReturns(ingclass.ControllerTypeNginxCommunity, nil).Maybe().
 				Parent
-			review := NewNginxIngress("", ic, nil)
+			review := NewNginxIngress("", ic, nil, nil)
 
 			var ing netv1.Ingress
 			b, err := json.Marshal(ing)
@@ -193,7 +193,7 @@ func TestNginxIngress_CanReviewChecksIngressClass(t *testing.T) {
 				OnGetDefaultController().TypedReturns(test.defaultController, nil).Maybe().
 				Parent
 
-			review := NewNginxIngress("", i, nil)
+			review := NewNginxIngress("", i, nil, nil)
 
 			ing := netv1.Ingress{
 				ObjectMeta: metav1.ObjectMeta{
@@ -462,6 +462,29 @@ func TestNginxIngress_Review(t *testing.T) {
 				"nginx.ingress.kubernetes.io/server-snippet":        "##hub-snippet-start\nlocation /callback { proxy_pass http://hub-agent.default.svc.cluster.local/my-policy; \nproxy_set_header From nginx;\nproxy_set_header X-Forwarded-Uri $request_uri;\nproxy_set_header X-Forwarded-Host $host;\nproxy_set_header X-Forwarded-Proto $scheme;\nproxy_set_header X-Forwarded-Method $request_method;}\n##hub-snippet-end\n# Stuff after.",
 			},
 		},
+		{
+			desc: "oidc annotations with host specific redirect URLs",
+			config: acp.Config{
+				OIDC: &oidc.Config{
+					RedirectURL: "/callback",
+					Hosts: []oidc.HostConfig{
+						{Host: "foo.example.com", RedirectURL: "https://foo.example.com/oauth/callback"},
+						{Host: "*.example.org", RedirectURL: "/callback"},
+					},
+				},
+			},
+			ingAnnotations: map[string]string{
+				"hub.traefik.io/access-control-policy": "my-policy",
+			},
+			wantPatch: map[string]string{
+				"hub.traefik.io/access-control-policy":              "my-policy",
+				"nginx.ingress.kubernetes.io/auth-signin":           "$url_redirect",
+				"nginx.ingress.kubernetes.io/auth-snippet":          "##hub-snippet-start\nproxy_set_header From nginx;\nproxy_set_header X-Forwarded-Uri $request_uri;\nproxy_set_header X-Forwarded-Host $host;\nproxy_set_header X-Forwarded-Proto $scheme;\nproxy_set_header X-Forwarded-Method $request_method;\n##hub-snippet-end",
+				"nginx.ingress.kubernetes.io/auth-url":              "http://hub-agent.default.svc.cluster.local/my-policy",
+				"nginx.ingress.kubernetes.io/configuration-snippet": "##hub-snippet-start\nauth_request_set $value_0 $upstream_http_Authorization; proxy_set_header Authorization $value_0;\nauth_request_set $value_1 $upstream_http_Cookie; proxy_set_header Cookie $value_1;\n auth_request_set $url_redirect $upstream_http_url_redirect;\n##hub-snippet-end",
+				"nginx.ingress.kubernetes.io/server-snippet":        "##hub-snippet-start\nlocation /callback { proxy_pass http://hub-agent.default.svc.cluster.local/my-policy; \nproxy_set_header From nginx;\nproxy_set_header X-Forwarded-Uri $request_uri;\nproxy_set_header X-Forwarded-Host $host;\nproxy_set_header X-Forwarded-Proto $scheme;\nproxy_set_header X-Forwarded-Method $request_method;}\nlocation /oauth/callback { proxy_pass http://hub-agent.default.svc.cluster.local/my-policy; \nproxy_set_header From nginx;\nproxy_set_header X-Forwarded-Uri $request_uri;\nproxy_set_header X-Forwarded-Host $host;\nproxy_set_header X-Forwarded-Proto $scheme;\nproxy_set_header X-Forwarded-Method $request_method;}\n##hub-snippet-end",
+			},
+		},
 		{
 			desc:    "no previous ACP and no current ACP returns an empty patch",
 			noPatch: true,
@@ -474,9 +497,9 @@ func TestNginxIngress_Review(t *testing.T) {
 			t.Parallel()
 
 			policyGetter := newPolicyGetterMock(t).
-				OnGetConfig(mock.Anything).TypedReturns(&test.config, nil).Maybe().
+				OnGetConfig(mock.Anything, "test").TypedReturns("my-policy", &test.config, nil).Maybe().
 				Parent
-			rev := NewNginxIngress("http://hub-agent.default.svc.cluster.local", nil, policyGetter)
+			rev := NewNginxIngress("http://hub-agent.default.svc.cluster.local", nil, policyGetter, nil)
 
 			ing := struct {
 				Metadata metav1.ObjectMeta `json:"metadata"`
@@ -522,10 +545,23 @@ func TestNginxIngress_Review(t *testing.T) {
 			}
 			assert.NotNil(t, patch)
 
-			assert.Equal(t, 3, len(patch))
-			assert.Equal(t, "replace", patch["op"])
-			assert.Equal(t, "/metadata/annotations", patch["path"])
-			assert.Equal(t, test.wantPatch, patch["value"].(map[string]string))
+			require.Len(t, patch, 1)
+			assert.Equal(t, 3, len(patch[0]))
+			assert.Equal(t, "replace", patch[0]["op"])
+			assert.Equal(t, "/metadata/annotations", patch[0]["path"])
+			assert.Equal(t, test.wantPatch, patch[0]["value"].(map[string]string))
 		})
 	}
 }
+
+func TestGenNginxAnnotations_namespacedPolicy(t *testing.T) {
+	polCfg := &acp.Config{
+		OIDC: &oidc.Config{RedirectURL: "https://example.com/callback"},
+	}
+
+	anno, err := genNginxAnnotations("my-policy@my-ns", polCfg, "http://hub-agent.default.svc.cluster.local")
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://hub-agent.default.svc.cluster.local/my-ns/my-policy", anno[authURL])
+	assert.Contains(t, anno[serverSnippet], "proxy_pass http://hub-agent.default.svc.cluster.local/my-ns/my-policy;")
+}
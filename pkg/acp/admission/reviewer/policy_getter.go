@@ -22,11 +22,14 @@ import (
 
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
 	hubinformer "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/informers/externalversions"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
 )
 
 // PolicyGetter allow to get an access control policy configuration.
 type PolicyGetter interface {
-	GetConfig(canonicalName string) (*acp.Config, error)
+	// GetConfig returns the configuration of the policy the given name refers to from the given namespace, along with
+	// the canonical name of this policy.
+	GetConfig(name, namespace string) (canonicalName string, cfg *acp.Config, err error)
 }
 
 // PolGetter implementation the PolicyGetter interface.
@@ -39,12 +42,22 @@ func NewPolGetter(informer hubinformer.SharedInformerFactory) *PolGetter {
 	return &PolGetter{informer: informer}
 }
 
-// GetConfig gets ACP configuration.
-func (p PolGetter) GetConfig(canonicalName string) (*acp.Config, error) {
-	policy, err := p.informer.Hub().V1alpha1().AccessControlPolicies().Lister().Get(canonicalName)
+// GetConfig gets ACP configuration. Names resolve to the namespaced policy of the given namespace first, and fall
+// back to the cluster-wide policy of the same name.
+func (p PolGetter) GetConfig(name, namespace string) (string, *acp.Config, error) {
+	nsPolicy, err := p.informer.Hub().V1alpha1().NamespacedAccessControlPolicies().Lister().
+		NamespacedAccessControlPolicies(namespace).Get(name)
+	switch {
+	case err == nil:
+		return acp.CanonicalName(name, namespace), acp.ConfigFromNamespacedPolicy(nsPolicy), nil
+	case !kerror.IsNotFound(err):
+		return "", nil, fmt.Errorf("get namespaced ACP: %w", err)
+	}
+
+	policy, err := p.informer.Hub().V1alpha1().AccessControlPolicies().Lister().Get(name)
 	if err != nil {
-		return nil, fmt.Errorf("get ACP: %w", err)
+		return "", nil, fmt.Errorf("get ACP: %w", err)
 	}
 
-	return acp.ConfigFromPolicy(policy), nil
+	return name, acp.ConfigFromPolicy(policy), nil
 }
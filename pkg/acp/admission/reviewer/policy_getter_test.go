@@ -0,0 +1,113 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package reviewer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
+	hubkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/clientset/versioned/fake"
+	hubinformer "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/informers/externalversions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPolGetter_GetConfig(t *testing.T) {
+	clusterSpec := hubv1alpha1.AccessControlPolicySpec{
+		BasicAuth: &hubv1alpha1.AccessControlPolicyBasicAuth{Users: []string{"cluster:pass"}},
+	}
+	namespacedSpec := hubv1alpha1.AccessControlPolicySpec{
+		BasicAuth: &hubv1alpha1.AccessControlPolicyBasicAuth{Users: []string{"namespaced:pass"}},
+	}
+
+	clientSet := hubkubemock.NewSimpleClientset(
+		&hubv1alpha1.AccessControlPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-policy"},
+			Spec:       clusterSpec,
+		},
+		&hubv1alpha1.NamespacedAccessControlPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "my-ns"},
+			Spec:       namespacedSpec,
+		},
+		&hubv1alpha1.NamespacedAccessControlPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "tenant-policy", Namespace: "my-ns"},
+			Spec:       namespacedSpec,
+		},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hubInformer := hubinformer.NewSharedInformerFactory(clientSet, 0)
+	hubInformer.Hub().V1alpha1().AccessControlPolicies().Informer()
+	hubInformer.Hub().V1alpha1().NamespacedAccessControlPolicies().Informer()
+	hubInformer.Start(ctx.Done())
+	hubInformer.WaitForCacheSync(ctx.Done())
+
+	getter := NewPolGetter(hubInformer)
+
+	tests := []struct {
+		desc          string
+		name          string
+		namespace     string
+		wantCanonical string
+		wantUsers     []string
+		wantErr       bool
+	}{
+		{
+			desc:          "namespaced policy takes precedence",
+			name:          "my-policy",
+			namespace:     "my-ns",
+			wantCanonical: "my-policy@my-ns",
+			wantUsers:     []string{"namespaced:pass"},
+		},
+		{
+			desc:          "fallback on the cluster-wide policy",
+			name:          "my-policy",
+			namespace:     "other-ns",
+			wantCanonical: "my-policy",
+			wantUsers:     []string{"cluster:pass"},
+		},
+		{
+			desc:      "namespaced policy of another namespace",
+			name:      "tenant-policy",
+			namespace: "other-ns",
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			canonicalName, cfg, err := getter.GetConfig(test.name, test.namespace)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, test.wantCanonical, canonicalName)
+			require.NotNil(t, cfg.BasicAuth)
+			assert.Equal(t, test.wantUsers, []string(cfg.BasicAuth.Users))
+		})
+	}
+}
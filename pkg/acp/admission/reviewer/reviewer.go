@@ -18,6 +18,10 @@ along with this program. If not, see <https://www.gnu.org/licenses/>.
 package reviewer
 
 import (
+	"context"
+
+	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+	traefikiov1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefikio/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,6 +32,19 @@ type IngressClasses interface {
 	GetDefaultController() (string, error)
 }
 
+type dryRunKey struct{}
+
+// WithDryRun returns a context in which reviews have no side effects: the forwardAuth middlewares and KongPlugins
+// the returned patches refer to are neither created nor updated.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+func isDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
 func isNetV1Ingress(resource metav1.GroupVersionKind) bool {
 	return resource.Group == "networking.k8s.io" && resource.Version == "v1" && resource.Kind == "Ingress"
 }
@@ -41,5 +58,24 @@ func isExtV1Beta1Ingress(resource metav1.GroupVersionKind) bool {
 }
 
 func isTraefikV1Alpha1IngressRoute(resource metav1.GroupVersionKind) bool {
-	return resource.Group == "traefik.containo.us" && resource.Version == "v1alpha1" && resource.Kind == "IngressRoute"
+	return isTraefikGroup(resource.Group) && resource.Version == "v1alpha1" && resource.Kind == "IngressRoute"
+}
+
+// isTraefikGroup reports whether the given API group is one of the Traefik CRDs: traefik.io from Traefik v2.10 on,
+// traefik.containo.us before.
+func isTraefikGroup(group string) bool {
+	return group == traefikiov1alpha1.GroupName || group == traefikv1alpha1.GroupName
+}
+
+func isGatewayHTTPRoute(resource metav1.GroupVersionKind) bool {
+	if resource.Group != "gateway.networking.k8s.io" || resource.Kind != "HTTPRoute" {
+		return false
+	}
+
+	switch resource.Version {
+	case "v1", "v1beta1", "v1alpha2":
+		return true
+	default:
+		return false
+	}
 }
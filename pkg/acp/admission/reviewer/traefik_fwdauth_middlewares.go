@@ -28,17 +28,28 @@ import (
 	"github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/typed/traefik/v1alpha1"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Labels of the forwardAuth middlewares generated for ACPs. The ACP labels hold the name and the namespace of the
+// policy a middleware has been generated for. The name is left empty if it is not a valid label value.
+const (
+	LabelManagedBy    = "app.kubernetes.io/managed-by"
+	LabelACP          = "hub.traefik.io/access-control-policy"
+	LabelACPNamespace = "hub.traefik.io/access-control-policy-namespace"
 )
 
 // FwdAuthMiddlewares manages Traefik forwardAuth middlewares.
+// The given client manages the Middlewares of the API group served by the cluster, see kube.TraefikMiddlewares.
 type FwdAuthMiddlewares struct {
 	agentAddress     string
 	policies         PolicyGetter
-	traefikClientSet v1alpha1.TraefikV1alpha1Interface
+	traefikClientSet v1alpha1.MiddlewaresGetter
 }
 
 // NewFwdAuthMiddlewares returns a new FwdAuthMiddlewares.
-func NewFwdAuthMiddlewares(agentAddr string, policies PolicyGetter, traefikClientSet v1alpha1.TraefikV1alpha1Interface) FwdAuthMiddlewares {
+func NewFwdAuthMiddlewares(agentAddr string, policies PolicyGetter, traefikClientSet v1alpha1.MiddlewaresGetter) FwdAuthMiddlewares {
 	return FwdAuthMiddlewares{
 		agentAddress:     agentAddr,
 		policies:         policies,
@@ -49,7 +60,10 @@ func NewFwdAuthMiddlewares(agentAddr string, policies PolicyGetter, traefikClien
 // Setup first checks if there is already a middleware for this policy.
 // If one is found, it makes sure it has the correct spec and if it's not the case, it updates it.
 // If no middleware is found, a new one is created for this policy.
-// NOTE: forward auth middlewares deletion is to be done elsewhere, when ACPs are deleted.
+// The given policy name resolves within the given namespace, see PolicyGetter.
+// Middlewares are labeled with the policy they are generated for; deleting the ones no longer referenced is done
+// elsewhere, see admission.MiddlewareCollector.
+// In dry runs, the middleware name is returned without creating nor updating the middleware, see WithDryRun.
 func (m FwdAuthMiddlewares) Setup(ctx context.Context, polName, namespace string) (string, error) {
 	logger := log.Ctx(ctx).With().
 		Str("acp_name", polName).
@@ -58,13 +72,17 @@ func (m FwdAuthMiddlewares) Setup(ctx context.Context, polName, namespace string
 
 	logger.Debug().Msg("Setting up ForwardAuth middleware")
 
-	acpCfg, err := m.policies.GetConfig(polName)
+	canonicalPolName, acpCfg, err := m.policies.GetConfig(polName, namespace)
 	if err != nil {
 		return "", err
 	}
 
-	name := middlewareName(polName)
-	if err = m.setupMiddleware(ctx, name, namespace, polName, acpCfg); err != nil {
+	name := middlewareName(canonicalPolName)
+	if isDryRun(ctx) {
+		return name, nil
+	}
+
+	if err = m.setupMiddleware(ctx, name, namespace, canonicalPolName, acpCfg); err != nil {
 		return "", fmt.Errorf("setup ForwardAuth middleware: %w", err)
 	}
 
@@ -90,7 +108,9 @@ func (m *FwdAuthMiddlewares) setupMiddleware(ctx context.Context, name, namespac
 		return err
 	}
 
-	if reflect.DeepEqual(currentMiddleware.Spec, newSpec) {
+	newLabels := MiddlewareLabels(currentMiddleware.Labels, canonicalPolName)
+
+	if reflect.DeepEqual(currentMiddleware.Spec, newSpec) && reflect.DeepEqual(currentMiddleware.Labels, newLabels) {
 		logger.Debug().Msg("Existing ForwardAuth middleware is up do date")
 		return nil
 	}
@@ -98,6 +118,7 @@ func (m *FwdAuthMiddlewares) setupMiddleware(ctx context.Context, name, namespac
 	logger.Debug().Msg("Existing ForwardAuth middleware is outdated, updating it")
 
 	currentMiddleware.Spec = newSpec
+	currentMiddleware.Labels = newLabels
 
 	_, err = m.traefikClientSet.Middlewares(namespace).Update(ctx, currentMiddleware, metav1.UpdateOptions{FieldManager: "hub-auth"})
 	if err != nil {
@@ -127,7 +148,7 @@ func (m *FwdAuthMiddlewares) newMiddlewareSpec(canonicalPolName string, cfg *acp
 
 	return traefikv1alpha1.MiddlewareSpec{
 		ForwardAuth: &traefikv1alpha1.ForwardAuth{
-			Address:             m.agentAddress + "/" + canonicalPolName,
+			Address:             m.agentAddress + acp.AuthServerPath(canonicalPolName),
 			AuthResponseHeaders: authResponseHeaders,
 		},
 	}, nil
@@ -143,6 +164,7 @@ func (m *FwdAuthMiddlewares) createMiddleware(ctx context.Context, name, namespa
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
 			Namespace: namespace,
+			Labels:    MiddlewareLabels(nil, canonicalPolName),
 		},
 		Spec: spec,
 	}
@@ -154,3 +176,42 @@ func (m *FwdAuthMiddlewares) createMiddleware(ctx context.Context, name, namespa
 
 	return nil
 }
+
+// MiddlewareLabels returns the given middleware labels along with the ones identifying it as generated for the
+// policy with the given canonical name.
+func MiddlewareLabels(current map[string]string, canonicalPolName string) map[string]string {
+	lbls := make(map[string]string, len(current)+3)
+	for k, v := range current {
+		lbls[k] = v
+	}
+
+	name, namespace := acp.SplitCanonicalName(canonicalPolName)
+	if len(validation.IsValidLabelValue(name)) > 0 {
+		name = ""
+	}
+
+	lbls[LabelManagedBy] = "traefik-hub"
+	lbls[LabelACP] = name
+	delete(lbls, LabelACPNamespace)
+	if namespace != "" {
+		lbls[LabelACPNamespace] = namespace
+	}
+
+	return lbls
+}
+
+// MiddlewareSelector returns the label selector matching the forwardAuth middlewares generated for the policy with the
+// given canonical name. It matches the middlewares of all policies if the name is empty.
+func MiddlewareSelector(canonicalPolName string) string {
+	if canonicalPolName == "" {
+		return LabelManagedBy + "=traefik-hub," + LabelACP
+	}
+
+	lbls := MiddlewareLabels(nil, canonicalPolName)
+	if _, ok := lbls[LabelACPNamespace]; !ok {
+		// Prevent matching the middlewares of the namespaced policies sharing the same name.
+		return labels.SelectorFromSet(lbls).String() + ",!" + LabelACPNamespace
+	}
+
+	return labels.SelectorFromSet(lbls).String()
+}
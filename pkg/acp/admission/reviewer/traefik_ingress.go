@@ -23,11 +23,13 @@ import (
 	"strings"
 
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/ingclass"
 	admv1 "k8s.io/api/admission/v1"
 )
 
-const annotationTraefikMiddlewares = "traefik.ingress.kubernetes.io/router.middlewares"
+// AnnotationTraefikMiddlewares is the annotation of Traefik ingresses listing the middlewares of their routers.
+const AnnotationTraefikMiddlewares = "traefik.ingress.kubernetes.io/router.middlewares"
 
 // TraefikIngress is a reviewer that can handle Traefik ingress resources.
 // Note that this reviewer requires Traefik middleware CRD to be defined in the cluster.
@@ -35,13 +37,15 @@ const annotationTraefikMiddlewares = "traefik.ingress.kubernetes.io/router.middl
 type TraefikIngress struct {
 	ingressClasses     IngressClasses
 	fwdAuthMiddlewares FwdAuthMiddlewares
+	defaultPolicies    DefaultPolicies
 }
 
 // NewTraefikIngress returns a Traefik ingress reviewer.
-func NewTraefikIngress(ingClasses IngressClasses, fwdAuthMiddlewares FwdAuthMiddlewares) *TraefikIngress {
+func NewTraefikIngress(ingClasses IngressClasses, fwdAuthMiddlewares FwdAuthMiddlewares, defaultPolicies DefaultPolicies) *TraefikIngress {
 	return &TraefikIngress{
 		ingressClasses:     ingClasses,
 		fwdAuthMiddlewares: fwdAuthMiddlewares,
+		defaultPolicies:    defaultPolicies,
 	}
 }
 
@@ -102,7 +106,7 @@ func (r TraefikIngress) CanReview(ar admv1.AdmissionReview) (bool, error) {
 }
 
 // Review reviews the given admission review request and optionally returns the required patch.
-func (r TraefikIngress) Review(ctx context.Context, ar admv1.AdmissionReview) (map[string]interface{}, error) {
+func (r TraefikIngress) Review(ctx context.Context, ar admv1.AdmissionReview) ([]map[string]interface{}, error) {
 	l := log.Ctx(ctx).With().Str("reviewer", "TraefikIngress").Logger()
 	ctx = l.WithContext(ctx)
 
@@ -118,15 +122,14 @@ func (r TraefikIngress) Review(ctx context.Context, ar admv1.AdmissionReview) (m
 		return nil, fmt.Errorf("parse raw objects: %w", err)
 	}
 
-	prevPolName := oldIng.Metadata.Annotations[AnnotationHubAuth]
-	polName := ing.Metadata.Annotations[AnnotationHubAuth]
+	prevPolName, polName, defaultPolName := ingressPolicies(r.defaultPolicies, ing.Metadata.Namespace, oldIng.Metadata.Annotations, ing.Metadata.Annotations)
 
 	if prevPolName == "" && polName == "" {
 		log.Ctx(ctx).Debug().Msg("No ACP defined")
 		return nil, nil
 	}
 
-	routerMiddlewares := ing.Metadata.Annotations[annotationTraefikMiddlewares]
+	routerMiddlewares := ing.Metadata.Annotations[AnnotationTraefikMiddlewares]
 
 	if prevPolName != "" {
 		routerMiddlewares = r.clearPreviousFwdAuthMiddleware(ctx, prevPolName, ing.Metadata.Namespace, routerMiddlewares)
@@ -145,33 +148,35 @@ func (r TraefikIngress) Review(ctx context.Context, ar admv1.AdmissionReview) (m
 		)
 	}
 
-	if ing.Metadata.Annotations[annotationTraefikMiddlewares] == routerMiddlewares {
+	if ing.Metadata.Annotations[AnnotationTraefikMiddlewares] == routerMiddlewares && ing.Metadata.Annotations[AnnotationAppliedDefaultHubAuth] == defaultPolName {
 		log.Ctx(ctx).Debug().Str("acp_name", polName).Msg("No patch required")
 		return nil, nil
 	}
 
+	hadAnnotations := ing.Metadata.Annotations != nil
+	ing.Metadata.Annotations = setAppliedDefaultPolicyAnnotation(ing.Metadata.Annotations, defaultPolName)
+
 	if routerMiddlewares != "" {
-		ing.Metadata.Annotations[annotationTraefikMiddlewares] = routerMiddlewares
+		ing.Metadata.Annotations[AnnotationTraefikMiddlewares] = routerMiddlewares
 	} else {
-		delete(ing.Metadata.Annotations, annotationTraefikMiddlewares)
+		delete(ing.Metadata.Annotations, AnnotationTraefikMiddlewares)
 	}
 
 	log.Ctx(ctx).Info().Str("acp_name", polName).Msg("Patching resource")
 
-	return map[string]interface{}{
-		"op":    "replace",
-		"path":  "/metadata/annotations",
-		"value": ing.Metadata.Annotations,
-	}, nil
+	return annotationsPatch(hadAnnotations, ing.Metadata.Annotations), nil
 }
 
 func (r TraefikIngress) clearPreviousFwdAuthMiddleware(ctx context.Context, polName, namespace, routerMiddlewares string) string {
 	log.Ctx(ctx).Debug().Str("prev_acp_name", polName).Msg("Clearing previous ACP settings")
 
-	middlewareName := middlewareName(polName)
-	oldCanonicalMiddlewareName := fmt.Sprintf("%s-%s@kubernetescrd", namespace, middlewareName)
+	// The previous policy may have been either the namespaced or the cluster-wide one.
+	for _, name := range middlewareNames(polName, namespace) {
+		oldCanonicalMiddlewareName := fmt.Sprintf("%s-%s@kubernetescrd", namespace, name)
+		routerMiddlewares = removeMiddleware(routerMiddlewares, oldCanonicalMiddlewareName)
+	}
 
-	return removeMiddleware(routerMiddlewares, oldCanonicalMiddlewareName)
+	return routerMiddlewares
 }
 
 // appendMiddleware appends newMiddleware to the comma-separated list of middlewareList.
@@ -197,8 +202,24 @@ func removeMiddleware(middlewareList, toRemove string) string {
 }
 
 // middlewareName returns the ForwardAuth middleware desc for the given ACP.
-func middlewareName(polName string) string {
-	return fmt.Sprintf("zz-%s", strings.ReplaceAll(polName, "@", "-"))
+// Policy names can't start with a hyphen and namespaces can't contain dots, so the middlewares of namespaced policies
+// can't be mistaken for the ones of cluster-wide policies, nor for the ones of policies of other namespaces.
+func middlewareName(canonicalPolName string) string {
+	name, namespace := acp.SplitCanonicalName(canonicalPolName)
+	if namespace == "" {
+		return "zz-" + name
+	}
+
+	return "zz--" + namespace + "." + name
+}
+
+// middlewareNames returns the names of the ForwardAuth middlewares the given policy name may refer to from the given
+// namespace: the one of the namespaced policy and the one of the cluster-wide policy.
+func middlewareNames(polName, namespace string) []string {
+	return []string{
+		middlewareName(acp.CanonicalName(polName, namespace)),
+		middlewareName(polName),
+	}
 }
 
 func isTraefik(ctrlr string) bool {
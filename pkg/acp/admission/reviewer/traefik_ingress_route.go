@@ -21,6 +21,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strconv"
 
 	"github.com/rs/zerolog/log"
 	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
@@ -30,12 +32,14 @@ import (
 // TraefikIngressRoute is a reviewer that can handle Traefik IngressRoute resources.
 type TraefikIngressRoute struct {
 	fwdAuthMiddlewares FwdAuthMiddlewares
+	defaultPolicies    DefaultPolicies
 }
 
 // NewTraefikIngressRoute returns a Traefik IngressRoute reviewer.
-func NewTraefikIngressRoute(fwdAuthMiddlewares FwdAuthMiddlewares) *TraefikIngressRoute {
+func NewTraefikIngressRoute(fwdAuthMiddlewares FwdAuthMiddlewares, defaultPolicies DefaultPolicies) *TraefikIngressRoute {
 	return &TraefikIngressRoute{
 		fwdAuthMiddlewares: fwdAuthMiddlewares,
+		defaultPolicies:    defaultPolicies,
 	}
 }
 
@@ -48,7 +52,7 @@ func (r TraefikIngressRoute) CanReview(ar admv1.AdmissionReview) (bool, error) {
 }
 
 // Review reviews the given admission review request and optionally returns the required patch.
-func (r TraefikIngressRoute) Review(ctx context.Context, ar admv1.AdmissionReview) (map[string]interface{}, error) {
+func (r TraefikIngressRoute) Review(ctx context.Context, ar admv1.AdmissionReview) ([]map[string]interface{}, error) {
 	logger := log.Ctx(ctx).With().Str("reviewer", "TraefikIngressRoute").Logger()
 	ctx = logger.WithContext(ctx)
 
@@ -64,81 +68,186 @@ func (r TraefikIngressRoute) Review(ctx context.Context, ar admv1.AdmissionRevie
 		return nil, fmt.Errorf("parse raw objects: %w", err)
 	}
 
-	prevPolName := oldIngRoute.Annotations[AnnotationHubAuth]
-	polName := ingRoute.Annotations[AnnotationHubAuth]
-	if prevPolName == "" && polName == "" {
+	prevPolNames := append(ReferencedPolicies(oldIngRoute.Annotations), oldIngRoute.Annotations[AnnotationAppliedDefaultHubAuth])
+
+	defaultPolName := defaultPolicy(r.defaultPolicies, ingRoute.Namespace, ingRoute.Annotations)
+
+	polNames, appliedDefaultPolName, err := routePolicies(ingRoute, defaultPolName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasPolicy(prevPolNames) && !hasPolicy(polNames) {
 		logger.Debug().Msg("No ACP defined")
 		return nil, nil
 	}
 
-	var updated bool
-	if prevPolName != "" {
-		updated = r.clearPreviousFwdAuthMiddleware(ctx, &ingRoute.Spec, prevPolName, ingRoute.Namespace)
+	// Middlewares of both the previous and the current policies are managed by this reviewer: the ones that
+	// no longer protect a route get removed from it.
+	managed := make(map[string]struct{})
+	for _, polName := range append(prevPolNames, polNames...) {
+		if polName == "" {
+			continue
+		}
+		for _, name := range middlewareNames(polName, ingRoute.Namespace) {
+			managed[name] = struct{}{}
+		}
 	}
 
-	var mdlwrName string
-	if polName != "" {
-		mdlwrName, err = r.fwdAuthMiddlewares.Setup(ctx, polName, ingRoute.Namespace)
+	mdlwrNames := make(map[string]string)
+	for _, polName := range polNames {
+		if _, ok := mdlwrNames[polName]; ok || polName == "" {
+			continue
+		}
+
+		mdlwrNames[polName], err = r.fwdAuthMiddlewares.Setup(ctx, polName, ingRoute.Namespace)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	if !updateIngressRoute(&ingRoute.Spec, mdlwrName, ingRoute.Namespace) && !updated {
-		logger.Debug().Str("acp_name", polName).Msg("No patch required")
+	var updated bool
+	for i := range ingRoute.Spec.Routes {
+		route := &ingRoute.Spec.Routes[i]
+
+		refs := setRouteMiddleware(route.Middlewares, managed, mdlwrNames[polNames[i]], ingRoute.Namespace)
+		if !reflect.DeepEqual(refs, route.Middlewares) {
+			route.Middlewares = refs
+			updated = true
+		}
+	}
+
+	var patches []map[string]interface{}
+	if updated {
+		patches = append(patches, map[string]interface{}{
+			"op":    "replace",
+			"path":  "/spec/routes",
+			"value": ingRoute.Spec.Routes,
+		})
+	}
+
+	if ingRoute.Annotations[AnnotationAppliedDefaultHubAuth] != appliedDefaultPolName {
+		hadAnnotations := ingRoute.Annotations != nil
+		anno := setAppliedDefaultPolicyAnnotation(ingRoute.Annotations, appliedDefaultPolName)
+
+		patches = append(patches, annotationsPatchOp(hadAnnotations, anno))
+	}
+
+	if len(patches) == 0 {
+		logger.Debug().Strs("acp_names", polNames).Msg("No patch required")
 		return nil, nil
 	}
 
-	logger.Info().Str("acp_name", polName).Msg("Patching resource")
+	logger.Info().Strs("acp_names", polNames).Msg("Patching resource")
 
-	return map[string]interface{}{
-		"op":    "replace",
-		"path":  "/spec/routes",
-		"value": ingRoute.Spec.Routes,
-	}, nil
+	return patches, nil
 }
 
-func updateIngressRoute(spec *traefikv1alpha1.IngressRouteSpec, name, namespace string) (updated bool) {
-	for i, route := range spec.Routes {
-		var found bool
-		for _, middleware := range route.Middlewares {
-			if middleware.Name == name {
-				found = true
-				break
-			}
+// setRouteMiddleware makes sure the given middleware is the only managed middleware referenced by a route,
+// preserving the position of the references that are kept. An empty name removes every managed middleware.
+func setRouteMiddleware(refs []traefikv1alpha1.MiddlewareRef, managed map[string]struct{}, name, namespace string) []traefikv1alpha1.MiddlewareRef {
+	var (
+		found   bool
+		newRefs []traefikv1alpha1.MiddlewareRef
+	)
+	for _, ref := range refs {
+		if name != "" && ref.Name == name {
+			found = true
+			newRefs = append(newRefs, ref)
+			continue
 		}
-		if !found {
-			route.Middlewares = append(route.Middlewares, traefikv1alpha1.MiddlewareRef{
-				Name:      name,
-				Namespace: namespace,
-			})
-			spec.Routes[i].Middlewares = route.Middlewares
-			updated = true
+
+		if _, ok := managed[ref.Name]; ok && ref.Namespace == namespace {
+			continue
+		}
+
+		newRefs = append(newRefs, ref)
+	}
+
+	if name != "" && !found {
+		newRefs = append(newRefs, traefikv1alpha1.MiddlewareRef{
+			Name:      name,
+			Namespace: namespace,
+		})
+	}
+
+	if len(newRefs) == 0 && refs != nil {
+		return refs[:0]
+	}
+
+	return newRefs
+}
+
+// routePolicies returns the name of the ACP protecting each route of the given IngressRoute, or an empty name
+// for the routes that are not protected. See AnnotationHubRouteAuth. The given default ACP protects the routes
+// the IngressRoute annotations don't specify an ACP for, it is returned if it protects at least one of them.
+func routePolicies(ingRoute traefikv1alpha1.IngressRoute, defaultPolName string) (polNames []string, appliedDefaultPolName string, err error) {
+	routePols, err := ParseRoutePolicies(ingRoute.Annotations[AnnotationHubRouteAuth])
+	if err != nil {
+		return nil, "", err
+	}
+
+	polNames = make([]string, len(ingRoute.Spec.Routes))
+	for i, route := range ingRoute.Spec.Routes {
+		polName, ok := routePols[strconv.Itoa(i)]
+		if !ok {
+			polName, ok = routePols[route.Match]
+		}
+		if !ok {
+			polName = ingRoute.Annotations[AnnotationHubAuth]
+		}
+		if !ok && polName == "" {
+			polName = defaultPolName
+			appliedDefaultPolName = defaultPolName
 		}
+
+		polNames[i] = polName
 	}
 
-	return updated
+	return polNames, appliedDefaultPolName, nil
 }
 
-func (r TraefikIngressRoute) clearPreviousFwdAuthMiddleware(ctx context.Context, spec *traefikv1alpha1.IngressRouteSpec, oldPolName, namespace string) (updated bool) {
-	log.Ctx(ctx).Debug().Str("prev_acp_name", oldPolName).Msg("Clearing previous ACP settings")
+// hasPolicy reports whether one of the given ACP names is not empty.
+func hasPolicy(polNames []string) bool {
+	for _, polName := range polNames {
+		if polName != "" {
+			return true
+		}
+	}
+
+	return false
+}
 
-	mdlwrName := middlewareName(oldPolName)
+// ReferencedPolicies returns the names of the ACPs referenced by the given IngressRoute annotations.
+// Malformed route mappings are ignored, they are reported when the IngressRoute is reviewed.
+func ReferencedPolicies(anno map[string]string) []string {
+	var polNames []string
+	if polName := anno[AnnotationHubAuth]; polName != "" {
+		polNames = append(polNames, polName)
+	}
 
-	for i, route := range spec.Routes {
-		var refs []traefikv1alpha1.MiddlewareRef
-		for _, middleware := range route.Middlewares {
-			if middleware.Name == mdlwrName && middleware.Namespace == namespace {
-				updated = true
-				continue
-			}
-			refs = append(refs, middleware)
+	routePols, _ := ParseRoutePolicies(anno[AnnotationHubRouteAuth])
+	for _, polName := range routePols {
+		if polName != "" {
+			polNames = append(polNames, polName)
 		}
+	}
+
+	return polNames
+}
+
+// ParseRoutePolicies parses the value of an AnnotationHubRouteAuth annotation.
+func ParseRoutePolicies(value string) (map[string]string, error) {
+	if value == "" {
+		return nil, nil
+	}
 
-		spec.Routes[i].Middlewares = refs
+	var routePols map[string]string
+	if err := json.Unmarshal([]byte(value), &routePols); err != nil {
+		return nil, fmt.Errorf("parse %s annotation: %w", AnnotationHubRouteAuth, err)
 	}
 
-	return updated
+	return routePols, nil
 }
 
 // parseRawIngressRoutes parses raw ingressRoutes from admission requests.
@@ -28,6 +28,7 @@ import (
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/basicauth"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/oidc"
 	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
 	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
 	admv1 "k8s.io/api/admission/v1"
@@ -51,6 +52,15 @@ func TestTraefikIngressRoute_CanReviewChecksKind(t *testing.T) {
 			},
 			canReview: true,
 		},
+		{
+			desc: "can review traefik.io v1alpha1 IngressRoute",
+			kind: metav1.GroupVersionKind{
+				Group:   "traefik.io",
+				Version: "v1alpha1",
+				Kind:    "IngressRoute",
+			},
+			canReview: true,
+		},
 		{
 			desc: "can't review invalid traefik.containo.us IngressRoute version",
 			kind: metav1.GroupVersionKind{
@@ -122,7 +132,7 @@ func TestTraefikIngressRoute_CanReviewChecksKind(t *testing.T) {
 			t.Parallel()
 
 			fwdAuthMdlwrs := NewFwdAuthMiddlewares("", nil, nil)
-			review := NewTraefikIngressRoute(fwdAuthMdlwrs)
+			review := NewTraefikIngressRoute(fwdAuthMdlwrs, nil)
 
 			var ing netv1.Ingress
 			b, err := json.Marshal(ing)
@@ -178,7 +188,7 @@ func TestTraefikIngressRoute_ReviewAddsAuthentication(t *testing.T) {
 									Namespace: "test",
 								},
 								{
-									Name:      "zz-my-old-policy-test",
+									Name:      "zz--test.my-old-policy",
 									Namespace: "test",
 								},
 							},
@@ -191,7 +201,7 @@ func TestTraefikIngressRoute_ReviewAddsAuthentication(t *testing.T) {
 					Name:      "name",
 					Namespace: "test",
 					Annotations: map[string]string{
-						"hub.traefik.io/access-control-policy": "my-policy@test",
+						"hub.traefik.io/access-control-policy": "my-policy",
 						"custom-annotation":                    "foobar",
 					},
 				},
@@ -212,7 +222,7 @@ func TestTraefikIngressRoute_ReviewAddsAuthentication(t *testing.T) {
 									Namespace: "test",
 								},
 								{
-									Name:      "zz-my-old-policy-test",
+									Name:      "zz--test.my-old-policy",
 									Namespace: "test",
 								},
 							},
@@ -236,7 +246,7 @@ func TestTraefikIngressRoute_ReviewAddsAuthentication(t *testing.T) {
 							Namespace: "test",
 						},
 						{
-							Name:      "zz-my-policy-test",
+							Name:      "zz--test.my-policy",
 							Namespace: "test",
 						},
 					},
@@ -268,7 +278,7 @@ func TestTraefikIngressRoute_ReviewAddsAuthentication(t *testing.T) {
 					Name:      "name",
 					Namespace: "test",
 					Annotations: map[string]string{
-						"hub.traefik.io/access-control-policy": "my-policy@test",
+						"hub.traefik.io/access-control-policy": "my-policy",
 						"custom-annotation":                    "foobar",
 					},
 				},
@@ -280,7 +290,7 @@ func TestTraefikIngressRoute_ReviewAddsAuthentication(t *testing.T) {
 				{
 					Middlewares: []traefikv1alpha1.MiddlewareRef{
 						{
-							Name:      "zz-my-policy-test",
+							Name:      "zz--test.my-policy",
 							Namespace: "test",
 						},
 					},
@@ -298,10 +308,10 @@ func TestTraefikIngressRoute_ReviewAddsAuthentication(t *testing.T) {
 			traefikClientSet := traefikkubemock.NewSimpleClientset()
 
 			policies := newPolicyGetterMock(t)
-			policies.OnGetConfig("my-policy@test").TypedReturns(test.config, nil).Once()
+			policies.OnGetConfig("my-policy", "test").TypedReturns("my-policy@test", test.config, nil).Once()
 
 			fwdAuthMdlwrs := NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1())
-			rev := NewTraefikIngressRoute(fwdAuthMdlwrs)
+			rev := NewTraefikIngressRoute(fwdAuthMdlwrs, nil)
 
 			oldB, err := json.Marshal(test.oldIng)
 			require.NoError(t, err)
@@ -324,11 +334,12 @@ func TestTraefikIngressRoute_ReviewAddsAuthentication(t *testing.T) {
 			assert.NoError(t, err)
 			assert.NotNil(t, patch)
 
-			assert.Equal(t, 3, len(patch))
-			assert.Equal(t, "replace", patch["op"])
-			assert.Equal(t, "/spec/routes", patch["path"])
+			require.Len(t, patch, 1)
+			assert.Equal(t, 3, len(patch[0]))
+			assert.Equal(t, "replace", patch[0]["op"])
+			assert.Equal(t, "/spec/routes", patch[0]["path"])
 
-			b, err = json.Marshal(patch["value"])
+			b, err = json.Marshal(patch[0]["value"])
 			require.NoError(t, err)
 
 			var middlewares []traefikv1alpha1.Route
@@ -341,7 +352,7 @@ func TestTraefikIngressRoute_ReviewAddsAuthentication(t *testing.T) {
 				}
 			}
 
-			m, err := traefikClientSet.TraefikV1alpha1().Middlewares("test").Get(context.Background(), "zz-my-policy-test", metav1.GetOptions{})
+			m, err := traefikClientSet.TraefikV1alpha1().Middlewares("test").Get(context.Background(), "zz--test.my-policy", metav1.GetOptions{})
 			assert.NoError(t, err)
 			assert.NotNil(t, m)
 
@@ -383,7 +394,7 @@ func TestTraefikIngressRoute_ReviewUpdatesExistingMiddleware(t *testing.T) {
 
 			middleware := traefikv1alpha1.Middleware{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      "zz-my-policy-test",
+					Name:      "zz--test.my-policy",
 					Namespace: "test",
 				},
 				Spec: traefikv1alpha1.MiddlewareSpec{
@@ -395,17 +406,17 @@ func TestTraefikIngressRoute_ReviewUpdatesExistingMiddleware(t *testing.T) {
 			traefikClientSet := traefikkubemock.NewSimpleClientset(&middleware)
 
 			policies := newPolicyGetterMock(t)
-			policies.OnGetConfig("my-policy@test").TypedReturns(test.config, nil).Once()
+			policies.OnGetConfig("my-policy", "test").TypedReturns("my-policy@test", test.config, nil).Once()
 
 			fwdAuthMdlwrs := NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1())
-			rev := NewTraefikIngressRoute(fwdAuthMdlwrs)
+			rev := NewTraefikIngressRoute(fwdAuthMdlwrs, nil)
 
 			ing := traefikv1alpha1.IngressRoute{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      "name",
 					Namespace: "test",
 					Annotations: map[string]string{
-						"hub.traefik.io/access-control-policy": "my-policy@test",
+						"hub.traefik.io/access-control-policy": "my-policy",
 						"custom-annotation":                    "foobar",
 					},
 				},
@@ -436,7 +447,7 @@ func TestTraefikIngressRoute_ReviewUpdatesExistingMiddleware(t *testing.T) {
 				},
 			}
 
-			m, err := traefikClientSet.TraefikV1alpha1().Middlewares("test").Get(context.Background(), "zz-my-policy-test", metav1.GetOptions{})
+			m, err := traefikClientSet.TraefikV1alpha1().Middlewares("test").Get(context.Background(), "zz--test.my-policy", metav1.GetOptions{})
 			assert.NoError(t, err)
 			assert.NotNil(t, m)
 			assert.Equal(t, []string{"fwdHeader"}, m.Spec.ForwardAuth.AuthResponseHeaders)
@@ -445,7 +456,7 @@ func TestTraefikIngressRoute_ReviewUpdatesExistingMiddleware(t *testing.T) {
 			assert.NoError(t, err)
 			assert.NotNil(t, p)
 
-			m, err = traefikClientSet.TraefikV1alpha1().Middlewares("test").Get(context.Background(), "zz-my-policy-test", metav1.GetOptions{})
+			m, err = traefikClientSet.TraefikV1alpha1().Middlewares("test").Get(context.Background(), "zz--test.my-policy", metav1.GetOptions{})
 			assert.NoError(t, err)
 			assert.NotNil(t, m)
 
@@ -453,3 +464,126 @@ func TestTraefikIngressRoute_ReviewUpdatesExistingMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestTraefikIngressRoute_ReviewPerRoutePolicies(t *testing.T) {
+	traefikClientSet := traefikkubemock.NewSimpleClientset()
+
+	policies := newPolicyGetterMock(t).
+		OnGetConfig("jwt", "test").TypedReturns("jwt", &acp.Config{JWT: &jwt.Config{}}, nil).Once().
+		OnGetConfig("oidc", "test").TypedReturns("oidc@test", &acp.Config{OIDC: &oidc.Config{}}, nil).Once().
+		Parent
+
+	rev := NewTraefikIngressRoute(NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1()), nil)
+
+	oldIng := traefikv1alpha1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "test",
+			Annotations: map[string]string{
+				"hub.traefik.io/access-control-policy": "oidc",
+			},
+		},
+	}
+	ing := traefikv1alpha1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "test",
+			Annotations: map[string]string{
+				"hub.traefik.io/access-control-policy":         "oidc",
+				"hub.traefik.io/route-access-control-policies": `{"PathPrefix(` + "`/api`" + `)": "jwt", "2": ""}`,
+			},
+		},
+		Spec: traefikv1alpha1.IngressRouteSpec{
+			Routes: []traefikv1alpha1.Route{
+				{
+					Match: "PathPrefix(`/api`)",
+					Middlewares: []traefikv1alpha1.MiddlewareRef{
+						{Name: "zz--test.oidc", Namespace: "test"},
+						{Name: "custom-middleware", Namespace: "test"},
+					},
+				},
+				{
+					Match: "PathPrefix(`/`)",
+					Middlewares: []traefikv1alpha1.MiddlewareRef{
+						{Name: "zz--test.oidc", Namespace: "test"},
+					},
+				},
+				{
+					Match: "PathPrefix(`/public`)",
+					Middlewares: []traefikv1alpha1.MiddlewareRef{
+						{Name: "zz--test.oidc", Namespace: "test"},
+					},
+				},
+			},
+		},
+	}
+
+	oldB, err := json.Marshal(oldIng)
+	require.NoError(t, err)
+	b, err := json.Marshal(ing)
+	require.NoError(t, err)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object:    runtime.RawExtension{Raw: b},
+			OldObject: runtime.RawExtension{Raw: oldB},
+		},
+	}
+
+	patch, err := rev.Review(context.Background(), ar)
+	require.NoError(t, err)
+	require.NotNil(t, patch)
+
+	assert.Equal(t, "/spec/routes", patch[0]["path"])
+
+	wantRoutes := []traefikv1alpha1.Route{
+		{
+			Match: "PathPrefix(`/api`)",
+			Middlewares: []traefikv1alpha1.MiddlewareRef{
+				{Name: "custom-middleware", Namespace: "test"},
+				{Name: "zz-jwt", Namespace: "test"},
+			},
+		},
+		{
+			Match: "PathPrefix(`/`)",
+			Middlewares: []traefikv1alpha1.MiddlewareRef{
+				{Name: "zz--test.oidc", Namespace: "test"},
+			},
+		},
+		{
+			Match:       "PathPrefix(`/public`)",
+			Middlewares: []traefikv1alpha1.MiddlewareRef{},
+		},
+	}
+	assert.Equal(t, wantRoutes, patch[0]["value"])
+
+	_, err = traefikClientSet.TraefikV1alpha1().Middlewares("test").Get(context.Background(), "zz-jwt", metav1.GetOptions{})
+	require.NoError(t, err)
+	_, err = traefikClientSet.TraefikV1alpha1().Middlewares("test").Get(context.Background(), "zz--test.oidc", metav1.GetOptions{})
+	require.NoError(t, err)
+}
+
+func TestTraefikIngressRoute_ReviewInvalidRoutePolicies(t *testing.T) {
+	rev := NewTraefikIngressRoute(NewFwdAuthMiddlewares("", nil, nil), nil)
+
+	ing := traefikv1alpha1.IngressRoute{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "name",
+			Namespace: "test",
+			Annotations: map[string]string{
+				"hub.traefik.io/route-access-control-policies": "jwt",
+			},
+		},
+	}
+	b, err := json.Marshal(ing)
+	require.NoError(t, err)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object: runtime.RawExtension{Raw: b},
+		},
+	}
+
+	_, err = rev.Review(context.Background(), ar)
+	assert.Error(t, err)
+}
@@ -34,6 +34,7 @@ import (
 	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
 	admv1 "k8s.io/api/admission/v1"
 	netv1 "k8s.io/api/networking/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -127,7 +128,7 @@ func TestTraefikIngress_CanReviewChecksKind(t *testing.T) {
 			t.Parallel()
 
 			fwdAuthMdlwrs := NewFwdAuthMiddlewares("", nil, nil)
-			review := NewTraefikIngress(ingClasses, fwdAuthMdlwrs)
+			review := NewTraefikIngress(ingClasses, fwdAuthMdlwrs, nil)
 
 			var ing netv1.Ingress
 			b, err := json.Marshal(ing)
@@ -272,7 +273,7 @@ func TestTraefikIngress_CanReviewChecksIngressClass(t *testing.T) {
 			if test.ingressClassesMock != nil {
 				ic = test.ingressClassesMock(t)
 			}
-			review := NewTraefikIngress(ic, fwdAuthMdlwrs)
+			review := NewTraefikIngress(ic, fwdAuthMdlwrs, nil)
 
 			ing := netv1.Ingress{
 				ObjectMeta: metav1.ObjectMeta{
@@ -327,17 +328,17 @@ func TestTraefikIngress_ReviewAddsAuthentication(t *testing.T) {
 			oldIngAnno: map[string]string{
 				AnnotationHubAuth:   "my-old-policy@test",
 				"custom-annotation": "foobar",
-				"traefik.ingress.kubernetes.io/router.middlewares": "test-zz-my-old-policy-test@kubernetescrd",
+				"traefik.ingress.kubernetes.io/router.middlewares": "test-zz--test.my-old-policy@kubernetescrd",
 			},
 			ingAnno: map[string]string{
-				AnnotationHubAuth:   "my-policy@test",
+				AnnotationHubAuth:   "my-policy",
 				"custom-annotation": "foobar",
 				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd",
 			},
 			wantPatch: map[string]string{
-				AnnotationHubAuth:   "my-policy@test",
+				AnnotationHubAuth:   "my-policy",
 				"custom-annotation": "foobar",
-				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd,test-zz-my-policy-test@kubernetescrd",
+				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd,test-zz--test.my-policy@kubernetescrd",
 			},
 			wantAuthResponseHeaders: []string{"fwdHeader"},
 		},
@@ -349,14 +350,14 @@ func TestTraefikIngress_ReviewAddsAuthentication(t *testing.T) {
 			}},
 			oldIngAnno: map[string]string{},
 			ingAnno: map[string]string{
-				AnnotationHubAuth:   "my-policy@test",
+				AnnotationHubAuth:   "my-policy",
 				"custom-annotation": "foobar",
 				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd",
 			},
 			wantPatch: map[string]string{
-				AnnotationHubAuth:   "my-policy@test",
+				AnnotationHubAuth:   "my-policy",
 				"custom-annotation": "foobar",
-				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd,test-zz-my-policy-test@kubernetescrd",
+				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd,test-zz--test.my-policy@kubernetescrd",
 			},
 			wantAuthResponseHeaders: []string{"User", "Authorization"},
 		},
@@ -369,14 +370,14 @@ func TestTraefikIngress_ReviewAddsAuthentication(t *testing.T) {
 			}},
 			oldIngAnno: map[string]string{},
 			ingAnno: map[string]string{
-				AnnotationHubAuth:   "my-policy@test",
+				AnnotationHubAuth:   "my-policy",
 				"custom-annotation": "foobar",
 				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd",
 			},
 			wantPatch: map[string]string{
-				AnnotationHubAuth:   "my-policy@test",
+				AnnotationHubAuth:   "my-policy",
 				"custom-annotation": "foobar",
-				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd,test-zz-my-policy-test@kubernetescrd",
+				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd,test-zz--test.my-policy@kubernetescrd",
 			},
 			wantAuthResponseHeaders: []string{"fwdHeader", "Authorization", "Cookie"},
 		},
@@ -394,14 +395,14 @@ func TestTraefikIngress_ReviewAddsAuthentication(t *testing.T) {
 			},
 			oldIngAnno: map[string]string{},
 			ingAnno: map[string]string{
-				AnnotationHubAuth:   "my-policy@test",
+				AnnotationHubAuth:   "my-policy",
 				"custom-annotation": "foobar",
 				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd",
 			},
 			wantPatch: map[string]string{
-				AnnotationHubAuth:   "my-policy@test",
+				AnnotationHubAuth:   "my-policy",
 				"custom-annotation": "foobar",
-				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd,test-zz-my-policy-test@kubernetescrd",
+				"traefik.ingress.kubernetes.io/router.middlewares": "custom-middleware@kubernetescrd,test-zz--test.my-policy@kubernetescrd",
 			},
 			wantAuthResponseHeaders: []string{"fwdHeader", "Authorization", "Cookie"},
 		},
@@ -415,11 +416,11 @@ func TestTraefikIngress_ReviewAddsAuthentication(t *testing.T) {
 			traefikClientSet := traefikkubemock.NewSimpleClientset()
 
 			policies := newPolicyGetterMock(t)
-			policies.OnGetConfig("my-policy@test").TypedReturns(test.config, nil).Once()
+			policies.OnGetConfig("my-policy", "test").TypedReturns("my-policy@test", test.config, nil).Once()
 
 			fwdAuthMdlwrs := NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1())
 
-			rev := NewTraefikIngress(newIngressClassesMock(t), fwdAuthMdlwrs)
+			rev := NewTraefikIngress(newIngressClassesMock(t), fwdAuthMdlwrs, nil)
 
 			oldIng := struct {
 				Metadata metav1.ObjectMeta `json:"metadata"`
@@ -460,20 +461,26 @@ func TestTraefikIngress_ReviewAddsAuthentication(t *testing.T) {
 			assert.NoError(t, err)
 			assert.NotNil(t, patch)
 
-			assert.Equal(t, 3, len(patch))
-			assert.Equal(t, "replace", patch["op"])
-			assert.Equal(t, "/metadata/annotations", patch["path"])
-			assert.Equal(t, len(test.wantPatch), len(patch["value"].(map[string]string)))
+			require.Len(t, patch, 1)
+			assert.Equal(t, 3, len(patch[0]))
+			assert.Equal(t, "replace", patch[0]["op"])
+			assert.Equal(t, "/metadata/annotations", patch[0]["path"])
+			assert.Equal(t, len(test.wantPatch), len(patch[0]["value"].(map[string]string)))
 			for k := range test.wantPatch {
-				assert.Equal(t, test.wantPatch[k], patch["value"].(map[string]string)[k])
+				assert.Equal(t, test.wantPatch[k], patch[0]["value"].(map[string]string)[k])
 			}
 
 			m, err := traefikClientSet.TraefikV1alpha1().Middlewares("test").
-				Get(context.Background(), "zz-my-policy-test", metav1.GetOptions{})
+				Get(context.Background(), "zz--test.my-policy", metav1.GetOptions{})
 			assert.NoError(t, err)
 			assert.NotNil(t, m)
 
 			assert.Equal(t, test.wantAuthResponseHeaders, m.Spec.ForwardAuth.AuthResponseHeaders)
+			assert.Equal(t, map[string]string{
+				"app.kubernetes.io/managed-by":                   "traefik-hub",
+				"hub.traefik.io/access-control-policy":           "my-policy",
+				"hub.traefik.io/access-control-policy-namespace": "test",
+			}, m.Labels)
 		})
 	}
 }
@@ -511,7 +518,7 @@ func TestTraefikIngress_ReviewUpdatesExistingMiddleware(t *testing.T) {
 
 			middleware := traefikv1alpha1.Middleware{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      "zz-my-policy-test",
+					Name:      "zz--test.my-policy",
 					Namespace: "test",
 				},
 				Spec: traefikv1alpha1.MiddlewareSpec{
@@ -524,10 +531,10 @@ func TestTraefikIngress_ReviewUpdatesExistingMiddleware(t *testing.T) {
 			traefikClientSet := traefikkubemock.NewSimpleClientset(&middleware)
 
 			policies := newPolicyGetterMock(t)
-			policies.OnGetConfig("my-policy@test").TypedReturns(test.config, nil).Once()
+			policies.OnGetConfig("my-policy", "test").TypedReturns("my-policy@test", test.config, nil).Once()
 
 			fwdAuthMdlwrs := NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1())
-			rev := NewTraefikIngress(newIngressClassesMock(t), fwdAuthMdlwrs)
+			rev := NewTraefikIngress(newIngressClassesMock(t), fwdAuthMdlwrs, nil)
 
 			ing := struct {
 				Metadata metav1.ObjectMeta `json:"metadata"`
@@ -535,7 +542,7 @@ func TestTraefikIngress_ReviewUpdatesExistingMiddleware(t *testing.T) {
 				Metadata: metav1.ObjectMeta{
 					Name:        "name",
 					Namespace:   "test",
-					Annotations: map[string]string{AnnotationHubAuth: "my-policy@test"},
+					Annotations: map[string]string{AnnotationHubAuth: "my-policy"},
 				},
 			}
 			b, err := json.Marshal(ing)
@@ -548,7 +555,7 @@ func TestTraefikIngress_ReviewUpdatesExistingMiddleware(t *testing.T) {
 			}
 
 			m, err := traefikClientSet.TraefikV1alpha1().Middlewares("test").
-				Get(context.Background(), "zz-my-policy-test", metav1.GetOptions{})
+				Get(context.Background(), "zz--test.my-policy", metav1.GetOptions{})
 			assert.NoError(t, err)
 			assert.NotNil(t, m)
 			assert.Equal(t, []string{"fwdHeader"}, m.Spec.ForwardAuth.AuthResponseHeaders)
@@ -558,7 +565,7 @@ func TestTraefikIngress_ReviewUpdatesExistingMiddleware(t *testing.T) {
 			assert.NotNil(t, p)
 
 			m, err = traefikClientSet.TraefikV1alpha1().Middlewares("test").
-				Get(context.Background(), "zz-my-policy-test", metav1.GetOptions{})
+				Get(context.Background(), "zz--test.my-policy", metav1.GetOptions{})
 			assert.NoError(t, err)
 			assert.NotNil(t, m)
 
@@ -566,3 +573,119 @@ func TestTraefikIngress_ReviewUpdatesExistingMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestTraefikIngress_ReviewSwitchesToNamespacedPolicy(t *testing.T) {
+	traefikClientSet := traefikkubemock.NewSimpleClientset()
+
+	policies := newPolicyGetterMock(t)
+	policies.OnGetConfig("my-policy", "test").TypedReturns("my-policy@test", &acp.Config{
+		JWT: &jwt.Config{ForwardHeaders: map[string]string{"fwdHeader": "claim"}},
+	}, nil).Once()
+
+	fwdAuthMdlwrs := NewFwdAuthMiddlewares("http://hub-agent-auth-server.hub.svc.cluster.local", policies, traefikClientSet.TraefikV1alpha1())
+	rev := NewTraefikIngress(newIngressClassesMock(t), fwdAuthMdlwrs, nil)
+
+	// The Ingress used to be protected by the cluster-wide policy, before a namespaced policy with the same name
+	// got created in its namespace.
+	anno := map[string]string{
+		AnnotationHubAuth:            "my-policy",
+		AnnotationTraefikMiddlewares: "custom-middleware@kubernetescrd,test-zz-my-policy@kubernetescrd",
+	}
+	ing := struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}{
+		Metadata: metav1.ObjectMeta{Name: "name", Namespace: "test", Annotations: anno},
+	}
+	b, err := json.Marshal(ing)
+	require.NoError(t, err)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object:    runtime.RawExtension{Raw: b},
+			OldObject: runtime.RawExtension{Raw: b},
+		},
+	}
+
+	p, err := rev.Review(context.Background(), ar)
+	require.NoError(t, err)
+	require.NotNil(t, p)
+
+	assert.Equal(t, "custom-middleware@kubernetescrd,test-zz--test.my-policy@kubernetescrd",
+		p[0]["value"].(map[string]string)[AnnotationTraefikMiddlewares])
+
+	m, err := traefikClientSet.TraefikV1alpha1().Middlewares("test").
+		Get(context.Background(), "zz--test.my-policy", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://hub-agent-auth-server.hub.svc.cluster.local/test/my-policy", m.Spec.ForwardAuth.Address)
+}
+
+func TestTraefikIngress_ReviewDryRunDoesNotCreateMiddleware(t *testing.T) {
+	traefikClientSet := traefikkubemock.NewSimpleClientset()
+
+	policies := newPolicyGetterMock(t)
+	policies.OnGetConfig("my-policy", "test").TypedReturns("my-policy", &acp.Config{
+		JWT: &jwt.Config{ForwardHeaders: map[string]string{"fwdHeader": "claim"}},
+	}, nil).Once()
+
+	fwdAuthMdlwrs := NewFwdAuthMiddlewares("", policies, traefikClientSet.TraefikV1alpha1())
+	rev := NewTraefikIngress(newIngressClassesMock(t), fwdAuthMdlwrs, nil)
+
+	ing := struct {
+		Metadata metav1.ObjectMeta `json:"metadata"`
+	}{
+		Metadata: metav1.ObjectMeta{Name: "name", Namespace: "test", Annotations: map[string]string{AnnotationHubAuth: "my-policy"}},
+	}
+	b, err := json.Marshal(ing)
+	require.NoError(t, err)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Object:    runtime.RawExtension{Raw: b},
+			OldObject: runtime.RawExtension{Raw: b},
+		},
+	}
+
+	p, err := rev.Review(WithDryRun(context.Background()), ar)
+	require.NoError(t, err)
+	require.NotNil(t, p)
+
+	assert.Equal(t, "test-zz-my-policy@kubernetescrd", p[0]["value"].(map[string]string)[AnnotationTraefikMiddlewares])
+
+	_, err = traefikClientSet.TraefikV1alpha1().Middlewares("test").
+		Get(context.Background(), "zz-my-policy", metav1.GetOptions{})
+	assert.True(t, kerror.IsNotFound(err))
+}
+
+func TestMiddlewareName(t *testing.T) {
+	tests := []struct {
+		desc           string
+		canonicalName  string
+		wantMiddleware string
+	}{
+		{
+			desc:           "cluster-wide policy",
+			canonicalName:  "foo-bar",
+			wantMiddleware: "zz-foo-bar",
+		},
+		{
+			desc:           "namespaced policy",
+			canonicalName:  "foo@bar",
+			wantMiddleware: "zz--bar.foo",
+		},
+		{
+			desc:           "namespaced policy with a dotted name",
+			canonicalName:  "foo.bar@baz",
+			wantMiddleware: "zz--baz.foo.bar",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.wantMiddleware, middlewareName(test.canonicalName))
+		})
+	}
+}
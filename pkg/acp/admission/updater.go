@@ -22,33 +22,54 @@ import (
 	"fmt"
 
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/reviewer"
 	"github.com/traefik/hub-agent-kubernetes/pkg/kubevers"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
 )
 
-// IngressUpdater handles ingress updates when ACP configurations are modified.
+// httpRouteVersions are the Gateway API versions HTTPRoutes are looked up with, by order of preference.
+var httpRouteVersions = []string{"v1", "v1beta1", "v1alpha2"}
+
+// ingressRouteGroups are the API groups Traefik IngressRoutes may be served under.
+var ingressRouteGroups = []string{"traefik.io", "traefik.containo.us"}
+
+// resourceFilter reports whether a resource of the given namespace, with the given annotations, has to be updated.
+type resourceFilter func(namespace string, anno map[string]string) bool
+
+// IngressUpdater handles ingress, IngressRoute and HTTPRoute updates when ACP configurations or default ACPs of
+// namespaces are modified.
 type IngressUpdater struct {
 	informer  informers.SharedInformerFactory
 	clientSet clientset.Interface
+	dynClient dynamic.Interface
 
-	cancelUpd map[string]context.CancelFunc
+	cancelUpd   map[string]context.CancelFunc
+	cancelNsUpd map[string]context.CancelFunc
 
-	polNameCh chan string
+	polNameCh   chan string
+	namespaceCh chan string
 
 	supportsNetV1Ingresses bool
 }
 
 // NewIngressUpdater return a new IngressUpdater.
-func NewIngressUpdater(informer informers.SharedInformerFactory, clientSet clientset.Interface, kubeVersion string) *IngressUpdater {
+func NewIngressUpdater(informer informers.SharedInformerFactory, clientSet clientset.Interface, dynClient dynamic.Interface, kubeVersion string) *IngressUpdater {
 	return &IngressUpdater{
 		informer:               informer,
 		clientSet:              clientSet,
+		dynClient:              dynClient,
 		cancelUpd:              map[string]context.CancelFunc{},
+		cancelNsUpd:            map[string]context.CancelFunc{},
 		polNameCh:              make(chan string),
+		namespaceCh:            make(chan string),
 		supportsNetV1Ingresses: kubevers.SupportsNetV1Ingresses(kubeVersion),
 	}
 }
@@ -73,28 +94,76 @@ func (u *IngressUpdater) Run(ctx context.Context) {
 				}
 			}(polName)
 
+		case namespace := <-u.namespaceCh:
+			if cancel, ok := u.cancelNsUpd[namespace]; ok {
+				cancel()
+				delete(u.cancelNsUpd, namespace)
+			}
+
+			ctxUpd, cancel := context.WithCancel(ctx)
+			u.cancelNsUpd[namespace] = cancel
+
+			go func(namespace string) {
+				err := u.updateNamespace(ctxUpd, namespace)
+				if err != nil {
+					log.Error().Err(err).Str("namespace", namespace).Msg("Unable to update ingresses of namespace")
+				}
+			}(namespace)
+
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-// Update notifies the IngressUpdater control loop that it should update ingresses referencing the given ACP if they had
-// a header-related configuration change.
+// Update notifies the IngressUpdater control loop that it should update ingresses, IngressRoutes and HTTPRoutes
+// referencing the given ACP if they had a header-related configuration change.
 func (u *IngressUpdater) Update(polName string) {
 	u.polNameCh <- polName
 }
 
+// UpdateNamespace notifies the IngressUpdater control loop that it should update ingresses and IngressRoutes of the
+// given namespace relying on its default ACP, as it changed.
+func (u *IngressUpdater) UpdateNamespace(namespace string) {
+	u.namespaceCh <- namespace
+}
+
 func (u *IngressUpdater) updateIngresses(ctx context.Context, polName string) error {
+	filter := u.referencesPolicy(polName)
+
+	var err error
+	if !u.supportsNetV1Ingresses {
+		err = u.updateV1beta1Ingresses(ctx, metav1.NamespaceAll, filter)
+	} else {
+		err = u.updateV1Ingresses(ctx, metav1.NamespaceAll, filter)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = u.updateIngressRoutes(ctx, metav1.NamespaceAll, filter); err != nil {
+		return err
+	}
+
+	return u.updateHTTPRoutes(ctx, polName)
+}
+
+func (u *IngressUpdater) updateNamespace(ctx context.Context, namespace string) error {
+	var err error
 	if !u.supportsNetV1Ingresses {
-		return u.updateV1beta1Ingresses(ctx, polName)
+		err = u.updateV1beta1Ingresses(ctx, namespace, usesDefaultPolicy)
+	} else {
+		err = u.updateV1Ingresses(ctx, namespace, usesDefaultPolicy)
+	}
+	if err != nil {
+		return err
 	}
 
-	return u.updateV1Ingresses(ctx, polName)
+	return u.updateIngressRoutes(ctx, namespace, usesDefaultPolicy)
 }
 
-func (u *IngressUpdater) updateV1Ingresses(ctx context.Context, polName string) error {
-	ingList, err := u.informer.Networking().V1().Ingresses().Lister().List(labels.Everything())
+func (u *IngressUpdater) updateV1Ingresses(ctx context.Context, namespace string, filter resourceFilter) error {
+	ingList, err := u.informer.Networking().V1().Ingresses().Lister().Ingresses(namespace).List(labels.Everything())
 	if err != nil {
 		return fmt.Errorf("list ingresses: %w", err)
 	}
@@ -110,12 +179,7 @@ func (u *IngressUpdater) updateV1Ingresses(ctx context.Context, polName string)
 		default:
 		}
 
-		ok := shouldUpdate(ing.Annotations[reviewer.AnnotationHubAuth], polName)
-		if err != nil {
-			log.Error().Err(err).Str("ingress_name", ing.Name).Str("ingress_namespace", ing.Namespace).Msg("Unable to determine if ingress should be updated")
-			continue
-		}
-		if !ok {
+		if !filter(ing.Namespace, ing.Annotations) {
 			continue
 		}
 
@@ -128,9 +192,9 @@ func (u *IngressUpdater) updateV1Ingresses(ctx context.Context, polName string)
 	return nil
 }
 
-func (u *IngressUpdater) updateV1beta1Ingresses(ctx context.Context, polName string) error {
+func (u *IngressUpdater) updateV1beta1Ingresses(ctx context.Context, namespace string, filter resourceFilter) error {
 	// As the minimum supported version is 1.14, we don't need to support the extension group.
-	ingList, err := u.informer.Networking().V1beta1().Ingresses().Lister().List(labels.Everything())
+	ingList, err := u.informer.Networking().V1beta1().Ingresses().Lister().Ingresses(namespace).List(labels.Everything())
 	if err != nil {
 		return fmt.Errorf("list legacy ingresses: %w", err)
 	}
@@ -146,32 +210,167 @@ func (u *IngressUpdater) updateV1beta1Ingresses(ctx context.Context, polName str
 		default:
 		}
 
-		ok := shouldUpdate(ing.Annotations[reviewer.AnnotationHubAuth], polName)
+		if !filter(ing.Namespace, ing.Annotations) {
+			continue
+		}
+
+		_, err = u.clientSet.NetworkingV1beta1().Ingresses(ing.Namespace).Update(ctx, ing, metav1.UpdateOptions{FieldManager: "hub-auth"})
 		if err != nil {
-			log.Error().Err(err).Str("ingress_name", ing.Name).Str("ingress_namespace", ing.Namespace).Msg("Unable to determine if legacy ingress should be updated")
+			log.Error().Err(err).Str("ingress_name", ing.Name).Str("ingress_namespace", ing.Namespace).Msg("Unable to update legacy ingress")
 			continue
 		}
-		if !ok {
+	}
+	return nil
+}
+
+// updateIngressRoutes updates the Traefik IngressRoutes of the given namespace matching the given filter, for the
+// admission webhook to review them again. API groups that are not served are skipped.
+func (u *IngressUpdater) updateIngressRoutes(ctx context.Context, namespace string, filter resourceFilter) error {
+	for _, group := range ingressRouteGroups {
+		gvr := schema.GroupVersionResource{Group: group, Version: "v1alpha1", Resource: "ingressroutes"}
+
+		ingRoutes, err := u.dynClient.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if kerror.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("list %s IngressRoutes: %w", group, err)
+		}
+
+		log.Debug().Str("group", group).Int("ingress_route_number", len(ingRoutes.Items)).Msg("Updating IngressRoutes")
+
+		for i := range ingRoutes.Items {
+			ingRoute := &ingRoutes.Items[i]
+
+			// Don't continue if the context was canceled to prevent being spammed
+			// with context canceled errors on every request we would send otherwise.
+			select {
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+
+			if !filter(ingRoute.GetNamespace(), ingRoute.GetAnnotations()) {
+				continue
+			}
+
+			_, err = u.dynClient.Resource(gvr).Namespace(ingRoute.GetNamespace()).Update(ctx, ingRoute, metav1.UpdateOptions{FieldManager: "hub-auth"})
+			if err != nil {
+				log.Error().Err(err).Str("ingress_route_name", ingRoute.GetName()).Str("ingress_route_namespace", ingRoute.GetNamespace()).Msg("Unable to update IngressRoute")
+				continue
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateHTTPRoutes updates the Gateway API HTTPRoutes referencing the given ACP, for the admission webhook to review
+// them again. It does nothing when the Gateway API is not installed in the cluster.
+func (u *IngressUpdater) updateHTTPRoutes(ctx context.Context, polName string) error {
+	gvr, routes, err := listHTTPRoutes(ctx, u.dynClient)
+	if err != nil {
+		return fmt.Errorf("list HTTPRoutes: %w", err)
+	}
+	if routes == nil {
+		log.Debug().Msg("Gateway API HTTPRoutes are not available, skipping")
+		return nil
+	}
+
+	log.Debug().Int("http_route_number", len(routes.Items)).Msg("Updating HTTPRoutes")
+
+	for i := range routes.Items {
+		route := &routes.Items[i]
+
+		// Don't continue if the context was canceled to prevent being spammed
+		// with context canceled errors on every request we would send otherwise.
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		if !shouldUpdate(route.GetNamespace(), route.GetAnnotations()[reviewer.AnnotationHubAuth], polName) {
 			continue
 		}
 
-		_, err = u.clientSet.NetworkingV1beta1().Ingresses(ing.Namespace).Update(ctx, ing, metav1.UpdateOptions{FieldManager: "hub-auth"})
+		_, err = u.dynClient.Resource(gvr).Namespace(route.GetNamespace()).Update(ctx, route, metav1.UpdateOptions{FieldManager: "hub-auth"})
 		if err != nil {
-			log.Error().Err(err).Str("ingress_name", ing.Name).Str("ingress_namespace", ing.Namespace).Msg("Unable to update legacy ingress")
+			log.Error().Err(err).Str("http_route_name", route.GetName()).Str("http_route_namespace", route.GetNamespace()).Msg("Unable to update HTTPRoute")
 			continue
 		}
 	}
+
 	return nil
 }
 
-func shouldUpdate(hubAuthAnno, polName string) bool {
+// listHTTPRoutes lists HTTPRoutes using the first Gateway API version served by the cluster. It returns a nil list if
+// none is served.
+func listHTTPRoutes(ctx context.Context, dynClient dynamic.Interface) (schema.GroupVersionResource, *unstructured.UnstructuredList, error) {
+	for _, version := range httpRouteVersions {
+		gvr := schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: version, Resource: "httproutes"}
+
+		routes, err := dynClient.Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if kerror.IsNotFound(err) {
+				continue
+			}
+			return schema.GroupVersionResource{}, nil, err
+		}
+
+		return gvr, routes, nil
+	}
+
+	return schema.GroupVersionResource{}, nil, nil
+}
+
+// shouldUpdate reports whether an ingress of the given namespace, with the given ACP annotation, may refer to the
+// policy with the given canonical name. Namespaced policies can only be referred to from their namespace.
+func shouldUpdate(namespace, hubAuthAnno, canonicalPolName string) bool {
 	if hubAuthAnno == "" {
 		return false
 	}
 
+	polName, polNamespace := acp.SplitCanonicalName(canonicalPolName)
 	if hubAuthAnno != polName {
 		return false
 	}
 
-	return true
+	return polNamespace == "" || polNamespace == namespace
+}
+
+// referencesPolicy returns a filter matching the ingresses and IngressRoutes that may be protected by the policy
+// with the given canonical name, either explicitly or as the default ACP of their namespace.
+func (u *IngressUpdater) referencesPolicy(canonicalPolName string) resourceFilter {
+	return func(namespace string, anno map[string]string) bool {
+		polNames := reviewer.ReferencedPolicies(anno)
+		if usesDefaultPolicy(namespace, anno) {
+			polNames = append(polNames, namespaceDefaultPolicy(u.informer, namespace))
+		}
+
+		for _, polName := range polNames {
+			if shouldUpdate(namespace, polName, canonicalPolName) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// namespaceDefaultPolicy returns the name of the default ACP of the given namespace, if any, as known by the given
+// informer.
+func namespaceDefaultPolicy(informer informers.SharedInformerFactory, namespace string) string {
+	ns, err := informer.Core().V1().Namespaces().Lister().Get(namespace)
+	if err != nil {
+		return ""
+	}
+
+	return ns.Annotations[reviewer.AnnotationDefaultHubAuth]
+}
+
+// usesDefaultPolicy reports whether an ingress or an IngressRoute with the given annotations may be protected by the
+// default ACP of its namespace.
+func usesDefaultPolicy(_ string, anno map[string]string) bool {
+	return anno[reviewer.AnnotationHubAuth] == "" && anno[reviewer.AnnotationHubAuthOptOut] != "true"
 }
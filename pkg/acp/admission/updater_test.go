@@ -0,0 +1,222 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package admission
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func TestShouldUpdate(t *testing.T) {
+	tests := []struct {
+		desc             string
+		namespace        string
+		hubAuthAnno      string
+		canonicalPolName string
+		want             bool
+	}{
+		{
+			desc:             "no annotation",
+			namespace:        "my-ns",
+			canonicalPolName: "my-policy",
+		},
+		{
+			desc:             "cluster-wide policy",
+			namespace:        "my-ns",
+			hubAuthAnno:      "my-policy",
+			canonicalPolName: "my-policy",
+			want:             true,
+		},
+		{
+			desc:             "other cluster-wide policy",
+			namespace:        "my-ns",
+			hubAuthAnno:      "my-policy",
+			canonicalPolName: "other-policy",
+		},
+		{
+			desc:             "namespaced policy of the same namespace",
+			namespace:        "my-ns",
+			hubAuthAnno:      "my-policy",
+			canonicalPolName: "my-policy@my-ns",
+			want:             true,
+		},
+		{
+			desc:             "namespaced policy of another namespace",
+			namespace:        "my-ns",
+			hubAuthAnno:      "my-policy",
+			canonicalPolName: "my-policy@other-ns",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, shouldUpdate(test.namespace, test.hubAuthAnno, test.canonicalPolName))
+		})
+	}
+}
+
+func TestIngressUpdater_updateHTTPRoutes(t *testing.T) {
+	newRoute := func(name, namespace, polName string) *unstructured.Unstructured {
+		route := &unstructured.Unstructured{}
+		route.SetAPIVersion("gateway.networking.k8s.io/v1")
+		route.SetKind("HTTPRoute")
+		route.SetName(name)
+		route.SetNamespace(namespace)
+		if polName != "" {
+			route.SetAnnotations(map[string]string{"hub.traefik.io/access-control-policy": polName})
+		}
+		return route
+	}
+
+	listKinds := map[schema.GroupVersionResource]string{
+		{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}: "HTTPRouteList",
+	}
+	client := dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		newRoute("protected", "my-ns", "my-policy"),
+		newRoute("other-namespace", "other-ns", "my-policy"),
+		newRoute("other-policy", "my-ns", "other-policy"),
+		newRoute("unprotected", "my-ns", ""),
+	)
+
+	u := NewIngressUpdater(nil, nil, client, "v1.22")
+
+	err := u.updateHTTPRoutes(context.Background(), "my-policy@my-ns")
+	require.NoError(t, err)
+
+	var updated []string
+	for _, action := range client.Actions() {
+		if updateAction, ok := action.(ktesting.UpdateAction); ok {
+			obj := updateAction.GetObject().(*unstructured.Unstructured)
+			updated = append(updated, obj.GetNamespace()+"/"+obj.GetName())
+		}
+	}
+
+	assert.Equal(t, []string{"my-ns/protected"}, updated)
+}
+
+func TestIngressUpdater_updateIngressRoutes(t *testing.T) {
+	newIngRoute := func(group, name, namespace string, anno map[string]string) *unstructured.Unstructured {
+		ingRoute := &unstructured.Unstructured{}
+		ingRoute.SetAPIVersion(group + "/v1alpha1")
+		ingRoute.SetKind("IngressRoute")
+		ingRoute.SetName(name)
+		ingRoute.SetNamespace(namespace)
+		ingRoute.SetAnnotations(anno)
+		return ingRoute
+	}
+
+	listKinds := map[schema.GroupVersionResource]string{
+		{Group: "traefik.io", Version: "v1alpha1", Resource: "ingressroutes"}:          "IngressRouteList",
+		{Group: "traefik.containo.us", Version: "v1alpha1", Resource: "ingressroutes"}: "IngressRouteList",
+	}
+	client := dynfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		newIngRoute("traefik.io", "protected", "my-ns", map[string]string{
+			"hub.traefik.io/access-control-policy": "my-policy",
+		}),
+		newIngRoute("traefik.containo.us", "route-protected", "my-ns", map[string]string{
+			"hub.traefik.io/access-control-policy":         "other-policy",
+			"hub.traefik.io/route-access-control-policies": `{"0": "my-policy"}`,
+		}),
+		newIngRoute("traefik.io", "other-policy", "my-ns", map[string]string{
+			"hub.traefik.io/access-control-policy":         "other-policy",
+			"hub.traefik.io/route-access-control-policies": `{"0": "other-policy"}`,
+		}),
+		newIngRoute("traefik.io", "other-namespace", "other-ns", map[string]string{
+			"hub.traefik.io/access-control-policy": "my-policy",
+		}),
+		newIngRoute("traefik.io", "unprotected", "other-ns", nil),
+		newIngRoute("traefik.io", "default-protected", "my-ns", nil),
+		newIngRoute("traefik.io", "opted-out", "my-ns", map[string]string{
+			"hub.traefik.io/default-access-control-policy-opt-out": "true",
+		}),
+	)
+
+	informer := informers.NewSharedInformerFactory(kubefake.NewSimpleClientset(), 0)
+	err := informer.Core().V1().Namespaces().Informer().GetIndexer().Add(&corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-ns",
+			Annotations: map[string]string{"hub.traefik.io/default-access-control-policy": "my-policy"},
+		},
+	})
+	require.NoError(t, err)
+
+	u := NewIngressUpdater(informer, nil, client, "v1.22")
+
+	err = u.updateIngressRoutes(context.Background(), metav1.NamespaceAll, u.referencesPolicy("my-policy@my-ns"))
+	require.NoError(t, err)
+
+	var updated []string
+	for _, action := range client.Actions() {
+		if updateAction, ok := action.(ktesting.UpdateAction); ok {
+			obj := updateAction.GetObject().(*unstructured.Unstructured)
+			updated = append(updated, obj.GetNamespace()+"/"+obj.GetName())
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"my-ns/protected", "my-ns/route-protected", "my-ns/default-protected"}, updated)
+}
+
+func TestUsesDefaultPolicy(t *testing.T) {
+	tests := []struct {
+		desc string
+		anno map[string]string
+		want bool
+	}{
+		{
+			desc: "no annotation",
+			want: true,
+		},
+		{
+			desc: "default policy already applied",
+			anno: map[string]string{"hub.traefik.io/applied-default-access-control-policy": "my-policy"},
+			want: true,
+		},
+		{
+			desc: "explicit policy",
+			anno: map[string]string{"hub.traefik.io/access-control-policy": "my-policy"},
+		},
+		{
+			desc: "opted out",
+			anno: map[string]string{"hub.traefik.io/default-access-control-policy-opt-out": "true"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, usesDefaultPolicy("my-ns", test.anno))
+		})
+	}
+}
@@ -25,15 +25,22 @@ import (
 	"net/http"
 
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/metrics"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/reviewer"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	admv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const tracerName = "github.com/traefik/hub-agent-kubernetes/pkg/acp/admission"
+
 // Reviewer allows to review an admission review request.
 type Reviewer interface {
 	CanReview(ar admv1.AdmissionReview) (bool, error)
-	Review(ctx context.Context, ar admv1.AdmissionReview) (map[string]interface{}, error)
+	Review(ctx context.Context, ar admv1.AdmissionReview) ([]map[string]interface{}, error)
 }
 
 type reviewerWarning struct {
@@ -62,11 +69,16 @@ func NewHandler(reviewers []Reviewer) *Handler {
 
 // ServeHTTP implements http.Handler.
 func (h Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), "admission.ingress", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
 	// We always decode the admission request in an admv1 object regardless
 	// of the request version as it is strictly identical to the admv1beta1 object.
 	var ar admv1.AdmissionReview
 	if err := json.NewDecoder(req.Body).Decode(&ar); err != nil {
 		log.Error().Err(err).Msg("Unable to decode admission request")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
@@ -78,8 +90,9 @@ func (h Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			Str("resource_name", ar.Request.Name).
 			Str("resource_namespace", ar.Request.Namespace).
 			Logger()
+		span.SetAttributes(requestAttributes(ar.Request)...)
 	}
-	ctx := l.WithContext(req.Context())
+	ctx = l.WithContext(ctx)
 
 	patch, err := h.review(ctx, ar)
 	if err != nil {
@@ -89,6 +102,8 @@ func (h Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			setReviewWarningResponse(&ar, warn)
 		} else {
 			log.Ctx(ctx).Error().Err(err).Msg("Unable to handle admission request")
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
 			setReviewErrorResponse(&ar, err)
 		}
 	} else {
@@ -131,16 +146,19 @@ func (h Handler) review(ctx context.Context, ar admv1.AdmissionReview) ([]byte,
 			ar.Request.Name, ar.Request.Kind, ar.Request.Namespace)
 	}
 
-	resourcePatch, err := rev.Review(ctx, ar)
+	metrics.SetReviewer(ctx, rev)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("admission.reviewer", metrics.ReviewerName(rev)))
+
+	patches, err := rev.Review(ctx, ar)
 	if err != nil {
 		return nil, fmt.Errorf("reviewing resource %q of kind %q in namespace %q: %w", ar.Request.Name, ar.Request.Kind, ar.Request.Namespace, err)
 	}
 
-	if resourcePatch == nil {
+	if len(patches) == 0 {
 		return nil, nil
 	}
 
-	b, err := json.Marshal([]map[string]interface{}{resourcePatch})
+	b, err := json.Marshal(patches)
 	if err != nil {
 		return nil, fmt.Errorf("serialize patches: %w", err)
 	}
@@ -201,6 +219,17 @@ func setReviewResponse(ar *admv1.AdmissionReview, patch []byte) {
 	}
 }
 
+// requestAttributes returns the span attributes describing the given admission request.
+func requestAttributes(req *admv1.AdmissionRequest) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("admission.uid", string(req.UID)),
+		attribute.String("admission.operation", string(req.Operation)),
+		attribute.String("admission.resource.kind", req.Kind.String()),
+		attribute.String("admission.resource.name", req.Name),
+		attribute.String("admission.resource.namespace", req.Namespace),
+	}
+}
+
 func isUsingACP(ar admv1.AdmissionReview) (bool, error) {
 	var obj struct {
 		Metadata metav1.ObjectMeta `json:"metadata"`
@@ -23,12 +23,17 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/metrics"
 	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
 	"github.com/traefik/hub-agent-kubernetes/pkg/platform"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	admv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -49,6 +54,7 @@ type Backend interface {
 // ACPHandler is an HTTP handler that can be used as a Kubernetes Mutating Admission Controller.
 type ACPHandler struct {
 	backend Backend
+	client  *http.Client
 	now     func() time.Time
 }
 
@@ -56,17 +62,23 @@ type ACPHandler struct {
 func NewACPHandler(backend Backend) *ACPHandler {
 	return &ACPHandler{
 		backend: backend,
+		client:  &http.Client{Timeout: probeTimeout},
 		now:     time.Now,
 	}
 }
 
 // ServeHTTP implements http.Handler.
 func (h ACPHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), "admission.acp", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
 	// We always decode the admission request in an admv1 object regardless
 	// of the request version as it is strictly identical to the admv1beta1 object.
 	var ar admv1.AdmissionReview
 	if err := json.NewDecoder(req.Body).Decode(&ar); err != nil {
 		log.Error().Err(err).Msg("Unable to decode admission request")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
@@ -78,12 +90,15 @@ func (h ACPHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			Str("resource_name", ar.Request.Name).
 			Str("resource_namespace", ar.Request.Namespace).
 			Logger()
+		span.SetAttributes(requestAttributes(ar.Request)...)
 	}
-	ctx := l.WithContext(req.Context())
+	ctx = l.WithContext(ctx)
 
-	patches, err := h.review(ctx, ar.Request)
+	patches, warnings, err := h.review(ctx, ar.Request)
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Msg("Unable to handle admission request")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 
 		if errors.Is(err, platform.ErrVersionConflict) {
 			err = errors.New("platform conflict: a more recent version of this resource is available")
@@ -92,6 +107,7 @@ func (h ACPHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		setReviewErrorResponse(&ar, err)
 	} else {
 		setReviewResponse(&ar, patches)
+		ar.Response.Warnings = warnings
 	}
 
 	if err = json.NewEncoder(rw).Encode(ar); err != nil {
@@ -104,72 +120,180 @@ func (h ACPHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 // review reviews a CREATE/UPDATE/DELETE operation on an ACP.
 // It makes sure the operation is not based on an outdated version of the resource.
 // As the backend is the source of truth, we cannot permit that.
-func (h ACPHandler) review(ctx context.Context, req *admv1.AdmissionRequest) ([]byte, error) {
+// Created and updated ACPs are compiled beforehand, to reject the ones the auth server would not be able to build.
+// Local-only ACPs are not sent to the platform, see hubv1alpha1.AnnotationLocalOnly.
+func (h ACPHandler) review(ctx context.Context, req *admv1.AdmissionRequest) (patches []byte, warnings []string, err error) {
 	logger := log.Ctx(ctx)
 
+	if isNamespacedACPRequest(req.Kind) {
+		return nil, nil, reviewNamespacedACP(ctx, req)
+	}
+
 	if !isACPRequest(req.Kind) {
-		return nil, fmt.Errorf("unsupported resource %s", req.Kind.String())
+		return nil, nil, fmt.Errorf("unsupported resource %s", req.Kind.String())
 	}
 
 	logger.Info().Msg("Reviewing AccessControlPolicy resource")
 
-	if req.DryRun != nil && *req.DryRun {
-		return nil, nil
-	}
-
 	newACP, oldACP, err := parseRawACPs(req.Object.Raw, req.OldObject.Raw)
 	if err != nil {
-		return nil, fmt.Errorf("parse raw objects: %w", err)
+		return nil, nil, fmt.Errorf("parse raw objects: %w", err)
 	}
 
-	if newACP != nil {
+	// Policies synchronized by the platform are only patched by the platform. This doesn't apply when a policy
+	// becomes or stops being local-only, as its spec hash may still match the one it was last synchronized with.
+	if newACP != nil && !newACP.IsLocalOnly() && (oldACP == nil || !oldACP.IsLocalOnly()) {
 		var hash string
 		hash, err = newACP.Spec.Hash()
 		if err != nil {
-			return nil, fmt.Errorf("build hash new ACP spec: %w", err)
+			return nil, nil, fmt.Errorf("build hash new ACP spec: %w", err)
 		}
 		if hash == newACP.Status.SpecHash {
 			log.Debug().Str("name", newACP.Name).Str("namespace", newACP.Namespace).Msg("No patch applied since the admission request came from platform")
-			return nil, nil
+			return nil, nil, nil
+		}
+	}
+
+	// Local-only policies are not synchronized by the platform, so their spec hash doesn't tell whether their spec
+	// changed. Their metadata and status updates, such as the auth server reporting their conditions, are not
+	// validated again, which would probe their OIDC issuer every time.
+	localOnlyUnchanged := oldACP != nil && newACP != nil && oldACP.IsLocalOnly() && newACP.IsLocalOnly() &&
+		reflect.DeepEqual(oldACP.Spec, newACP.Spec)
+
+	if req.Operation == admv1.Create || (req.Operation == admv1.Update && !localOnlyUnchanged) {
+		warnings, err = h.validatePolicy(ctx, newACP)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
 
+	if req.DryRun != nil && *req.DryRun {
+		return nil, warnings, nil
+	}
+
 	switch req.Operation {
 	case admv1.Create:
+		if newACP.IsLocalOnly() {
+			logger.Info().Msg("Skipping platform synchronization of local-only AccessControlPolicy resource")
+			return nil, warnings, nil
+		}
+
 		logger.Info().Msg("Creating AccessControlPolicy resource")
 
-		var a *acp.ACP
-		a, err = h.backend.CreateACP(ctx, newACP)
+		patches, err = h.createACP(ctx, newACP)
 		if err != nil {
-			return nil, fmt.Errorf("create ACP: %w", err)
+			return nil, nil, err
 		}
-		newACP.Status.Version = a.Version
-
-		return h.buildPatches(newACP)
+		return patches, warnings, nil
 
 	case admv1.Update:
-		logger.Info().Msg("Updating AccessControlPolicy resource")
+		switch {
+		case oldACP.IsLocalOnly() && newACP.IsLocalOnly():
+			logger.Info().Msg("Skipping platform synchronization of local-only AccessControlPolicy resource")
+			return nil, warnings, nil
+
+		case newACP.IsLocalOnly():
+			logger.Info().Msg("Deleting AccessControlPolicy resource from the platform as it became local-only")
+
+			if err = h.deleteACP(ctx, oldACP); err != nil {
+				return nil, nil, err
+			}
+			return nil, warnings, nil
+
+		case oldACP.IsLocalOnly():
+			logger.Info().Msg("Creating AccessControlPolicy resource on the platform as it is no longer local-only")
+
+			patches, err = h.createACP(ctx, newACP)
+			if errors.Is(err, platform.ErrVersionConflict) {
+				// A policy with the same name exists on the platform, it was shadowed by this local-only one.
+				return nil, nil, fmt.Errorf("a policy named %q already exists on the platform: delete it from the platform or keep this policy local-only", newACP.Name)
+			}
 
-		var a *acp.ACP
-		a, err = h.backend.UpdateACP(ctx, oldACP.Status.Version, newACP)
+		default:
+			logger.Info().Msg("Updating AccessControlPolicy resource")
+
+			patches, err = h.updateACP(ctx, oldACP, newACP)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("update ACP: %w", err)
+			return nil, nil, err
 		}
-		newACP.Status.Version = a.Version
-
-		return h.buildPatches(newACP)
+		return patches, warnings, nil
 
 	case admv1.Delete:
+		if oldACP.IsLocalOnly() {
+			logger.Info().Msg("Skipping platform synchronization of local-only AccessControlPolicy resource")
+			return nil, nil, nil
+		}
+
 		logger.Info().Msg("Deleting AccessControlPolicy resource")
 
-		if err = h.backend.DeleteACP(ctx, oldACP.Status.Version, oldACP.Name); err != nil {
-			return nil, fmt.Errorf("delete: %w", err)
+		if err = h.deleteACP(ctx, oldACP); err != nil {
+			return nil, nil, err
 		}
-		return nil, nil
+		return nil, nil, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unsupported operation %q", req.Operation)
+	}
+}
 
+// reviewNamespacedACP reviews a CREATE/UPDATE/DELETE operation on a namespaced ACP. Namespaced ACPs are not
+// synchronized with the platform, created and updated ones are only validated.
+func reviewNamespacedACP(ctx context.Context, req *admv1.AdmissionRequest) error {
+	switch req.Operation {
+	case admv1.Create, admv1.Update:
+	case admv1.Delete:
+		return nil
 	default:
-		return nil, fmt.Errorf("unsupported operation %q", req.Operation)
+		return fmt.Errorf("unsupported operation %q", req.Operation)
+	}
+
+	log.Ctx(ctx).Info().Msg("Reviewing NamespacedAccessControlPolicy resource")
+
+	var policy hubv1alpha1.NamespacedAccessControlPolicy
+	if err := json.Unmarshal(req.Object.Raw, &policy); err != nil {
+		return fmt.Errorf("unmarshal reviewed namespaced ACP: %w", err)
+	}
+	if policy.Namespace == "" {
+		policy.Namespace = req.Namespace
 	}
+
+	return validateNamespacedPolicy(&policy)
+}
+
+func (h ACPHandler) createACP(ctx context.Context, policy *hubv1alpha1.AccessControlPolicy) ([]byte, error) {
+	start := time.Now()
+	a, err := h.backend.CreateACP(ctx, policy)
+	metrics.ObservePlatformRequest("CreateACP", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("create ACP: %w", err)
+	}
+	policy.Status.Version = a.Version
+
+	return h.buildPatches(policy)
+}
+
+func (h ACPHandler) updateACP(ctx context.Context, oldPolicy, policy *hubv1alpha1.AccessControlPolicy) ([]byte, error) {
+	start := time.Now()
+	a, err := h.backend.UpdateACP(ctx, oldPolicy.Status.Version, policy)
+	metrics.ObservePlatformRequest("UpdateACP", start, err)
+	if err != nil {
+		return nil, fmt.Errorf("update ACP: %w", err)
+	}
+	policy.Status.Version = a.Version
+
+	return h.buildPatches(policy)
+}
+
+func (h ACPHandler) deleteACP(ctx context.Context, policy *hubv1alpha1.AccessControlPolicy) error {
+	start := time.Now()
+	err := h.backend.DeleteACP(ctx, policy.Status.Version, policy.Name)
+	metrics.ObservePlatformRequest("DeleteACP", start, err)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+
+	return nil
 }
 
 func (h ACPHandler) buildPatches(policy *hubv1alpha1.AccessControlPolicy) ([]byte, error) {
@@ -209,3 +333,7 @@ func parseRawACPs(newRaw, oldRaw []byte) (newACP, oldACP *hubv1alpha1.AccessCont
 func isACPRequest(kind metav1.GroupVersionKind) bool {
 	return kind.Kind == "AccessControlPolicy" && kind.Group == "hub.traefik.io" && kind.Version == "v1alpha1"
 }
+
+func isNamespacedACPRequest(kind metav1.GroupVersionKind) bool {
+	return kind.Kind == "NamespacedAccessControlPolicy" && kind.Group == "hub.traefik.io" && kind.Version == "v1alpha1"
+}
@@ -21,17 +21,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
 	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
 	"github.com/traefik/hub-agent-kubernetes/pkg/platform"
 	admv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -48,7 +51,7 @@ func TestWebhookPolicy_ServeHTTP_Create(t *testing.T) {
 		},
 		Spec: hubv1alpha1.AccessControlPolicySpec{
 			JWT: &hubv1alpha1.AccessControlPolicyJWT{
-				PublicKey: "secret",
+				SigningSecret: "secret",
 			},
 		},
 	}
@@ -146,7 +149,7 @@ func TestWebhookPolicy_ServeHTTP_Update(t *testing.T) {
 		},
 		Spec: hubv1alpha1.AccessControlPolicySpec{
 			JWT: &hubv1alpha1.AccessControlPolicyJWT{
-				PublicKey: "secretUpdated",
+				SigningSecret: "secretUpdated",
 			},
 		},
 	}
@@ -177,7 +180,7 @@ func TestWebhookPolicy_ServeHTTP_Update(t *testing.T) {
 					},
 					Spec: hubv1alpha1.AccessControlPolicySpec{
 						JWT: &hubv1alpha1.AccessControlPolicyJWT{
-							PublicKey: "secret",
+							SigningSecret: "secret",
 						},
 					},
 					Status: hubv1alpha1.AccessControlPolicyStatus{Version: "oldVersion"},
@@ -321,7 +324,7 @@ func TestWebhookPolicy_ServeHTTP_Delete(t *testing.T) {
 							},
 							Spec: hubv1alpha1.AccessControlPolicySpec{
 								JWT: &hubv1alpha1.AccessControlPolicyJWT{
-									PublicKey: "secret",
+									SigningSecret: "secret",
 								},
 							},
 							Status: hubv1alpha1.AccessControlPolicyStatus{Version: "oldVersion"},
@@ -356,7 +359,7 @@ func TestWebhookPolicy_ServeHTTP_Delete(t *testing.T) {
 func TestWebhookPolicy_ServeHTTP_NotApplyPatch(t *testing.T) {
 	spec := hubv1alpha1.AccessControlPolicySpec{
 		JWT: &hubv1alpha1.AccessControlPolicyJWT{
-			PublicKey: "secret",
+			SigningSecret: "secret",
 		},
 	}
 
@@ -516,3 +519,433 @@ func mustMarshal(t *testing.T, obj interface{}) []byte {
 
 	return b
 }
+
+func TestWebhookPolicy_ServeHTTP_invalidPolicy(t *testing.T) {
+	tests := []struct {
+		desc    string
+		spec    hubv1alpha1.AccessControlPolicySpec
+		wantMsg string
+	}{
+		{
+			desc: "invalid claims expression",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{
+					SigningSecret: "secret",
+					Claims:        "Equals(`grp`",
+				},
+			},
+			wantMsg: `invalid AccessControlPolicy "acp": spec.jwt.claims: invalid expression: `,
+		},
+		{
+			desc: "ill-formatted public key",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{
+					PublicKey: "secret",
+				},
+			},
+			wantMsg: `invalid AccessControlPolicy "acp": spec.jwt.publicKey: must be a PEM-encoded public key`,
+		},
+		{
+			desc: "invalid OIDC issuer",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				OIDC: &hubv1alpha1.AccessControlOIDC{
+					Issuer: "ftp://issuer",
+					Secret: &corev1.SecretReference{Namespace: "default", Name: "secret"},
+				},
+			},
+			wantMsg: `invalid AccessControlPolicy "acp": spec.oidc.issuer: `,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			h := NewACPHandler(newBackendMock(t))
+
+			gotResp := serveACPReview(t, h, admv1.Create, test.spec, false)
+
+			assert.False(t, gotResp.Allowed)
+			require.NotNil(t, gotResp.Result)
+			assert.Contains(t, gotResp.Result.Message, test.wantMsg)
+		})
+	}
+}
+
+func TestWebhookPolicy_ServeHTTP_invalidPolicyDryRun(t *testing.T) {
+	h := NewACPHandler(newBackendMock(t))
+
+	spec := hubv1alpha1.AccessControlPolicySpec{
+		BasicAuth: &hubv1alpha1.AccessControlPolicyBasicAuth{
+			Users: []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/", "test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"},
+		},
+	}
+
+	gotResp := serveACPReview(t, h, admv1.Update, spec, true)
+
+	assert.False(t, gotResp.Allowed)
+	require.NotNil(t, gotResp.Result)
+	assert.Contains(t, gotResp.Result.Message, "spec.basicAuth.users")
+}
+
+func TestWebhookPolicy_ServeHTTP_unreachableIssuer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/.well-known/openid-configuration" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		desc         string
+		issuer       string
+		wantWarnings []string
+	}{
+		{
+			desc:   "reachable issuer",
+			issuer: srv.URL,
+		},
+		{
+			desc:   "issuer returning an error",
+			issuer: srv.URL + "/unknown",
+			wantWarnings: []string{
+				fmt.Sprintf("spec.oidc.issuer: unable to reach issuer %q: unexpected status code 404", srv.URL+"/unknown"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			spec := hubv1alpha1.AccessControlPolicySpec{
+				OIDC: &hubv1alpha1.AccessControlOIDC{
+					Issuer:   test.issuer,
+					ClientID: "client-id",
+					Secret:   &corev1.SecretReference{Namespace: "default", Name: "secret"},
+				},
+			}
+
+			h := NewACPHandler(newBackendMock(t))
+
+			gotResp := serveACPReview(t, h, admv1.Create, spec, true)
+
+			assert.True(t, gotResp.Allowed)
+			assert.Equal(t, test.wantWarnings, gotResp.Warnings)
+		})
+	}
+
+	t.Run("unreachable issuer", func(t *testing.T) {
+		spec := hubv1alpha1.AccessControlPolicySpec{
+			OIDC: &hubv1alpha1.AccessControlOIDC{
+				Issuer:   unreachable.URL,
+				ClientID: "client-id",
+				Secret:   &corev1.SecretReference{Namespace: "default", Name: "secret"},
+			},
+		}
+
+		h := NewACPHandler(newBackendMock(t))
+
+		gotResp := serveACPReview(t, h, admv1.Create, spec, true)
+
+		assert.True(t, gotResp.Allowed)
+		require.Len(t, gotResp.Warnings, 1)
+		assert.Contains(t, gotResp.Warnings[0], fmt.Sprintf("spec.oidc.issuer: unable to reach issuer %q: ", unreachable.URL))
+	})
+}
+
+func TestWebhookPolicy_ServeHTTP_localOnly(t *testing.T) {
+	spec := hubv1alpha1.AccessControlPolicySpec{
+		JWT: &hubv1alpha1.AccessControlPolicyJWT{
+			SigningSecret: "secret",
+		},
+	}
+	hash, err := spec.Hash()
+	require.NoError(t, err)
+
+	localPolicy := &hubv1alpha1.AccessControlPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "AccessControlPolicy",
+			APIVersion: "hub.traefik.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "acp",
+			Annotations: map[string]string{hubv1alpha1.AnnotationLocalOnly: "true"},
+		},
+		Spec:   spec,
+		Status: hubv1alpha1.AccessControlPolicyStatus{Version: "version-1", SpecHash: hash},
+	}
+	syncedPolicy := localPolicy.DeepCopy()
+	syncedPolicy.Annotations = nil
+
+	// Local-only policies are validated when created or when their spec changes only.
+	invalidLocalPolicy := localPolicy.DeepCopy()
+	invalidLocalPolicy.Spec.JWT.Claims = "Equals(`grp`"
+	invalidLocalPolicyWithStatus := invalidLocalPolicy.DeepCopy()
+	invalidLocalPolicyWithStatus.Status.Conditions = []metav1.Condition{{Type: "Ready", Status: metav1.ConditionFalse}}
+
+	tests := []struct {
+		desc        string
+		op          admv1.Operation
+		oldPolicy   *hubv1alpha1.AccessControlPolicy
+		newPolicy   *hubv1alpha1.AccessControlPolicy
+		on          func(b *backendMock)
+		wantPatched bool
+		wantErr     string
+	}{
+		{
+			desc:      "create local-only policy",
+			op:        admv1.Create,
+			newPolicy: localPolicy,
+		},
+		{
+			desc:      "update local-only policy",
+			op:        admv1.Update,
+			oldPolicy: localPolicy,
+			newPolicy: localPolicy,
+		},
+		{
+			desc:      "update local-only policy status",
+			op:        admv1.Update,
+			oldPolicy: invalidLocalPolicy,
+			newPolicy: invalidLocalPolicyWithStatus,
+		},
+		{
+			desc:      "update local-only policy spec",
+			op:        admv1.Update,
+			oldPolicy: localPolicy,
+			newPolicy: invalidLocalPolicy,
+			wantErr:   `invalid AccessControlPolicy "acp": spec.jwt.claims: invalid expression: `,
+		},
+		{
+			desc:      "delete local-only policy",
+			op:        admv1.Delete,
+			oldPolicy: localPolicy,
+		},
+		{
+			desc:      "policy becoming local-only",
+			op:        admv1.Update,
+			oldPolicy: syncedPolicy,
+			newPolicy: localPolicy,
+			on: func(b *backendMock) {
+				b.OnDeleteACP("version-1", "acp").TypedReturns(nil).Once()
+			},
+		},
+		{
+			desc:      "policy no longer local-only",
+			op:        admv1.Update,
+			oldPolicy: localPolicy,
+			newPolicy: syncedPolicy,
+			on: func(b *backendMock) {
+				b.OnCreateACPRaw(mock.Anything).TypedReturns(&acp.ACP{Version: "version-2"}, nil).Once()
+			},
+			wantPatched: true,
+		},
+		{
+			desc:      "policy no longer local-only conflicting with a platform policy",
+			op:        admv1.Update,
+			oldPolicy: localPolicy,
+			newPolicy: syncedPolicy,
+			on: func(b *backendMock) {
+				b.OnCreateACPRaw(mock.Anything).TypedReturns(nil, platform.ErrVersionConflict).Once()
+			},
+			wantErr: `a policy named "acp" already exists on the platform`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			backend := newBackendMock(t)
+			if test.on != nil {
+				test.on(backend)
+			}
+
+			admissionRev := admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					UID: "id",
+					Kind: metav1.GroupVersionKind{
+						Group:   "hub.traefik.io",
+						Version: "v1alpha1",
+						Kind:    "AccessControlPolicy",
+					},
+					Name:      "acp",
+					Operation: test.op,
+				},
+				Response: &admv1.AdmissionResponse{},
+			}
+			if test.oldPolicy != nil {
+				admissionRev.Request.OldObject = runtime.RawExtension{Raw: mustMarshal(t, test.oldPolicy)}
+			}
+			if test.newPolicy != nil {
+				admissionRev.Request.Object = runtime.RawExtension{Raw: mustMarshal(t, test.newPolicy)}
+			}
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/", bytes.NewBuffer(mustMarshal(t, admissionRev)))
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			NewACPHandler(backend).ServeHTTP(rec, req)
+
+			var gotAr admv1.AdmissionReview
+			err = json.NewDecoder(rec.Body).Decode(&gotAr)
+			require.NoError(t, err)
+
+			require.NotNil(t, gotAr.Response)
+			if test.wantErr != "" {
+				assert.False(t, gotAr.Response.Allowed)
+				require.NotNil(t, gotAr.Response.Result)
+				assert.Contains(t, gotAr.Response.Result.Message, test.wantErr)
+				return
+			}
+
+			assert.True(t, gotAr.Response.Allowed)
+			assert.Equal(t, test.wantPatched, gotAr.Response.Patch != nil)
+		})
+	}
+}
+
+func TestWebhookPolicy_ServeHTTP_namespacedPolicy(t *testing.T) {
+	tests := []struct {
+		desc    string
+		op      admv1.Operation
+		spec    hubv1alpha1.AccessControlPolicySpec
+		wantMsg string
+	}{
+		{
+			desc: "valid policy",
+			op:   admv1.Create,
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				BasicAuth: &hubv1alpha1.AccessControlPolicyBasicAuth{
+					Users: []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"},
+				},
+			},
+		},
+		{
+			desc: "secret outside of the policy namespace",
+			op:   admv1.Update,
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				OIDC: &hubv1alpha1.AccessControlOIDC{
+					Issuer:   "https://idp.example.com",
+					ClientID: "client",
+					Secret:   &corev1.SecretReference{Namespace: "hub", Name: "secret"},
+				},
+			},
+			wantMsg: `invalid NamespacedAccessControlPolicy "acp@my-ns": spec.oidc.secret.namespace: must be empty or equal to the namespace of the policy`,
+		},
+		{
+			desc: "internal JWKs URL",
+			op:   admv1.Create,
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{JWKsURL: "https://10.0.0.1/jwks.json"},
+			},
+			wantMsg: `invalid NamespacedAccessControlPolicy "acp@my-ns": spec.jwt.jwksUrl: must not target a private IP address`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Namespaced policies are not synchronized with the platform.
+			h := NewACPHandler(newBackendMock(t))
+
+			policy := hubv1alpha1.NamespacedAccessControlPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "acp", Namespace: "my-ns"},
+				Spec:       test.spec,
+			}
+
+			b := mustMarshal(t, admv1.AdmissionReview{
+				Request: &admv1.AdmissionRequest{
+					UID: "id",
+					Kind: metav1.GroupVersionKind{
+						Group:   "hub.traefik.io",
+						Version: "v1alpha1",
+						Kind:    "NamespacedAccessControlPolicy",
+					},
+					Name:      "acp",
+					Namespace: "my-ns",
+					Operation: test.op,
+					Object:    runtime.RawExtension{Raw: mustMarshal(t, policy)},
+				},
+				Response: &admv1.AdmissionResponse{},
+			})
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/", bytes.NewBuffer(b))
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			var gotAr admv1.AdmissionReview
+			err = json.NewDecoder(rec.Body).Decode(&gotAr)
+			require.NoError(t, err)
+
+			if test.wantMsg == "" {
+				assert.True(t, gotAr.Response.Allowed)
+				assert.Nil(t, gotAr.Response.Patch)
+				return
+			}
+
+			assert.False(t, gotAr.Response.Allowed)
+			require.NotNil(t, gotAr.Response.Result)
+			assert.Equal(t, test.wantMsg, gotAr.Response.Result.Message)
+		})
+	}
+}
+
+func serveACPReview(t *testing.T, h *ACPHandler, op admv1.Operation, spec hubv1alpha1.AccessControlPolicySpec, dryRun bool) *admv1.AdmissionResponse {
+	t.Helper()
+
+	policy := hubv1alpha1.AccessControlPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "AccessControlPolicy",
+			APIVersion: "hub.traefik.io/v1alpha1",
+		},
+		ObjectMeta: metav1.ObjectMeta{Name: "acp"},
+		Spec:       spec,
+	}
+
+	admissionRev := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			UID: "id",
+			Kind: metav1.GroupVersionKind{
+				Group:   "hub.traefik.io",
+				Version: "v1alpha1",
+				Kind:    "AccessControlPolicy",
+			},
+			Name:      "acp",
+			Operation: op,
+			DryRun:    &dryRun,
+			Object: runtime.RawExtension{
+				Raw: mustMarshal(t, policy),
+			},
+		},
+		Response: &admv1.AdmissionResponse{},
+	}
+	if op == admv1.Update {
+		admissionRev.Request.OldObject = runtime.RawExtension{Raw: mustMarshal(t, policy)}
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, "/", bytes.NewBuffer(mustMarshal(t, admissionRev)))
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var gotAr admv1.AdmissionReview
+	err = json.NewDecoder(rec.Body).Decode(&gotAr)
+	require.NoError(t, err)
+
+	return gotAr.Response
+}
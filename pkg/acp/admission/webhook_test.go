@@ -108,9 +108,9 @@ func TestWebhook_ServeHTTP(t *testing.T) {
 				reviewer := newReviewerMock(t)
 				reviewer.OnCanReviewRaw(mock.Anything).TypedReturns(true, nil).Once()
 				reviewer.OnReviewRaw(mock.Anything).TypedReturns(
-					map[string]interface{}{
+					[]map[string]interface{}{{
 						"value": "add-acp",
-					}, nil).Once()
+					}}, nil).Once()
 
 				return []Reviewer{reviewer}
 			},
@@ -133,9 +133,9 @@ func TestWebhook_ServeHTTP(t *testing.T) {
 				reviewer := newReviewerMock(t)
 				reviewer.OnCanReviewRaw(mock.Anything).TypedReturns(true, nil).Once()
 				reviewer.OnReviewRaw(mock.Anything).TypedReturns(
-					map[string]interface{}{
+					[]map[string]interface{}{{
 						"value": "remove-acp",
-					}, nil).Once()
+					}}, nil).Once()
 
 				return []Reviewer{reviewer}
 			},
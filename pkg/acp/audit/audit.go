@@ -0,0 +1,300 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package audit records the access decisions made by the auth server.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/identity"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
+)
+
+const redacted = "REDACTED"
+
+// redactableFields are the fields of an Event which can be redacted.
+var redactableFields = map[string]struct{}{
+	"subject":  {},
+	"email":    {},
+	"groups":   {},
+	"method":   {},
+	"host":     {},
+	"uri":      {},
+	"clientIp": {},
+	"reason":   {},
+}
+
+// Event is an access decision.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Policy   string    `json:"policy"`
+	Subject  string    `json:"subject,omitempty"`
+	Email    string    `json:"email,omitempty"`
+	Groups   []string  `json:"groups,omitempty"`
+	Method   string    `json:"method,omitempty"`
+	Host     string    `json:"host,omitempty"`
+	URI      string    `json:"uri,omitempty"`
+	ClientIP string    `json:"clientIp,omitempty"`
+	Result   string    `json:"result"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// Sink receives audit events.
+type Sink interface {
+	Write(event Event) error
+}
+
+// Config configures an audit Logger.
+type Config struct {
+	// SampleRate is the ratio, between 0 and 1, of allowed requests to record.
+	// Denied requests are always recorded.
+	SampleRate float64
+	// RedactFields is the list of event fields, as named in their JSON representation, to redact.
+	RedactFields []string
+	// TrustedProxies is the list of IPs and CIDRs of the proxies in front of the reverse proxy. Their entries are
+	// skipped, from right to left, when looking for the client IP in the X-Forwarded-For header.
+	TrustedProxies []string
+}
+
+// Logger records access decisions into sinks.
+type Logger struct {
+	sinks          []Sink
+	sampleRate     float64
+	redact         map[string]struct{}
+	trustedProxies []*net.IPNet
+
+	now    func() time.Time
+	sample func() float64
+}
+
+// NewLogger returns a new Logger writing events to the given sinks.
+func NewLogger(cfg Config, sinks ...Sink) (*Logger, error) {
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return nil, fmt.Errorf("sample rate must be between 0 and 1, got %v", cfg.SampleRate)
+	}
+
+	redact := make(map[string]struct{})
+	for _, field := range cfg.RedactFields {
+		if _, ok := redactableFields[field]; !ok {
+			return nil, fmt.Errorf("unknown field %q", field)
+		}
+		redact[field] = struct{}{}
+	}
+
+	trustedProxies := make([]*net.IPNet, 0, len(cfg.TrustedProxies))
+	for _, proxy := range cfg.TrustedProxies {
+		ipNet, err := parseIPNet(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parse trusted proxy %q: %w", proxy, err)
+		}
+		trustedProxies = append(trustedProxies, ipNet)
+	}
+
+	return &Logger{
+		sinks:          sinks,
+		sampleRate:     cfg.SampleRate,
+		redact:         redact,
+		trustedProxies: trustedProxies,
+		now:            time.Now,
+		sample:         rand.Float64, //nolint:gosec // No need for a cryptographically secure source to sample events.
+	}, nil
+}
+
+// Handler returns a handler recording the decisions the given ACP handler makes.
+func (l *Logger) Handler(policy string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		ctx := identity.NewContext(req.Context())
+		ctx = context.WithValue(ctx, reasonKey{}, &reasonHolder{})
+		ctx = metrics.WithDecision(ctx)
+		req = req.WithContext(ctx)
+
+		recorder := metrics.NewStatusRecorder(rw)
+		next.ServeHTTP(recorder, req)
+
+		event := Event{
+			Time:     l.now().UTC(),
+			Policy:   policy,
+			Method:   req.Header.Get("X-Forwarded-Method"),
+			Host:     req.Header.Get("X-Forwarded-Host"),
+			URI:      req.Header.Get("X-Forwarded-Uri"),
+			ClientIP: l.clientIP(req),
+			Result:   metrics.Decision(ctx, recorder.Code()),
+			Reason:   reasonFromContext(ctx),
+		}
+
+		if id, ok := identity.FromContext(ctx); ok {
+			event.Subject = id.Subject
+			event.Email = id.Email
+			event.Groups = id.Groups
+		}
+
+		l.Log(event)
+	})
+}
+
+// Log records the given event, unless it's sampled out.
+func (l *Logger) Log(event Event) {
+	if event.Result == metrics.ResultAllowed && l.sample() >= l.sampleRate {
+		return
+	}
+
+	l.redactFields(&event)
+
+	for _, sink := range l.sinks {
+		if err := sink.Write(event); err != nil {
+			log.Error().Err(err).Str("acp_name", event.Policy).Msg("Unable to write audit event")
+		}
+	}
+}
+
+func (l *Logger) redactFields(event *Event) {
+	for field := range l.redact {
+		switch field {
+		case "subject":
+			event.Subject = redactString(event.Subject)
+		case "email":
+			event.Email = redactString(event.Email)
+		case "groups":
+			if len(event.Groups) > 0 {
+				event.Groups = []string{redacted}
+			}
+		case "method":
+			event.Method = redactString(event.Method)
+		case "host":
+			event.Host = redactString(event.Host)
+		case "uri":
+			event.URI = redactString(event.URI)
+		case "clientIp":
+			event.ClientIP = redactString(event.ClientIP)
+		case "reason":
+			event.Reason = redactString(event.Reason)
+		}
+	}
+}
+
+func redactString(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+type reasonKey struct{}
+
+type reasonHolder struct {
+	mu     sync.Mutex
+	reason string
+}
+
+// SetReason records the reason of the decision made for the request of the given context.
+// It does nothing if the request isn't audited.
+func SetReason(ctx context.Context, reason string) {
+	h, ok := ctx.Value(reasonKey{}).(*reasonHolder)
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	h.reason = reason
+	h.mu.Unlock()
+}
+
+func reasonFromContext(ctx context.Context) string {
+	h, ok := ctx.Value(reasonKey{}).(*reasonHolder)
+	if !ok {
+		return ""
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.reason
+}
+
+// clientIP returns the IP of the client which sent the request to the reverse proxy.
+// As the client controls the first entries of the X-Forwarded-For header, it is read from right to left: the client IP
+// is the first entry which isn't a trusted proxy.
+func (l *Logger) clientIP(req *http.Request) string {
+	var hops []string
+	for _, fwdFor := range req.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(fwdFor, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+
+	if len(hops) > 0 {
+		for i := len(hops) - 1; i > 0; i-- {
+			if !l.isTrustedProxy(hops[i]) {
+				return hops[i]
+			}
+		}
+		return hops[0]
+	}
+
+	if realIP := req.Header.Get("X-Real-Ip"); realIP != "" {
+		return realIP
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+func (l *Logger) isTrustedProxy(hop string) bool {
+	ip := net.ParseIP(hop)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range l.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIPNet parses the given CIDR or IP, the latter being considered as a single address network.
+func parseIPNet(s string) (*net.IPNet, error) {
+	if strings.Contains(s, "/") {
+		_, ipNet, err := net.ParseCIDR(s)
+		return ipNet, err
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errors.New("invalid IP")
+	}
+
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
@@ -0,0 +1,227 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/identity"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
+)
+
+type sinkMock struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (s *sinkMock) Write(event Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestLogger_Handler(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		desc      string
+		cfg       Config
+		handler   http.HandlerFunc
+		sample    float64
+		wantEvent *Event
+	}{
+		{
+			desc: "allowed request",
+			cfg:  Config{SampleRate: 1},
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				identity.Set(req.Context(), identity.Identity{Subject: "john", Email: "john@example.com", Groups: []string{"dev"}})
+				rw.WriteHeader(http.StatusOK)
+			},
+			wantEvent: &Event{
+				Time:     now,
+				Policy:   "my-policy",
+				Subject:  "john",
+				Email:    "john@example.com",
+				Groups:   []string{"dev"},
+				Method:   http.MethodGet,
+				Host:     "example.com",
+				URI:      "/foo?bar=baz",
+				ClientIP: "10.0.0.1",
+				Result:   "allowed",
+			},
+		},
+		{
+			desc: "denied request with reason",
+			cfg:  Config{SampleRate: 1},
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				SetReason(req.Context(), "invalid JWT")
+				rw.WriteHeader(http.StatusUnauthorized)
+			},
+			wantEvent: &Event{
+				Time:     now,
+				Policy:   "my-policy",
+				Method:   http.MethodGet,
+				Host:     "example.com",
+				URI:      "/foo?bar=baz",
+				ClientIP: "10.0.0.1",
+				Result:   "unauthorized",
+				Reason:   "invalid JWT",
+			},
+		},
+		{
+			desc: "authenticated user redirected",
+			cfg:  Config{SampleRate: 1},
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				metrics.SetDecision(req.Context(), metrics.ResultAllowed)
+				http.Redirect(rw, req, "https://example.com/foo?bar=baz", http.StatusFound)
+			},
+			wantEvent: &Event{
+				Time:     now,
+				Policy:   "my-policy",
+				Method:   http.MethodGet,
+				Host:     "example.com",
+				URI:      "/foo?bar=baz",
+				ClientIP: "10.0.0.1",
+				Result:   "allowed",
+			},
+		},
+		{
+			desc: "allowed request sampled out",
+			cfg:  Config{SampleRate: 0.5},
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			},
+			sample: 0.6,
+		},
+		{
+			desc: "denied request never sampled out",
+			cfg:  Config{SampleRate: 0},
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusForbidden)
+			},
+			sample: 0.6,
+			wantEvent: &Event{
+				Time:     now,
+				Policy:   "my-policy",
+				Method:   http.MethodGet,
+				Host:     "example.com",
+				URI:      "/foo?bar=baz",
+				ClientIP: "10.0.0.1",
+				Result:   "forbidden",
+			},
+		},
+		{
+			desc: "redacted fields",
+			cfg:  Config{SampleRate: 1, RedactFields: []string{"email", "groups", "uri", "clientIp", "reason"}},
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				identity.Set(req.Context(), identity.Identity{Subject: "john", Email: "john@example.com", Groups: []string{"dev", "ops"}})
+				rw.WriteHeader(http.StatusOK)
+			},
+			wantEvent: &Event{
+				Time:     now,
+				Policy:   "my-policy",
+				Subject:  "john",
+				Email:    "REDACTED",
+				Groups:   []string{"REDACTED"},
+				Method:   http.MethodGet,
+				Host:     "example.com",
+				URI:      "REDACTED",
+				ClientIP: "REDACTED",
+				Result:   "allowed",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			sink := &sinkMock{}
+
+			logger, err := NewLogger(test.cfg, sink)
+			require.NoError(t, err)
+
+			logger.now = func() time.Time { return now }
+			logger.sample = func() float64 { return test.sample }
+
+			req := httptest.NewRequest(http.MethodGet, "/my-policy", nil)
+			req.Header.Set("X-Forwarded-Method", http.MethodGet)
+			req.Header.Set("X-Forwarded-Host", "example.com")
+			req.Header.Set("X-Forwarded-Uri", "/foo?bar=baz")
+			req.Header.Set("X-Forwarded-For", "192.168.0.1, 10.0.0.1")
+
+			logger.Handler("my-policy", test.handler).ServeHTTP(httptest.NewRecorder(), req)
+
+			if test.wantEvent == nil {
+				assert.Empty(t, sink.events)
+				return
+			}
+
+			require.Len(t, sink.events, 1)
+			assert.Equal(t, *test.wantEvent, sink.events[0])
+		})
+	}
+}
+
+func TestNewLogger_invalidConfig(t *testing.T) {
+	_, err := NewLogger(Config{SampleRate: 1.5})
+	assert.Error(t, err)
+
+	_, err = NewLogger(Config{SampleRate: 1, RedactFields: []string{"password"}})
+	assert.EqualError(t, err, `unknown field "password"`)
+
+	_, err = NewLogger(Config{SampleRate: 1, TrustedProxies: []string{"10.0.0"}})
+	assert.EqualError(t, err, `parse trusted proxy "10.0.0": invalid IP`)
+}
+
+func TestLogger_clientIP(t *testing.T) {
+	logger, err := NewLogger(Config{TrustedProxies: []string{"10.1.0.0/16", "10.2.0.1"}})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.3:1234"
+	assert.Equal(t, "10.0.0.3", logger.clientIP(req))
+
+	req.Header.Set("X-Real-Ip", "10.0.0.2")
+	assert.Equal(t, "10.0.0.2", logger.clientIP(req))
+
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	assert.Equal(t, "10.0.0.1", logger.clientIP(req))
+
+	// Entries added by the client are ignored.
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1")
+	assert.Equal(t, "10.0.0.1", logger.clientIP(req))
+
+	// Trusted proxies are skipped, even across several headers.
+	req.Header.Set("X-Forwarded-For", "1.2.3.4, 10.0.0.1, 10.1.2.3")
+	req.Header.Add("X-Forwarded-For", "10.2.0.1")
+	assert.Equal(t, "10.0.0.1", logger.clientIP(req))
+
+	// The leftmost entry is used when all the others are trusted proxies.
+	req.Header.Set("X-Forwarded-For", "10.0.0.1, 10.2.0.1")
+	assert.Equal(t, "10.0.0.1", logger.clientIP(req))
+}
@@ -0,0 +1,293 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WriterSink writes events as JSON lines to a writer, such as the standard output.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a new WriterSink.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write writes the given event.
+func (s *WriterSink) Write(event Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(b, '\n'))
+	return err
+}
+
+// FileSink writes events as JSON lines to a file, which is rotated once it reaches its maximum size.
+type FileSink struct {
+	mu sync.Mutex
+
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+// NewFileSink returns a new FileSink writing to the given path. Once the file exceeds maxSize bytes, it is renamed
+// with a ".1" suffix, previous backups being shifted, and at most maxBackups of them are kept.
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	if maxSize <= 0 {
+		return nil, errors.New("max size must be positive")
+	}
+	if maxBackups < 0 {
+		return nil, errors.New("max backups must be positive")
+	}
+
+	s := &FileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Write writes the given event.
+func (s *FileSink) Write(event Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size > 0 && s.size+int64(len(b)) > s.maxSize {
+		if err = s.rotate(); err != nil {
+			return fmt.Errorf("rotate: %w", err)
+		}
+	}
+
+	n, err := s.file.Write(b)
+	s.size += int64(n)
+
+	return err
+}
+
+// Close closes the underlying file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("stat file: %w", err)
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	return nil
+}
+
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("close file: %w", err)
+	}
+
+	if s.maxBackups == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove file: %w", err)
+		}
+
+		return s.open()
+	}
+
+	for i := s.maxBackups - 1; i > 0; i-- {
+		err := os.Rename(s.backupPath(i), s.backupPath(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("shift backup: %w", err)
+		}
+	}
+
+	if err := os.Rename(s.path, s.backupPath(1)); err != nil {
+		return fmt.Errorf("backup file: %w", err)
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) backupPath(i int) string {
+	return s.path + "." + strconv.Itoa(i)
+}
+
+// WebhookSink sends events in batches to an HTTP endpoint, as a JSON array.
+type WebhookSink struct {
+	client        *http.Client
+	url           string
+	batchSize     int
+	flushInterval time.Duration
+
+	mu     sync.Mutex
+	events []Event
+	flush  chan struct{}
+}
+
+// NewWebhookSink returns a new WebhookSink. Batches are sent once they hold batchSize events, or every flushInterval.
+func NewWebhookSink(client *http.Client, url string, batchSize int, flushInterval time.Duration) (*WebhookSink, error) {
+	if url == "" {
+		return nil, errors.New("missing URL")
+	}
+	if batchSize <= 0 {
+		return nil, errors.New("batch size must be positive")
+	}
+	if flushInterval <= 0 {
+		return nil, errors.New("flush interval must be positive")
+	}
+
+	return &WebhookSink{
+		client:        client,
+		url:           url,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		flush:         make(chan struct{}, 1),
+	}, nil
+}
+
+// Write queues the given event.
+func (s *WebhookSink) Write(event Event) error {
+	s.mu.Lock()
+	s.events = append(s.events, event)
+	full := len(s.events) >= s.batchSize
+	s.mu.Unlock()
+
+	if full {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Run sends the queued events until the given context is done, at which point the remaining events are sent.
+func (s *WebhookSink) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.send(ctx)
+
+		case <-s.flush:
+			s.send(ctx)
+
+		case <-ctx.Done():
+			sendCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.send(sendCtx)
+			cancel()
+
+			return
+		}
+	}
+}
+
+func (s *WebhookSink) send(ctx context.Context) {
+	for {
+		s.mu.Lock()
+		n := len(s.events)
+		if n > s.batchSize {
+			n = s.batchSize
+		}
+		batch := s.events[:n:n]
+		s.events = s.events[n:]
+		s.mu.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := s.post(ctx, batch); err != nil {
+			log.Error().Err(err).Int("events", len(batch)).Msg("Unable to send audit events")
+		}
+	}
+}
+
+func (s *WebhookSink) post(ctx context.Context, events []Event) error {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("marshal events: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterSink_Write(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	require.NoError(t, sink.Write(Event{Policy: "my-policy", Result: "allowed"}))
+	require.NoError(t, sink.Write(Event{Policy: "my-policy", Result: "forbidden"}))
+
+	assert.Equal(t, `{"time":"0001-01-01T00:00:00Z","policy":"my-policy","result":"allowed"}
+{"time":"0001-01-01T00:00:00Z","policy":"my-policy","result":"forbidden"}
+`, buf.String())
+}
+
+func TestFileSink_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	event := Event{Policy: "my-policy", Result: "allowed"}
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	lineSize := int64(len(b) + 1)
+
+	// Make room for two events per file.
+	sink, err := NewFileSink(path, 2*lineSize, 2)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sink.Close() })
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, sink.Write(event))
+	}
+
+	assertLines(t, path, 1)
+	assertLines(t, path+".1", 2)
+	assertLines(t, path+".2", 2)
+	assert.NoFileExists(t, path+".3")
+}
+
+func assertLines(t *testing.T, path string, want int) {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, want, strings.Count(string(b), "\n"), path)
+}
+
+func TestWebhookSink_Run(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]Event
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+
+		var events []Event
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&events))
+
+		mu.Lock()
+		batches = append(batches, events)
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+
+	sink, err := NewWebhookSink(srv.Client(), srv.URL, 2, time.Hour)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sink.Run(ctx)
+		close(done)
+	}()
+
+	require.NoError(t, sink.Write(Event{Policy: "a"}))
+	require.NoError(t, sink.Write(Event{Policy: "b"}))
+
+	// The first batch is full, hence sent right away.
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return len(batches) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, sink.Write(Event{Policy: "c"}))
+
+	// Pending events are sent when stopping.
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, batches, 2)
+	assert.Equal(t, []Event{{Policy: "a"}, {Policy: "b"}}, batches[0])
+	assert.Equal(t, []Event{{Policy: "c"}}, batches[1])
+}
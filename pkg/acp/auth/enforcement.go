@@ -0,0 +1,107 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package auth
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
+)
+
+// auditModeHandler wraps the handler of a policy in audit mode. Requests are always let through, and the ones the
+// policy would have denied are reported. If wouldDenyHeader is set, it is added to these requests.
+func auditModeHandler(name, wouldDenyHeader string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		req = req.WithContext(metrics.WithDecision(req.Context()))
+
+		resp := newBufferedResponse()
+		next.ServeHTTP(resp, req)
+
+		result := metrics.Decision(req.Context(), resp.code)
+		if result == metrics.ResultAllowed {
+			resp.writeTo(rw)
+			return
+		}
+
+		log.Info().
+			Str("acp_name", name).
+			Int("status_code", resp.code).
+			Str("result", result).
+			Str("forwarded_host", req.Header.Get("X-Forwarded-Host")).
+			Str("forwarded_uri", req.Header.Get("X-Forwarded-Uri")).
+			Msg("Request would have been denied by a policy in audit mode")
+
+		metrics.IncWouldDeny(name, result)
+
+		// Headers set by the policy handler, such as redirections or cookies, only make sense when denying the request
+		// so they are dropped.
+		if wouldDenyHeader != "" {
+			rw.Header().Set(wouldDenyHeader, strconv.Itoa(resp.code))
+		}
+		rw.WriteHeader(http.StatusOK)
+	})
+}
+
+// bufferedResponse holds the response of a handler, so it can be inspected before being written.
+type bufferedResponse struct {
+	header      http.Header
+	code        int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{
+		header: make(http.Header),
+		code:   http.StatusOK,
+	}
+}
+
+func (r *bufferedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *bufferedResponse) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+
+	r.code = code
+	r.wroteHeader = true
+}
+
+func (r *bufferedResponse) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+
+	return r.body.Write(b)
+}
+
+func (r *bufferedResponse) writeTo(rw http.ResponseWriter) {
+	for name, values := range r.header {
+		rw.Header()[name] = values
+	}
+
+	rw.WriteHeader(r.code)
+
+	if _, err := rw.Write(r.body.Bytes()); err != nil {
+		log.Error().Err(err).Msg("Unable to write response")
+	}
+}
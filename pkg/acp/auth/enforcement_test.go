@@ -0,0 +1,112 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditModeHandler(t *testing.T) {
+	tests := []struct {
+		desc            string
+		handler         http.HandlerFunc
+		wouldDenyHeader string
+		wantHeaders     http.Header
+	}{
+		{
+			desc: "allowed request is passed through",
+			handler: func(rw http.ResponseWriter, _ *http.Request) {
+				rw.Header().Set("X-User", "john")
+				rw.WriteHeader(http.StatusOK)
+			},
+			wouldDenyHeader: "X-Hub-Auth-Would-Deny",
+			wantHeaders:     http.Header{"X-User": {"john"}},
+		},
+		{
+			desc: "denied request is let through",
+			handler: func(rw http.ResponseWriter, _ *http.Request) {
+				rw.WriteHeader(http.StatusUnauthorized)
+			},
+			wantHeaders: http.Header{},
+		},
+		{
+			desc: "denied request is let through with would deny header",
+			handler: func(rw http.ResponseWriter, _ *http.Request) {
+				rw.WriteHeader(http.StatusForbidden)
+			},
+			wouldDenyHeader: "X-Hub-Auth-Would-Deny",
+			wantHeaders:     http.Header{"X-Hub-Auth-Would-Deny": {"403"}},
+		},
+		{
+			desc: "redirection to the provider is dropped",
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				http.SetCookie(rw, &http.Cookie{Name: "state", Value: "foo"})
+				http.Redirect(rw, req, "https://provider.example.com", http.StatusFound)
+			},
+			wouldDenyHeader: "X-Hub-Auth-Would-Deny",
+			wantHeaders:     http.Header{"X-Hub-Auth-Would-Deny": {"302"}},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/my-policy", nil)
+
+			auditModeHandler("my-policy", test.wouldDenyHeader, test.handler).ServeHTTP(rw, req)
+
+			assert.Equal(t, http.StatusOK, rw.Code)
+			assert.Equal(t, test.wantHeaders, rw.Header())
+			assert.Empty(t, rw.Body.String())
+		})
+	}
+}
+
+func TestWatcher_auditMode(t *testing.T) {
+	switcher := NewHandlerSwitcher()
+	watcher := newWatcher(switcher, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	t.Cleanup(cancel)
+
+	go watcher.Run(ctx)
+
+	policy := createPolicy("1", "my-policy")
+	policy.Spec.EnforcementMode = "audit"
+	policy.Spec.WouldDenyHeader = "X-Hub-Auth-Would-Deny"
+	watcher.OnAdd(policy)
+
+	time.Sleep(10 * time.Millisecond)
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "http://localhost/my-policy", nil)
+
+	switcher.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "401", rw.Header().Get("X-Hub-Auth-Would-Deny"))
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/audit"
+)
+
+// Evaluation is the decision an ACP handler made for a request.
+type Evaluation struct {
+	// StatusCode is the status code of the response of the handler.
+	StatusCode int
+	// Result is either allowed, unauthorized, forbidden or error.
+	Result string
+	// Reason is the reason of the decision, as reported in audit events.
+	Reason string
+
+	Subject string
+	Email   string
+	Groups  []string
+
+	// Header holds the headers of the response of the handler. When the request is allowed, they are the headers
+	// forwarded to the upstream.
+	Header http.Header
+	// Body is the body of the response of the handler.
+	Body string
+}
+
+// Evaluate runs the given request through the handler the auth server builds for the given policy, without its
+// enforcement mode: the decision is reported as if the policy was enforced. The request must be shaped as the ones
+// sent by the reverse proxy, with its X-Forwarded-* headers.
+func Evaluate(ctx context.Context, name string, cfg *acp.Config, req *http.Request) (*Evaluation, error) {
+	handler, err := buildRoute(ctx, name, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("build handler: %w", err)
+	}
+
+	sink := &evaluationSink{}
+	auditor, err := audit.NewLogger(audit.Config{SampleRate: 1}, sink)
+	if err != nil {
+		return nil, fmt.Errorf("new audit logger: %w", err)
+	}
+
+	resp := newBufferedResponse()
+	auditor.Handler(name, handler).ServeHTTP(resp, req.WithContext(ctx))
+
+	return &Evaluation{
+		StatusCode: resp.code,
+		Result:     sink.event.Result,
+		Reason:     sink.event.Reason,
+		Subject:    sink.event.Subject,
+		Email:      sink.event.Email,
+		Groups:     sink.event.Groups,
+		Header:     resp.header,
+		Body:       resp.body.String(),
+	}, nil
+}
+
+// evaluationSink keeps the last audit event it received.
+type evaluationSink struct {
+	event audit.Event
+}
+
+func (s *evaluationSink) Write(event audit.Event) error {
+	s.event = event
+	return nil
+}
@@ -3,6 +3,9 @@ package auth
 import (
 	"net/http"
 	"sync"
+
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
 )
 
 // HTTPHandlerSwitcher allows hot switching of http.ServeMux.
@@ -23,7 +26,7 @@ func (h *HTTPHandlerSwitcher) ServeHTTP(rw http.ResponseWriter, req *http.Reques
 	handler := h.handler
 	h.handlerMu.RUnlock()
 
-	handler.ServeHTTP(rw, req)
+	metrics.InstrumentHandler(policyName(handler, req), handler).ServeHTTP(rw, req)
 }
 
 // UpdateHandler safely updates the current http.ServeMux with a new one.
@@ -36,3 +39,19 @@ func (h *HTTPHandlerSwitcher) UpdateHandler(handler http.Handler) {
 	h.handler = handler
 	h.handlerMu.Unlock()
 }
+
+// policyName returns the canonical name of the policy the given handler routes the given request to.
+// Requests which don't match any policy are reported as metrics.PolicyUnknown, to keep the metrics cardinality bounded.
+func policyName(handler http.Handler, req *http.Request) string {
+	mux, ok := handler.(*http.ServeMux)
+	if !ok {
+		return metrics.PolicyUnknown
+	}
+
+	_, pattern := mux.Handler(req)
+	if pattern == "" {
+		return metrics.PolicyUnknown
+	}
+
+	return acp.CanonicalNameFromAuthServerPath(pattern)
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
+)
+
+func TestHTTPHandlerSwitcher_instrumentsRequests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/my-ns/switcher-policy", http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusForbidden)
+	}))
+
+	switcher := NewHandlerSwitcher()
+	switcher.UpdateHandler(mux)
+
+	rw := httptest.NewRecorder()
+	switcher.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/my-ns/switcher-policy", nil))
+	assert.Equal(t, http.StatusForbidden, rw.Code)
+
+	rw = httptest.NewRecorder()
+	switcher.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/unknown-policy", nil))
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+
+	rw = httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Contains(t, rw.Body.String(), `hub_auth_decisions_total{policy="switcher-policy@my-ns",result="forbidden"} 1`)
+	assert.Contains(t, rw.Body.String(), `hub_auth_decisions_total{policy="unknown",result="error"} 1`)
+}
@@ -0,0 +1,299 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/oidc"
+	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
+	hubclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/clientset/versioned"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// Condition types reported in the status of AccessControlPolicies.
+const (
+	ConditionReady             = "Ready"
+	ConditionHandlerBuilt      = "HandlerBuilt"
+	ConditionSecretResolved    = "SecretResolved"
+	ConditionProviderReachable = "ProviderReachable"
+)
+
+// policyConditions returns the conditions of a policy given the errors met while resolving its secret and building
+// its handler.
+func policyConditions(cfg *acp.Config, secretErr, buildErr error) []metav1.Condition {
+	isOIDC := oidcConfig(cfg) != nil
+
+	secretResolved := metav1.Condition{
+		Type:    ConditionSecretResolved,
+		Status:  metav1.ConditionTrue,
+		Reason:  "SecretResolved",
+		Message: "The Secret referenced by the policy is resolved",
+	}
+	switch {
+	case !isOIDC:
+		secretResolved.Reason = "NotRequired"
+		secretResolved.Message = "The policy doesn't reference any Secret"
+	case secretErr != nil:
+		secretResolved.Status = metav1.ConditionFalse
+		secretResolved.Reason = "SecretNotResolved"
+		secretResolved.Message = secretErr.Error()
+	}
+
+	providerReachable := metav1.Condition{
+		Type:    ConditionProviderReachable,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ProviderReachable",
+		Message: "The OIDC provider is reachable",
+	}
+	var providerErr oidc.ProviderError
+	switch {
+	case !isOIDC:
+		providerReachable.Reason = "NotRequired"
+		providerReachable.Message = "The policy doesn't use an OIDC provider"
+	case errors.As(buildErr, &providerErr):
+		providerReachable.Status = metav1.ConditionFalse
+		providerReachable.Reason = "ProviderUnreachable"
+		providerReachable.Message = providerErr.Error()
+	case buildErr != nil:
+		providerReachable.Status = metav1.ConditionUnknown
+		providerReachable.Reason = "NotChecked"
+		providerReachable.Message = "The OIDC provider can't be checked until the policy configuration is valid"
+	}
+
+	handlerBuilt := metav1.Condition{
+		Type:    ConditionHandlerBuilt,
+		Status:  metav1.ConditionTrue,
+		Reason:  "HandlerBuilt",
+		Message: "The policy handler is built",
+	}
+	if buildErr != nil {
+		handlerBuilt.Status = metav1.ConditionFalse
+		handlerBuilt.Reason = "BuildFailed"
+		handlerBuilt.Message = buildErr.Error()
+	}
+
+	ready := metav1.Condition{
+		Type:    ConditionReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Ready",
+		Message: "The policy is ready",
+	}
+	for _, cond := range []metav1.Condition{handlerBuilt, secretResolved} {
+		if cond.Status != metav1.ConditionTrue {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = cond.Reason
+			ready.Message = cond.Message
+			break
+		}
+	}
+
+	return []metav1.Condition{ready, handlerBuilt, secretResolved, providerReachable}
+}
+
+// StatusUpdater writes policy conditions into AccessControlPolicy statuses. As every auth server replica computes
+// the same conditions, only the one elected as leader writes them.
+type StatusUpdater struct {
+	client hubclientset.Interface
+
+	mu      sync.Mutex
+	leader  bool
+	desired map[string][]metav1.Condition
+	dirty   map[string]struct{}
+
+	notify        chan struct{}
+	retryInterval time.Duration
+}
+
+// NewStatusUpdater returns a new StatusUpdater.
+func NewStatusUpdater(client hubclientset.Interface) *StatusUpdater {
+	return &StatusUpdater{
+		client:        client,
+		desired:       make(map[string][]metav1.Condition),
+		dirty:         make(map[string]struct{}),
+		notify:        make(chan struct{}, 1),
+		retryInterval: 10 * time.Second,
+	}
+}
+
+// Set sets the conditions of the given policy.
+func (u *StatusUpdater) Set(name string, conditions []metav1.Condition) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if reflect.DeepEqual(u.desired[name], conditions) {
+		return
+	}
+
+	u.desired[name] = conditions
+	u.dirty[name] = struct{}{}
+
+	u.trigger()
+}
+
+// Delete forgets about the given policy.
+func (u *StatusUpdater) Delete(name string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	delete(u.desired, name)
+	delete(u.dirty, name)
+}
+
+// SetLeader sets whether this replica is the leader, hence writes statuses.
+func (u *StatusUpdater) SetLeader(leader bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.leader = leader
+	if !leader {
+		return
+	}
+
+	// Statuses may have been written by a previous leader, which could see a different state.
+	for name := range u.desired {
+		u.dirty[name] = struct{}{}
+	}
+
+	u.trigger()
+}
+
+// Run writes statuses until the given context is done.
+func (u *StatusUpdater) Run(ctx context.Context) {
+	ticker := time.NewTicker(u.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-u.notify:
+			u.writeStatuses(ctx)
+
+		case <-ticker.C:
+			u.writeStatuses(ctx)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// trigger requests statuses to be written. It must be called with the lock held.
+func (u *StatusUpdater) trigger() {
+	select {
+	case u.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (u *StatusUpdater) writeStatuses(ctx context.Context) {
+	u.mu.Lock()
+	if !u.leader {
+		u.mu.Unlock()
+		return
+	}
+
+	pending := make(map[string][]metav1.Condition, len(u.dirty))
+	for name := range u.dirty {
+		pending[name] = u.desired[name]
+	}
+	u.dirty = make(map[string]struct{})
+	u.mu.Unlock()
+
+	for name, conditions := range pending {
+		if err := u.writeStatus(ctx, name, conditions); err != nil {
+			log.Error().Err(err).Str("acp_name", name).Msg("Unable to update ACP status")
+
+			u.mu.Lock()
+			// The policy may have been deleted or updated in the meantime.
+			if _, ok := u.desired[name]; ok {
+				u.dirty[name] = struct{}{}
+			}
+			u.mu.Unlock()
+		}
+	}
+}
+
+func (u *StatusUpdater) writeStatus(ctx context.Context, canonicalName string, conditions []metav1.Condition) error {
+	name, namespace := acp.SplitCanonicalName(canonicalName)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ctxUpdate, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		if namespace != "" {
+			return u.writeNamespacedStatus(ctxUpdate, name, namespace, conditions)
+		}
+
+		policy, err := u.client.HubV1alpha1().AccessControlPolicies().Get(ctxUpdate, name, metav1.GetOptions{})
+		if kerror.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !setConditions(&policy.Status, policy.Generation, conditions) {
+			return nil
+		}
+
+		_, err = u.client.HubV1alpha1().AccessControlPolicies().Update(ctxUpdate, policy, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (u *StatusUpdater) writeNamespacedStatus(ctx context.Context, name, namespace string, conditions []metav1.Condition) error {
+	client := u.client.HubV1alpha1().NamespacedAccessControlPolicies(namespace)
+
+	policy, err := client.Get(ctx, name, metav1.GetOptions{})
+	if kerror.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !setConditions(&policy.Status, policy.Generation, conditions) {
+		return nil
+	}
+
+	_, err = client.Update(ctx, policy, metav1.UpdateOptions{})
+	return err
+}
+
+// setConditions sets the given conditions in the given status. It reports whether the status changed.
+func setConditions(status *hubv1alpha1.AccessControlPolicyStatus, generation int64, conditions []metav1.Condition) bool {
+	current := status.Conditions
+	status.Conditions = make([]metav1.Condition, len(current))
+	copy(status.Conditions, current)
+
+	for _, condition := range conditions {
+		condition.ObservedGeneration = generation
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+
+	return !reflect.DeepEqual(current, status.Conditions)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/oidc"
+	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
+	hubkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPolicyConditions(t *testing.T) {
+	jwtCfg := &acp.Config{JWT: &jwt.Config{}}
+	oidcCfg := &acp.Config{OIDC: &oidc.Config{}}
+
+	tests := []struct {
+		desc      string
+		cfg       *acp.Config
+		secretErr error
+		buildErr  error
+		want      map[string]metav1.ConditionStatus
+		wantReady string
+	}{
+		{
+			desc: "healthy JWT policy",
+			cfg:  jwtCfg,
+			want: map[string]metav1.ConditionStatus{
+				ConditionReady:             metav1.ConditionTrue,
+				ConditionHandlerBuilt:      metav1.ConditionTrue,
+				ConditionSecretResolved:    metav1.ConditionTrue,
+				ConditionProviderReachable: metav1.ConditionTrue,
+			},
+			wantReady: "Ready",
+		},
+		{
+			desc:     "invalid JWT policy",
+			cfg:      jwtCfg,
+			buildErr: errors.New("empty or ill-formatted public key"),
+			want: map[string]metav1.ConditionStatus{
+				ConditionReady:             metav1.ConditionFalse,
+				ConditionHandlerBuilt:      metav1.ConditionFalse,
+				ConditionSecretResolved:    metav1.ConditionTrue,
+				ConditionProviderReachable: metav1.ConditionTrue,
+			},
+			wantReady: "BuildFailed",
+		},
+		{
+			desc:      "OIDC policy with a missing secret",
+			cfg:       oidcCfg,
+			secretErr: errors.New("secret ns/secret not found"),
+			buildErr:  errors.New("validate configuration: missing client secret"),
+			want: map[string]metav1.ConditionStatus{
+				ConditionReady:             metav1.ConditionFalse,
+				ConditionHandlerBuilt:      metav1.ConditionFalse,
+				ConditionSecretResolved:    metav1.ConditionFalse,
+				ConditionProviderReachable: metav1.ConditionUnknown,
+			},
+			wantReady: "BuildFailed",
+		},
+		{
+			desc:     "OIDC policy with an unreachable provider",
+			cfg:      oidcCfg,
+			buildErr: oidc.ProviderError{Err: errors.New("connection refused")},
+			want: map[string]metav1.ConditionStatus{
+				ConditionReady:             metav1.ConditionFalse,
+				ConditionHandlerBuilt:      metav1.ConditionFalse,
+				ConditionSecretResolved:    metav1.ConditionTrue,
+				ConditionProviderReachable: metav1.ConditionFalse,
+			},
+			wantReady: "BuildFailed",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			conditions := policyConditions(test.cfg, test.secretErr, test.buildErr)
+
+			got := make(map[string]metav1.ConditionStatus)
+			for _, condition := range conditions {
+				got[condition.Type] = condition.Status
+			}
+
+			assert.Equal(t, test.want, got)
+			assert.Equal(t, ConditionReady, conditions[0].Type)
+			assert.Equal(t, test.wantReady, conditions[0].Reason)
+		})
+	}
+}
+
+func TestStatusUpdater(t *testing.T) {
+	policy := &hubv1alpha1.AccessControlPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Generation: 3},
+	}
+	client := hubkubemock.NewSimpleClientset(policy)
+
+	updater := NewStatusUpdater(client)
+	updater.retryInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go updater.Run(ctx)
+
+	updater.Set("my-policy", policyConditions(&acp.Config{JWT: &jwt.Config{}}, nil, nil))
+
+	// Only the leader writes statuses.
+	time.Sleep(50 * time.Millisecond)
+
+	got, err := client.HubV1alpha1().AccessControlPolicies().Get(ctx, "my-policy", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, got.Status.Conditions)
+
+	updater.SetLeader(true)
+
+	assert.Eventually(t, func() bool {
+		got, err = client.HubV1alpha1().AccessControlPolicies().Get(ctx, "my-policy", metav1.GetOptions{})
+		require.NoError(t, err)
+
+		return len(got.Status.Conditions) == 4
+	}, time.Second, 10*time.Millisecond)
+
+	ready := got.Status.Conditions[0]
+	assert.Equal(t, ConditionReady, ready.Type)
+	assert.Equal(t, metav1.ConditionTrue, ready.Status)
+	assert.Equal(t, int64(3), ready.ObservedGeneration)
+	assert.False(t, ready.LastTransitionTime.IsZero())
+}
@@ -23,15 +23,20 @@ import (
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/mitchellh/hashstructure/v2"
 	"github.com/rs/zerolog/log"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/audit"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/basicauth"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/identity"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/oidc"
 	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	corelisters "k8s.io/client-go/listers/core/v1"
 )
 
 // NOTE: if we use the same watcher for all resources, then we need to restart it when new CRDs are
@@ -40,97 +45,303 @@ import (
 // Also, if multiple clients of this watcher are not interested in the same resources
 // add a parameter to NewWatcher to subscribe only to a subset of events.
 
-type oidcSecret struct {
-	ClientSecret string
+// Bounds of the delay before building again the handler of a policy which configuration didn't change since the last
+// failed build. The delay doubles on each failure.
+const (
+	minBuildRetryDelay = 10 * time.Second
+	maxBuildRetryDelay = 5 * time.Minute
+)
+
+// policyHandler is the handler built for a policy, along with the hash of the configuration it was built from.
+type policyHandler struct {
+	hash    uint64
+	handler http.Handler
+}
+
+// buildError is the error met while building the handler of a policy, along with the hash of the configuration it
+// was built from. Building the handler again, which may reach the network like OIDC discovery does, is only attempted
+// once the configuration changes or the retry time is reached.
+type buildError struct {
+	hash     uint64
+	err      error
+	failures int
+	retryAt  time.Time
 }
 
 // Watcher watches access control policy resources and builds configurations out of them.
 type Watcher struct {
-	key string
+	key     string
+	minter  *identity.Minter
+	auditor *audit.Logger
 
 	configsMu sync.RWMutex
 	configs   map[string]*acp.Config
-	previous  uint64
 
-	secrets map[string]oidcSecret
+	// secrets and configMaps list the Secrets and ConfigMaps policies reference. They are read on demand, when
+	// building handlers, rather than copied on every event.
+	secrets    corelisters.SecretLister
+	configMaps corelisters.ConfigMapLister
+
+	// handlers holds the handlers built for each policy, and buildErrs the errors met while building the others.
+	// They are only accessed by Run.
+	handlers  map[string]policyHandler
+	buildErrs map[string]buildError
+
+	statuses *StatusUpdater
 
 	refresh chan struct{}
 
 	switcher *HTTPHandlerSwitcher
+
+	now func() time.Time
 }
 
 // NewWatcher returns a new watcher to track ACP resources. It calls the given Updater when an ACP is modified at most
-// once every throttle.
-func NewWatcher(switcher *HTTPHandlerSwitcher, key string) *Watcher {
+// once every throttle. The given minter, if any, is used to mint identity tokens for the policies requiring it.
+// The given auditor, if any, records the decisions of all policies, and the given status updater, if any, reports
+// their health. Referenced Secrets and ConfigMaps are read from the given listers, which informers must also notify
+// the watcher of their changes.
+func NewWatcher(switcher *HTTPHandlerSwitcher, key string, minter *identity.Minter, auditor *audit.Logger, statuses *StatusUpdater,
+	secrets corelisters.SecretLister, configMaps corelisters.ConfigMapLister,
+) *Watcher {
 	return &Watcher{
-		key:      key,
-		configs:  make(map[string]*acp.Config),
-		secrets:  make(map[string]oidcSecret),
-		refresh:  make(chan struct{}, 1),
-		switcher: switcher,
+		key:        key,
+		minter:     minter,
+		auditor:    auditor,
+		configs:    make(map[string]*acp.Config),
+		secrets:    secrets,
+		configMaps: configMaps,
+		handlers:   make(map[string]policyHandler),
+		buildErrs:  make(map[string]buildError),
+		statuses:   statuses,
+		refresh:    make(chan struct{}, 1),
+		switcher:   switcher,
+		now:        time.Now,
 	}
 }
 
 // Run launches listener if the watcher is dirty.
 func (w *Watcher) Run(ctx context.Context) {
+	var retry *time.Timer
+
 	for {
 		select {
 		case <-w.refresh:
-			w.configsMu.RLock()
-
-			w.populateSecrets()
+			changed := w.refreshHandlers(ctx)
 
-			hash, err := hashstructure.Hash(w.configs, hashstructure.FormatV2, nil)
-			if err != nil {
-				log.Error().Err(err).Msg("Unable to hash")
+			// Handlers which failed to build are built again once their retry time is reached.
+			if retry != nil {
+				retry.Stop()
+			}
+			if retryAt, ok := w.nextBuildRetry(); ok {
+				retry = time.AfterFunc(retryAt.Sub(w.now()), func() {
+					select {
+					case w.refresh <- struct{}{}:
+					default:
+					}
+				})
 			}
 
-			w.configsMu.RUnlock()
-
-			if err == nil && w.previous == hash {
+			if !changed {
 				continue
 			}
 
-			w.previous = hash
-
 			log.Debug().Msg("Refreshing ACP handlers")
 
-			w.switcher.UpdateHandler(w.buildRoutes(ctx))
+			w.switcher.UpdateHandler(w.buildRoutes())
 
 		case <-ctx.Done():
+			if retry != nil {
+				retry.Stop()
+			}
 			return
 		}
 	}
 }
 
-func (w *Watcher) populateSecrets() {
+// populateSecrets populates the policy configurations with the secrets they reference. It returns, by policy name,
+// the errors met while resolving secrets.
+func (w *Watcher) populateSecrets() map[string]error {
+	errs := make(map[string]error)
+
 	for name, config := range w.configs {
-		logger := log.With().Str("acp_name", name).Logger()
-		cfg := config.OIDC
-		if cfg == nil && config.OIDCGoogle != nil {
-			cfg = &config.OIDCGoogle.Config
+		var err error
+		if config.JWT != nil {
+			err = w.populateJWTValues(name, config.JWT)
+		} else if cfg := oidcConfig(config); cfg != nil {
+			err = w.populateOIDCSecret(name, cfg)
 		}
 
-		if cfg == nil {
-			continue
+		if err != nil {
+			errs[name] = err
 		}
+	}
+
+	return errs
+}
+
+// populateOIDCSecret populates the given OIDC configuration with the client secret it references. The client secret
+// is cleared when it can't be resolved, so the policy handler doesn't keep using a stale one.
+func (w *Watcher) populateOIDCSecret(name string, cfg *oidc.Config) error {
+	logger := log.With().Str("acp_name", name).Logger()
+
+	cfg.ClientSecret = ""
+
+	if cfg.Secret == nil {
+		logger.Error().Msg("Secret is missing")
+		return errors.New("secret reference is missing")
+	}
+
+	logger = logger.With().Str("secret_namespace", cfg.Secret.Namespace).
+		Str("secret_name", cfg.Secret.Name).Logger()
+
+	// Namespaced policies must not be able to use the secrets of other namespaces.
+	if _, namespace := acp.SplitCanonicalName(name); namespace != "" && cfg.Secret.Namespace != namespace {
+		logger.Error().Msg("Secret is outside of the policy namespace")
+		return fmt.Errorf("secret %s/%s is outside of the policy namespace", cfg.Secret.Namespace, cfg.Secret.Name)
+	}
+
+	secret, err := w.secrets.Secrets(cfg.Secret.Namespace).Get(cfg.Secret.Name)
+	if kerror.IsNotFound(err) {
+		logger.Error().Msg("Secret is missing")
+		return fmt.Errorf("secret %s/%s not found", cfg.Secret.Namespace, cfg.Secret.Name)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to get secret")
+		return fmt.Errorf("get secret %s/%s: %w", cfg.Secret.Namespace, cfg.Secret.Name, err)
+	}
+
+	if err = populateSecrets(cfg, secret.Data); err != nil {
+		logger.Error().Err(err).Msg("error while populating secrets")
+		return err
+	}
+
+	return nil
+}
+
+// populateJWTValues populates the given JWT configuration with the values it references. Referenced values take
+// precedence over the inline ones.
+func (w *Watcher) populateJWTValues(name string, cfg *jwt.Config) error {
+	if cfg.SigningSecretRef != nil {
+		cfg.SigningSecret = ""
+
+		value, err := w.resolveValue(name, cfg.SigningSecretRef)
+		if err != nil {
+			return fmt.Errorf("resolve signing secret: %w", err)
+		}
+		cfg.SigningSecret = value
+	}
+
+	if cfg.PublicKeyRef != nil {
+		cfg.PublicKey = ""
+
+		value, err := w.resolveValue(name, cfg.PublicKeyRef)
+		if err != nil {
+			return fmt.Errorf("resolve public key: %w", err)
+		}
+		cfg.PublicKey = value
+	}
+
+	if cfg.JWKsRef != nil {
+		cfg.JWKsFile = ""
+
+		value, err := w.resolveValue(name, cfg.JWKsRef)
+		if err != nil {
+			return fmt.Errorf("resolve JWKs: %w", err)
+		}
+		cfg.JWKsFile = jwt.FileOrContent(value)
+	}
 
-		if cfg.Secret == nil {
-			logger.Error().Msg("Secret is missing")
+	return nil
+}
+
+// resolveValue returns the value referenced by the given policy. It must be called with the configs lock held.
+func (w *Watcher) resolveValue(name string, ref *jwt.ValueReference) (string, error) {
+	kind := "secret"
+	if ref.IsConfigMap() {
+		kind = "configmap"
+	}
+
+	logger := log.With().
+		Str("acp_name", name).
+		Str(kind+"_namespace", ref.Namespace).
+		Str(kind+"_name", ref.Name).
+		Str(kind+"_key", ref.Key).
+		Logger()
+
+	// Namespaced policies must not be able to use the resources of other namespaces.
+	if _, namespace := acp.SplitCanonicalName(name); namespace != "" && ref.Namespace != namespace {
+		logger.Error().Msg("Referenced value is outside of the policy namespace")
+		return "", fmt.Errorf("%s %s/%s is outside of the policy namespace", kind, ref.Namespace, ref.Name)
+	}
+
+	var (
+		value string
+		ok    bool
+		err   error
+	)
+	if ref.IsConfigMap() {
+		var configMap *corev1.ConfigMap
+		if configMap, err = w.configMaps.ConfigMaps(ref.Namespace).Get(ref.Name); err == nil {
+			value, ok = configMap.Data[ref.Key]
+		}
+	} else {
+		var secret *corev1.Secret
+		if secret, err = w.secrets.Secrets(ref.Namespace).Get(ref.Name); err == nil {
+			var b []byte
+			b, ok = secret.Data[ref.Key]
+			value = string(b)
+		}
+	}
+
+	if kerror.IsNotFound(err) {
+		logger.Error().Msg("Referenced resource is missing")
+		return "", fmt.Errorf("%s %s/%s not found", kind, ref.Namespace, ref.Name)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to get referenced resource")
+		return "", fmt.Errorf("get %s %s/%s: %w", kind, ref.Namespace, ref.Name, err)
+	}
+	if !ok || value == "" {
+		logger.Error().Msg("Referenced key is missing")
+		return "", fmt.Errorf("key %q is missing in %s %s/%s", ref.Key, kind, ref.Namespace, ref.Name)
+	}
+
+	return value, nil
+}
+
+// populateTemplates populates the deny response configurations of the policies with the templates they reference.
+// Policies which template can't be found fall back to the default one.
+func (w *Watcher) populateTemplates() {
+	for name, config := range w.configs {
+		cfg := config.DenyResponse()
+		if cfg == nil || cfg.TemplateRef == nil {
 			continue
 		}
 
-		logger = logger.With().Str("secret_namespace", cfg.Secret.Namespace).
-			Str("secret_name", cfg.Secret.Name).Logger()
+		ref := cfg.TemplateRef
+		cfg.Template = ""
 
-		secret, ok := w.secrets[cfg.Secret.Namespace+"@"+cfg.Secret.Name]
-		if !ok {
-			logger.Error().Msg("Secret is missing")
+		// Namespaced policies must not be able to use the ConfigMaps of other namespaces.
+		if _, namespace := acp.SplitCanonicalName(name); namespace != "" && ref.Namespace != namespace {
+			log.Warn().
+				Str("acp_name", name).
+				Str("configmap_namespace", ref.Namespace).
+				Str("configmap_name", ref.Name).
+				Msg("Deny response template is outside of the policy namespace, using the default one")
 			continue
 		}
 
-		if err := populateSecrets(cfg, secret); err != nil {
-			logger.Error().Err(err).Msg("error while populating secrets")
+		if configMap, err := w.configMaps.ConfigMaps(ref.Namespace).Get(ref.Name); err == nil {
+			cfg.Template = configMap.Data[ref.Key]
+		}
+		if cfg.Template == "" {
+			log.Warn().
+				Str("acp_name", name).
+				Str("configmap_namespace", ref.Namespace).
+				Str("configmap_name", ref.Name).
+				Str("configmap_key", ref.Key).
+				Msg("Deny response template is missing, using the default one")
 		}
 	}
 }
@@ -139,14 +350,20 @@ func (w *Watcher) populateSecrets() {
 func (w *Watcher) OnAdd(obj interface{}) {
 	switch v := obj.(type) {
 	case *hubv1alpha1.AccessControlPolicy:
-		w.updateConfigFromPolicy(v)
+		w.updateConfig(v.Name, acp.ConfigFromPolicy(v))
+
+	case *hubv1alpha1.NamespacedAccessControlPolicy:
+		w.updateConfig(acp.CanonicalName(v.Name, v.Namespace), acp.ConfigFromNamespacedPolicy(v))
 
 	case *corev1.Secret:
-		w.configsMu.Lock()
-		w.secrets[v.Namespace+"@"+v.Name] = oidcSecret{
-			ClientSecret: string(v.Data["clientSecret"]),
+		if !w.usesSecret(v) {
+			return
+		}
+
+	case *corev1.ConfigMap:
+		if !w.usesConfigMap(v) {
+			return
 		}
-		w.configsMu.Unlock()
 
 	default:
 		log.Error().
@@ -166,14 +383,20 @@ func (w *Watcher) OnAdd(obj interface{}) {
 func (w *Watcher) OnUpdate(_, newObj interface{}) {
 	switch v := newObj.(type) {
 	case *hubv1alpha1.AccessControlPolicy:
-		w.updateConfigFromPolicy(v)
+		w.updateConfig(v.Name, acp.ConfigFromPolicy(v))
+
+	case *hubv1alpha1.NamespacedAccessControlPolicy:
+		w.updateConfig(acp.CanonicalName(v.Name, v.Namespace), acp.ConfigFromNamespacedPolicy(v))
 
 	case *corev1.Secret:
-		w.configsMu.Lock()
-		w.secrets[v.Namespace+"@"+v.Name] = oidcSecret{
-			ClientSecret: string(v.Data["clientSecret"]),
+		if !w.usesSecret(v) {
+			return
+		}
+
+	case *corev1.ConfigMap:
+		if !w.usesConfigMap(v) {
+			return
 		}
-		w.configsMu.Unlock()
 
 	default:
 		log.Error().
@@ -189,31 +412,94 @@ func (w *Watcher) OnUpdate(_, newObj interface{}) {
 	}
 }
 
-func (w *Watcher) updateConfigFromPolicy(policy *hubv1alpha1.AccessControlPolicy) {
+// updateConfig stores the configuration of the policy with the given canonical name.
+func (w *Watcher) updateConfig(canonicalName string, cfg *acp.Config) {
 	w.configsMu.Lock()
 	defer w.configsMu.Unlock()
 
-	w.configs[policy.ObjectMeta.Name] = acp.ConfigFromPolicy(policy)
-	if w.configs[policy.ObjectMeta.Name].OIDC != nil {
-		w.configs[policy.ObjectMeta.Name].OIDC.Key = w.key
+	if cfg.OIDC != nil {
+		cfg.OIDC.Key = w.key
+	}
+	if cfg.OIDCGoogle != nil {
+		cfg.OIDCGoogle.Key = w.key
+	}
+
+	w.configs[canonicalName] = cfg
+}
+
+// usesSecret reports whether the given secret is used by a policy, in which case policy handlers must be refreshed.
+func (w *Watcher) usesSecret(secret *corev1.Secret) bool {
+	w.configsMu.RLock()
+	defer w.configsMu.RUnlock()
+
+	return w.isSecretReferenced(secret.Namespace + "@" + secret.Name)
+}
+
+// isSecretReferenced reports whether the secret with the given key is used by a policy.
+// It must be called with the configs lock held.
+func (w *Watcher) isSecretReferenced(key string) bool {
+	for _, config := range w.configs {
+		cfg := oidcConfig(config)
+		if cfg != nil && cfg.Secret != nil && cfg.Secret.Namespace+"@"+cfg.Secret.Name == key {
+			return true
+		}
+
+		for _, ref := range jwtValueReferences(config) {
+			if !ref.IsConfigMap() && ref.Namespace+"@"+ref.Name == key {
+				return true
+			}
+		}
 	}
-	if w.configs[policy.ObjectMeta.Name].OIDCGoogle != nil {
-		w.configs[policy.ObjectMeta.Name].OIDCGoogle.Key = w.key
+
+	return false
+}
+
+// usesConfigMap reports whether the given ConfigMap is used by a policy, in which case policy handlers must be
+// refreshed.
+func (w *Watcher) usesConfigMap(configMap *corev1.ConfigMap) bool {
+	w.configsMu.RLock()
+	defer w.configsMu.RUnlock()
+
+	return w.isConfigMapReferenced(configMap.Namespace + "@" + configMap.Name)
+}
+
+// isConfigMapReferenced reports whether the ConfigMap with the given key is used by a policy.
+// It must be called with the configs lock held.
+func (w *Watcher) isConfigMapReferenced(key string) bool {
+	for _, config := range w.configs {
+		cfg := config.DenyResponse()
+		if cfg != nil && cfg.TemplateRef != nil && cfg.TemplateRef.Namespace+"@"+cfg.TemplateRef.Name == key {
+			return true
+		}
+
+		for _, ref := range jwtValueReferences(config) {
+			if ref.IsConfigMap() && ref.Namespace+"@"+ref.Name == key {
+				return true
+			}
+		}
 	}
+
+	return false
 }
 
 // OnDelete implements Kubernetes cache.ResourceEventHandler so it can be used as an informer event handler.
 func (w *Watcher) OnDelete(obj interface{}) {
 	switch v := obj.(type) {
 	case *hubv1alpha1.AccessControlPolicy:
-		w.configsMu.Lock()
-		delete(w.configs, v.ObjectMeta.Name)
-		w.configsMu.Unlock()
+		w.deleteConfig(v.Name)
+
+	case *hubv1alpha1.NamespacedAccessControlPolicy:
+		w.deleteConfig(acp.CanonicalName(v.Name, v.Namespace))
 
 	case *corev1.Secret:
-		w.configsMu.Lock()
-		delete(w.secrets, v.Namespace+"@"+v.Name)
-		w.configsMu.Unlock()
+		if !w.usesSecret(v) {
+			return
+		}
+
+	case *corev1.ConfigMap:
+		if !w.usesConfigMap(v) {
+			return
+		}
 
 	default:
 		log.Error().
@@ -229,31 +515,178 @@ func (w *Watcher) OnDelete(obj interface{}) {
 	}
 }
 
-func (w *Watcher) buildRoutes(ctx context.Context) http.Handler {
-	w.configsMu.RLock()
-	defer w.configsMu.RUnlock()
+// deleteConfig removes the configuration of the policy with the given canonical name.
+func (w *Watcher) deleteConfig(canonicalName string) {
+	w.configsMu.Lock()
+	delete(w.configs, canonicalName)
+	w.configsMu.Unlock()
 
-	mux := http.NewServeMux()
+	if w.statuses != nil {
+		w.statuses.Delete(canonicalName)
+	}
+}
 
-	for name, cfg := range w.configs {
-		path := "/" + name
+// refreshHandlers builds the handlers of the policies which configuration changed since the last refresh, and drops
+// the handlers of the deleted policies. Handlers of unchanged policies are kept as is, along with their caches.
+// It reports whether any handler changed.
+func (w *Watcher) refreshHandlers(ctx context.Context) bool {
+	w.configsMu.Lock()
+	defer w.configsMu.Unlock()
+
+	secretErrs := w.populateSecrets()
+	w.populateTemplates()
 
+	var changed bool
+	for name := range w.handlers {
+		if _, ok := w.configs[name]; !ok {
+			delete(w.handlers, name)
+			changed = true
+		}
+	}
+
+	for name := range w.buildErrs {
+		if _, ok := w.configs[name]; !ok {
+			delete(w.buildErrs, name)
+		}
+	}
+
+	defer w.reportStatuses(secretErrs)
+
+	for name, cfg := range w.configs {
 		logger := log.With().Str("acp_name", name).Str("acp_type", getACPType(cfg)).Logger()
 
-		route, err := buildRoute(ctx, name, cfg)
+		hash, err := hashstructure.Hash(cfg, hashstructure.FormatV2, nil)
 		if err != nil {
-			logger.Error().Err(err).Msg("create ACP handler")
+			logger.Error().Err(err).Msg("Unable to hash")
+		}
+
+		if current, ok := w.handlers[name]; ok && err == nil && current.hash == hash {
 			continue
 		}
+		prevBuildErr, failed := w.buildErrs[name]
+		if failed && err == nil && prevBuildErr.hash == hash && w.now().Before(prevBuildErr.retryAt) {
+			continue
+		}
+
+		logger.Debug().Msg("Building ACP handler")
+
+		route, buildErr := w.buildRoute(ctx, name, cfg)
+
+		// Building a handler applies the configuration defaults, so the hash is computed again for the next refresh
+		// to see the configuration as unchanged.
+		hash, err = hashstructure.Hash(cfg, hashstructure.FormatV2, nil)
+		if err != nil {
+			logger.Error().Err(err).Msg("Unable to hash")
+		}
+
+		if buildErr != nil {
+			logger.Error().Err(buildErr).Msg("create ACP handler")
+
+			var failures int
+			if failed && prevBuildErr.hash == hash {
+				failures = prevBuildErr.failures
+			}
+			w.buildErrs[name] = buildError{
+				hash:     hash,
+				err:      buildErr,
+				failures: failures + 1,
+				retryAt:  w.now().Add(buildRetryDelay(failures)),
+			}
 
-		logger.Debug().Msg("Registering ACP handler")
+			if _, ok := w.handlers[name]; ok {
+				delete(w.handlers, name)
+				changed = true
+			}
+			continue
+		}
 
-		mux.Handle(path, route)
+		w.handlers[name] = policyHandler{hash: hash, handler: route}
+		delete(w.buildErrs, name)
+		changed = true
+	}
+
+	return changed
+}
+
+// reportStatuses reports the conditions of all policies. It must be called with the configs lock held.
+func (w *Watcher) reportStatuses(secretErrs map[string]error) {
+	if w.statuses == nil {
+		return
+	}
+
+	for name, cfg := range w.configs {
+		w.statuses.Set(name, policyConditions(cfg, secretErrs[name], w.buildErrs[name].err))
+	}
+}
+
+// nextBuildRetry returns the earliest time a handler which failed to build must be built again, if any.
+func (w *Watcher) nextBuildRetry() (time.Time, bool) {
+	var next time.Time
+	for _, buildErr := range w.buildErrs {
+		if next.IsZero() || buildErr.retryAt.Before(next) {
+			next = buildErr.retryAt
+		}
+	}
+
+	return next, !next.IsZero()
+}
+
+// buildRetryDelay returns the delay before building again a handler which failed to build the given number of times
+// in a row, with the same configuration.
+func buildRetryDelay(failures int) time.Duration {
+	delay := minBuildRetryDelay
+	for i := 0; i < failures && delay < maxBuildRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxBuildRetryDelay {
+		return maxBuildRetryDelay
+	}
+
+	return delay
+}
+
+func (w *Watcher) buildRoutes() http.Handler {
+	mux := http.NewServeMux()
+
+	for name, h := range w.handlers {
+		log.Debug().Str("acp_name", name).Msg("Registering ACP handler")
+
+		mux.Handle(acp.AuthServerPath(name), h.handler)
 	}
 
 	return mux
 }
 
+// buildRoute builds the handler of the given policy, along with the middlewares it needs.
+func (w *Watcher) buildRoute(ctx context.Context, name string, cfg *acp.Config) (http.Handler, error) {
+	route, err := buildRoute(ctx, name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.IdentityToken != nil {
+		if w.minter == nil {
+			return nil, errors.New("identity tokens are not enabled on this auth server")
+		}
+
+		route = w.minter.Handler(name, cfg.IdentityToken, route)
+	}
+
+	if w.auditor != nil {
+		route = w.auditor.Handler(name, route)
+	}
+
+	switch cfg.EnforcementMode {
+	case "", acp.EnforcementModeEnforce:
+	case acp.EnforcementModeAudit:
+		route = auditModeHandler(name, cfg.WouldDenyHeader, route)
+	default:
+		return nil, fmt.Errorf("unknown enforcement mode %q", cfg.EnforcementMode)
+	}
+
+	return route, nil
+}
+
 func buildRoute(ctx context.Context, name string, cfg *acp.Config) (http.Handler, error) {
 	switch {
 	case cfg.JWT != nil:
@@ -292,12 +725,41 @@ func getACPType(cfg *acp.Config) string {
 	}
 }
 
-func populateSecrets(config *oidc.Config, secret oidcSecret) error {
-	if secret.ClientSecret == "" {
+// oidcConfig returns the OIDC configuration of the given policy configuration, if any.
+func oidcConfig(config *acp.Config) *oidc.Config {
+	switch {
+	case config.OIDC != nil:
+		return config.OIDC
+	case config.OIDCGoogle != nil:
+		return &config.OIDCGoogle.Config
+	default:
+		return nil
+	}
+}
+
+// jwtValueReferences returns the value references of the JWT configuration of the given policy configuration, if any.
+func jwtValueReferences(config *acp.Config) []*jwt.ValueReference {
+	if config.JWT == nil {
+		return nil
+	}
+
+	var refs []*jwt.ValueReference
+	for _, ref := range []*jwt.ValueReference{config.JWT.SigningSecretRef, config.JWT.PublicKeyRef, config.JWT.JWKsRef} {
+		if ref != nil {
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
+
+func populateSecrets(config *oidc.Config, secret map[string][]byte) error {
+	clientSecret := string(secret["clientSecret"])
+	if clientSecret == "" {
 		return errors.New("clientSecret is missing in secret")
 	}
 
-	config.ClientSecret = secret.ClientSecret
+	config.ClientSecret = clientSecret
 
 	return nil
 }
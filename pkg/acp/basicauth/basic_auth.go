@@ -7,6 +7,9 @@ import (
 
 	goauth "github.com/abbot/go-http-auth"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/audit"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/deny"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/identity"
 )
 
 const defaultRealm = "hub"
@@ -20,6 +23,7 @@ type Config struct {
 	Realm                    string
 	StripAuthorizationHeader bool
 	ForwardUsernameHeader    string
+	DenyResponse             *deny.Config
 }
 
 // Handler is a basic auth ACP Handler.
@@ -29,6 +33,9 @@ type Handler struct {
 	forwardUsername    string
 	stripAuthorization bool
 	name               string
+
+	// denyResponder is nil when the policy doesn't customize its deny responses.
+	denyResponder *deny.Responder
 }
 
 // NewHandler creates a new basic auth ACP Handler.
@@ -52,27 +59,53 @@ func NewHandler(cfg *Config, name string) (*Handler, error) {
 
 	h.auth = &goauth.BasicAuth{Realm: realm, Secrets: h.secretBasic}
 
+	if cfg.DenyResponse != nil {
+		denyCfg := *cfg.DenyResponse
+		if denyCfg.Realm == "" {
+			denyCfg.Realm = realm
+		}
+
+		h.denyResponder, err = deny.NewResponder(&denyCfg)
+		if err != nil {
+			return nil, fmt.Errorf("new deny responder: %w", err)
+		}
+	}
+
 	return h, nil
 }
 
 func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	l := log.With().Str("handler_type", "BasicAuth").Str("handler_name", h.name).Logger()
 
+	reason := "missing credentials"
 	username, password, ok := req.BasicAuth()
 	if ok {
 		secret := h.auth.Secrets(username, h.auth.Realm)
 		if secret == "" || !goauth.CheckSecret(password, secret) {
+			reason = "invalid credentials"
 			ok = false
 		}
 	}
 
 	if !ok {
 		l.Debug().Msg("Authentication failed")
+		audit.SetReason(req.Context(), reason)
 
-		h.auth.RequireAuth(rw, req)
+		if h.denyResponder == nil {
+			h.auth.RequireAuth(rw, req)
+			return
+		}
+
+		h.denyResponder.Deny(rw, req, deny.Denial{
+			StatusCode:  http.StatusUnauthorized,
+			Scheme:      "Basic",
+			Description: "Valid credentials are required",
+		})
 		return
 	}
 
+	identity.Set(req.Context(), identity.Identity{Subject: username})
+
 	if h.forwardUsername != "" {
 		rw.Header().Set(h.forwardUsername, username)
 	}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/deny"
 )
 
 func TestBasicAuthFail(t *testing.T) {
@@ -48,3 +49,25 @@ func TestBasicAuthUserHeader(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 	assert.Equal(t, "test", rec.Header().Get("User"))
 }
+
+func TestBasicAuthDenyResponse(t *testing.T) {
+	cfg := &Config{
+		Users:        []string{"test:$apr1$H6uskkkW$IgXLP6ewTrSuBkTrqE8wj/"},
+		Realm:        "my-realm",
+		DenyResponse: &deny.Config{},
+	}
+	handler, err := NewHandler(cfg, "acp@my-ns")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	req.SetBasicAuth("test", "wrong")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Basic realm="my-realm"`, rec.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"Valid credentials are required"}`, rec.Body.String())
+}
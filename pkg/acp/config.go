@@ -22,6 +22,8 @@ import (
 	"strings"
 
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/basicauth"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/deny"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/identity"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/oidc"
 	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
@@ -33,8 +35,21 @@ type Config struct {
 	BasicAuth  *basicauth.Config
 	OIDC       *oidc.Config
 	OIDCGoogle *OIDCGoogle
+
+	IdentityToken *identity.Config
+
+	// EnforcementMode is either EnforcementModeEnforce or EnforcementModeAudit. It defaults to EnforcementModeEnforce.
+	EnforcementMode string
+	// WouldDenyHeader is, in audit mode, the header set on requests which would have been denied.
+	WouldDenyHeader string
 }
 
+// Enforcement modes.
+const (
+	EnforcementModeEnforce = "enforce"
+	EnforcementModeAudit   = "audit"
+)
+
 // OIDCGoogle is the Google OIDC configuration.
 type OIDCGoogle struct {
 	oidc.Config
@@ -44,9 +59,108 @@ type OIDCGoogle struct {
 
 // ConfigFromPolicy returns an ACP configuration for the given policy.
 func ConfigFromPolicy(policy *hubv1alpha1.AccessControlPolicy) *Config {
+	return configFromSpec(&policy.Spec)
+}
+
+// ConfigFromNamespacedPolicy returns an ACP configuration for the given namespaced policy. The Secret and ConfigMap
+// references without namespace resolve to the namespace of the policy.
+func ConfigFromNamespacedPolicy(policy *hubv1alpha1.NamespacedAccessControlPolicy) *Config {
+	cfg := configFromSpec(&policy.Spec)
+
+	oidcCfg := cfg.OIDC
+	if cfg.OIDCGoogle != nil {
+		oidcCfg = &cfg.OIDCGoogle.Config
+	}
+	if oidcCfg != nil && oidcCfg.Secret != nil && oidcCfg.Secret.Namespace == "" {
+		oidcCfg.Secret.Namespace = policy.Namespace
+	}
+
+	if denyCfg := cfg.DenyResponse(); denyCfg != nil && denyCfg.TemplateRef != nil && denyCfg.TemplateRef.Namespace == "" {
+		denyCfg.TemplateRef.Namespace = policy.Namespace
+	}
+
+	if cfg.JWT != nil {
+		for _, ref := range []*jwt.ValueReference{cfg.JWT.SigningSecretRef, cfg.JWT.PublicKeyRef, cfg.JWT.JWKsRef} {
+			if ref != nil && ref.Namespace == "" {
+				ref.Namespace = policy.Namespace
+			}
+		}
+	}
+
+	return cfg
+}
+
+func configFromSpec(spec *hubv1alpha1.AccessControlPolicySpec) *Config {
+	cfg := handlerConfigFromSpec(spec)
+
+	if spec.IdentityToken != nil {
+		cfg.IdentityToken = &identity.Config{Header: spec.IdentityToken.Header}
+		if cfg.IdentityToken.Header == "" {
+			cfg.IdentityToken.Header = identity.DefaultHeader
+		}
+	}
+
+	cfg.EnforcementMode = spec.EnforcementMode
+	cfg.WouldDenyHeader = spec.WouldDenyHeader
+
+	if spec.DenyResponse != nil {
+		cfg.setDenyResponse(denyConfigFromPolicy(spec.DenyResponse))
+	}
+
+	return cfg
+}
+
+// DenyResponse returns the deny response configuration of the handler, if any.
+func (c *Config) DenyResponse() *deny.Config {
 	switch {
-	case policy.Spec.JWT != nil:
-		jwtCfg := policy.Spec.JWT
+	case c.JWT != nil:
+		return c.JWT.DenyResponse
+	case c.BasicAuth != nil:
+		return c.BasicAuth.DenyResponse
+	case c.OIDC != nil:
+		return c.OIDC.DenyResponse
+	case c.OIDCGoogle != nil:
+		return c.OIDCGoogle.DenyResponse
+	default:
+		return nil
+	}
+}
+
+func (c *Config) setDenyResponse(cfg *deny.Config) {
+	switch {
+	case c.JWT != nil:
+		c.JWT.DenyResponse = cfg
+	case c.BasicAuth != nil:
+		c.BasicAuth.DenyResponse = cfg
+	case c.OIDC != nil:
+		c.OIDC.DenyResponse = cfg
+	case c.OIDCGoogle != nil:
+		c.OIDCGoogle.DenyResponse = cfg
+	}
+}
+
+func denyConfigFromPolicy(denyResp *hubv1alpha1.DenyResponse) *deny.Config {
+	cfg := &deny.Config{
+		UnauthorizedStatusCode: denyResp.UnauthorizedStatusCode,
+		ForbiddenStatusCode:    denyResp.ForbiddenStatusCode,
+		Realm:                  denyResp.Realm,
+	}
+
+	if denyResp.TemplateRef != nil {
+		cfg.TemplateRef = &deny.TemplateReference{
+			Namespace: denyResp.TemplateRef.Namespace,
+			Name:      denyResp.TemplateRef.Name,
+			Key:       denyResp.TemplateRef.Key,
+		}
+	}
+
+	return cfg
+}
+
+func handlerConfigFromSpec(spec *hubv1alpha1.AccessControlPolicySpec) *Config {
+	switch {
+	case spec.JWT != nil:
+		jwtCfg := spec.JWT
 
 		return &Config{
 			JWT: &jwt.Config{
@@ -59,11 +173,14 @@ func ConfigFromPolicy(policy *hubv1alpha1.AccessControlPolicy) *Config {
 				ForwardHeaders:             jwtCfg.ForwardHeaders,
 				TokenQueryKey:              jwtCfg.TokenQueryKey,
 				Claims:                     jwtCfg.Claims,
+				SigningSecretRef:           valueReference(jwtCfg.SigningSecretRef),
+				PublicKeyRef:               valueReference(jwtCfg.PublicKeyRef),
+				JWKsRef:                    valueReference(jwtCfg.JWKsRef),
 			},
 		}
 
-	case policy.Spec.BasicAuth != nil:
-		basicCfg := policy.Spec.BasicAuth
+	case spec.BasicAuth != nil:
+		basicCfg := spec.BasicAuth
 
 		return &Config{
 			BasicAuth: &basicauth.Config{
@@ -74,8 +191,8 @@ func ConfigFromPolicy(policy *hubv1alpha1.AccessControlPolicy) *Config {
 			},
 		}
 
-	case policy.Spec.OIDC != nil:
-		oidcCfg := policy.Spec.OIDC
+	case spec.OIDC != nil:
+		oidcCfg := spec.OIDC
 
 		conf := &Config{
 			OIDC: &oidc.Config{
@@ -106,19 +223,29 @@ func ConfigFromPolicy(policy *hubv1alpha1.AccessControlPolicy) *Config {
 			}
 		}
 
-		if oidcCfg.StateCookie != nil {
+		if oidcCfg.Session != nil {
 			conf.OIDC.Session = &oidc.AuthSession{
-				Path:     oidcCfg.Session.Path,
-				Domain:   oidcCfg.Session.Domain,
-				SameSite: oidcCfg.Session.SameSite,
-				Secure:   oidcCfg.Session.Secure,
-				Refresh:  oidcCfg.Session.Refresh,
+				Path:        oidcCfg.Session.Path,
+				Domain:      oidcCfg.Session.Domain,
+				SameSite:    oidcCfg.Session.SameSite,
+				Secure:      oidcCfg.Session.Secure,
+				Refresh:     oidcCfg.Session.Refresh,
+				MaxAge:      oidcCfg.Session.MaxAge,
+				IdleTimeout: oidcCfg.Session.IdleTimeout,
 			}
 		}
 
+		for _, host := range oidcCfg.Hosts {
+			conf.OIDC.Hosts = append(conf.OIDC.Hosts, oidc.HostConfig{
+				Host:         host.Host,
+				RedirectURL:  host.RedirectURL,
+				CookieDomain: host.CookieDomain,
+			})
+		}
+
 		return conf
-	case policy.Spec.OIDCGoogle != nil:
-		oidcGoogleCfg := policy.Spec.OIDCGoogle
+	case spec.OIDCGoogle != nil:
+		oidcGoogleCfg := spec.OIDCGoogle
 
 		conf := &Config{
 			OIDCGoogle: &OIDCGoogle{
@@ -152,22 +279,45 @@ func ConfigFromPolicy(policy *hubv1alpha1.AccessControlPolicy) *Config {
 			}
 		}
 
-		if oidcGoogleCfg.StateCookie != nil {
+		if oidcGoogleCfg.Session != nil {
 			conf.OIDCGoogle.Session = &oidc.AuthSession{
-				Path:     oidcGoogleCfg.Session.Path,
-				Domain:   oidcGoogleCfg.Session.Domain,
-				SameSite: oidcGoogleCfg.Session.SameSite,
-				Secure:   oidcGoogleCfg.Session.Secure,
-				Refresh:  oidcGoogleCfg.Session.Refresh,
+				Path:        oidcGoogleCfg.Session.Path,
+				Domain:      oidcGoogleCfg.Session.Domain,
+				SameSite:    oidcGoogleCfg.Session.SameSite,
+				Secure:      oidcGoogleCfg.Session.Secure,
+				Refresh:     oidcGoogleCfg.Session.Refresh,
+				MaxAge:      oidcGoogleCfg.Session.MaxAge,
+				IdleTimeout: oidcGoogleCfg.Session.IdleTimeout,
 			}
 		}
 
+		for _, host := range oidcGoogleCfg.Hosts {
+			conf.OIDCGoogle.Hosts = append(conf.OIDCGoogle.Hosts, oidc.HostConfig{
+				Host:         host.Host,
+				RedirectURL:  host.RedirectURL,
+				CookieDomain: host.CookieDomain,
+			})
+		}
+
 		return conf
 	default:
 		return &Config{}
 	}
 }
 
+func valueReference(ref *hubv1alpha1.ValueReference) *jwt.ValueReference {
+	if ref == nil {
+		return nil
+	}
+
+	return &jwt.ValueReference{
+		Kind:      ref.Kind,
+		Namespace: ref.Namespace,
+		Name:      ref.Name,
+		Key:       ref.Key,
+	}
+}
+
 // buildClaims builds the claims from the emails.
 func buildClaims(emails []string) string {
 	var claims []string
@@ -21,6 +21,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
+	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestBuildClaims(t *testing.T) {
@@ -54,3 +57,22 @@ func TestBuildClaims(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigFromNamespacedPolicy_jwtValueReferences(t *testing.T) {
+	policy := &hubv1alpha1.NamespacedAccessControlPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "my-ns"},
+		Spec: hubv1alpha1.AccessControlPolicySpec{
+			JWT: &hubv1alpha1.AccessControlPolicyJWT{
+				SigningSecretRef: &hubv1alpha1.ValueReference{Name: "jwt", Key: "secret"},
+				JWKsRef:          &hubv1alpha1.ValueReference{Kind: "ConfigMap", Namespace: "other-ns", Name: "jwks", Key: "jwks.json"},
+			},
+		},
+	}
+
+	cfg := ConfigFromNamespacedPolicy(policy)
+
+	assert.Equal(t, &jwt.ValueReference{Namespace: "my-ns", Name: "jwt", Key: "secret"}, cfg.JWT.SigningSecretRef)
+	assert.Nil(t, cfg.JWT.PublicKeyRef)
+	assert.Equal(t, &jwt.ValueReference{Kind: "ConfigMap", Namespace: "other-ns", Name: "jwks", Key: "jwks.json"}, cfg.JWT.JWKsRef)
+	assert.Empty(t, cfg.JWT.SigningSecret)
+}
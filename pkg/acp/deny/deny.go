@@ -0,0 +1,281 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package deny writes the responses of the requests denied by ACP handlers.
+package deny
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
+)
+
+const defaultTemplate = `<!DOCTYPE html>
+<html>
+<head><title>{{ .StatusCode }} {{ .Status }}</title></head>
+<body>
+<h1>{{ .StatusCode }} {{ .Status }}</h1>
+{{- if .Description }}
+<p>{{ .Description }}</p>
+{{- end }}
+</body>
+</html>
+`
+
+// Config configures the responses of denied requests.
+type Config struct {
+	// UnauthorizedStatusCode is the status code of the responses sent to unauthenticated requests. Defaults to 401.
+	UnauthorizedStatusCode int `json:"unauthorizedStatusCode,omitempty"`
+	// ForbiddenStatusCode is the status code of the responses sent to unauthorized requests. Defaults to 403.
+	ForbiddenStatusCode int `json:"forbiddenStatusCode,omitempty"`
+	// Realm is the realm advertised in the WWW-Authenticate header.
+	Realm string `json:"realm,omitempty"`
+	// TemplateRef references the ConfigMap key holding the HTML template rendered to browsers.
+	TemplateRef *TemplateReference `json:"templateRef,omitempty"`
+	// Template is the HTML template rendered to browsers. It is populated out of the TemplateRef.
+	Template string `json:"-"`
+}
+
+// TemplateReference references a ConfigMap key.
+type TemplateReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+// Denial describes a denied request.
+type Denial struct {
+	// StatusCode is either http.StatusUnauthorized or http.StatusForbidden.
+	StatusCode int
+	// Scheme is the authentication scheme advertised in the WWW-Authenticate header, if any.
+	Scheme string
+	// Error is the error code advertised in the WWW-Authenticate header, as defined by RFC 6750, if any.
+	Error string
+	// Description is a human-readable description of the error.
+	Description string
+}
+
+// Responder writes the responses of denied requests.
+type Responder struct {
+	unauthorizedCode int
+	forbiddenCode    int
+	realm            string
+	tmpl             *template.Template
+}
+
+// NewResponder returns a new Responder.
+func NewResponder(cfg *Config) (*Responder, error) {
+	r := &Responder{
+		unauthorizedCode: http.StatusUnauthorized,
+		forbiddenCode:    http.StatusForbidden,
+		realm:            cfg.Realm,
+	}
+
+	if cfg.UnauthorizedStatusCode != 0 {
+		if !isErrorCode(cfg.UnauthorizedStatusCode) {
+			return nil, fmt.Errorf("invalid unauthorized status code %d", cfg.UnauthorizedStatusCode)
+		}
+		r.unauthorizedCode = cfg.UnauthorizedStatusCode
+	}
+
+	if cfg.ForbiddenStatusCode != 0 {
+		if !isErrorCode(cfg.ForbiddenStatusCode) {
+			return nil, fmt.Errorf("invalid forbidden status code %d", cfg.ForbiddenStatusCode)
+		}
+		r.forbiddenCode = cfg.ForbiddenStatusCode
+	}
+
+	if strings.Contains(r.realm, `"`) {
+		return nil, errors.New("realm must not contain double quotes")
+	}
+
+	content := cfg.Template
+	if content == "" {
+		content = defaultTemplate
+	}
+
+	var err error
+	r.tmpl, err = template.New("deny").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+
+	return r, nil
+}
+
+// Deny writes the response of the given denied request. The response format is negotiated using the Accept header
+// of the request: it is either an RFC 7807 problem, an HTML page or plain text.
+// As custom status codes can't be told apart from errors, the decision is reported along with the request context.
+func (r *Responder) Deny(rw http.ResponseWriter, req *http.Request, d Denial) {
+	code := r.forbiddenCode
+	result := metrics.ResultForbidden
+	if d.StatusCode == http.StatusUnauthorized {
+		code = r.unauthorizedCode
+		result = metrics.ResultUnauthorized
+	}
+
+	metrics.SetDecision(req.Context(), result)
+
+	if code == http.StatusUnauthorized && d.Scheme != "" {
+		rw.Header().Set("WWW-Authenticate", Challenge(r.realm, d))
+	}
+
+	switch negotiate(req.Header.Get("Accept")) {
+	case "text/html":
+		r.writeHTML(rw, code, d)
+	case "text/plain":
+		http.Error(rw, http.StatusText(code), code)
+	default:
+		writeProblem(rw, code, d)
+	}
+}
+
+// Challenge returns the WWW-Authenticate challenge of the given denial, advertising the given realm if any.
+func Challenge(realm string, d Denial) string {
+	params := []string{}
+	if realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", realm))
+	}
+	if d.Error != "" {
+		params = append(params, fmt.Sprintf("error=%q", d.Error))
+	}
+	if d.Error != "" && d.Description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", d.Description))
+	}
+
+	if len(params) == 0 {
+		return d.Scheme
+	}
+	return d.Scheme + " " + strings.Join(params, ", ")
+}
+
+func (r *Responder) writeHTML(rw http.ResponseWriter, code int, d Denial) {
+	data := struct {
+		StatusCode  int
+		Status      string
+		Error       string
+		Description string
+	}{
+		StatusCode:  code,
+		Status:      http.StatusText(code),
+		Error:       d.Error,
+		Description: d.Description,
+	}
+
+	var buf bytes.Buffer
+	if err := r.tmpl.Execute(&buf, data); err != nil {
+		log.Error().Err(err).Msg("Unable to render deny template")
+		http.Error(rw, http.StatusText(code), code)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(code)
+	_, _ = rw.Write(buf.Bytes())
+}
+
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func writeProblem(rw http.ResponseWriter, code int, d Denial) {
+	b, err := json.Marshal(problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(code),
+		Status: code,
+		Detail: d.Description,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Unable to marshal problem")
+		http.Error(rw, http.StatusText(code), code)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/problem+json")
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(code)
+	_, _ = rw.Write(b)
+}
+
+// negotiate returns the media type, among the supported ones, preferred by the given Accept header.
+// It defaults to application/problem+json.
+func negotiate(accept string) string {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+
+	for _, r := range ranges {
+		if r.q <= 0 {
+			continue
+		}
+
+		switch r.mediaType {
+		case "application/problem+json", "application/json":
+			return "application/problem+json"
+		case "text/html", "application/xhtml+xml":
+			return "text/html"
+		case "text/plain":
+			return "text/plain"
+		case "*/*", "application/*":
+			return "application/problem+json"
+		case "text/*":
+			return "text/html"
+		}
+	}
+
+	return "application/problem+json"
+}
+
+func isErrorCode(code int) bool {
+	return code >= 400 && code < 600
+}
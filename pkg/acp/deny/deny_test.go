@@ -0,0 +1,223 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package deny
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
+)
+
+func TestNewResponder(t *testing.T) {
+	tests := []struct {
+		desc    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			desc: "empty configuration",
+		},
+		{
+			desc: "valid status codes",
+			cfg:  Config{UnauthorizedStatusCode: http.StatusNotFound, ForbiddenStatusCode: http.StatusNotFound},
+		},
+		{
+			desc:    "invalid unauthorized status code",
+			cfg:     Config{UnauthorizedStatusCode: http.StatusOK},
+			wantErr: true,
+		},
+		{
+			desc:    "invalid forbidden status code",
+			cfg:     Config{ForbiddenStatusCode: 600},
+			wantErr: true,
+		},
+		{
+			desc:    "realm with double quotes",
+			cfg:     Config{Realm: `my"realm`},
+			wantErr: true,
+		},
+		{
+			desc:    "invalid template",
+			cfg:     Config{Template: "{{ .StatusCode "},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewResponder(&test.cfg)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestResponder_Deny(t *testing.T) {
+	tests := []struct {
+		desc            string
+		cfg             Config
+		accept          string
+		denial          Denial
+		wantCode        int
+		wantContentType string
+		wantChallenge   string
+		wantBody        string
+		wantDecision    string
+	}{
+		{
+			desc:            "problem by default",
+			denial:          Denial{StatusCode: http.StatusForbidden, Description: "Access denied"},
+			wantCode:        http.StatusForbidden,
+			wantContentType: "application/problem+json",
+			wantBody:        `{"type":"about:blank","title":"Forbidden","status":403,"detail":"Access denied"}`,
+			wantDecision:    metrics.ResultForbidden,
+		},
+		{
+			desc:            "problem requested",
+			accept:          "text/html;q=0.5, application/json",
+			denial:          Denial{StatusCode: http.StatusForbidden},
+			wantCode:        http.StatusForbidden,
+			wantContentType: "application/problem+json",
+			wantBody:        `{"type":"about:blank","title":"Forbidden","status":403}`,
+			wantDecision:    metrics.ResultForbidden,
+		},
+		{
+			desc:            "plain text requested",
+			accept:          "text/plain",
+			denial:          Denial{StatusCode: http.StatusForbidden},
+			wantCode:        http.StatusForbidden,
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "Forbidden\n",
+			wantDecision:    metrics.ResultForbidden,
+		},
+		{
+			desc:            "default HTML page",
+			accept:          "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			denial:          Denial{StatusCode: http.StatusForbidden, Description: "<b>denied</b>"},
+			wantCode:        http.StatusForbidden,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "<!DOCTYPE html>\n<html>\n<head><title>403 Forbidden</title></head>\n<body>\n<h1>403 Forbidden</h1>\n<p>&lt;b&gt;denied&lt;/b&gt;</p>\n</body>\n</html>\n",
+			wantDecision:    metrics.ResultForbidden,
+		},
+		{
+			desc:            "custom HTML page",
+			cfg:             Config{Template: "<p>{{ .StatusCode }} {{ .Error }}</p>"},
+			accept:          "text/html",
+			denial:          Denial{StatusCode: http.StatusUnauthorized, Error: "invalid_token"},
+			wantCode:        http.StatusUnauthorized,
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "<p>401 invalid_token</p>",
+			wantDecision:    metrics.ResultUnauthorized,
+		},
+		{
+			desc:            "challenge without error",
+			cfg:             Config{Realm: "api"},
+			denial:          Denial{StatusCode: http.StatusUnauthorized, Scheme: "Bearer"},
+			wantCode:        http.StatusUnauthorized,
+			wantContentType: "application/problem+json",
+			wantChallenge:   `Bearer realm="api"`,
+			wantBody:        `{"type":"about:blank","title":"Unauthorized","status":401}`,
+			wantDecision:    metrics.ResultUnauthorized,
+		},
+		{
+			desc: "challenge with error",
+			cfg:  Config{Realm: "api"},
+			denial: Denial{
+				StatusCode:  http.StatusUnauthorized,
+				Scheme:      "Bearer",
+				Error:       "invalid_token",
+				Description: "The access token is invalid",
+			},
+			wantCode:        http.StatusUnauthorized,
+			wantContentType: "application/problem+json",
+			wantChallenge:   `Bearer realm="api", error="invalid_token", error_description="The access token is invalid"`,
+			wantBody:        `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"The access token is invalid"}`,
+			wantDecision:    metrics.ResultUnauthorized,
+		},
+		{
+			desc:            "overridden status codes",
+			cfg:             Config{UnauthorizedStatusCode: http.StatusNotFound},
+			denial:          Denial{StatusCode: http.StatusUnauthorized, Scheme: "Bearer"},
+			wantCode:        http.StatusNotFound,
+			wantContentType: "application/problem+json",
+			wantBody:        `{"type":"about:blank","title":"Not Found","status":404}`,
+			wantDecision:    metrics.ResultUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			responder, err := NewResponder(&test.cfg)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(metrics.WithDecision(req.Context()))
+			if test.accept != "" {
+				req.Header.Set("Accept", test.accept)
+			}
+			rw := httptest.NewRecorder()
+
+			responder.Deny(rw, req, test.denial)
+
+			assert.Equal(t, test.wantCode, rw.Code)
+			assert.Equal(t, test.wantContentType, rw.Header().Get("Content-Type"))
+			assert.Equal(t, test.wantChallenge, rw.Header().Get("WWW-Authenticate"))
+			assert.Equal(t, test.wantBody, rw.Body.String())
+			assert.Equal(t, test.wantDecision, metrics.Decision(req.Context(), rw.Code))
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: "application/problem+json"},
+		{accept: "*/*", want: "application/problem+json"},
+		{accept: "application/problem+json", want: "application/problem+json"},
+		{accept: "text/html", want: "text/html"},
+		{accept: "text/plain", want: "text/plain"},
+		{accept: "text/*", want: "text/html"},
+		{accept: "text/html;q=0.1, text/plain;q=0.9", want: "text/plain"},
+		{accept: "text/html;q=0, image/png", want: "application/problem+json"},
+		{accept: "invalid;;, text/html", want: "text/html"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.accept, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, negotiate(test.accept))
+		})
+	}
+}
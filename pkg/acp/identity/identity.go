@@ -0,0 +1,116 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package identity
+
+import (
+	"context"
+	"sync"
+)
+
+// Config configures the identity token minted for the upstream of an ACP.
+type Config struct {
+	// Header is the name of the header the identity token is forwarded in.
+	Header string
+}
+
+// Identity is the normalized identity of an authenticated request.
+type Identity struct {
+	Subject string
+	Email   string
+	Name    string
+	Groups  []string
+}
+
+type contextKey struct{}
+
+type holder struct {
+	mu       sync.Mutex
+	identity *Identity
+}
+
+// NewContext returns a copy of the given context in which ACP handlers can record the identity of
+// the request they authenticated. The given context is returned as is if it was already created by NewContext.
+func NewContext(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(contextKey{}).(*holder); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, contextKey{}, &holder{})
+}
+
+// Set records the identity of an authenticated request. It does nothing if the context wasn't
+// created with NewContext.
+func Set(ctx context.Context, id Identity) {
+	h, ok := ctx.Value(contextKey{}).(*holder)
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	h.identity = &id
+	h.mu.Unlock()
+}
+
+// FromContext returns the identity recorded in the given context, if any.
+func FromContext(ctx context.Context) (Identity, bool) {
+	h, ok := ctx.Value(contextKey{}).(*holder)
+	if !ok {
+		return Identity{}, false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.identity == nil {
+		return Identity{}, false
+	}
+	return *h.identity, true
+}
+
+// FromClaims builds an identity out of standard JWT or OIDC claims.
+func FromClaims(claims map[string]interface{}) Identity {
+	id := Identity{
+		Subject: stringClaim(claims, "sub"),
+		Email:   stringClaim(claims, "email"),
+		Name:    stringClaim(claims, "name"),
+	}
+
+	if id.Name == "" {
+		id.Name = stringClaim(claims, "preferred_username")
+	}
+
+	switch groups := claims["groups"].(type) {
+	case string:
+		id.Groups = []string{groups}
+	case []interface{}:
+		for _, group := range groups {
+			if s, ok := group.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	case []string:
+		id.Groups = groups
+	}
+
+	return id
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
@@ -0,0 +1,212 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package identity
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/square/go-jose.v2"
+)
+
+// JWKSPath is the path on which the auth server publishes the keys used to sign identity tokens.
+const JWKSPath = "/.well-known/jwks.json"
+
+// DefaultHeader is the header identity tokens are forwarded in when none is configured.
+const DefaultHeader = "X-Hub-Identity"
+
+const defaultTTL = time.Minute
+
+// Claims are the claims of an identity token.
+type Claims struct {
+	jwt.RegisteredClaims
+
+	Email  string   `json:"email,omitempty"`
+	Name   string   `json:"name,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+	Policy string   `json:"acp"`
+}
+
+// Minter mints short-lived identity tokens for upstream services.
+type Minter struct {
+	key    *ecdsa.PrivateKey
+	keyID  string
+	issuer string
+	ttl    time.Duration
+
+	now func() time.Time
+}
+
+// NewMinter returns a new Minter signing tokens with the given key. Tokens are valid for the given
+// TTL, or one minute if zero.
+func NewMinter(key *ecdsa.PrivateKey, issuer string, ttl time.Duration) (*Minter, error) {
+	if key == nil {
+		return nil, errors.New("missing signing key")
+	}
+
+	if ttl < 0 {
+		return nil, errors.New("token TTL must be positive")
+	}
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+
+	thumbprint, err := (&jose.JSONWebKey{Key: key.Public()}).Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("compute key thumbprint: %w", err)
+	}
+
+	return &Minter{
+		key:    key,
+		keyID:  base64.RawURLEncoding.EncodeToString(thumbprint),
+		issuer: issuer,
+		ttl:    ttl,
+		now:    time.Now,
+	}, nil
+}
+
+// Mint mints an identity token for the given identity, authenticated by the given policy.
+func (m *Minter) Mint(policy string, id Identity) (string, error) {
+	now := m.now()
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("generate token ID: %w", err)
+	}
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   id.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.ttl)),
+			ID:        base64.RawURLEncoding.EncodeToString(jti),
+		},
+		Email:  id.Email,
+		Name:   id.Name,
+		Groups: id.Groups,
+		Policy: policy,
+	}
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	tok.Header["kid"] = m.keyID
+
+	return tok.SignedString(m.key)
+}
+
+// Handler returns a handler which mints an identity token, forwarded in the configured header,
+// every time the given ACP handler lets a request through.
+func (m *Minter) Handler(policy string, cfg *Config, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		req = req.WithContext(NewContext(req.Context()))
+
+		next.ServeHTTP(&tokenResponseWriter{
+			ResponseWriter: rw,
+			req:            req,
+			minter:         m,
+			policy:         policy,
+			header:         cfg.Header,
+		}, req)
+	})
+}
+
+// ServeHTTP serves the JWKS holding the public key of the Minter.
+func (m *Minter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	keySet := jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{
+			{
+				Key:       m.key.Public(),
+				KeyID:     m.keyID,
+				Algorithm: string(jose.ES256),
+				Use:       "sig",
+			},
+		},
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Cache-Control", "public, max-age=300")
+
+	if err := json.NewEncoder(rw).Encode(keySet); err != nil {
+		log.Error().Err(err).Msg("Unable to encode JWKS")
+	}
+}
+
+// tokenResponseWriter adds an identity token to the response of an ACP handler right before it
+// lets a request through.
+type tokenResponseWriter struct {
+	http.ResponseWriter
+
+	req         *http.Request
+	minter      *Minter
+	policy      string
+	header      string
+	wroteHeader bool
+	// discard is set when the response got replaced by an error, in which case what the ACP handler
+	// writes is dropped.
+	discard bool
+}
+
+func (w *tokenResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if code == http.StatusOK {
+		if id, ok := FromContext(w.req.Context()); ok {
+			tok, err := w.minter.Mint(w.policy, id)
+			if err != nil {
+				log.Error().Err(err).Str("acp_name", w.policy).Msg("Unable to mint identity token")
+				http.Error(w.ResponseWriter, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				w.discard = true
+				return
+			}
+
+			w.ResponseWriter.Header().Set(w.header, tok)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *tokenResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.discard {
+		return len(b), nil
+	}
+
+	return w.ResponseWriter.Write(b)
+}
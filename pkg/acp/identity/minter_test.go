@@ -0,0 +1,196 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package identity
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func TestMinter_Handler(t *testing.T) {
+	tests := []struct {
+		desc      string
+		handler   http.HandlerFunc
+		wantCode  int
+		wantToken bool
+	}{
+		{
+			desc: "mints a token when the request is let through",
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				Set(req.Context(), Identity{Subject: "john", Email: "john@example.com", Groups: []string{"dev"}})
+				rw.WriteHeader(http.StatusOK)
+			},
+			wantCode:  http.StatusOK,
+			wantToken: true,
+		},
+		{
+			desc: "no token when the request is denied",
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				Set(req.Context(), Identity{Subject: "john"})
+				rw.WriteHeader(http.StatusForbidden)
+			},
+			wantCode: http.StatusForbidden,
+		},
+		{
+			desc: "no token when no identity was recorded",
+			handler: func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			},
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			minter := newMinter(t)
+
+			rw := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/my-policy", nil)
+
+			minter.Handler("my-policy", &Config{Header: "X-Identity"}, test.handler).ServeHTTP(rw, req)
+
+			assert.Equal(t, test.wantCode, rw.Code)
+
+			tok := rw.Header().Get("X-Identity")
+			if !test.wantToken {
+				assert.Empty(t, tok)
+				return
+			}
+
+			claims := verify(t, minter, tok)
+			assert.Equal(t, "hub-agent", claims.Issuer)
+			assert.Equal(t, "john", claims.Subject)
+			assert.Equal(t, "john@example.com", claims.Email)
+			assert.Equal(t, []string{"dev"}, claims.Groups)
+			assert.Equal(t, "my-policy", claims.Policy)
+			assert.Equal(t, claims.IssuedAt.Add(time.Minute), claims.ExpiresAt.Time)
+		})
+	}
+}
+
+func TestMinter_ServeHTTP(t *testing.T) {
+	minter := newMinter(t)
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, JWKSPath, nil)
+
+	minter.ServeHTTP(rw, req)
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+
+	var keySet jose.JSONWebKeySet
+	require.NoError(t, json.NewDecoder(rw.Body).Decode(&keySet))
+	require.Len(t, keySet.Keys, 1)
+
+	key := keySet.Keys[0]
+	assert.Equal(t, minter.keyID, key.KeyID)
+	assert.Equal(t, "ES256", key.Algorithm)
+	assert.Equal(t, "sig", key.Use)
+	assert.True(t, key.IsPublic())
+}
+
+func TestNewMinter_negativeTTL(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	_, err = NewMinter(key, "hub-agent", -time.Second)
+	assert.Error(t, err)
+}
+
+func TestFromClaims(t *testing.T) {
+	tests := []struct {
+		desc   string
+		claims map[string]interface{}
+		want   Identity
+	}{
+		{
+			desc: "standard claims",
+			claims: map[string]interface{}{
+				"sub":    "john",
+				"email":  "john@example.com",
+				"name":   "John Doe",
+				"groups": []interface{}{"dev", 1, "ops"},
+			},
+			want: Identity{Subject: "john", Email: "john@example.com", Name: "John Doe", Groups: []string{"dev", "ops"}},
+		},
+		{
+			desc: "preferred username and single group",
+			claims: map[string]interface{}{
+				"sub":                "john",
+				"preferred_username": "jdoe",
+				"groups":             "dev",
+			},
+			want: Identity{Subject: "john", Name: "jdoe", Groups: []string{"dev"}},
+		},
+		{
+			desc:   "unexpected claim types are ignored",
+			claims: map[string]interface{}{"sub": 42},
+			want:   Identity{},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, FromClaims(test.claims))
+		})
+	}
+}
+
+func newMinter(t *testing.T) *Minter {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	minter, err := NewMinter(key, "hub-agent", 0)
+	require.NoError(t, err)
+
+	return minter
+}
+
+func verify(t *testing.T, minter *Minter, tok string) *Claims {
+	t.Helper()
+
+	var claims Claims
+	parsed, err := jwt.ParseWithClaims(tok, &claims, func(tok *jwt.Token) (interface{}, error) {
+		assert.Equal(t, minter.keyID, tok.Header["kid"])
+		return minter.key.Public(), nil
+	})
+	require.NoError(t, err)
+	require.True(t, parsed.Valid)
+
+	return &claims
+}
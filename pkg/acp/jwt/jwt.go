@@ -32,7 +32,11 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	jwtreq "github.com/golang-jwt/jwt/v4/request"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/audit"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/deny"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/identity"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt/expr"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
 )
 
 // Config configures a JWT ACP handler.
@@ -46,6 +50,32 @@ type Config struct {
 	ForwardHeaders             map[string]string
 	TokenQueryKey              string
 	Claims                     string
+	DenyResponse               *deny.Config
+
+	// SigningSecretRef, PublicKeyRef and JWKsRef reference the Secret or ConfigMap keys the signing secret, public key
+	// and JWKs are read from. Once resolved, their values are set in SigningSecret, PublicKey and JWKsFile.
+	SigningSecretRef *ValueReference
+	PublicKeyRef     *ValueReference
+	JWKsRef          *ValueReference
+}
+
+// Kinds of resources a ValueReference can reference.
+const (
+	KindSecret    = "Secret"
+	KindConfigMap = "ConfigMap"
+)
+
+// ValueReference references a key of a Secret or of a ConfigMap.
+type ValueReference struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Key       string
+}
+
+// IsConfigMap reports whether the reference targets a ConfigMap, references targeting Secrets by default.
+func (r *ValueReference) IsConfigMap() bool {
+	return r.Kind == KindConfigMap
 }
 
 func (cfg *Config) keySet() (KeySet, error) {
@@ -92,6 +122,9 @@ type Handler struct {
 	fwdHeaders         map[string]string
 
 	validateCustomClaims expr.Predicate
+
+	// denyResponder is nil when the policy doesn't customize its deny responses.
+	denyResponder *deny.Responder
 }
 
 // NewHandler returns a new JWT ACP Handler.
@@ -144,6 +177,14 @@ func NewHandler(cfg *Config, polName string) (*Handler, error) {
 		return nil, err
 	}
 
+	var denyResponder *deny.Responder
+	if cfg.DenyResponse != nil {
+		denyResponder, err = deny.NewResponder(cfg.DenyResponse)
+		if err != nil {
+			return nil, fmt.Errorf("new deny responder: %w", err)
+		}
+	}
+
 	return &Handler{
 		name:                 polName,
 		signingSecret:        signingSecret,
@@ -155,6 +196,7 @@ func NewHandler(cfg *Config, polName string) (*Handler, error) {
 		fwdHeaders:           cfg.ForwardHeaders,
 		tokQryKey:            tokenQueryKey,
 		validateCustomClaims: pred,
+		denyResponder:        denyResponder,
 	}, nil
 }
 
@@ -172,17 +214,32 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			l.Error().Err(err).Msg("Unable to parse JWT")
 		}
 
-		rw.WriteHeader(http.StatusUnauthorized)
+		audit.SetReason(req.Context(), "invalid JWT")
+
+		denial := deny.Denial{StatusCode: http.StatusUnauthorized, Scheme: "Bearer"}
+		if !errors.Is(err, errNoToken) {
+			denial.Error = "invalid_token"
+			denial.Description = "The access token is invalid"
+		}
+		h.deny(rw, req, denial)
 		return
 	}
 
 	if h.validateCustomClaims != nil {
 		if !h.validateCustomClaims(tok.Claims.(jwt.MapClaims)) {
-			rw.WriteHeader(http.StatusForbidden)
+			audit.SetReason(req.Context(), "claims not allowed")
+			h.deny(rw, req, deny.Denial{
+				StatusCode:  http.StatusForbidden,
+				Scheme:      "Bearer",
+				Error:       "insufficient_scope",
+				Description: "The access token does not grant access to this resource",
+			})
 			return
 		}
 	}
 
+	identity.Set(req.Context(), identity.FromClaims(tok.Claims.(jwt.MapClaims)))
+
 	hdrs, err := expr.PluckClaims(h.fwdHeaders, tok.Claims.(jwt.MapClaims))
 	if err != nil {
 		l.Error().Err(err).Msg("Unable to set forwarded header")
@@ -203,6 +260,19 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	rw.WriteHeader(http.StatusOK)
 }
 
+// deny writes the response of a denied request, using the deny responder of the policy if any.
+func (h *Handler) deny(rw http.ResponseWriter, req *http.Request, d deny.Denial) {
+	if h.denyResponder == nil {
+		if d.StatusCode == http.StatusUnauthorized {
+			rw.Header().Set("WWW-Authenticate", deny.Challenge("", d))
+		}
+		rw.WriteHeader(d.StatusCode)
+		return
+	}
+
+	h.denyResponder.Deny(rw, req, d)
+}
+
 // keyFunc returns a function to find the correct key to validate its given JWT's signature.
 func (h *Handler) keyFunc(ctx context.Context) jwt.Keyfunc {
 	return func(tok *jwt.Token) (key interface{}, err error) {
@@ -261,6 +331,7 @@ func (h *Handler) resolveKey(ctx context.Context, tok *jwt.Token, kid string) (k
 
 	k, err := ks.Key(ctx, kid)
 	if err != nil {
+		metrics.IncJWKSFetchErrors(h.name)
 		return nil, fmt.Errorf("error searching for JSON web key: %w", err)
 	}
 
@@ -302,6 +373,9 @@ func (h *Handler) remoteKeySet(iss string) (*RemoteKeySet, error) {
 	return rks, nil
 }
 
+// errNoToken is returned when no JWT was found in a request.
+var errNoToken = errors.New("no JWT found in request")
+
 // jwtExtractor extracts JWTs from HTTP requests.
 type jwtExtractor struct {
 	tokQryKey string
@@ -316,7 +390,7 @@ func (j jwtExtractor) ExtractToken(req *http.Request) (string, error) {
 	}
 
 	if rawJWT == "" {
-		return "", errors.New("no JWT found in request")
+		return "", errNoToken
 	}
 
 	return rawJWT, nil
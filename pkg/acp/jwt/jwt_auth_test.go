@@ -30,6 +30,7 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/deny"
 	"gopkg.in/square/go-jose.v2"
 )
 
@@ -109,6 +110,7 @@ func TestServeHTTP(t *testing.T) {
 			jwtCfg:         Config{SigningSecret: "bibi"},
 			token:          "",
 			wantStatusCode: http.StatusUnauthorized,
+			wantHeader:     http.Header{"Www-Authenticate": []string{"Bearer"}},
 		},
 		{
 			name:           "token is valid",
@@ -121,6 +123,7 @@ func TestServeHTTP(t *testing.T) {
 			jwtCfg:         Config{SigningSecret: "bibi"},
 			token:          expiredJWT,
 			wantStatusCode: http.StatusUnauthorized,
+			wantHeader:     http.Header{"Www-Authenticate": []string{`Bearer error="invalid_token", error_description="The access token is invalid"`}},
 		},
 		{
 			name: "token is not for required group",
@@ -164,6 +167,7 @@ func TestServeHTTP(t *testing.T) {
 			jwtCfg:         Config{JWKsURL: "/.well-known/jwks.json"},
 			token:          validJWT,
 			wantStatusCode: http.StatusUnauthorized,
+			wantHeader:     http.Header{"Www-Authenticate": []string{`Bearer error="invalid_token", error_description="The access token is invalid"`}},
 		},
 		{
 			name: "nested header is forwarded (and header is canonicalized)",
@@ -212,6 +216,67 @@ func TestServeHTTP(t *testing.T) {
 	}
 }
 
+func TestServeHTTP_denyResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		wantStatusCode int
+		wantChallenge  string
+		wantBody       string
+	}{
+		{
+			name:           "token is missing",
+			wantStatusCode: http.StatusUnauthorized,
+			wantChallenge:  `Bearer realm="api"`,
+			wantBody:       `{"type":"about:blank","title":"Unauthorized","status":401}`,
+		},
+		{
+			name:           "token is expired",
+			token:          expiredJWT,
+			wantStatusCode: http.StatusUnauthorized,
+			wantChallenge:  `Bearer realm="api", error="invalid_token", error_description="The access token is invalid"`,
+			wantBody:       `{"type":"about:blank","title":"Unauthorized","status":401,"detail":"The access token is invalid"}`,
+		},
+		{
+			name:           "token is not for required group",
+			token:          missingGroupJWT,
+			wantStatusCode: http.StatusNotFound,
+			wantBody:       `{"type":"about:blank","title":"Not Found","status":404,"detail":"The access token does not grant access to this resource"}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			middleware, err := NewHandler(&Config{
+				SigningSecret: "bibi",
+				Claims:        "Equals(`grp`, `admin`)",
+				DenyResponse: &deny.Config{
+					ForbiddenStatusCode: http.StatusNotFound,
+					Realm:               "api",
+				},
+			}, "acp@my-ns")
+			require.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Accept", "application/json")
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			middleware.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.wantStatusCode, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			assert.Equal(t, test.wantChallenge, rec.Header().Get("WWW-Authenticate"))
+			assert.Equal(t, test.wantBody, rec.Body.String())
+		})
+	}
+}
+
 func TestExtractJWT(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,113 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// DecisionResult returns the decision result matching the given ACP handler response status code.
+// Redirections are sent to users who aren't authenticated yet, hence are considered unauthorized.
+func DecisionResult(code int) string {
+	switch {
+	case code >= 200 && code < 300:
+		return ResultAllowed
+	case code >= 300 && code < 400, code == http.StatusUnauthorized:
+		return ResultUnauthorized
+	case code == http.StatusForbidden:
+		return ResultForbidden
+	default:
+		return ResultError
+	}
+}
+
+type decisionKey struct{}
+
+type decisionHolder struct {
+	mu     sync.Mutex
+	result string
+}
+
+// WithDecision returns a context allowing the ACP handler serving the request to report its decision with
+// SetDecision. The given context is returned as is if it already allows it, so nested handlers share the decision.
+func WithDecision(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(decisionKey{}).(*decisionHolder); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, decisionKey{}, &decisionHolder{})
+}
+
+// SetDecision reports the result of the decision made for the request of the given context, for decisions which
+// can't be told from the response status code, like an authenticated user redirected to the page they asked for.
+// It does nothing if the context doesn't come from WithDecision.
+func SetDecision(ctx context.Context, result string) {
+	h, ok := ctx.Value(decisionKey{}).(*decisionHolder)
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	h.result = result
+	h.mu.Unlock()
+}
+
+// Decision returns the result of the decision made for the request of the given context, answered with the given
+// status code. The result reported with SetDecision, if any, takes precedence over the status code.
+func Decision(ctx context.Context, code int) string {
+	if h, ok := ctx.Value(decisionKey{}).(*decisionHolder); ok {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if h.result != "" {
+			return h.result
+		}
+	}
+
+	return DecisionResult(code)
+}
+
+// StatusRecorder is an http.ResponseWriter recording the status code of the response.
+type StatusRecorder struct {
+	http.ResponseWriter
+
+	code        int
+	wroteHeader bool
+}
+
+// NewStatusRecorder returns a StatusRecorder writing to the given http.ResponseWriter.
+func NewStatusRecorder(rw http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: rw, code: http.StatusOK}
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *StatusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.code = code
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Code returns the status code of the response.
+func (r *StatusRecorder) Code() int {
+	return r.code
+}
@@ -0,0 +1,127 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package metrics holds the Prometheus metrics of the auth server.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Decision results.
+const (
+	ResultAllowed      = "allowed"
+	ResultUnauthorized = "unauthorized"
+	ResultForbidden    = "forbidden"
+	ResultError        = "error"
+)
+
+// PolicyUnknown is the policy label value of the requests which don't match any policy.
+const PolicyUnknown = "unknown"
+
+const namespace = "hub_auth"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	decisions = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decisions_total",
+		Help:      "Number of auth decisions, by policy and result.",
+	}, []string{"policy", "result"})
+
+	decisionDuration = promauto.With(registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "decision_duration_seconds",
+		Help:      "Time taken to make auth decisions, by policy.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"policy"})
+
+	wouldDeny = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_mode_denials_total",
+		Help:      "Number of requests let through by policies in audit mode which would have been denied, by policy and result.",
+	}, []string{"policy", "result"})
+
+	jwksFetchErrors = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "jwks_fetch_errors_total",
+		Help:      "Number of failures to get a JWKS, by policy.",
+	}, []string{"policy"})
+
+	oidcRefreshes = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "oidc_token_refreshes_total",
+		Help:      "Number of OIDC token refreshes, by policy and result.",
+	}, []string{"policy", "result"})
+
+	sessionDecodeFailures = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "session_decode_failures_total",
+		Help:      "Number of OIDC session cookies which couldn't be decoded, by policy.",
+	}, []string{"policy"})
+)
+
+// Handler returns the HTTP handler exposing the auth server metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// InstrumentHandler returns a handler recording the decisions the given ACP handler makes.
+func InstrumentHandler(policy string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+
+		req = req.WithContext(WithDecision(req.Context()))
+
+		recorder := NewStatusRecorder(rw)
+		next.ServeHTTP(recorder, req)
+
+		decisionDuration.WithLabelValues(policy).Observe(time.Since(start).Seconds())
+		decisions.WithLabelValues(policy, Decision(req.Context(), recorder.Code())).Inc()
+	})
+}
+
+// IncWouldDeny records a request the given policy, in audit mode, would have denied with the given decision result.
+func IncWouldDeny(policy, result string) {
+	wouldDeny.WithLabelValues(policy, result).Inc()
+}
+
+// IncJWKSFetchErrors records a failure to get the JWKS of the given policy.
+func IncJWKSFetchErrors(policy string) {
+	jwksFetchErrors.WithLabelValues(policy).Inc()
+}
+
+// IncOIDCRefreshes records an OIDC token refresh for the given policy.
+func IncOIDCRefreshes(policy string, err error) {
+	res := "success"
+	if err != nil {
+		res = "failure"
+	}
+
+	oidcRefreshes.WithLabelValues(policy, res).Inc()
+}
+
+// IncSessionDecodeFailures records a session cookie of the given policy which couldn't be decoded.
+func IncSessionDecodeFailures(policy string) {
+	sessionDecodeFailures.WithLabelValues(policy).Inc()
+}
@@ -0,0 +1,106 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentHandler(t *testing.T) {
+	tests := []struct {
+		desc       string
+		code       int
+		wantResult string
+	}{
+		{desc: "implicit OK", wantResult: ResultAllowed},
+		{desc: "OK", code: http.StatusOK, wantResult: ResultAllowed},
+		{desc: "no content", code: http.StatusNoContent, wantResult: ResultAllowed},
+		{desc: "redirect", code: http.StatusFound, wantResult: ResultUnauthorized},
+		{desc: "unauthorized", code: http.StatusUnauthorized, wantResult: ResultUnauthorized},
+		{desc: "forbidden", code: http.StatusForbidden, wantResult: ResultForbidden},
+		{desc: "bad request", code: http.StatusBadRequest, wantResult: ResultError},
+		{desc: "internal server error", code: http.StatusInternalServerError, wantResult: ResultError},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			policy := "instrument-" + strings.ReplaceAll(test.desc, " ", "-")
+
+			handler := InstrumentHandler(policy, http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+				if test.code != 0 {
+					rw.WriteHeader(test.code)
+				}
+			}))
+
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/"+policy, nil))
+
+			assert.Equal(t, 1.0, testutil.ToFloat64(decisions.WithLabelValues(policy, test.wantResult)))
+
+			var duration dto.Metric
+			require.NoError(t, decisionDuration.WithLabelValues(policy).(prometheus.Metric).Write(&duration))
+			assert.Equal(t, uint64(1), duration.GetHistogram().GetSampleCount())
+		})
+	}
+}
+
+func TestIncOIDCRefreshes(t *testing.T) {
+	IncOIDCRefreshes("refreshes", nil)
+	IncOIDCRefreshes("refreshes", nil)
+	IncOIDCRefreshes("refreshes", errors.New("boom"))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(oidcRefreshes.WithLabelValues("refreshes", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(oidcRefreshes.WithLabelValues("refreshes", "failure")))
+}
+
+func TestHandler(t *testing.T) {
+	IncJWKSFetchErrors("handler")
+	IncSessionDecodeFailures("handler")
+
+	rw := httptest.NewRecorder()
+	Handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Contains(t, rw.Body.String(), `hub_auth_jwks_fetch_errors_total{policy="handler"} 1`)
+	assert.Contains(t, rw.Body.String(), `hub_auth_session_decode_failures_total{policy="handler"} 1`)
+}
+
+func TestDecision(t *testing.T) {
+	assert.Equal(t, ResultUnauthorized, Decision(context.Background(), http.StatusFound))
+
+	ctx := WithDecision(context.Background())
+	assert.Equal(t, ResultUnauthorized, Decision(ctx, http.StatusFound))
+
+	// Nested handlers share the decision.
+	SetDecision(WithDecision(ctx), ResultAllowed)
+	assert.Equal(t, ResultAllowed, Decision(ctx, http.StatusFound))
+}
@@ -0,0 +1,60 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package acp
+
+import "strings"
+
+// CanonicalName returns the canonical name of a policy. Namespaced policies are named `name@namespace`, while
+// cluster-wide policies are only named by their name.
+func CanonicalName(name, namespace string) string {
+	if namespace == "" {
+		return name
+	}
+
+	return name + "@" + namespace
+}
+
+// SplitCanonicalName returns the name and namespace of the policy with the given canonical name. The namespace of
+// cluster-wide policies is empty.
+func SplitCanonicalName(canonicalName string) (name, namespace string) {
+	name, namespace, _ = strings.Cut(canonicalName, "@")
+
+	return name, namespace
+}
+
+// AuthServerPath returns the path the policy with the given canonical name is served on by the auth server:
+// `/<name>` for cluster-wide policies and `/<namespace>/<name>` for namespaced ones.
+func AuthServerPath(canonicalName string) string {
+	name, namespace := SplitCanonicalName(canonicalName)
+	if namespace == "" {
+		return "/" + name
+	}
+
+	return "/" + namespace + "/" + name
+}
+
+// CanonicalNameFromAuthServerPath returns the canonical name of the policy served on the given auth server path.
+// It is the reverse of AuthServerPath.
+func CanonicalNameFromAuthServerPath(path string) string {
+	namespace, name, found := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if !found {
+		return namespace
+	}
+
+	return CanonicalName(name, namespace)
+}
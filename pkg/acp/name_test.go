@@ -0,0 +1,64 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package acp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalName(t *testing.T) {
+	tests := []struct {
+		desc          string
+		name          string
+		namespace     string
+		wantCanonical string
+		wantPath      string
+	}{
+		{
+			desc:          "cluster-wide policy",
+			name:          "my-policy",
+			wantCanonical: "my-policy",
+			wantPath:      "/my-policy",
+		},
+		{
+			desc:          "namespaced policy",
+			name:          "my-policy",
+			namespace:     "my-ns",
+			wantCanonical: "my-policy@my-ns",
+			wantPath:      "/my-ns/my-policy",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			canonicalName := CanonicalName(test.name, test.namespace)
+			assert.Equal(t, test.wantCanonical, canonicalName)
+			assert.Equal(t, test.wantPath, AuthServerPath(canonicalName))
+			assert.Equal(t, canonicalName, CanonicalNameFromAuthServerPath(test.wantPath))
+
+			name, namespace := SplitCanonicalName(canonicalName)
+			assert.Equal(t, test.name, name)
+			assert.Equal(t, test.namespace, namespace)
+		})
+	}
+}
@@ -19,6 +19,10 @@ package oidc
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/deny"
 )
 
 // Config holds the configuration for the OIDC middleware.
@@ -35,12 +39,31 @@ type Config struct {
 	Key         string            `json:"-"`
 	StateCookie *AuthStateCookie  `json:"stateCookie,omitempty"`
 	Session     *AuthSession      `json:"session,omitempty"`
+	// Hosts overrides the redirect URL and cookie domain for specific hosts, based on the X-Forwarded-Host header.
+	Hosts []HostConfig `json:"hosts,omitempty"`
 
 	// ForwardHeaders defines headers that should be added to the request and populated with values extracted from the ID token.
 	ForwardHeaders map[string]string `json:"forwardHeaders,omitempty"`
 	// Claims defines an expression to perform validation on the ID token. For example:
 	//     Equals(`grp`, `admin`) && Equals(`scope`, `deploy`)
 	Claims string `json:"claims,omitempty"`
+
+	// DenyResponse customizes the responses of denied requests.
+	DenyResponse *deny.Config `json:"denyResponse,omitempty"`
+
+	// Discovery, if set, describes the provider instead of the discovery document the issuer serves.
+	Discovery *Discovery `json:"-"`
+}
+
+// Discovery is an OpenID provider discovery document, as served on the .well-known/openid-configuration endpoint of
+// the issuer.
+type Discovery struct {
+	Issuer      string   `json:"issuer"`
+	AuthURL     string   `json:"authorization_endpoint"`
+	TokenURL    string   `json:"token_endpoint"`
+	JWKSURL     string   `json:"jwks_uri"`
+	UserInfoURL string   `json:"userinfo_endpoint"`
+	Algorithms  []string `json:"id_token_signing_alg_values_supported"`
 }
 
 // ApplyDefaultValues applies default values on the given dynamic configuration.
@@ -121,9 +144,48 @@ func (cfg *Config) Validate() error {
 		return errors.New("missing redirect URL")
 	}
 
+	if cfg.Session.MaxAge < 0 {
+		return errors.New("session max age must be positive")
+	}
+
+	if cfg.Session.IdleTimeout < 0 {
+		return errors.New("session idle timeout must be positive")
+	}
+
+	for _, host := range cfg.Hosts {
+		if host.Host == "" {
+			return errors.New("missing host")
+		}
+
+		if strings.Contains(strings.TrimPrefix(host.Host, "*."), "*") {
+			return fmt.Errorf("invalid host %q: wildcard is only supported as the first label", host.Host)
+		}
+	}
+
 	return nil
 }
 
+// hostConfig returns the host settings matching the given host, if any.
+// Exact matches take precedence over wildcard ones.
+func (cfg *Config) hostConfig(host string) *HostConfig {
+	host = strings.ToLower(host)
+
+	var wildcard *HostConfig
+	for i, hostCfg := range cfg.Hosts {
+		pattern := strings.ToLower(hostCfg.Host)
+
+		if pattern == host {
+			return &cfg.Hosts[i]
+		}
+
+		if wildcard == nil && strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+			wildcard = &cfg.Hosts[i]
+		}
+	}
+
+	return wildcard
+}
+
 // SecretReference represents a Secret Reference.
 // It has enough information to retrieve secret in any namespace.
 type SecretReference struct {
@@ -131,6 +193,15 @@ type SecretReference struct {
 	Namespace string
 }
 
+// HostConfig holds the settings overridden for requests targeting a given host.
+type HostConfig struct {
+	// Host is the host these settings apply to. A leading wildcard (e.g. *.example.com) matches any subdomain.
+	Host        string `json:"host,omitempty"`
+	RedirectURL string `json:"redirectUrl,omitempty"`
+	// CookieDomain is the domain of both the state and session cookies.
+	CookieDomain string `json:"cookieDomain,omitempty"`
+}
+
 // AuthStateCookie carries the state cookie configuration.
 type AuthStateCookie struct {
 	Path     string `json:"path,omitempty"`
@@ -146,6 +217,15 @@ type AuthSession struct {
 	SameSite string `json:"sameSite,omitempty"`
 	Secure   bool   `json:"secure,omitempty"`
 	Refresh  *bool  `json:"refresh,omitempty"`
+
+	// MaxAge is the maximum lifetime of a session in seconds. Once reached, users have to re-authenticate
+	// even if the session could be refreshed.
+	MaxAge int `json:"maxAge,omitempty"`
+	// IdleTimeout is the duration in seconds after which a session without activity expires.
+	// Activity is persisted in the session cookie, either through a redirect or on the authenticated response, which
+	// only ingress-nginx copies to the response sent to the client. Hence, behind other proxies, only requests which
+	// can be redirected extend the session.
+	IdleTimeout int `json:"idleTimeout,omitempty"`
 }
 
 // ptrBool returns a pointer to boolean.
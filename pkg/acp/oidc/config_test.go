@@ -0,0 +1,127 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package oidc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_HostConfig(t *testing.T) {
+	cfg := &Config{
+		Hosts: []HostConfig{
+			{Host: "*.example.com", RedirectURL: "https://auth.example.com/callback"},
+			{Host: "App.Example.com", RedirectURL: "/callback"},
+			{Host: "example.org", CookieDomain: "example.org"},
+		},
+	}
+
+	tests := []struct {
+		desc     string
+		host     string
+		wantHost string
+	}{
+		{
+			desc:     "exact match takes precedence over wildcard",
+			host:     "app.example.com",
+			wantHost: "App.Example.com",
+		},
+		{
+			desc:     "wildcard match",
+			host:     "foo.bar.example.com",
+			wantHost: "*.example.com",
+		},
+		{
+			desc: "wildcard doesn't match the parent domain",
+			host: "example.com",
+		},
+		{
+			desc:     "case insensitive match",
+			host:     "EXAMPLE.org",
+			wantHost: "example.org",
+		},
+		{
+			desc: "no match",
+			host: "example.net",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			got := cfg.hostConfig(test.host)
+			if test.wantHost == "" {
+				assert.Nil(t, got)
+				return
+			}
+
+			if assert.NotNil(t, got) {
+				assert.Equal(t, test.wantHost, got.Host)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateHosts(t *testing.T) {
+	tests := []struct {
+		desc    string
+		hosts   []HostConfig
+		wantErr string
+	}{
+		{
+			desc:  "valid hosts",
+			hosts: []HostConfig{{Host: "*.example.com"}, {Host: "example.org"}},
+		},
+		{
+			desc:    "missing host",
+			hosts:   []HostConfig{{RedirectURL: "/callback"}},
+			wantErr: "missing host",
+		},
+		{
+			desc:    "wildcard not in first label",
+			hosts:   []HostConfig{{Host: "foo.*.example.com"}},
+			wantErr: `invalid host "foo.*.example.com": wildcard is only supported as the first label`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := &Config{
+				Issuer:       "foo",
+				ClientID:     "bar",
+				ClientSecret: "bat",
+				Key:          "secret1234567890",
+				Hosts:        test.hosts,
+			}
+
+			err := cfg.Validate()
+			if test.wantErr != "" {
+				assert.EqualError(t, err, test.wantErr)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -34,7 +35,11 @@ import (
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/audit"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/deny"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/identity"
 	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt/expr"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/metrics"
 	"golang.org/x/oauth2"
 )
 
@@ -75,6 +80,11 @@ type SessionData struct {
 
 	// Expiry is the expiration time of the access token.
 	Expiry time.Time
+
+	// CreatedAt is the Unix time at which the user authenticated. It is kept across token refreshes.
+	CreatedAt int64 `json:",omitempty"`
+	// LastActivity is the Unix time at which the session has been persisted after being used for the last time.
+	LastActivity int64 `json:",omitempty"`
 }
 
 // IsExpired determines if the current access token is expired.
@@ -111,11 +121,54 @@ type Handler struct {
 	session  SessionStore
 	block    cipher.Block
 
+	// hostSessions holds the session stores of hosts overriding the cookie domain, indexed by host.
+	hostSessions map[string]SessionStore
+
 	validateClaims expr.Predicate
 
+	// denyResponder is nil when the policy doesn't customize its deny responses.
+	denyResponder *deny.Responder
+
 	client *http.Client
 
 	cfg *Config
+	now func() time.Time
+}
+
+// ProviderError is returned when the OIDC provider can't be discovered.
+type ProviderError struct {
+	Err error
+}
+
+func (e ProviderError) Error() string {
+	return "unable to create provider: " + e.Err.Error()
+}
+
+func (e ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// newProvider returns the provider of the given configuration, discovering it from its issuer unless the
+// configuration holds its discovery document.
+func newProvider(ctx context.Context, cfg *Config) (*oidc.Provider, error) {
+	if cfg.Discovery == nil {
+		return oidc.NewProvider(ctx, cfg.Issuer)
+	}
+
+	if cfg.Discovery.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("issuer did not match the issuer of the discovery document, expected %q got %q", cfg.Issuer, cfg.Discovery.Issuer)
+	}
+
+	providerCfg := &oidc.ProviderConfig{
+		IssuerURL:   cfg.Discovery.Issuer,
+		AuthURL:     cfg.Discovery.AuthURL,
+		TokenURL:    cfg.Discovery.TokenURL,
+		UserInfoURL: cfg.Discovery.UserInfoURL,
+		JWKSURL:     cfg.Discovery.JWKSURL,
+		Algorithms:  cfg.Discovery.Algorithms,
+	}
+
+	return providerCfg.NewProvider(ctx), nil
 }
 
 // NewHandler creates a new instance of a Handler from an auth source.
@@ -126,9 +179,9 @@ func NewHandler(ctx context.Context, cfg *Config, name string) (*Handler, error)
 
 	client := newHTTPClient()
 
-	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, client), cfg.Issuer)
+	provider, err := newProvider(oidc.ClientContext(ctx, client), cfg)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create provider: %w", err)
+		return nil, ProviderError{Err: err}
 	}
 
 	var pred expr.Predicate
@@ -139,11 +192,31 @@ func NewHandler(ctx context.Context, cfg *Config, name string) (*Handler, error)
 		}
 	}
 
+	var denyResponder *deny.Responder
+	if cfg.DenyResponse != nil {
+		denyResponder, err = deny.NewResponder(cfg.DenyResponse)
+		if err != nil {
+			return nil, fmt.Errorf("new deny responder: %w", err)
+		}
+	}
+
 	block, err := aes.NewCipher([]byte(cfg.Key))
 	if err != nil {
 		return nil, fmt.Errorf("new cipher: %w", err)
 	}
 
+	hostSessions := make(map[string]SessionStore)
+	for _, host := range cfg.Hosts {
+		if host.CookieDomain == "" {
+			continue
+		}
+
+		sessionCfg := *cfg.Session
+		sessionCfg.Domain = host.CookieDomain
+
+		hostSessions[host.Host] = NewCookieSessionStore(name+"-session", block, &sessionCfg, newRandom(), maxCookieSize)
+	}
+
 	return &Handler{
 		name:     name,
 		cfg:      cfg,
@@ -156,9 +229,12 @@ func NewHandler(ctx context.Context, cfg *Config, name string) (*Handler, error)
 		},
 		rand:           newRandom(),
 		session:        NewCookieSessionStore(name+"-session", block, cfg.Session, newRandom(), maxCookieSize),
+		hostSessions:   hostSessions,
 		block:          block,
 		validateClaims: pred,
+		denyResponder:  denyResponder,
 		client:         client,
+		now:            time.Now,
 	}, nil
 }
 
@@ -183,8 +259,10 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	forwardedURL := fmt.Sprintf("%s://%s%s", req.Header.Get("X-Forwarded-Proto"), req.Header.Get("X-Forwarded-Host"), req.Header.Get("X-Forwarded-Uri"))
 	forwardedMethod := req.Header.Get("X-Forwarded-Method")
 
+	session := h.sessionStore(req)
+
 	if equalURL(forwardedURL, logoutURL) && forwardedMethod == http.MethodDelete {
-		if err := h.session.Delete(rw, req); err != nil {
+		if err := session.Delete(rw, req); err != nil {
 			logger.Debug().Err(err).Msg("Unable to delete the session")
 		}
 
@@ -193,21 +271,35 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	sess, err := h.session.Get(req)
+	sess, err := session.Get(req)
 	if err != nil {
 		logger.Debug().Err(err).Msg("Unable to get the session")
-		http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		metrics.IncSessionDecodeFailures(h.name)
+		audit.SetReason(req.Context(), "invalid session")
+		h.deny(rw, req, deny.Denial{StatusCode: http.StatusUnauthorized, Description: "Authentication is required"})
 
 		return
 	}
 
+	// A session which reached its maximum lifetime or idle timeout is dropped, even if its tokens
+	// could be refreshed, so the user has to authenticate again.
+	if sess != nil && h.sessionEnded(sess) {
+		logger.Debug().Msg("Session reached its maximum lifetime or idle timeout")
+
+		if err = session.Delete(rw, req); err != nil {
+			logger.Debug().Err(err).Msg("Unable to delete the session")
+		}
+
+		sess = nil
+	}
+
 	// We get in here either because we're in the initial run (no session yet),
 	// or if we have an expired session, but session refreshing is disabled by
 	// configuration. For the gritty details, it means we don't need to refresh tokens (so
 	// we won't ask for them), so we don't need to be in offline access, and we don't
 	// need the (user consent) prompt after asking for credentials.
 	if sess == nil || (sess.IsExpired() && !(*h.cfg.Session.Refresh)) {
-		redirectURL := resolveURL(req, h.cfg.RedirectURL)
+		redirectURL := h.redirectURL(req)
 
 		if equalURL(forwardedURL, redirectURL) {
 			logger.Debug().Msg("Handle provider callback")
@@ -220,12 +312,14 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 		if !h.shouldRedirect(req) {
 			logger.Debug().Msg("Received a request that should not be redirected")
-			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			audit.SetReason(req.Context(), "no session")
+			h.deny(rw, req, deny.Denial{StatusCode: http.StatusUnauthorized, Description: "Authentication is required"})
 
 			return
 		}
 
 		// 1st step of diagram, i.e. the (unauthenticated) request is coming from the user.
+		audit.SetReason(req.Context(), "no session, redirected to the provider")
 		h.redirectToProvider(rw, req, redirectURL)
 
 		return
@@ -236,28 +330,36 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	if err != nil {
 		logger.Debug().Err(err).Msg("Unable to refresh the session")
 
-		if err = h.session.Delete(rw, req); err != nil {
+		if err = session.Delete(rw, req); err != nil {
 			logger.Debug().Err(err).Msg("Unable to delete the session")
 		}
 
 		if !h.shouldRedirect(req) {
 			logger.Debug().Err(err).Msg("Received a request that should not be redirected")
-			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			audit.SetReason(req.Context(), "session refresh failed")
+			h.deny(rw, req, deny.Denial{StatusCode: http.StatusUnauthorized, Description: "Authentication is required"})
 
 			return
 		}
 
 		// 1st step of diagram, restart from scratch, as if initial request.
-		redirectURL := resolveURL(req, h.cfg.RedirectURL)
+		audit.SetReason(req.Context(), "session refresh failed, redirected to the provider")
+		redirectURL := h.redirectURL(req)
 		h.redirectToProvider(rw, req, redirectURL)
 
 		return
 	}
 
+	// The last activity is only persisted once it's stale enough, to avoid writing the session cookie on every request.
+	touchSession := !refreshSession && h.shouldTouchSession(sess)
+	if touchSession {
+		sess.LastActivity = h.now().Unix()
+	}
+
 	// Refresh the session is possible only if we can return a redirect to the user.
 	// If we can't, we check the token and continue without update the session user.
-	if refreshSession && h.shouldRedirect(req) {
-		if err = h.session.Update(rw, req, *sess); err != nil {
+	if (refreshSession || touchSession) && h.shouldRedirect(req) {
+		if err = session.Update(rw, req, *sess); err != nil {
 			logger.Debug().Err(err).Msg("Unable to refresh the session")
 			http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 
@@ -265,6 +367,7 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		}
 
 		if req.Header.Get("From") != "nginx" {
+			metrics.SetDecision(req.Context(), metrics.ResultAllowed)
 			http.Redirect(
 				rw,
 				req,
@@ -274,6 +377,13 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 			return
 		}
+	} else if touchSession && req.Header.Get("From") == "nginx" {
+		// Requests which can't be redirected, like API calls, can only persist their activity in the cookie set on
+		// the authenticated response. Only ingress-nginx copies it to the response sent to the client, other proxies
+		// drop it: there, only navigations extend the session, see Config.Session.IdleTimeout.
+		if err = session.Update(rw, req, *sess); err != nil {
+			logger.Debug().Err(err).Msg("Unable to persist the session activity")
+		}
 	}
 
 	// 9th step of diagram.
@@ -281,6 +391,7 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	idToken, err = h.verifier.Verify(req.Context(), sess.IDToken)
 	if err != nil {
 		logger.Debug().Err(err).Msg("Invalid ID token")
+		audit.SetReason(req.Context(), "invalid ID token")
 		http.Error(rw, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 
 		return
@@ -296,11 +407,14 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	if h.validateClaims != nil && !h.validateClaims(claims) {
 		logger.Debug().Err(err).Msg("Unauthorized claim")
-		http.Error(rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		audit.SetReason(req.Context(), "claims not allowed")
+		h.deny(rw, req, deny.Denial{StatusCode: http.StatusForbidden, Description: "Access to this resource is not allowed"})
 
 		return
 	}
 
+	identity.Set(req.Context(), identity.FromClaims(claims))
+
 	if err = h.forwardHeader(rw, claims); err != nil {
 		logger.Error().Err(err).Msg("Unable to set forwarded header")
 		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -310,11 +424,21 @@ func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 
 	// 10th step of diagram.
 	rw.Header().Set("Authorization", "Bearer "+sess.AccessToken)
-	h.session.RemoveCookie(rw, req)
+	session.RemoveCookie(rw, req)
 
 	rw.WriteHeader(http.StatusOK)
 }
 
+// deny writes the response of a denied request, using the deny responder of the policy if any.
+func (h *Handler) deny(rw http.ResponseWriter, req *http.Request, d deny.Denial) {
+	if h.denyResponder == nil {
+		http.Error(rw, http.StatusText(d.StatusCode), d.StatusCode)
+		return
+	}
+
+	h.denyResponder.Deny(rw, req, d)
+}
+
 func (h *Handler) forwardHeader(rw http.ResponseWriter, claims map[string]interface{}) error {
 	hdrs, err := expr.PluckClaims(h.cfg.ForwardHeaders, claims)
 	if err != nil {
@@ -341,6 +465,7 @@ func (h *Handler) maybeRefreshSession(ctx context.Context, sess *SessionData) (s
 	// spec: section 12.
 	ts := h.oauth.TokenSource(ctx, sess.ToToken())
 	tok, err := ts.Token()
+	metrics.IncOIDCRefreshes(h.name, err)
 	if err != nil {
 		return nil, false, err
 	}
@@ -356,10 +481,43 @@ func (h *Handler) maybeRefreshSession(ctx context.Context, sess *SessionData) (s
 		RefreshToken: tok.RefreshToken,
 		IDToken:      rawIDToken,
 		Expiry:       tok.Expiry,
+		CreatedAt:    sess.CreatedAt,
+		LastActivity: h.now().Unix(),
 	}
 	return sess, true, nil
 }
 
+// sessionEnded reports whether the given session exceeded its configured maximum lifetime or idle timeout.
+// Sessions created before these limits were configured don't carry the required timestamps and are considered ended.
+func (h *Handler) sessionEnded(sess *SessionData) bool {
+	now := h.now().Unix()
+
+	if maxAge := h.cfg.Session.MaxAge; maxAge > 0 {
+		if sess.CreatedAt == 0 || now > sess.CreatedAt+int64(maxAge) {
+			return true
+		}
+	}
+
+	if idleTimeout := h.cfg.Session.IdleTimeout; idleTimeout > 0 {
+		if sess.LastActivity == 0 || now > sess.LastActivity+int64(idleTimeout) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shouldTouchSession reports whether the last activity of the given session needs to be persisted.
+// It is only the case once a quarter of the idle timeout elapsed since the last update.
+func (h *Handler) shouldTouchSession(sess *SessionData) bool {
+	idleTimeout := h.cfg.Session.IdleTimeout
+	if idleTimeout <= 0 {
+		return false
+	}
+
+	return h.now().Unix()-sess.LastActivity >= int64(idleTimeout)/4
+}
+
 func (h *Handler) redirectToProvider(rw http.ResponseWriter, req *http.Request, redirectURL string) {
 	logger := log.With().Str("handler_type", "OIDC").Str("handler_name", h.name).Logger()
 	originalURL := fmt.Sprintf("%s://%s%s", req.Header.Get("X-Forwarded-Proto"), req.Header.Get("X-Forwarded-Host"), req.Header.Get("X-Forwarded-Uri"))
@@ -372,7 +530,7 @@ func (h *Handler) redirectToProvider(rw http.ResponseWriter, req *http.Request,
 		OriginURL:  originalURL,
 	}
 
-	stateCookie, err := h.newStateCookie(state)
+	stateCookie, err := h.newStateCookie(state, h.stateCookieDomain(req))
 	if err != nil {
 		logger.Debug().Err(err).Msg("Unable to create state cookie")
 		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -488,21 +646,26 @@ func (h *Handler) handleProviderCallback(rw http.ResponseWriter, req *http.Reque
 	}
 
 	// 8th step of diagram.
+	now := h.now().Unix()
 	sess := &SessionData{
 		AccessToken:  oauth2Token.AccessToken,
 		TokenType:    oauth2Token.TokenType,
 		RefreshToken: oauth2Token.RefreshToken,
 		IDToken:      rawIDToken,
 		Expiry:       oauth2Token.Expiry,
+		CreatedAt:    now,
+		LastActivity: now,
 	}
-	if err = h.session.Create(rw, *sess); err != nil {
+	if err = h.sessionStore(req).Create(rw, *sess); err != nil {
 		logger.Debug().Err(err).Msg("Unable to create session")
 		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	h.clearStateCookie(rw)
+	h.clearStateCookie(rw, h.stateCookieDomain(req))
 
 	// 8th step of diagram.
+	// The user is now authenticated and redirected to the page they asked for.
+	metrics.SetDecision(req.Context(), metrics.ResultAllowed)
 	http.Redirect(rw, req, state.OriginURL, http.StatusFound)
 }
 
@@ -530,7 +693,7 @@ func (h *Handler) getStateCookie(r *http.Request) (*StateData, error) {
 	return &state, nil
 }
 
-func (h *Handler) newStateCookie(state StateData) (*http.Cookie, error) {
+func (h *Handler) newStateCookie(state StateData, domain string) (*http.Cookie, error) {
 	statePayload, err := json.Marshal(state)
 	if err != nil {
 		return nil, fmt.Errorf("serialize state: %w", err)
@@ -551,19 +714,48 @@ func (h *Handler) newStateCookie(state StateData) (*http.Cookie, error) {
 		HttpOnly: true,
 		SameSite: parseSameSite(h.cfg.StateCookie.SameSite),
 		Secure:   h.cfg.StateCookie.Secure,
-		Domain:   h.cfg.StateCookie.Domain,
+		Domain:   domain,
 	}, nil
 }
 
-func (h *Handler) clearStateCookie(w http.ResponseWriter) {
+func (h *Handler) clearStateCookie(w http.ResponseWriter, domain string) {
 	http.SetCookie(w, &http.Cookie{
 		Name:   h.name + "-state",
 		Path:   "/",
 		MaxAge: -1,
-		Domain: h.cfg.StateCookie.Domain,
+		Domain: domain,
 	})
 }
 
+// redirectURL returns the redirect URL to use for the forwarded host of the given request.
+func (h *Handler) redirectURL(req *http.Request) string {
+	if hostCfg := h.cfg.hostConfig(forwardedHost(req)); hostCfg != nil && hostCfg.RedirectURL != "" {
+		return resolveURL(req, hostCfg.RedirectURL)
+	}
+
+	return resolveURL(req, h.cfg.RedirectURL)
+}
+
+// stateCookieDomain returns the state cookie domain to use for the forwarded host of the given request.
+func (h *Handler) stateCookieDomain(req *http.Request) string {
+	if hostCfg := h.cfg.hostConfig(forwardedHost(req)); hostCfg != nil && hostCfg.CookieDomain != "" {
+		return hostCfg.CookieDomain
+	}
+
+	return h.cfg.StateCookie.Domain
+}
+
+// sessionStore returns the session store to use for the forwarded host of the given request.
+func (h *Handler) sessionStore(req *http.Request) SessionStore {
+	if hostCfg := h.cfg.hostConfig(forwardedHost(req)); hostCfg != nil {
+		if store, ok := h.hostSessions[hostCfg.Host]; ok {
+			return store
+		}
+	}
+
+	return h.session
+}
+
 func (h *Handler) shouldRedirect(req *http.Request) bool {
 	forwardedMethod := req.Header.Get("X-Forwarded-Method")
 	if forwardedMethod == http.MethodPost ||
@@ -577,6 +769,16 @@ func (h *Handler) shouldRedirect(req *http.Request) bool {
 	return !strings.Contains(req.Header.Get("X-Forwarded-Uri"), "favicon.ico")
 }
 
+// forwardedHost returns the forwarded host of the given request, without port.
+func forwardedHost(r *http.Request) string {
+	host := r.Header.Get("X-Forwarded-Host")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+
+	return host
+}
+
 func resolveURL(r *http.Request, u string) string {
 	if u == "" {
 		return u
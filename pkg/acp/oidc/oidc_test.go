@@ -62,6 +62,53 @@ func TestNewMiddlewareFromSource_ValidatesConfiguration(t *testing.T) {
 			},
 			wantErr: "validate configuration: missing client ID",
 		},
+		{
+			desc: "negative session max age",
+			cfg: &Config{
+				Issuer:       "foo",
+				ClientID:     "bar",
+				ClientSecret: "bat",
+				Key:          "secret1234567890",
+				RedirectURL:  "test",
+				Session:      &AuthSession{MaxAge: -1},
+			},
+			wantErr: "validate configuration: session max age must be positive",
+		},
+		{
+			desc: "negative session idle timeout",
+			cfg: &Config{
+				Issuer:       "foo",
+				ClientID:     "bar",
+				ClientSecret: "bat",
+				Key:          "secret1234567890",
+				RedirectURL:  "test",
+				Session:      &AuthSession{IdleTimeout: -1},
+			},
+			wantErr: "validate configuration: session idle timeout must be positive",
+		},
+		{
+			desc: "discovery document of another issuer",
+			cfg: &Config{
+				Issuer:       "https://foo.example.com",
+				ClientID:     "bar",
+				ClientSecret: "bat",
+				Key:          "secret1234567890",
+				RedirectURL:  "test",
+				Discovery:    &Discovery{Issuer: "https://bar.example.com"},
+			},
+			wantErr: `unable to create provider: issuer did not match the issuer of the discovery document, expected "https://foo.example.com" got "https://bar.example.com"`,
+		},
+		{
+			desc: "discovery document",
+			cfg: &Config{
+				Issuer:       "https://foo.example.com",
+				ClientID:     "bar",
+				ClientSecret: "bat",
+				Key:          "secret1234567890",
+				RedirectURL:  "test",
+				Discovery:    &Discovery{Issuer: "https://foo.example.com"},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -208,6 +255,62 @@ func TestMiddleware_RedirectsCorrectly(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc:    "redirects with host specific redirect URL and cookie domain",
+			request: httptest.NewRequest(http.MethodGet, "/foo", nil),
+			cfg: &Config{
+				RedirectURL: "http://example.com/callback",
+				StateCookie: &AuthStateCookie{
+					Path:   "/",
+					Domain: "example.com",
+				},
+				Hosts: []HostConfig{
+					{Host: "*.com", RedirectURL: "http://wildcard.com/callback"},
+					{Host: "test.com", RedirectURL: "/oauth/callback", CookieDomain: "test.com"},
+				},
+			},
+			wantStatus:      http.StatusFound,
+			wantRedirect:    true,
+			wantRedirectURL: "http://test.com/oauth/callback",
+			wantCookies: map[string]*http.Cookie{
+				"test-state": {
+					Name:     "test-state",
+					Path:     "/",
+					Domain:   "test.com",
+					SameSite: http.SameSiteLaxMode,
+					MaxAge:   600,
+					HttpOnly: true,
+				},
+			},
+		},
+		{
+			desc:    "redirects with wildcard host redirect URL",
+			request: httptest.NewRequest(http.MethodGet, "/foo", nil),
+			cfg: &Config{
+				RedirectURL: "http://example.com/callback",
+				StateCookie: &AuthStateCookie{
+					Path:   "/",
+					Domain: "example.com",
+				},
+				Hosts: []HostConfig{
+					{Host: "*.net", RedirectURL: "http://other.net/callback"},
+					{Host: "*.com", RedirectURL: "http://wildcard.com/callback"},
+				},
+			},
+			wantStatus:      http.StatusFound,
+			wantRedirect:    true,
+			wantRedirectURL: "http://wildcard.com/callback",
+			wantCookies: map[string]*http.Cookie{
+				"test-state": {
+					Name:     "test-state",
+					Path:     "/",
+					Domain:   "example.com",
+					SameSite: http.SameSiteLaxMode,
+					MaxAge:   600,
+					HttpOnly: true,
+				},
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -298,10 +401,14 @@ func TestMiddleware_ExchangesTokenOnCallback(t *testing.T) {
 		OnExchangeRaw(mock.Anything, mock.Anything).TypedReturns(oauth2tok, nil).Once().
 		Parent
 
+	now := time.Date(2022, time.June, 1, 12, 0, 0, 0, time.UTC)
+
 	wantSession := SessionData{
-		AccessToken: oauth2tok.AccessToken,
-		IDToken:     jwtToken,
-		TokenType:   oauth2tok.TokenType,
+		AccessToken:  oauth2tok.AccessToken,
+		IDToken:      jwtToken,
+		TokenType:    oauth2tok.TokenType,
+		CreatedAt:    now.Unix(),
+		LastActivity: now.Unix(),
 	}
 
 	session := newSessionStoreMock(t).
@@ -313,6 +420,7 @@ func TestMiddleware_ExchangesTokenOnCallback(t *testing.T) {
 	handler.oauth = oauth
 	handler.session = session
 	handler.cfg = &cfg
+	handler.now = func() time.Time { return now }
 
 	state := StateData{
 		RedirectID: "aaaaa",
@@ -320,7 +428,7 @@ func TestMiddleware_ExchangesTokenOnCallback(t *testing.T) {
 		OriginURL:  "http://app.bar.com",
 	}
 
-	stateCookie, err := handler.newStateCookie(state)
+	stateCookie, err := handler.newStateCookie(state, "")
 	require.NoError(t, err)
 
 	w := httptest.NewRecorder()
@@ -506,6 +614,178 @@ func TestMiddleware_ForwardsCorrectly(t *testing.T) {
 	}
 }
 
+func TestMiddleware_EnforcesSessionLimits(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		desc         string
+		method       string
+		session      *AuthSession
+		createdAt    time.Time
+		lastActivity time.Time
+		nginx        bool
+
+		wantStatus       int
+		wantDelete       bool
+		wantUpdate       bool
+		wantProviderURL  bool
+		wantLastActivity time.Time
+	}{
+		{
+			desc:         "forwards call if session is within limits",
+			method:       http.MethodGet,
+			session:      &AuthSession{MaxAge: 3600, IdleTimeout: 600},
+			createdAt:    now.Add(-30 * time.Minute),
+			lastActivity: now.Add(-time.Minute),
+			wantStatus:   http.StatusOK,
+		},
+		{
+			desc:            "redirects to provider if session max age is reached",
+			method:          http.MethodGet,
+			session:         &AuthSession{MaxAge: 3600},
+			createdAt:       now.Add(-2 * time.Hour),
+			lastActivity:    now.Add(-time.Minute),
+			wantStatus:      http.StatusFound,
+			wantDelete:      true,
+			wantProviderURL: true,
+		},
+		{
+			desc:            "redirects to provider if session is idle",
+			method:          http.MethodGet,
+			session:         &AuthSession{IdleTimeout: 600},
+			createdAt:       now.Add(-30 * time.Minute),
+			lastActivity:    now.Add(-20 * time.Minute),
+			wantStatus:      http.StatusFound,
+			wantDelete:      true,
+			wantProviderURL: true,
+		},
+		{
+			desc:            "redirects to provider if session has no creation date",
+			method:          http.MethodGet,
+			session:         &AuthSession{MaxAge: 3600},
+			wantStatus:      http.StatusFound,
+			wantDelete:      true,
+			wantProviderURL: true,
+		},
+		{
+			desc:       "returns unauthorized if session max age is reached and method is POST",
+			method:     http.MethodPost,
+			session:    &AuthSession{MaxAge: 3600},
+			createdAt:  now.Add(-2 * time.Hour),
+			wantStatus: http.StatusUnauthorized,
+			wantDelete: true,
+		},
+		{
+			desc:             "updates last activity once stale",
+			method:           http.MethodGet,
+			session:          &AuthSession{IdleTimeout: 600},
+			createdAt:        now.Add(-30 * time.Minute),
+			lastActivity:     now.Add(-3 * time.Minute),
+			wantStatus:       http.StatusFound,
+			wantUpdate:       true,
+			wantLastActivity: now,
+		},
+		{
+			desc:         "forwards call without updating stale last activity if method is POST",
+			method:       http.MethodPost,
+			session:      &AuthSession{IdleTimeout: 600},
+			createdAt:    now.Add(-30 * time.Minute),
+			lastActivity: now.Add(-3 * time.Minute),
+			wantStatus:   http.StatusOK,
+		},
+		{
+			desc:             "forwards call and updates stale last activity if method is POST behind nginx",
+			method:           http.MethodPost,
+			session:          &AuthSession{IdleTimeout: 600},
+			createdAt:        now.Add(-30 * time.Minute),
+			lastActivity:     now.Add(-3 * time.Minute),
+			nginx:            true,
+			wantStatus:       http.StatusOK,
+			wantUpdate:       true,
+			wantLastActivity: now,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			cfg := &Config{
+				Issuer:       "http://foo.com",
+				ClientID:     "clientID",
+				ClientSecret: "secret1234567890",
+				RedirectURL:  "http://foo.com/callback",
+				Session:      test.session,
+			}
+			cfg.ApplyDefaultValues()
+
+			session := newSessionStoreMock(t).
+				OnGetRaw(mock.Anything).TypedReturns(&SessionData{
+				AccessToken:  "test",
+				IDToken:      jwtToken,
+				Expiry:       now.Add(time.Hour),
+				CreatedAt:    unix(test.createdAt),
+				LastActivity: unix(test.lastActivity),
+			}, nil).Once().
+				Parent
+
+			if test.wantDelete {
+				session.OnDeleteRaw(mock.Anything, mock.Anything).TypedReturns(nil).Once()
+			}
+			if test.wantUpdate {
+				session.OnUpdateRaw(mock.Anything, mock.Anything, mock.MatchedBy(func(data SessionData) bool {
+					return data.LastActivity == test.wantLastActivity.Unix() && data.CreatedAt == test.createdAt.Unix()
+				})).TypedReturns(nil).Once()
+			}
+			if test.wantStatus == http.StatusOK {
+				session.OnRemoveCookieRaw(mock.Anything, mock.Anything).Once()
+			}
+
+			handler := buildHandler(t)
+			handler.oauth = &oauth2.Config{
+				Endpoint: oauth2.Endpoint{AuthURL: "http://provider.com"},
+			}
+			handler.session = session
+			handler.cfg = cfg
+			handler.now = func() time.Time { return now }
+
+			r := httptest.NewRequest(test.method, "http://app.com/foo", nil)
+			r.Header.Set("X-Forwarded-Method", r.Method)
+			r.Header.Set("X-Forwarded-Proto", "http")
+			r.Header.Set("X-Forwarded-Host", r.Host)
+			r.Header.Set("X-Forwarded-URI", r.URL.RequestURI())
+			if test.nginx {
+				r.Header.Set("From", "nginx")
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			assert.Equal(t, test.wantStatus, w.Code)
+
+			if test.wantStatus != http.StatusFound {
+				return
+			}
+
+			location, err := url.Parse(w.Header().Get("location"))
+			require.NoError(t, err)
+
+			if test.wantProviderURL {
+				assert.Equal(t, "provider.com", location.Host)
+				return
+			}
+			assert.Equal(t, "http://app.com/foo", location.String())
+		})
+	}
+}
+
+func unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
 func TestMiddleware_LogsOutCorrectly(t *testing.T) {
 	tests := []struct {
 		desc      string
@@ -581,6 +861,7 @@ func buildHandler(t *testing.T) *Handler {
 		rand:     newRandom(),
 		client:   client,
 		verifier: verifier,
+		now:      time.Now,
 	}
 }
 
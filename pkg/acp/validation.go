@@ -0,0 +1,442 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package acp
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt/expr"
+	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// FieldError is an error affecting a field of an access control policy.
+type FieldError struct {
+	// Field is the path of the field, such as spec.jwt.claims.
+	Field  string
+	Detail string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Detail
+}
+
+// ValidateSpec validates the given access control policy spec. Validation happens offline: neither the referenced
+// Secrets and ConfigMaps, nor the remote JWKs and OIDC issuers are resolved.
+// Cluster-wide policies have no namespace to default the one of the resources they reference to, so it's required.
+func ValidateSpec(spec *hubv1alpha1.AccessControlPolicySpec) []FieldError {
+	errs := validateSpec(spec)
+
+	for _, ref := range namespacedReferences(spec) {
+		if ref.namespace == "" {
+			errs = append(errs, FieldError{Field: ref.field + ".namespace", Detail: "is required"})
+		}
+	}
+
+	return errs
+}
+
+func validateSpec(spec *hubv1alpha1.AccessControlPolicySpec) []FieldError {
+	var errs []FieldError
+
+	var set int
+	for _, isSet := range []bool{spec.JWT != nil, spec.BasicAuth != nil, spec.OIDC != nil, spec.OIDCGoogle != nil} {
+		if isSet {
+			set++
+		}
+	}
+
+	switch {
+	case set == 0:
+		errs = append(errs, FieldError{Field: "spec", Detail: "one of jwt, basicAuth, oidc or oidcGoogle must be set"})
+	case set > 1:
+		errs = append(errs, FieldError{Field: "spec", Detail: "only one of jwt, basicAuth, oidc or oidcGoogle can be set"})
+	}
+
+	if spec.JWT != nil {
+		errs = append(errs, validateJWT("spec.jwt", spec.JWT)...)
+	}
+	if spec.BasicAuth != nil {
+		errs = append(errs, validateBasicAuth("spec.basicAuth", spec.BasicAuth)...)
+	}
+	if spec.OIDC != nil {
+		errs = append(errs, validateOIDC("spec.oidc", spec.OIDC)...)
+	}
+	if spec.OIDCGoogle != nil {
+		errs = append(errs, validateOIDCGoogle("spec.oidcGoogle", spec.OIDCGoogle)...)
+	}
+
+	switch spec.EnforcementMode {
+	case "", EnforcementModeEnforce, EnforcementModeAudit:
+	default:
+		errs = append(errs, FieldError{
+			Field:  "spec.enforcementMode",
+			Detail: fmt.Sprintf("unsupported value %q: must be %s or %s", spec.EnforcementMode, EnforcementModeEnforce, EnforcementModeAudit),
+		})
+	}
+
+	if spec.DenyResponse != nil {
+		errs = append(errs, validateDenyResponse("spec.denyResponse", spec.DenyResponse)...)
+	}
+
+	return errs
+}
+
+// ValidateNamespacedSpec validates the given spec of a namespaced access control policy of the given namespace.
+// On top of the ValidateSpec checks, it makes sure the policy doesn't reach beyond its namespace: it can only reference
+// the Secrets and ConfigMaps of its namespace, and can neither read the files of the auth server nor make it call
+// internal endpoints.
+func ValidateNamespacedSpec(spec *hubv1alpha1.AccessControlPolicySpec, namespace string) []FieldError {
+	errs := validateSpec(spec)
+
+	for _, ref := range namespacedReferences(spec) {
+		if ref.namespace != "" && ref.namespace != namespace {
+			errs = append(errs, FieldError{Field: ref.field + ".namespace", Detail: "must be empty or equal to the namespace of the policy"})
+		}
+	}
+
+	if spec.JWT != nil {
+		if spec.JWT.JWKsFile != "" && !strings.HasPrefix(strings.TrimSpace(spec.JWT.JWKsFile), "{") {
+			errs = append(errs, FieldError{Field: "spec.jwt.jwksFile", Detail: "must be the content of a JWKs file, paths are not allowed"})
+		}
+		// JWKs URL paths are resolved against the issuer of the token, which is picked by the client.
+		if spec.JWT.JWKsURL != "" {
+			if err := validatePublicURL(spec.JWT.JWKsURL); err != nil {
+				errs = append(errs, FieldError{Field: "spec.jwt.jwksUrl", Detail: err.Error()})
+			}
+		}
+	}
+
+	if spec.OIDC != nil && spec.OIDC.Issuer != "" {
+		if err := validatePublicURL(spec.OIDC.Issuer); err != nil {
+			errs = append(errs, FieldError{Field: "spec.oidc.issuer", Detail: err.Error()})
+		}
+	}
+
+	return errs
+}
+
+type namespacedReference struct {
+	field     string
+	namespace string
+}
+
+// namespacedReferences returns the namespaces of the resources the given spec references, along with their field.
+func namespacedReferences(spec *hubv1alpha1.AccessControlPolicySpec) []namespacedReference {
+	var refs []namespacedReference
+
+	if spec.JWT != nil {
+		for _, ref := range []struct {
+			field string
+			ref   *hubv1alpha1.ValueReference
+		}{
+			{field: "spec.jwt.signingSecretRef", ref: spec.JWT.SigningSecretRef},
+			{field: "spec.jwt.publicKeyRef", ref: spec.JWT.PublicKeyRef},
+			{field: "spec.jwt.jwksRef", ref: spec.JWT.JWKsRef},
+		} {
+			if ref.ref != nil {
+				refs = append(refs, namespacedReference{field: ref.field, namespace: ref.ref.Namespace})
+			}
+		}
+	}
+	if spec.OIDC != nil && spec.OIDC.Secret != nil {
+		refs = append(refs, namespacedReference{field: "spec.oidc.secret", namespace: spec.OIDC.Secret.Namespace})
+	}
+	if spec.OIDCGoogle != nil && spec.OIDCGoogle.Secret != nil {
+		refs = append(refs, namespacedReference{field: "spec.oidcGoogle.secret", namespace: spec.OIDCGoogle.Secret.Namespace})
+	}
+	if spec.DenyResponse != nil && spec.DenyResponse.TemplateRef != nil {
+		refs = append(refs, namespacedReference{field: "spec.denyResponse.templateRef", namespace: spec.DenyResponse.TemplateRef.Namespace})
+	}
+
+	return refs
+}
+
+func validateJWT(field string, spec *hubv1alpha1.AccessControlPolicyJWT) []FieldError {
+	var errs []FieldError
+
+	if spec.SigningSecret == "" && spec.SigningSecretRef == nil &&
+		spec.PublicKey == "" && spec.PublicKeyRef == nil &&
+		spec.JWKsFile == "" && spec.JWKsRef == nil && spec.JWKsURL == "" {
+		errs = append(errs, FieldError{
+			Field:  field,
+			Detail: "one of signingSecret, signingSecretRef, publicKey, publicKeyRef, jwksFile, jwksRef or jwksUrl must be set",
+		})
+	}
+
+	if spec.SigningSecret != "" && spec.SigningSecretRef != nil {
+		errs = append(errs, FieldError{Field: field + ".signingSecretRef", Detail: "cannot be set along with signingSecret"})
+	}
+	if spec.PublicKey != "" && spec.PublicKeyRef != nil {
+		errs = append(errs, FieldError{Field: field + ".publicKeyRef", Detail: "cannot be set along with publicKey"})
+	}
+	if spec.JWKsFile != "" && spec.JWKsRef != nil {
+		errs = append(errs, FieldError{Field: field + ".jwksRef", Detail: "cannot be set along with jwksFile"})
+	}
+
+	if spec.SigningSecret != "" && spec.SigningSecretBase64Encoded {
+		if _, err := base64.StdEncoding.DecodeString(spec.SigningSecret); err != nil {
+			errs = append(errs, FieldError{Field: field + ".signingSecret", Detail: fmt.Sprintf("invalid base64-encoded secret: %v", err)})
+		}
+	}
+
+	if spec.PublicKey != "" {
+		if err := validatePublicKey(spec.PublicKey); err != nil {
+			errs = append(errs, FieldError{Field: field + ".publicKey", Detail: err.Error()})
+		}
+	}
+
+	// A JWKs file can either be a path or the content of the file. Paths can't be checked offline.
+	if strings.HasPrefix(strings.TrimSpace(spec.JWKsFile), "{") {
+		if _, err := jwt.NewContentKeySet([]byte(spec.JWKsFile)); err != nil {
+			errs = append(errs, FieldError{Field: field + ".jwksFile", Detail: fmt.Sprintf("invalid JWKs: %v", err)})
+		}
+	}
+
+	if spec.JWKsURL != "" && !strings.HasPrefix(spec.JWKsURL, "/") {
+		if err := validateHTTPURL(spec.JWKsURL); err != nil {
+			errs = append(errs, FieldError{Field: field + ".jwksUrl", Detail: err.Error() + " or a path"})
+		}
+	}
+
+	if spec.Claims != "" {
+		if _, err := expr.Parse(spec.Claims); err != nil {
+			errs = append(errs, FieldError{Field: field + ".claims", Detail: fmt.Sprintf("invalid expression: %v", err)})
+		}
+	}
+
+	errs = append(errs, validateValueReference(field+".signingSecretRef", spec.SigningSecretRef)...)
+	errs = append(errs, validateValueReference(field+".publicKeyRef", spec.PublicKeyRef)...)
+	errs = append(errs, validateValueReference(field+".jwksRef", spec.JWKsRef)...)
+
+	return errs
+}
+
+func validatePublicKey(key string) error {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return errors.New("must be a PEM-encoded public key")
+	}
+
+	if _, err := x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+
+	return nil
+}
+
+func validateValueReference(field string, ref *hubv1alpha1.ValueReference) []FieldError {
+	if ref == nil {
+		return nil
+	}
+
+	var errs []FieldError
+
+	switch ref.Kind {
+	case "", jwt.KindSecret, jwt.KindConfigMap:
+	default:
+		errs = append(errs, FieldError{
+			Field:  field + ".kind",
+			Detail: fmt.Sprintf("unsupported value %q: must be %s or %s", ref.Kind, jwt.KindSecret, jwt.KindConfigMap),
+		})
+	}
+
+	if ref.Name == "" {
+		errs = append(errs, FieldError{Field: field + ".name", Detail: "is required"})
+	}
+	if ref.Key == "" {
+		errs = append(errs, FieldError{Field: field + ".key", Detail: "is required"})
+	}
+
+	return errs
+}
+
+func validateBasicAuth(field string, spec *hubv1alpha1.AccessControlPolicyBasicAuth) []FieldError {
+	if len(spec.Users) == 0 {
+		return []FieldError{{Field: field + ".users", Detail: "at least one user is required"}}
+	}
+
+	var errs []FieldError
+
+	users := make(map[string]struct{})
+	for i, user := range spec.Users {
+		userField := fmt.Sprintf("%s.users[%d]", field, i)
+
+		parts := strings.Split(user, ":")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			errs = append(errs, FieldError{Field: userField, Detail: "must be formatted as name:hashed-password"})
+			continue
+		}
+
+		if _, ok := users[parts[0]]; ok {
+			errs = append(errs, FieldError{Field: userField, Detail: fmt.Sprintf("duplicate user %q", parts[0])})
+		}
+		users[parts[0]] = struct{}{}
+	}
+
+	return errs
+}
+
+func validateOIDC(field string, spec *hubv1alpha1.AccessControlOIDC) []FieldError {
+	var errs []FieldError
+
+	if spec.Issuer == "" {
+		errs = append(errs, FieldError{Field: field + ".issuer", Detail: "is required"})
+	} else if err := validateHTTPURL(spec.Issuer); err != nil {
+		errs = append(errs, FieldError{Field: field + ".issuer", Detail: err.Error()})
+	}
+
+	if spec.Claims != "" {
+		if _, err := expr.Parse(spec.Claims); err != nil {
+			errs = append(errs, FieldError{Field: field + ".claims", Detail: fmt.Sprintf("invalid expression: %v", err)})
+		}
+	}
+
+	errs = append(errs, validateOIDCClient(field, spec.ClientID, spec.Secret)...)
+	errs = append(errs, validateOIDCSession(field+".session", spec.Session)...)
+	errs = append(errs, validateOIDCHosts(field+".hosts", spec.Hosts)...)
+
+	return errs
+}
+
+func validateOIDCGoogle(field string, spec *hubv1alpha1.AccessControlOIDCGoogle) []FieldError {
+	var errs []FieldError
+
+	errs = append(errs, validateOIDCClient(field, spec.ClientID, spec.Secret)...)
+	errs = append(errs, validateOIDCSession(field+".session", spec.Session)...)
+	errs = append(errs, validateOIDCHosts(field+".hosts", spec.Hosts)...)
+
+	return errs
+}
+
+func validateOIDCClient(field, clientID string, secret *corev1.SecretReference) []FieldError {
+	var errs []FieldError
+
+	if clientID == "" {
+		errs = append(errs, FieldError{Field: field + ".clientId", Detail: "is required"})
+	}
+
+	if secret == nil || secret.Name == "" {
+		errs = append(errs, FieldError{Field: field + ".secret", Detail: "a reference to the Secret holding the client secret is required"})
+	}
+
+	return errs
+}
+
+func validateOIDCSession(field string, session *hubv1alpha1.Session) []FieldError {
+	if session == nil {
+		return nil
+	}
+
+	var errs []FieldError
+
+	if session.MaxAge < 0 {
+		errs = append(errs, FieldError{Field: field + ".maxAge", Detail: "must be positive"})
+	}
+	if session.IdleTimeout < 0 {
+		errs = append(errs, FieldError{Field: field + ".idleTimeout", Detail: "must be positive"})
+	}
+
+	return errs
+}
+
+func validateOIDCHosts(field string, hosts []hubv1alpha1.OIDCHost) []FieldError {
+	var errs []FieldError
+
+	for i, host := range hosts {
+		hostField := fmt.Sprintf("%s[%d].host", field, i)
+
+		switch {
+		case host.Host == "":
+			errs = append(errs, FieldError{Field: hostField, Detail: "is required"})
+		case strings.Contains(strings.TrimPrefix(host.Host, "*."), "*"):
+			errs = append(errs, FieldError{Field: hostField, Detail: "wildcard is only supported as the first label"})
+		}
+	}
+
+	return errs
+}
+
+func validateDenyResponse(field string, spec *hubv1alpha1.DenyResponse) []FieldError {
+	var errs []FieldError
+
+	if spec.UnauthorizedStatusCode != 0 && (spec.UnauthorizedStatusCode < 400 || spec.UnauthorizedStatusCode > 599) {
+		errs = append(errs, FieldError{Field: field + ".unauthorizedStatusCode", Detail: "must be between 400 and 599"})
+	}
+	if spec.ForbiddenStatusCode != 0 && (spec.ForbiddenStatusCode < 400 || spec.ForbiddenStatusCode > 599) {
+		errs = append(errs, FieldError{Field: field + ".forbiddenStatusCode", Detail: "must be between 400 and 599"})
+	}
+
+	if strings.Contains(spec.Realm, `"`) {
+		errs = append(errs, FieldError{Field: field + ".realm", Detail: "must not contain double quotes"})
+	}
+
+	if spec.TemplateRef != nil {
+		if spec.TemplateRef.Name == "" {
+			errs = append(errs, FieldError{Field: field + ".templateRef.name", Detail: "is required"})
+		}
+		if spec.TemplateRef.Key == "" {
+			errs = append(errs, FieldError{Field: field + ".templateRef.key", Detail: "is required"})
+		}
+	}
+
+	return errs
+}
+
+func validateHTTPURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an absolute HTTP(S) URL")
+	}
+
+	return nil
+}
+
+// validatePublicURL makes sure the given URL is an HTTPS URL which host doesn't obviously belong to the cluster or to
+// a private network. Host names are not resolved.
+func validatePublicURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("must be an absolute HTTPS URL")
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+			return errors.New("must not target a private IP address")
+		}
+		return nil
+	}
+
+	if !strings.Contains(host, ".") || host == "localhost" ||
+		strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") ||
+		strings.HasSuffix(host, ".internal") || strings.HasSuffix(host, ".svc") || strings.Contains(host, ".svc.") {
+		return errors.New("must not target an internal host")
+	}
+
+	return nil
+}
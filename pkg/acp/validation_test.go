@@ -0,0 +1,269 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package acp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateSpec(t *testing.T) {
+	testCases := []struct {
+		desc string
+		spec hubv1alpha1.AccessControlPolicySpec
+		want []FieldError
+	}{
+		{
+			desc: "valid JWT",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{
+					SigningSecret: "secret",
+					Claims:        "Equals(`grp`, `admin`)",
+				},
+			},
+		},
+		{
+			desc: "no handler",
+			spec: hubv1alpha1.AccessControlPolicySpec{},
+			want: []FieldError{{Field: "spec", Detail: "one of jwt, basicAuth, oidc or oidcGoogle must be set"}},
+		},
+		{
+			desc: "several handlers",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT:       &hubv1alpha1.AccessControlPolicyJWT{SigningSecret: "secret"},
+				BasicAuth: &hubv1alpha1.AccessControlPolicyBasicAuth{Users: []string{"user:hash"}},
+			},
+			want: []FieldError{{Field: "spec", Detail: "only one of jwt, basicAuth, oidc or oidcGoogle can be set"}},
+		},
+		{
+			desc: "invalid JWT",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{
+					SigningSecret:              "not base64!",
+					SigningSecretBase64Encoded: true,
+					SigningSecretRef:           &hubv1alpha1.ValueReference{Kind: "Pod", Namespace: "default", Name: "jwt"},
+					PublicKey:                  "not a key",
+					JWKsFile:                   `{"keys": [}`,
+					JWKsURL:                    "example.com/jwks.json",
+					Claims:                     "Equals(`grp`",
+				},
+			},
+			want: []FieldError{
+				{Field: "spec.jwt.signingSecretRef", Detail: "cannot be set along with signingSecret"},
+				{Field: "spec.jwt.signingSecret", Detail: "invalid base64-encoded secret: illegal base64 data at input byte 3"},
+				{Field: "spec.jwt.publicKey", Detail: "must be a PEM-encoded public key"},
+				{Field: "spec.jwt.jwksFile", Detail: "invalid JWKs: unable to decode JWK set from content: invalid character '}' looking for beginning of value"},
+				{Field: "spec.jwt.jwksUrl", Detail: "must be an absolute HTTP(S) URL or a path"},
+				{Field: "spec.jwt.claims", Detail: "invalid expression: unable to parse expression: 1:13: missing ',' before newline in argument list"},
+				{Field: "spec.jwt.signingSecretRef.kind", Detail: `unsupported value "Pod": must be Secret or ConfigMap`},
+				{Field: "spec.jwt.signingSecretRef.key", Detail: "is required"},
+			},
+		},
+		{
+			desc: "JWT without keys",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{},
+			},
+			want: []FieldError{{
+				Field:  "spec.jwt",
+				Detail: "one of signingSecret, signingSecretRef, publicKey, publicKeyRef, jwksFile, jwksRef or jwksUrl must be set",
+			}},
+		},
+		{
+			desc: "invalid basic auth users",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				BasicAuth: &hubv1alpha1.AccessControlPolicyBasicAuth{
+					Users: []string{"user:hash", "user", "user:other-hash", ":hash"},
+				},
+			},
+			want: []FieldError{
+				{Field: "spec.basicAuth.users[1]", Detail: "must be formatted as name:hashed-password"},
+				{Field: "spec.basicAuth.users[2]", Detail: `duplicate user "user"`},
+				{Field: "spec.basicAuth.users[3]", Detail: "must be formatted as name:hashed-password"},
+			},
+		},
+		{
+			desc: "no basic auth users",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				BasicAuth: &hubv1alpha1.AccessControlPolicyBasicAuth{},
+			},
+			want: []FieldError{{Field: "spec.basicAuth.users", Detail: "at least one user is required"}},
+		},
+		{
+			desc: "invalid OIDC",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				OIDC: &hubv1alpha1.AccessControlOIDC{
+					Issuer:  "accounts.example.com",
+					Claims:  "Equals(`grp`",
+					Session: &hubv1alpha1.Session{MaxAge: -1},
+					Hosts:   []hubv1alpha1.OIDCHost{{Host: "app.*.example.com"}},
+				},
+				EnforcementMode: "dry-run",
+			},
+			want: []FieldError{
+				{Field: "spec.oidc.issuer", Detail: "must be an absolute HTTP(S) URL"},
+				{Field: "spec.oidc.claims", Detail: "invalid expression: unable to parse expression: 1:13: missing ',' before newline in argument list"},
+				{Field: "spec.oidc.clientId", Detail: "is required"},
+				{Field: "spec.oidc.secret", Detail: "a reference to the Secret holding the client secret is required"},
+				{Field: "spec.oidc.session.maxAge", Detail: "must be positive"},
+				{Field: "spec.oidc.hosts[0].host", Detail: "wildcard is only supported as the first label"},
+				{Field: "spec.enforcementMode", Detail: `unsupported value "dry-run": must be enforce or audit`},
+			},
+		},
+		{
+			desc: "valid OIDC Google",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				OIDCGoogle: &hubv1alpha1.AccessControlOIDCGoogle{
+					ClientID: "client-id",
+					Secret:   &corev1.SecretReference{Namespace: "default", Name: "oidc"},
+				},
+			},
+		},
+		{
+			desc: "invalid deny response",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				BasicAuth: &hubv1alpha1.AccessControlPolicyBasicAuth{Users: []string{"user:hash"}},
+				DenyResponse: &hubv1alpha1.DenyResponse{
+					UnauthorizedStatusCode: 302,
+					Realm:                  `my "realm"`,
+					TemplateRef:            &hubv1alpha1.ConfigMapKeyReference{Namespace: "default", Name: "templates"},
+				},
+			},
+			want: []FieldError{
+				{Field: "spec.denyResponse.unauthorizedStatusCode", Detail: "must be between 400 and 599"},
+				{Field: "spec.denyResponse.realm", Detail: "must not contain double quotes"},
+				{Field: "spec.denyResponse.templateRef.key", Detail: "is required"},
+			},
+		},
+		{
+			desc: "references without namespace",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				OIDC: &hubv1alpha1.AccessControlOIDC{
+					Issuer:   "https://issuer.example.com",
+					ClientID: "client",
+					Secret:   &corev1.SecretReference{Name: "oidc"},
+				},
+				DenyResponse: &hubv1alpha1.DenyResponse{
+					TemplateRef: &hubv1alpha1.ConfigMapKeyReference{Name: "templates", Key: "deny"},
+				},
+			},
+			want: []FieldError{
+				{Field: "spec.oidc.secret.namespace", Detail: "is required"},
+				{Field: "spec.denyResponse.templateRef.namespace", Detail: "is required"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, ValidateSpec(&test.spec))
+		})
+	}
+}
+
+func TestValidateNamespacedSpec(t *testing.T) {
+	testCases := []struct {
+		desc string
+		spec hubv1alpha1.AccessControlPolicySpec
+		want []FieldError
+	}{
+		{
+			desc: "valid JWT",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{
+					SigningSecretRef: &hubv1alpha1.ValueReference{Name: "jwt", Key: "secret"},
+					JWKsURL:          "https://idp.example.com/jwks.json",
+				},
+			},
+		},
+		{
+			desc: "references outside of the policy namespace",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{
+					SigningSecretRef: &hubv1alpha1.ValueReference{Namespace: "other-ns", Name: "jwt", Key: "secret"},
+					PublicKeyRef:     &hubv1alpha1.ValueReference{Namespace: "my-ns", Name: "jwt", Key: "public"},
+				},
+				DenyResponse: &hubv1alpha1.DenyResponse{
+					TemplateRef: &hubv1alpha1.ConfigMapKeyReference{Namespace: "other-ns", Name: "templates", Key: "deny"},
+				},
+			},
+			want: []FieldError{
+				{Field: "spec.jwt.signingSecretRef.namespace", Detail: "must be empty or equal to the namespace of the policy"},
+				{Field: "spec.denyResponse.templateRef.namespace", Detail: "must be empty or equal to the namespace of the policy"},
+			},
+		},
+		{
+			desc: "JWKs file path",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{JWKsFile: "/etc/jwks.json"},
+			},
+			want: []FieldError{{Field: "spec.jwt.jwksFile", Detail: "must be the content of a JWKs file, paths are not allowed"}},
+		},
+		{
+			desc: "JWKs URL path",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{JWKsURL: "/.well-known/jwks.json"},
+			},
+			want: []FieldError{{Field: "spec.jwt.jwksUrl", Detail: "must be an absolute HTTPS URL"}},
+		},
+		{
+			desc: "JWKs URL targeting a Service",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				JWT: &hubv1alpha1.AccessControlPolicyJWT{JWKsURL: "https://hub-agent.hub.svc.cluster.local/jwks.json"},
+			},
+			want: []FieldError{{Field: "spec.jwt.jwksUrl", Detail: "must not target an internal host"}},
+		},
+		{
+			desc: "OIDC issuer targeting a private IP address",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				OIDC: &hubv1alpha1.AccessControlOIDC{
+					Issuer:   "https://169.254.169.254",
+					ClientID: "client",
+					Secret:   &corev1.SecretReference{Name: "secret"},
+				},
+			},
+			want: []FieldError{{Field: "spec.oidc.issuer", Detail: "must not target a private IP address"}},
+		},
+		{
+			desc: "plain HTTP OIDC issuer",
+			spec: hubv1alpha1.AccessControlPolicySpec{
+				OIDC: &hubv1alpha1.AccessControlOIDC{
+					Issuer:   "http://idp.example.com",
+					ClientID: "client",
+					Secret:   &corev1.SecretReference{Name: "secret"},
+				},
+			},
+			want: []FieldError{{Field: "spec.oidc.issuer", Detail: "must be an absolute HTTPS URL"}},
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, ValidateNamespacedSpec(&test.spec, "my-ns"))
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/jwt"
 	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
 	hubclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/clientset/versioned"
 	hubinformer "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/hub/informers/externalversions"
@@ -100,6 +101,11 @@ func (w *Watcher) Run(ctx context.Context) {
 				// We delete the policy from the map, since we use this map to delete unused policies.
 				delete(policiesByID, a.Name)
 
+				if found && policy.IsLocalOnly() {
+					log.Debug().Str("name", a.Name).Msg("Local-only ACP shadows an ACP of the platform, skipping")
+					continue
+				}
+
 				if found && !needUpdate(a, policy) {
 					continue
 				}
@@ -171,6 +177,11 @@ func (w *Watcher) updatePolicy(ctx context.Context, acp ACP, policy *hubv1alpha1
 
 func (w *Watcher) cleanPolicies(ctx context.Context, policies map[string]*hubv1alpha1.AccessControlPolicy) {
 	for _, p := range policies {
+		// Local-only policies are unknown to the platform by design.
+		if p.IsLocalOnly() {
+			continue
+		}
+
 		ctxDelete, cancel := context.WithTimeout(ctx, 5*time.Second)
 		err := w.hubClientSet.HubV1alpha1().AccessControlPolicies().Delete(ctxDelete, p.Name, metav1.DeleteOptions{})
 		if err != nil {
@@ -218,13 +229,23 @@ func buildAccessControlPolicySpec(a ACP) hubv1alpha1.AccessControlPolicySpec {
 
 		if a.OIDCGoogle.Session != nil {
 			spec.OIDCGoogle.Session = &hubv1alpha1.Session{
-				SameSite: a.OIDCGoogle.Session.SameSite,
-				Secure:   a.OIDCGoogle.Session.Secure,
-				Domain:   a.OIDCGoogle.Session.Domain,
-				Path:     a.OIDCGoogle.Session.Path,
-				Refresh:  a.OIDCGoogle.Session.Refresh,
+				SameSite:    a.OIDCGoogle.Session.SameSite,
+				Secure:      a.OIDCGoogle.Session.Secure,
+				Domain:      a.OIDCGoogle.Session.Domain,
+				Path:        a.OIDCGoogle.Session.Path,
+				Refresh:     a.OIDCGoogle.Session.Refresh,
+				MaxAge:      a.OIDCGoogle.Session.MaxAge,
+				IdleTimeout: a.OIDCGoogle.Session.IdleTimeout,
 			}
 		}
+
+		for _, host := range a.OIDCGoogle.Hosts {
+			spec.OIDCGoogle.Hosts = append(spec.OIDCGoogle.Hosts, hubv1alpha1.OIDCHost{
+				Host:         host.Host,
+				RedirectURL:  host.RedirectURL,
+				CookieDomain: host.CookieDomain,
+			})
+		}
 	case a.OIDC != nil:
 		spec.OIDC = &hubv1alpha1.AccessControlOIDC{
 			Issuer:         a.OIDC.Issuer,
@@ -255,14 +276,24 @@ func buildAccessControlPolicySpec(a ACP) hubv1alpha1.AccessControlPolicySpec {
 
 		if a.OIDC.Session != nil {
 			spec.OIDC.Session = &hubv1alpha1.Session{
-				SameSite: a.OIDC.Session.SameSite,
-				Secure:   a.OIDC.Session.Secure,
-				Domain:   a.OIDC.Session.Domain,
-				Path:     a.OIDC.Session.Path,
-				Refresh:  a.OIDC.Session.Refresh,
+				SameSite:    a.OIDC.Session.SameSite,
+				Secure:      a.OIDC.Session.Secure,
+				Domain:      a.OIDC.Session.Domain,
+				Path:        a.OIDC.Session.Path,
+				Refresh:     a.OIDC.Session.Refresh,
+				MaxAge:      a.OIDC.Session.MaxAge,
+				IdleTimeout: a.OIDC.Session.IdleTimeout,
 			}
 		}
 
+		for _, host := range a.OIDC.Hosts {
+			spec.OIDC.Hosts = append(spec.OIDC.Hosts, hubv1alpha1.OIDCHost{
+				Host:         host.Host,
+				RedirectURL:  host.RedirectURL,
+				CookieDomain: host.CookieDomain,
+			})
+		}
+
 	case a.JWT != nil:
 		spec.JWT = &hubv1alpha1.AccessControlPolicyJWT{
 			SigningSecret:              a.JWT.SigningSecret,
@@ -274,6 +305,9 @@ func buildAccessControlPolicySpec(a ACP) hubv1alpha1.AccessControlPolicySpec {
 			ForwardHeaders:             a.JWT.ForwardHeaders,
 			TokenQueryKey:              a.JWT.TokenQueryKey,
 			Claims:                     a.JWT.Claims,
+			SigningSecretRef:           specValueReference(a.JWT.SigningSecretRef),
+			PublicKeyRef:               specValueReference(a.JWT.PublicKeyRef),
+			JWKsRef:                    specValueReference(a.JWT.JWKsRef),
 		}
 
 	case a.BasicAuth != nil:
@@ -285,5 +319,41 @@ func buildAccessControlPolicySpec(a ACP) hubv1alpha1.AccessControlPolicySpec {
 		}
 	}
 
+	if a.IdentityToken != nil {
+		spec.IdentityToken = &hubv1alpha1.IdentityToken{Header: a.IdentityToken.Header}
+	}
+
+	spec.EnforcementMode = a.EnforcementMode
+	spec.WouldDenyHeader = a.WouldDenyHeader
+
+	if denyResp := a.Config.DenyResponse(); denyResp != nil {
+		spec.DenyResponse = &hubv1alpha1.DenyResponse{
+			UnauthorizedStatusCode: denyResp.UnauthorizedStatusCode,
+			ForbiddenStatusCode:    denyResp.ForbiddenStatusCode,
+			Realm:                  denyResp.Realm,
+		}
+
+		if denyResp.TemplateRef != nil {
+			spec.DenyResponse.TemplateRef = &hubv1alpha1.ConfigMapKeyReference{
+				Namespace: denyResp.TemplateRef.Namespace,
+				Name:      denyResp.TemplateRef.Name,
+				Key:       denyResp.TemplateRef.Key,
+			}
+		}
+	}
+
 	return spec
 }
+
+func specValueReference(ref *jwt.ValueReference) *hubv1alpha1.ValueReference {
+	if ref == nil {
+		return nil
+	}
+
+	return &hubv1alpha1.ValueReference{
+		Kind:      ref.Kind,
+		Namespace: ref.Namespace,
+		Name:      ref.Name,
+		Key:       ref.Key,
+	}
+}
@@ -56,8 +56,32 @@ var toDelete = &hubv1alpha1.AccessControlPolicy{
 	},
 }
 
+var localOnly = &hubv1alpha1.AccessControlPolicy{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:        "localOnly",
+		Annotations: map[string]string{hubv1alpha1.AnnotationLocalOnly: "true"},
+	},
+	Spec: hubv1alpha1.AccessControlPolicySpec{
+		JWT: &hubv1alpha1.AccessControlPolicyJWT{
+			PublicKey: "localValue",
+		},
+	},
+}
+
+var localOnlyShadowing = &hubv1alpha1.AccessControlPolicy{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:        "localOnlyShadowing",
+		Annotations: map[string]string{hubv1alpha1.AnnotationLocalOnly: "true"},
+	},
+	Spec: hubv1alpha1.AccessControlPolicySpec{
+		JWT: &hubv1alpha1.AccessControlPolicyJWT{
+			PublicKey: "localValue",
+		},
+	},
+}
+
 func Test_WatcherRun(t *testing.T) {
-	clientSetHub := hubkubemock.NewSimpleClientset([]runtime.Object{toUpdate, toDelete}...)
+	clientSetHub := hubkubemock.NewSimpleClientset([]runtime.Object{toUpdate, toDelete, localOnly, localOnlyShadowing}...)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	hubInformer := hubinformer.NewSharedInformerFactory(clientSetHub, 0)
@@ -87,6 +111,14 @@ func Test_WatcherRun(t *testing.T) {
 					},
 				},
 			},
+			{
+				Name: "localOnlyShadowing",
+				Config: Config{
+					JWT: &jwt.Config{
+						PublicKey: "platformValue",
+					},
+				},
+			},
 		}, nil).
 		Run(func(_ mock.Arguments) {
 			callCount++
@@ -110,4 +142,12 @@ func Test_WatcherRun(t *testing.T) {
 
 	_, err = clientSetHub.HubV1alpha1().AccessControlPolicies().Get(ctx, "toDelete", metav1.GetOptions{})
 	require.Error(t, err)
+
+	policy, err = clientSetHub.HubV1alpha1().AccessControlPolicies().Get(ctx, "localOnly", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "localValue", policy.Spec.JWT.PublicKey)
+
+	policy, err = clientSetHub.HubV1alpha1().AccessControlPolicies().Get(ctx, "localOnlyShadowing", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "localValue", policy.Spec.JWT.PublicKey)
 }
@@ -27,12 +27,19 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// AnnotationLocalOnly is the annotation to set to "true" on an AccessControlPolicy to keep it in the cluster only.
+// Such a policy is neither synchronized with the platform nor deleted when the platform doesn't know about it.
+const AnnotationLocalOnly = "hub.traefik.io/local-only"
+
 // +genclient
 // +genclient:nonNamespaced
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // AccessControlPolicy defines an access control policy.
 // +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type AccessControlPolicy struct {
 	metav1.TypeMeta `json:",inline"`
 	// +optional
@@ -45,12 +52,70 @@ type AccessControlPolicy struct {
 	Status AccessControlPolicyStatus `json:"status,omitempty"`
 }
 
+// IsLocalOnly reports whether the policy is kept in the cluster only, see AnnotationLocalOnly.
+func (a *AccessControlPolicy) IsLocalOnly() bool {
+	return a.Annotations[AnnotationLocalOnly] == "true"
+}
+
 // AccessControlPolicySpec configures an access control policy.
 type AccessControlPolicySpec struct {
 	JWT        *AccessControlPolicyJWT       `json:"jwt,omitempty"`
 	BasicAuth  *AccessControlPolicyBasicAuth `json:"basicAuth,omitempty"`
 	OIDC       *AccessControlOIDC            `json:"oidc,omitempty"`
 	OIDCGoogle *AccessControlOIDCGoogle      `json:"oidcGoogle,omitempty"`
+
+	// IdentityToken enables the forwarding of a short-lived identity token, signed by the agent,
+	// to the upstream of the requests this policy lets through.
+	IdentityToken *IdentityToken `json:"identityToken,omitempty"`
+
+	// EnforcementMode is the enforcement mode of the policy. In audit mode, requests are always let through and the
+	// decisions the policy would have made are only reported. Defaults to enforce.
+	// +optional
+	// +kubebuilder:validation:Enum=enforce;audit
+	EnforcementMode string `json:"enforcementMode,omitempty"`
+	// WouldDenyHeader is the name of the header added, in audit mode, to the requests the policy would have denied.
+	// +optional
+	WouldDenyHeader string `json:"wouldDenyHeader,omitempty"`
+
+	// DenyResponse customizes the responses of the requests denied by the policy.
+	// +optional
+	DenyResponse *DenyResponse `json:"denyResponse,omitempty"`
+}
+
+// DenyResponse customizes the responses of denied requests. The response format is negotiated using the Accept header
+// of the request: it is either an RFC 7807 problem, an HTML page or plain text.
+// Custom status codes are only honored by Traefik: Nginx only accepts 401 and 403 from its auth_request module and
+// answers any other code with a 500.
+type DenyResponse struct {
+	// UnauthorizedStatusCode is the status code of the responses sent to unauthenticated requests. Defaults to 401.
+	// +optional
+	// +kubebuilder:validation:Minimum=400
+	// +kubebuilder:validation:Maximum=599
+	UnauthorizedStatusCode int `json:"unauthorizedStatusCode,omitempty"`
+	// ForbiddenStatusCode is the status code of the responses sent to unauthorized requests. Defaults to 403.
+	// +optional
+	// +kubebuilder:validation:Minimum=400
+	// +kubebuilder:validation:Maximum=599
+	ForbiddenStatusCode int `json:"forbiddenStatusCode,omitempty"`
+	// Realm is the realm advertised in the WWW-Authenticate header.
+	// +optional
+	Realm string `json:"realm,omitempty"`
+	// TemplateRef references the ConfigMap key holding the HTML template rendered to browsers.
+	// +optional
+	TemplateRef *ConfigMapKeyReference `json:"templateRef,omitempty"`
+}
+
+// ConfigMapKeyReference references a ConfigMap key.
+type ConfigMapKeyReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+}
+
+// IdentityToken configures the identity token forwarded to upstreams.
+type IdentityToken struct {
+	// Header is the name of the header the identity token is forwarded in. Defaults to X-Hub-Identity.
+	Header string `json:"header,omitempty"`
 }
 
 // Hash return AccessControlPolicySpec hash.
@@ -77,6 +142,31 @@ type AccessControlPolicyJWT struct {
 	ForwardHeaders             map[string]string `json:"forwardHeaders,omitempty"`
 	TokenQueryKey              string            `json:"tokenQueryKey,omitempty"`
 	Claims                     string            `json:"claims,omitempty"`
+
+	// SigningSecretRef references the Secret or ConfigMap key holding the signing secret.
+	// +optional
+	SigningSecretRef *ValueReference `json:"signingSecretRef,omitempty"`
+	// PublicKeyRef references the Secret or ConfigMap key holding the PEM-encoded public key.
+	// +optional
+	PublicKeyRef *ValueReference `json:"publicKeyRef,omitempty"`
+	// JWKsRef references the Secret or ConfigMap key holding the JSON Web Key Set.
+	// +optional
+	JWKsRef *ValueReference `json:"jwksRef,omitempty"`
+}
+
+// ValueReference references a key of a Secret or of a ConfigMap. Only the reference is synchronized with the platform,
+// the value being resolved by the agent.
+type ValueReference struct {
+	// Kind is the kind of the referenced resource. Defaults to Secret.
+	// +optional
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind,omitempty"`
+	// Namespace is the namespace of the referenced resource. It defaults, for namespaced policies, to the namespace of
+	// the policy.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
 }
 
 // AccessControlPolicyBasicAuth holds the HTTP basic authentication configuration.
@@ -101,6 +191,8 @@ type AccessControlOIDC struct {
 
 	StateCookie *StateCookie `json:"stateCookie,omitempty"`
 	Session     *Session     `json:"session,omitempty"`
+	// Hosts overrides the redirect URL and cookie domain for specific hosts.
+	Hosts []OIDCHost `json:"hosts,omitempty"`
 
 	Scopes         []string          `json:"scopes,omitempty"`
 	ForwardHeaders map[string]string `json:"forwardHeaders,omitempty"`
@@ -120,6 +212,8 @@ type AccessControlOIDCGoogle struct {
 
 	StateCookie *StateCookie `json:"stateCookie,omitempty"`
 	Session     *Session     `json:"session,omitempty"`
+	// Hosts overrides the redirect URL and cookie domain for specific hosts.
+	Hosts []OIDCHost `json:"hosts,omitempty"`
 
 	ForwardHeaders map[string]string `json:"forwardHeaders,omitempty"`
 	// Emails are the allowed emails to connect.
@@ -147,6 +241,22 @@ type Session struct {
 	Domain   string `json:"domain,omitempty"`
 	Path     string `json:"path,omitempty"`
 	Refresh  *bool  `json:"refresh,omitempty"`
+
+	// MaxAge is the maximum lifetime of a session in seconds, regardless of token refreshes.
+	MaxAge int `json:"maxAge,omitempty"`
+	// IdleTimeout is the duration in seconds after which an inactive session expires.
+	// Behind ingress-nginx, every request extends the session. Behind other proxies, only navigations (requests
+	// other than POST, PUT, PATCH and DELETE) do, API calls don't.
+	IdleTimeout int `json:"idleTimeout,omitempty"`
+}
+
+// OIDCHost holds the OIDC settings of a given host.
+type OIDCHost struct {
+	// Host is the host these settings apply to. A leading wildcard (e.g. *.example.com) matches any subdomain.
+	Host        string `json:"host"`
+	RedirectURL string `json:"redirectUrl,omitempty"`
+	// CookieDomain is the domain of both the state and session cookies.
+	CookieDomain string `json:"cookieDomain,omitempty"`
 }
 
 // AccessControlPolicyStatus is the status of the access control policy.
@@ -154,6 +264,10 @@ type AccessControlPolicyStatus struct {
 	Version  string      `json:"version,omitempty"`
 	SyncedAt metav1.Time `json:"syncedAt,omitempty"`
 	SpecHash string      `json:"specHash,omitempty"`
+
+	// Conditions reports the health of the policy, as seen by the auth server.
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
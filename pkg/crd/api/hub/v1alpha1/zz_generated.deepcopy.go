@@ -23,6 +23,7 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -56,6 +57,11 @@ func (in *AccessControlOIDC) DeepCopyInto(out *AccessControlOIDC) {
 		*out = new(Session)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]OIDCHost, len(*in))
+		copy(*out, *in)
+	}
 	if in.Scopes != nil {
 		in, out := &in.Scopes, &out.Scopes
 		*out = make([]string, len(*in))
@@ -111,6 +117,11 @@ func (in *AccessControlOIDCGoogle) DeepCopyInto(out *AccessControlOIDCGoogle) {
 		*out = new(Session)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]OIDCHost, len(*in))
+		copy(*out, *in)
+	}
 	if in.ForwardHeaders != nil {
 		in, out := &in.ForwardHeaders, &out.ForwardHeaders
 		*out = make(map[string]string, len(*in))
@@ -195,6 +206,21 @@ func (in *AccessControlPolicyJWT) DeepCopyInto(out *AccessControlPolicyJWT) {
 			(*out)[key] = val
 		}
 	}
+	if in.SigningSecretRef != nil {
+		in, out := &in.SigningSecretRef, &out.SigningSecretRef
+		*out = new(ValueReference)
+		**out = **in
+	}
+	if in.PublicKeyRef != nil {
+		in, out := &in.PublicKeyRef, &out.PublicKeyRef
+		*out = new(ValueReference)
+		**out = **in
+	}
+	if in.JWKsRef != nil {
+		in, out := &in.JWKsRef, &out.JWKsRef
+		*out = new(ValueReference)
+		**out = **in
+	}
 	return
 }
 
@@ -264,6 +290,16 @@ func (in *AccessControlPolicySpec) DeepCopyInto(out *AccessControlPolicySpec) {
 		*out = new(AccessControlOIDCGoogle)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.IdentityToken != nil {
+		in, out := &in.IdentityToken, &out.IdentityToken
+		*out = new(IdentityToken)
+		**out = **in
+	}
+	if in.DenyResponse != nil {
+		in, out := &in.DenyResponse, &out.DenyResponse
+		*out = new(DenyResponse)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -281,6 +317,13 @@ func (in *AccessControlPolicySpec) DeepCopy() *AccessControlPolicySpec {
 func (in *AccessControlPolicyStatus) DeepCopyInto(out *AccessControlPolicyStatus) {
 	*out = *in
 	in.SyncedAt.DeepCopyInto(&out.SyncedAt)
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -294,6 +337,43 @@ func (in *AccessControlPolicyStatus) DeepCopy() *AccessControlPolicyStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyReference) DeepCopyInto(out *ConfigMapKeyReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyReference.
+func (in *ConfigMapKeyReference) DeepCopy() *ConfigMapKeyReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DenyResponse) DeepCopyInto(out *DenyResponse) {
+	*out = *in
+	if in.TemplateRef != nil {
+		in, out := &in.TemplateRef, &out.TemplateRef
+		*out = new(ConfigMapKeyReference)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DenyResponse.
+func (in *DenyResponse) DeepCopy() *DenyResponse {
+	if in == nil {
+		return nil
+	}
+	out := new(DenyResponse)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EdgeIngress) DeepCopyInto(out *EdgeIngress) {
 	*out = *in
@@ -426,6 +506,22 @@ func (in *EdgeIngressStatus) DeepCopy() *EdgeIngressStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdentityToken) DeepCopyInto(out *IdentityToken) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdentityToken.
+func (in *IdentityToken) DeepCopy() *IdentityToken {
+	if in == nil {
+		return nil
+	}
+	out := new(IdentityToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressClass) DeepCopyInto(out *IngressClass) {
 	*out = *in
@@ -502,6 +598,83 @@ func (in *IngressClassSpec) DeepCopy() *IngressClassSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacedAccessControlPolicy) DeepCopyInto(out *NamespacedAccessControlPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedAccessControlPolicy.
+func (in *NamespacedAccessControlPolicy) DeepCopy() *NamespacedAccessControlPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacedAccessControlPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NamespacedAccessControlPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespacedAccessControlPolicyList) DeepCopyInto(out *NamespacedAccessControlPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NamespacedAccessControlPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespacedAccessControlPolicyList.
+func (in *NamespacedAccessControlPolicyList) DeepCopy() *NamespacedAccessControlPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespacedAccessControlPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NamespacedAccessControlPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCHost) DeepCopyInto(out *OIDCHost) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCHost.
+func (in *OIDCHost) DeepCopy() *OIDCHost {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCHost)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Session) DeepCopyInto(out *Session) {
 	*out = *in
@@ -559,3 +732,19 @@ func (in *TLS) DeepCopy() *TLS {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValueReference) DeepCopyInto(out *ValueReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValueReference.
+func (in *ValueReference) DeepCopy() *ValueReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ValueReference)
+	in.DeepCopyInto(out)
+	return out
+}
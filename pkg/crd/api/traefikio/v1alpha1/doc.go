@@ -0,0 +1,3 @@
+// Package v1alpha1 is the v1alpha1 version of the API served under the traefik.io group by Traefik v2.10 and later.
+// +groupName=traefik.io
+package v1alpha1
@@ -0,0 +1,43 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package v1alpha1
+
+import (
+	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+)
+
+// The traefik.io group serves the same resources as the traefik.containo.us one, only the group differs.
+// Sharing the Go types lets the code handling those resources work with both groups.
+type (
+	// IngressRoute is an IngressRoute of the traefik.io group.
+	IngressRoute = traefikv1alpha1.IngressRoute
+	// IngressRouteList is a list of IngressRoutes of the traefik.io group.
+	IngressRouteList = traefikv1alpha1.IngressRouteList
+	// Middleware is a Middleware of the traefik.io group.
+	Middleware = traefikv1alpha1.Middleware
+	// MiddlewareList is a list of Middlewares of the traefik.io group.
+	MiddlewareList = traefikv1alpha1.MiddlewareList
+	// TLSOption is a TLSOption of the traefik.io group.
+	TLSOption = traefikv1alpha1.TLSOption
+	// TLSOptionList is a list of TLSOptions of the traefik.io group.
+	TLSOptionList = traefikv1alpha1.TLSOptionList
+	// TraefikService is a TraefikService of the traefik.io group.
+	TraefikService = traefikv1alpha1.TraefikService
+	// TraefikServiceList is a list of TraefikServices of the traefik.io group.
+	TraefikServiceList = traefikv1alpha1.TraefikServiceList
+)
@@ -40,6 +40,10 @@ func (c *FakeHubV1alpha1) IngressClasses() v1alpha1.IngressClassInterface {
 	return &FakeIngressClasses{c}
 }
 
+func (c *FakeHubV1alpha1) NamespacedAccessControlPolicies(namespace string) v1alpha1.NamespacedAccessControlPolicyInterface {
+	return &FakeNamespacedAccessControlPolicies{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeHubV1alpha1) RESTClient() rest.Interface {
@@ -23,3 +23,5 @@ type AccessControlPolicyExpansion interface{}
 type EdgeIngressExpansion interface{}
 
 type IngressClassExpansion interface{}
+
+type NamespacedAccessControlPolicyExpansion interface{}
@@ -29,6 +29,7 @@ type HubV1alpha1Interface interface {
 	AccessControlPoliciesGetter
 	EdgeIngressesGetter
 	IngressClassesGetter
+	NamespacedAccessControlPoliciesGetter
 }
 
 // HubV1alpha1Client is used to interact with features provided by the hub.traefik.io group.
@@ -48,6 +49,10 @@ func (c *HubV1alpha1Client) IngressClasses() IngressClassInterface {
 	return newIngressClasses(c)
 }
 
+func (c *HubV1alpha1Client) NamespacedAccessControlPolicies(namespace string) NamespacedAccessControlPolicyInterface {
+	return newNamespacedAccessControlPolicies(c, namespace)
+}
+
 // NewForConfig creates a new HubV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*HubV1alpha1Client, error) {
 	config := *c
@@ -59,6 +59,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Hub().V1alpha1().EdgeIngresses().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("ingressclasses"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Hub().V1alpha1().IngressClasses().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("namespacedaccesscontrolpolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Hub().V1alpha1().NamespacedAccessControlPolicies().Informer()}, nil
 
 	}
 
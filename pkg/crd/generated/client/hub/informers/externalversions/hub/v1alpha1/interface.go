@@ -30,6 +30,8 @@ type Interface interface {
 	EdgeIngresses() EdgeIngressInformer
 	// IngressClasses returns a IngressClassInformer.
 	IngressClasses() IngressClassInformer
+	// NamespacedAccessControlPolicies returns a NamespacedAccessControlPolicyInformer.
+	NamespacedAccessControlPolicies() NamespacedAccessControlPolicyInformer
 }
 
 type version struct {
@@ -57,3 +59,8 @@ func (v *version) EdgeIngresses() EdgeIngressInformer {
 func (v *version) IngressClasses() IngressClassInformer {
 	return &ingressClassInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
 }
+
+// NamespacedAccessControlPolicies returns a NamespacedAccessControlPolicyInformer.
+func (v *version) NamespacedAccessControlPolicies() NamespacedAccessControlPolicyInformer {
+	return &namespacedAccessControlPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -33,3 +33,11 @@ type EdgeIngressNamespaceListerExpansion interface{}
 // IngressClassListerExpansion allows custom methods to be added to
 // IngressClassLister.
 type IngressClassListerExpansion interface{}
+
+// NamespacedAccessControlPolicyListerExpansion allows custom methods to be added to
+// NamespacedAccessControlPolicyLister.
+type NamespacedAccessControlPolicyListerExpansion interface{}
+
+// NamespacedAccessControlPolicyNamespaceListerExpansion allows custom methods to be added to
+// NamespacedAccessControlPolicyNamespaceLister.
+type NamespacedAccessControlPolicyNamespaceListerExpansion interface{}
@@ -26,13 +26,20 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/metrics"
 	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
 	"github.com/traefik/hub-agent-kubernetes/pkg/edgeingress"
 	"github.com/traefik/hub-agent-kubernetes/pkg/platform"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	admv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const tracerName = "github.com/traefik/hub-agent-kubernetes/pkg/edgeingress/admission"
+
 // Backend manages edge ingresses.
 type Backend interface {
 	CreateEdgeIngress(ctx context.Context, ing *platform.CreateEdgeIngressReq) (*edgeingress.EdgeIngress, error)
@@ -56,11 +63,16 @@ func NewHandler(backend Backend) *Handler {
 
 // ServeHTTP implements http.Handler.
 func (h Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), "admission.edge-ingress", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
 	// We always decode the admission request in an admv1 object regardless
 	// of the request version as it is strictly identical to the admv1beta1 object.
 	var ar admv1.AdmissionReview
 	if err := json.NewDecoder(req.Body).Decode(&ar); err != nil {
 		log.Error().Err(err).Msg("Unable to decode admission request")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
 		return
 	}
@@ -72,12 +84,15 @@ func (h Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 			Str("resource_name", ar.Request.Name).
 			Str("resource_namespace", ar.Request.Namespace).
 			Logger()
+		span.SetAttributes(requestAttributes(ar.Request)...)
 	}
-	ctx := l.WithContext(req.Context())
+	ctx = l.WithContext(ctx)
 
 	patches, err := h.review(ctx, ar.Request)
 	if err != nil {
 		log.Ctx(ctx).Error().Err(err).Msg("Unable to handle admission request")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 
 		if errors.Is(err, platform.ErrVersionConflict) {
 			err = errors.New("platform conflict: a more recent version of this resource is available")
@@ -157,7 +172,9 @@ func (h Handler) reviewCreateOperation(ctx context.Context, edgeIng *hubv1alpha1
 		createReq.ACP = &platform.ACP{Name: edgeIng.Spec.ACP.Name}
 	}
 
+	start := time.Now()
 	createdEdgeIng, err := h.backend.CreateEdgeIngress(ctx, createReq)
+	metrics.ObservePlatformRequest("CreateEdgeIngress", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("create edge ingress: %w", err)
 	}
@@ -180,7 +197,9 @@ func (h Handler) reviewUpdateOperation(ctx context.Context, oldEdgeIng, newEdgeI
 		}
 	}
 
+	start := time.Now()
 	updatedEdgeIng, err := h.backend.UpdateEdgeIngress(ctx, oldEdgeIng.Namespace, oldEdgeIng.Name, oldEdgeIng.Status.Version, updateReq)
+	metrics.ObservePlatformRequest("UpdateEdgeIngress", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("update edge ingress: %w", err)
 	}
@@ -191,7 +210,10 @@ func (h Handler) reviewUpdateOperation(ctx context.Context, oldEdgeIng, newEdgeI
 func (h Handler) reviewDeleteOperation(ctx context.Context, oldEdgeIng *hubv1alpha1.EdgeIngress) ([]byte, error) {
 	log.Ctx(ctx).Info().Msg("Deleting EdgeIngress resource")
 
-	if err := h.backend.DeleteEdgeIngress(ctx, oldEdgeIng.Namespace, oldEdgeIng.Name, oldEdgeIng.Status.Version); err != nil {
+	start := time.Now()
+	err := h.backend.DeleteEdgeIngress(ctx, oldEdgeIng.Namespace, oldEdgeIng.Name, oldEdgeIng.Status.Version)
+	metrics.ObservePlatformRequest("DeleteEdgeIngress", start, err)
+	if err != nil {
 		return nil, fmt.Errorf("delete edge ingress: %w", err)
 	}
 	return nil, nil
@@ -254,6 +276,17 @@ func setReviewResponse(ar *admv1.AdmissionReview, patch []byte) {
 	}
 }
 
+// requestAttributes returns the span attributes describing the given admission request.
+func requestAttributes(req *admv1.AdmissionRequest) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("admission.uid", string(req.UID)),
+		attribute.String("admission.operation", string(req.Operation)),
+		attribute.String("admission.resource.kind", req.Kind.String()),
+		attribute.String("admission.resource.name", req.Name),
+		attribute.String("admission.resource.namespace", req.Namespace),
+	}
+}
+
 func isEdgeIngressRequest(kind metav1.GroupVersionKind) bool {
 	return kind.Kind == "EdgeIngress" && kind.Group == "hub.traefik.io" && kind.Version == "v1alpha1"
 }
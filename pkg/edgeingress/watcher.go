@@ -72,11 +72,11 @@ type Watcher struct {
 	hubClientSet     hubclientset.Interface
 	hubInformer      hubinformer.SharedInformerFactory
 	clientSet        clientset.Interface
-	traefikClientSet v1alpha1.TraefikV1alpha1Interface
+	traefikClientSet v1alpha1.MiddlewaresGetter
 }
 
 // NewWatcher returns a new Watcher.
-func NewWatcher(client PlatformClient, hubClientSet hubclientset.Interface, clientSet clientset.Interface, traefikClientSet v1alpha1.TraefikV1alpha1Interface, hubInformer hubinformer.SharedInformerFactory, config WatcherConfig) (*Watcher, error) {
+func NewWatcher(client PlatformClient, hubClientSet hubclientset.Interface, clientSet clientset.Interface, traefikClientSet v1alpha1.MiddlewaresGetter, hubInformer hubinformer.SharedInformerFactory, config WatcherConfig) (*Watcher, error) {
 	return &Watcher{
 		config: config,
 
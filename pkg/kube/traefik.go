@@ -0,0 +1,75 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package kube
+
+import (
+	"strings"
+
+	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+	traefikiov1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefikio/v1alpha1"
+	traefikclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned"
+	traefikclient "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/typed/traefik/v1alpha1"
+	traefikioclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned"
+	traefikioclient "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned/typed/traefikio/v1alpha1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// TraefikAPIGroup returns the API group the Traefik CRDs are served under: traefik.io from Traefik v2.10 on and
+// traefik.containo.us before. The traefik.io group is preferred when both are served. An empty group is returned when
+// the Traefik CRDs are not installed.
+func TraefikAPIGroup(client discovery.DiscoveryInterface) (string, error) {
+	for _, gv := range []schema.GroupVersion{traefikiov1alpha1.SchemeGroupVersion, traefikv1alpha1.SchemeGroupVersion} {
+		resources, err := client.ServerResourcesForGroupVersion(gv.String())
+		if err != nil {
+			if kerror.IsNotFound(err) ||
+				// because the fake client doesn't return the right error type.
+				strings.HasSuffix(err.Error(), " not found") {
+				continue
+			}
+			return "", err
+		}
+
+		if len(resources.APIResources) > 0 {
+			return gv.Group, nil
+		}
+	}
+
+	return "", nil
+}
+
+// TraefikMiddlewares returns the client managing the Traefik Middlewares of the given API group, falling back on the
+// traefik.containo.us group.
+func TraefikMiddlewares(group string, traefikClientSet traefikclientset.Interface, traefikIOClientSet traefikioclientset.Interface) traefikclient.MiddlewaresGetter {
+	if group == traefikiov1alpha1.GroupName {
+		return traefikIOMiddlewares{client: traefikIOClientSet.TraefikV1alpha1()}
+	}
+
+	return traefikClientSet.TraefikV1alpha1()
+}
+
+// traefikIOMiddlewares adapts the traefik.io client to the traefik.containo.us one, both groups sharing the same types.
+type traefikIOMiddlewares struct {
+	client traefikioclient.TraefikV1alpha1Interface
+}
+
+// Middlewares returns the client managing the traefik.io Middlewares of the given namespace.
+func (m traefikIOMiddlewares) Middlewares(namespace string) traefikclient.MiddlewareInterface {
+	return m.client.Middlewares(namespace)
+}
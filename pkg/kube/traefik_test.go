@@ -0,0 +1,126 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package kube
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
+	traefikiokubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubemock "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTraefikAPIGroup(t *testing.T) {
+	tests := []struct {
+		desc          string
+		groupVersions []string
+		want          string
+	}{
+		{
+			desc: "no Traefik CRDs",
+		},
+		{
+			desc:          "traefik.containo.us only",
+			groupVersions: []string{"traefik.containo.us/v1alpha1"},
+			want:          "traefik.containo.us",
+		},
+		{
+			desc:          "traefik.io only",
+			groupVersions: []string{"traefik.io/v1alpha1"},
+			want:          "traefik.io",
+		},
+		{
+			desc:          "both groups",
+			groupVersions: []string{"traefik.containo.us/v1alpha1", "traefik.io/v1alpha1"},
+			want:          "traefik.io",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			kubeClient := kubemock.NewSimpleClientset()
+			for _, gv := range test.groupVersions {
+				kubeClient.Resources = append(kubeClient.Resources, &metav1.APIResourceList{
+					GroupVersion: gv,
+					APIResources: []metav1.APIResource{{Kind: "Middleware"}},
+				})
+			}
+
+			got, err := TraefikAPIGroup(kubeClient.Discovery())
+			require.NoError(t, err)
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestTraefikMiddlewares(t *testing.T) {
+	tests := []struct {
+		desc          string
+		group         string
+		wantTraefik   int
+		wantTraefikIO int
+	}{
+		{
+			desc:        "traefik.containo.us",
+			group:       "traefik.containo.us",
+			wantTraefik: 1,
+		},
+		{
+			desc:          "traefik.io",
+			group:         "traefik.io",
+			wantTraefikIO: 1,
+		},
+		{
+			desc:        "no group",
+			wantTraefik: 1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			traefikClientSet := traefikkubemock.NewSimpleClientset()
+			traefikIOClientSet := traefikiokubemock.NewSimpleClientset()
+
+			middlewares := TraefikMiddlewares(test.group, traefikClientSet, traefikIOClientSet)
+
+			mdlwr := &traefikv1alpha1.Middleware{ObjectMeta: metav1.ObjectMeta{Name: "my-middleware", Namespace: "my-ns"}}
+			_, err := middlewares.Middlewares("my-ns").Create(context.Background(), mdlwr, metav1.CreateOptions{})
+			require.NoError(t, err)
+
+			traefikList, err := traefikClientSet.TraefikV1alpha1().Middlewares("my-ns").List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Len(t, traefikList.Items, test.wantTraefik)
+
+			traefikIOList, err := traefikIOClientSet.TraefikV1alpha1().Middlewares("my-ns").List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Len(t, traefikIOList.Items, test.wantTraefikIO)
+		})
+	}
+}
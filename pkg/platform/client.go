@@ -39,6 +39,7 @@ import (
 	"github.com/traefik/hub-agent-kubernetes/pkg/logger"
 	"github.com/traefik/hub-agent-kubernetes/pkg/topology/state"
 	"github.com/traefik/hub-agent-kubernetes/pkg/version"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // ErrVersionConflict indicates a conflict error on the EdgeIngress resource being modified.
@@ -126,6 +127,13 @@ func NewClient(baseURL, token string) (*Client, error) {
 	client := retryablehttp.NewClient()
 	client.RetryMax = 4
 	client.Logger = logger.NewWrappedLogger(log.Logger.With().Str("component", "platform_client").Logger())
+	// Requests are traced as children of the caller span, whose context is propagated to the platform.
+	// Spans are only recorded if a TracerProvider is registered globally.
+	client.HTTPClient.Transport = otelhttp.NewTransport(client.HTTPClient.Transport,
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return "platform " + req.Method
+		}),
+	)
 
 	return &Client{
 		baseURL:    u,
@@ -26,10 +26,16 @@ import (
 	"github.com/hashicorp/go-version"
 	"github.com/rs/zerolog/log"
 	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+	traefikiov1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefikio/v1alpha1"
 	traefikclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned"
 	traefikinformer "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/informers/externalversions"
+	traefikioclientset "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned"
+	traefikioinformer "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/informers/externalversions"
+	"github.com/traefik/hub-agent-kubernetes/pkg/kube"
 	"github.com/traefik/hub-agent-kubernetes/pkg/kubevers"
 	kerror "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
@@ -41,11 +47,15 @@ type Fetcher struct {
 
 	k8s       informers.SharedInformerFactory
 	traefik   traefikinformer.SharedInformerFactory
+	traefikIO traefikioinformer.SharedInformerFactory
 	clientSet clientset.Interface
+
+	// traefikGroup is the API group the Traefik CRDs are fetched from.
+	traefikGroup string
 }
 
-// NewFetcher creates a new Fetcher.
-func NewFetcher(ctx context.Context, clientSet clientset.Interface, traefikClientSet traefikclientset.Interface) (*Fetcher, error) {
+// NewFetcher creates a new Fetcher. The Traefik CRDs are fetched from the API group served by the cluster.
+func NewFetcher(ctx context.Context, clientSet clientset.Interface, traefikClientSet traefikclientset.Interface, traefikIOClientSet traefikioclientset.Interface) (*Fetcher, error) {
 	serverVersion, err := clientSet.Discovery().ServerVersion()
 	if err != nil {
 		return nil, fmt.Errorf("get server version: %w", err)
@@ -60,10 +70,10 @@ func NewFetcher(ctx context.Context, clientSet clientset.Interface, traefikClien
 		return nil, fmt.Errorf("unsupported version: %s", serverSemVer)
 	}
 
-	return watchAll(ctx, clientSet, traefikClientSet, serverVersion.GitVersion)
+	return watchAll(ctx, clientSet, traefikClientSet, traefikIOClientSet, serverVersion.GitVersion)
 }
 
-func watchAll(ctx context.Context, clientSet clientset.Interface, traefikClientSet traefikclientset.Interface, serverVersion string) (*Fetcher, error) {
+func watchAll(ctx context.Context, clientSet clientset.Interface, traefikClientSet traefikclientset.Interface, traefikIOClientSet traefikioclientset.Interface, serverVersion string) (*Fetcher, error) {
 	kubernetesFactory := informers.NewSharedInformerFactoryWithOptions(clientSet, 5*time.Minute)
 
 	kubernetesFactory.Core().V1().Pods().Informer()
@@ -83,16 +93,33 @@ func watchAll(ctx context.Context, clientSet clientset.Interface, traefikClientS
 	}
 
 	traefikFactory := traefikinformer.NewSharedInformerFactoryWithOptions(traefikClientSet, 5*time.Minute)
+	traefikIOFactory := traefikioinformer.NewSharedInformerFactoryWithOptions(traefikIOClientSet, 5*time.Minute)
 
-	hasTraefikCRDs, err := hasTraefikCRDs(clientSet.Discovery())
+	traefikGroup, err := kube.TraefikAPIGroup(clientSet.Discovery())
 	if err != nil {
-		return nil, fmt.Errorf("check presence of Traefik IngressRoute, TraefikService and TLSOption CRD: %w", err)
+		return nil, fmt.Errorf("detect Traefik API group: %w", err)
+	}
+
+	var hasCRDs bool
+	if traefikGroup != "" {
+		hasCRDs, err = hasTraefikCRDs(clientSet.Discovery(), traefikGroup)
+		if err != nil {
+			return nil, fmt.Errorf("check presence of Traefik IngressRoute, TraefikService and TLSOption CRD: %w", err)
+		}
 	}
 
-	if hasTraefikCRDs {
+	switch {
+	case hasCRDs && traefikGroup == traefikiov1alpha1.GroupName:
+		traefikIOFactory.Traefik().V1alpha1().IngressRoutes().Informer()
+		traefikIOFactory.Traefik().V1alpha1().TraefikServices().Informer()
+
+	case hasCRDs:
 		traefikFactory.Traefik().V1alpha1().IngressRoutes().Informer()
 		traefikFactory.Traefik().V1alpha1().TraefikServices().Informer()
-	} else {
+
+	default:
+		traefikGroup = traefikv1alpha1.GroupName
+
 		msg := "The agent has been installed in a cluster where the Traefik Proxy CustomResourceDefinitions are not installed. " +
 			"If you want to install these CustomResourceDefinitions and take advantage of them in Traefik Hub, " +
 			"the agent needs to be restarted in order to load them. " +
@@ -102,6 +129,7 @@ func watchAll(ctx context.Context, clientSet clientset.Interface, traefikClientS
 
 	kubernetesFactory.Start(ctx.Done())
 	traefikFactory.Start(ctx.Done())
+	traefikIOFactory.Start(ctx.Done())
 
 	for typ, ok := range kubernetesFactory.WaitForCacheSync(ctx.Done()) {
 		if !ok {
@@ -115,11 +143,19 @@ func watchAll(ctx context.Context, clientSet clientset.Interface, traefikClientS
 		}
 	}
 
+	for typ, ok := range traefikIOFactory.WaitForCacheSync(ctx.Done()) {
+		if !ok {
+			return nil, fmt.Errorf("timed out waiting for Traefik (traefik.io) CRD caches to sync %s", typ)
+		}
+	}
+
 	return &Fetcher{
 		serverVersion: serverVersion,
 		k8s:           kubernetesFactory,
 		traefik:       traefikFactory,
+		traefikIO:     traefikIOFactory,
 		clientSet:     clientSet,
+		traefikGroup:  traefikGroup,
 	}, nil
 }
 
@@ -147,8 +183,10 @@ func (f *Fetcher) FetchState() (*Cluster, error) {
 	return &cluster, nil
 }
 
-func hasTraefikCRDs(clientSet discovery.DiscoveryInterface) (bool, error) {
-	crdList, err := clientSet.ServerResourcesForGroupVersion(traefikv1alpha1.SchemeGroupVersion.String())
+func hasTraefikCRDs(clientSet discovery.DiscoveryInterface, group string) (bool, error) {
+	gv := schema.GroupVersion{Group: group, Version: "v1alpha1"}
+
+	crdList, err := clientSet.ServerResourcesForGroupVersion(gv.String())
 	if err != nil {
 		if kerror.IsNotFound(err) ||
 			// because the fake client doesn't return the right error type.
@@ -175,6 +213,24 @@ func hasTraefikCRDs(clientSet discovery.DiscoveryInterface) (bool, error) {
 	return true, nil
 }
 
+// listIngressRoutes lists the IngressRoutes of the Traefik API group served by the cluster.
+func (f *Fetcher) listIngressRoutes() ([]*traefikv1alpha1.IngressRoute, error) {
+	if f.traefikGroup == traefikiov1alpha1.GroupName {
+		return f.traefikIO.Traefik().V1alpha1().IngressRoutes().Lister().List(labels.Everything())
+	}
+
+	return f.traefik.Traefik().V1alpha1().IngressRoutes().Lister().List(labels.Everything())
+}
+
+// getTraefikService gets a TraefikService of the Traefik API group served by the cluster.
+func (f *Fetcher) getTraefikService(namespace, name string) (*traefikv1alpha1.TraefikService, error) {
+	if f.traefikGroup == traefikiov1alpha1.GroupName {
+		return f.traefikIO.Traefik().V1alpha1().TraefikServices().Lister().TraefikServices(namespace).Get(name)
+	}
+
+	return f.traefik.Traefik().V1alpha1().TraefikServices().Lister().TraefikServices(namespace).Get(name)
+}
+
 func objectKey(name, ns string) string {
 	return name + "@" + ns
 }
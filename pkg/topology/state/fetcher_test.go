@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
+	traefikiokubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned/fake"
 	netv1 "k8s.io/api/networking/v1"
 	netv1beta1 "k8s.io/api/networking/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -73,7 +74,7 @@ func TestNewFetcher_handlesUnsupportedVersions(t *testing.T) {
 
 			fakeDiscovery.FakedServerVersion = &version.Info{GitVersion: test.serverVersion}
 
-			_, err := NewFetcher(context.Background(), kubeClient, traefikClient)
+			_, err := NewFetcher(context.Background(), kubeClient, traefikClient, traefikiokubemock.NewSimpleClientset())
 			test.wantErr(t, err)
 		})
 	}
@@ -190,7 +191,7 @@ func TestNewFetcher_handlesAllIngressAPIVersions(t *testing.T) {
 
 			fakeDiscovery.FakedServerVersion = &version.Info{GitVersion: test.serverVersion}
 
-			f, err := NewFetcher(context.Background(), kubeClient, traefikClient)
+			f, err := NewFetcher(context.Background(), kubeClient, traefikClient, traefikiokubemock.NewSimpleClientset())
 			require.NoError(t, err)
 
 			got, err := f.getIngresses()
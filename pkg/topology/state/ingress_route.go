@@ -21,7 +21,6 @@ import (
 	"strings"
 
 	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
@@ -33,7 +32,7 @@ const (
 )
 
 func (f *Fetcher) getIngressRoutes() (map[string]*IngressRoute, error) {
-	ingressRoutes, err := f.traefik.Traefik().V1alpha1().IngressRoutes().Lister().List(labels.Everything())
+	ingressRoutes, err := f.listIngressRoutes()
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +69,7 @@ func (f *Fetcher) getIngressRoutes() (map[string]*IngressRoute, error) {
 		ing := &IngressRoute{
 			ResourceMeta: ResourceMeta{
 				Kind:      ResourceKindIngressRoute,
-				Group:     traefikv1alpha1.GroupName,
+				Group:     f.traefikGroup,
 				Name:      ingressRoute.Name,
 				Namespace: ingressRoute.Namespace,
 			},
@@ -117,7 +116,7 @@ func (f *Fetcher) getRouteServicesFromTraefikService(parentNamespace, namespace,
 		namespace = parentNamespace
 	}
 
-	ts, err := f.traefik.Traefik().V1alpha1().TraefikServices().Lister().TraefikServices(namespace).Get(name)
+	ts, err := f.getTraefikService(namespace, name)
 	if err != nil {
 		return nil, err
 	}
@@ -24,18 +24,25 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+	traefikiov1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefikio/v1alpha1"
 	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
+	traefikiokubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned/fake"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubemock "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
-// Mandatory to be able to parse traefik.containo.us/v1alpha1 resources.
+// Mandatory to be able to parse traefik.containo.us/v1alpha1 and traefik.io/v1alpha1 resources.
 func init() {
 	err := traefikv1alpha1.AddToScheme(scheme.Scheme)
 	if err != nil {
 		panic(err)
 	}
+
+	err = traefikiov1alpha1.AddToScheme(scheme.Scheme)
+	if err != nil {
+		panic(err)
+	}
 }
 
 func TestFetcher_GetIngressRoutes(t *testing.T) {
@@ -277,7 +284,7 @@ func TestFetcher_GetIngressRoutes(t *testing.T) {
 
 			traefikClient := traefikkubemock.NewSimpleClientset(objects...)
 
-			f, err := watchAll(context.Background(), kubeClient, traefikClient, "v1.20.1")
+			f, err := watchAll(context.Background(), kubeClient, traefikClient, traefikiokubemock.NewSimpleClientset(), "v1.20.1")
 			require.NoError(t, err)
 
 			got, err := f.getIngressRoutes()
@@ -287,3 +294,67 @@ func TestFetcher_GetIngressRoutes(t *testing.T) {
 		})
 	}
 }
+
+func TestFetcher_GetIngressRoutes_traefikIO(t *testing.T) {
+	objects := loadK8sObjects(t, "fixtures/ingress-route/traefik-io-ingress-route-one-weighted-traefik-service.yml")
+
+	kubeClient := kubemock.NewSimpleClientset()
+	// Faking having Traefik CRDs installed on cluster under the traefik.io group.
+	kubeClient.Resources = append(kubeClient.Resources, &metav1.APIResourceList{
+		GroupVersion: traefikiov1alpha1.SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{
+			{Kind: ResourceKindIngressRoute},
+			{Kind: ResourceKindTraefikService},
+			{Kind: ResourceKindTLSOption},
+		},
+	})
+
+	traefikIOClient := traefikiokubemock.NewSimpleClientset(objects...)
+
+	f, err := watchAll(context.Background(), kubeClient, traefikkubemock.NewSimpleClientset(), traefikIOClient, "v1.20.1")
+	require.NoError(t, err)
+
+	got, err := f.getIngressRoutes()
+	require.NoError(t, err)
+
+	want := map[string]*IngressRoute{
+		"name@ns.ingressroute.traefik.io": {
+			ResourceMeta: ResourceMeta{
+				Kind:      ResourceKindIngressRoute,
+				Group:     traefikiov1alpha1.GroupName,
+				Name:      "name",
+				Namespace: "ns",
+			},
+			IngressMeta: IngressMeta{},
+			TLS: &IngressRouteTLS{
+				Domains: []traefikv1alpha1.Domain{
+					{
+						Main: "foo.com",
+						SANs: []string{"bar.foo.com"},
+					},
+				},
+				SecretName: "secret",
+			},
+			Routes: []Route{
+				{
+					Match: "Host(`foo.com`)",
+					Services: []RouteService{
+						{
+							Name:       "service1",
+							Namespace:  "ns",
+							PortNumber: 80,
+						},
+						{
+							Name:       "service2",
+							Namespace:  "ns",
+							PortNumber: 80,
+						},
+					},
+				},
+			},
+			Services: []string{"service1@ns", "service2@ns"},
+		},
+	}
+
+	assert.Equal(t, want, got)
+}
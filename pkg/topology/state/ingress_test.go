@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
+	traefikiokubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned/fake"
 	corev1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -87,7 +88,7 @@ func TestFetcher_GetIngresses(t *testing.T) {
 	kubeClient := kubemock.NewSimpleClientset(objects...)
 	traefikClient := traefikkubemock.NewSimpleClientset()
 
-	f, err := watchAll(context.Background(), kubeClient, traefikClient, "v1.20.1")
+	f, err := watchAll(context.Background(), kubeClient, traefikClient, traefikiokubemock.NewSimpleClientset(), "v1.20.1")
 	require.NoError(t, err)
 
 	got, err := f.getIngresses()
@@ -167,7 +168,7 @@ func TestFetcher_FetchIngresses(t *testing.T) {
 	kubeClient := kubemock.NewSimpleClientset(objects...)
 	traefikClient := traefikkubemock.NewSimpleClientset()
 
-	f, err := watchAll(context.Background(), kubeClient, traefikClient, "v1.18")
+	f, err := watchAll(context.Background(), kubeClient, traefikClient, traefikiokubemock.NewSimpleClientset(), "v1.18")
 	require.NoError(t, err)
 
 	got, err := f.fetchIngresses()
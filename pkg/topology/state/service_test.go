@@ -24,6 +24,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	traefikkubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefik/clientset/versioned/fake"
+	traefikiokubemock "github.com/traefik/hub-agent-kubernetes/pkg/crd/generated/client/traefikio/clientset/versioned/fake"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -87,7 +88,7 @@ func TestFetcher_GetServices(t *testing.T) {
 	kubeClient := kubemock.NewSimpleClientset(objects...)
 	traefikClient := traefikkubemock.NewSimpleClientset()
 
-	f, err := watchAll(context.Background(), kubeClient, traefikClient, "v1.20.1")
+	f, err := watchAll(context.Background(), kubeClient, traefikClient, traefikiokubemock.NewSimpleClientset(), "v1.20.1")
 	require.NoError(t, err)
 
 	gotSvcs, err := f.getServices()
@@ -156,7 +157,7 @@ func TestFetcher_GetServicesWithExternalIPs(t *testing.T) {
 	kubeClient := kubemock.NewSimpleClientset(objects...)
 	traefikClient := traefikkubemock.NewSimpleClientset()
 
-	f, err := watchAll(context.Background(), kubeClient, traefikClient, "v1.20.1")
+	f, err := watchAll(context.Background(), kubeClient, traefikClient, traefikiokubemock.NewSimpleClientset(), "v1.20.1")
 	require.NoError(t, err)
 
 	gotSvcs, err := f.getServices()
@@ -231,7 +232,7 @@ func TestFetcher_GetServiceLogs(t *testing.T) {
 
 	traefikClient := traefikkubemock.NewSimpleClientset()
 
-	f, err := watchAll(context.Background(), kubeClient, traefikClient, "v1.20.1")
+	f, err := watchAll(context.Background(), kubeClient, traefikClient, traefikiokubemock.NewSimpleClientset(), "v1.20.1")
 	require.NoError(t, err)
 
 	got, err := f.GetServiceLogs(context.Background(), "myns", "myService", 20, 200)
@@ -315,7 +316,7 @@ func TestFetcher_GetServiceLogsHandlesTooManyPods(t *testing.T) {
 
 	traefikClient := traefikkubemock.NewSimpleClientset()
 
-	f, err := watchAll(context.Background(), kubeClient, traefikClient, "v1.20.1")
+	f, err := watchAll(context.Background(), kubeClient, traefikClient, traefikiokubemock.NewSimpleClientset(), "v1.20.1")
 	require.NoError(t, err)
 
 	got, err := f.GetServiceLogs(context.Background(), "myns", "myService", 2, 200)
@@ -0,0 +1,154 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package validation
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Report formats.
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// Failures returns the number of results holding errors.
+func Failures(results []Result) int {
+	var failures int
+	for _, result := range results {
+		if len(result.Errors) > 0 {
+			failures++
+		}
+	}
+
+	return failures
+}
+
+// WriteReport writes the given results in the given format.
+func WriteReport(w io.Writer, format string, results []Result) error {
+	switch format {
+	case FormatText:
+		return writeText(w, results)
+	case FormatJSON:
+		return writeJSON(w, results)
+	case FormatJUnit:
+		return writeJUnit(w, results)
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+func writeText(w io.Writer, results []Result) error {
+	var b strings.Builder
+	for _, result := range results {
+		if len(result.Errors) == 0 {
+			fmt.Fprintf(&b, "PASS %s: %s\n", result.File, result.ID())
+			continue
+		}
+
+		fmt.Fprintf(&b, "FAIL %s: %s\n", result.File, result.ID())
+		for _, err := range result.Errors {
+			fmt.Fprintf(&b, "  - %s\n", err)
+		}
+	}
+	fmt.Fprintf(&b, "\n%d resource(s) validated, %d failure(s)\n", len(results), Failures(results))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type jsonReport struct {
+	Results  []Result `json:"results"`
+	Failures int      `json:"failures"`
+}
+
+func writeJSON(w io.Writer, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(jsonReport{Results: results, Failures: Failures(results)})
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Content string `xml:",chardata"`
+}
+
+func writeJUnit(w io.Writer, results []Result) error {
+	suite := junitTestSuite{
+		Name:     "hub-manifests",
+		Tests:    len(results),
+		Failures: Failures(results),
+	}
+
+	for _, result := range results {
+		testCase := junitTestCase{
+			ClassName: result.File,
+			Name:      result.ID(),
+		}
+
+		if len(result.Errors) > 0 {
+			testCase.Failure = &junitFailure{
+				Message: fmt.Sprintf("%d error(s)", len(result.Errors)),
+				Content: strings.Join(result.Errors, "\n"),
+			}
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,472 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package validation validates Hub manifests offline.
+package validation
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/ingclass"
+	"github.com/traefik/hub-agent-kubernetes/pkg/acp/admission/reviewer"
+	hubv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/hub/v1alpha1"
+	traefikv1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefik/v1alpha1"
+	traefikiov1alpha1 "github.com/traefik/hub-agent-kubernetes/pkg/crd/api/traefikio/v1alpha1"
+	admv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ktypes "k8s.io/apimachinery/pkg/types"
+	kyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// IngressClass is an IngressClass existing in the cluster the manifests are applied to.
+type IngressClass struct {
+	Name       string
+	Controller string
+	Default    bool
+}
+
+// Options configures the validation.
+type Options struct {
+	// IngressClasses are the IngressClasses existing in the cluster, in addition to the ones defined by the manifests.
+	IngressClasses []IngressClass
+}
+
+// Result is the outcome of the validation of a resource.
+type Result struct {
+	File      string   `json:"file"`
+	Kind      string   `json:"kind,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// ID returns a human-readable identifier of the validated resource.
+func (r Result) ID() string {
+	switch {
+	case r.Kind == "":
+		return r.File
+	case r.Namespace == "":
+		return r.Kind + " " + r.Name
+	default:
+		return r.Kind + " " + r.Namespace + "/" + r.Name
+	}
+}
+
+// resource is a resource read from a manifest.
+type resource struct {
+	file string
+	gvk  schema.GroupVersionKind
+	meta metav1.ObjectMeta
+	// raw is the JSON representation of the resource.
+	raw []byte
+}
+
+// Validate validates the manifests found in the given directory, and its subdirectories. The access control policies,
+// the EdgeIngresses and the Ingresses and IngressRoutes using access control policies are validated, other resources
+// are ignored.
+func Validate(dir string, opts Options) ([]Result, error) {
+	resources, results, err := readManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	v := newValidator(resources, opts)
+
+	for _, res := range resources {
+		result, ok := v.validate(res)
+		if ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].File < results[j].File
+	})
+
+	return results, nil
+}
+
+// readManifests reads the resources of the manifests of the given directory. It returns a result for each document
+// which can't be decoded.
+func readManifests(dir string) ([]resource, []Result, error) {
+	var (
+		resources []resource
+		results   []Result
+	)
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+
+		reader := kyaml.NewYAMLReader(bufio.NewReader(file))
+		for {
+			doc, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				results = append(results, Result{File: path, Errors: []string{fmt.Sprintf("read manifest: %v", err)}})
+				return nil
+			}
+
+			if len(bytes.TrimSpace(doc)) == 0 {
+				continue
+			}
+
+			res, err := decodeResource(path, doc)
+			if err != nil {
+				results = append(results, Result{File: path, Errors: []string{err.Error()}})
+				continue
+			}
+			if res != nil {
+				resources = append(resources, *res)
+			}
+		}
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("read manifests: %w", err)
+	}
+
+	return resources, results, nil
+}
+
+func decodeResource(file string, doc []byte) (*resource, error) {
+	raw, err := yaml.YAMLToJSON(doc)
+	if err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+
+	// Documents only made of comments are decoded as null.
+	if bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+
+	var obj struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata"`
+	}
+	if err = yaml.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+
+	if obj.Namespace == "" {
+		obj.Namespace = metav1.NamespaceDefault
+	}
+
+	return &resource{
+		file: file,
+		gvk:  obj.GroupVersionKind(),
+		meta: obj.ObjectMeta,
+		raw:  raw,
+	}, nil
+}
+
+// ingressReviewer is a reviewer of the admission webhook handling Ingresses.
+type ingressReviewer interface {
+	CanReview(ar admv1.AdmissionReview) (bool, error)
+}
+
+type validator struct {
+	ingressClasses *ingclass.Watcher
+	reviewers      []ingressReviewer
+
+	policies           map[string]struct{}
+	namespacedPolicies map[string]struct{}
+}
+
+func newValidator(resources []resource, opts Options) *validator {
+	v := &validator{
+		ingressClasses:     ingclass.NewWatcher(),
+		policies:           make(map[string]struct{}),
+		namespacedPolicies: make(map[string]struct{}),
+	}
+
+	// Ingress classes are resolved with the reviewers of the admission webhook, so they resolve exactly as they do
+	// once applied. Only their ability to review the resources is used, so they don't need their dependencies.
+	v.reviewers = []ingressReviewer{
+		reviewer.NewNginxIngress("", v.ingressClasses, nil, nil),
+		reviewer.NewHAProxyIngress("", v.ingressClasses, nil, nil),
+		reviewer.NewKongIngress(v.ingressClasses, reviewer.KongPlugins{}, nil),
+		reviewer.NewTraefikIngress(v.ingressClasses, reviewer.FwdAuthMiddlewares{}, nil),
+	}
+
+	for _, class := range opts.IngressClasses {
+		v.addIngressClass("flag", class.Name, class.Controller, class.Default)
+	}
+
+	for _, res := range resources {
+		switch {
+		case isIngressClass(res.gvk):
+			var class hubv1alpha1.IngressClass
+			if err := yaml.Unmarshal(res.raw, &class); err != nil {
+				continue
+			}
+			v.addIngressClass(res.gvk.String(), class.Name, class.Spec.Controller, class.Annotations[annotationDefaultIngressClass] == "true")
+
+		case isHubKind(res.gvk, "AccessControlPolicy"):
+			v.policies[res.meta.Name] = struct{}{}
+
+		case isHubKind(res.gvk, "NamespacedAccessControlPolicy"):
+			v.namespacedPolicies[acp.CanonicalName(res.meta.Name, res.meta.Namespace)] = struct{}{}
+		}
+	}
+
+	return v
+}
+
+const annotationDefaultIngressClass = "ingressclass.kubernetes.io/is-default-class"
+
+func (v *validator) addIngressClass(source, name, controller string, isDefault bool) {
+	class := &hubv1alpha1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{
+			// The watcher indexes ingress classes by UID, which manifests don't have.
+			UID:  ktypes.UID(source + "/" + name),
+			Name: name,
+		},
+		Spec: hubv1alpha1.IngressClassSpec{Controller: controller},
+	}
+	if isDefault {
+		class.Annotations = map[string]string{annotationDefaultIngressClass: "true"}
+	}
+
+	v.ingressClasses.OnAdd(class)
+}
+
+// validate validates the given resource. It reports whether the resource is one of the validated ones.
+func (v *validator) validate(res resource) (Result, bool) {
+	result := Result{
+		File: res.file,
+		Kind: res.gvk.Kind,
+		Name: res.meta.Name,
+	}
+
+	var errs []string
+	switch {
+	case isHubKind(res.gvk, "AccessControlPolicy"):
+		errs = v.validatePolicy(res)
+
+	case isHubKind(res.gvk, "NamespacedAccessControlPolicy"):
+		result.Namespace = res.meta.Namespace
+		errs = v.validateNamespacedPolicy(res)
+
+	case isHubKind(res.gvk, "EdgeIngress"):
+		result.Namespace = res.meta.Namespace
+		errs = v.validateEdgeIngress(res)
+
+	case isIngress(res.gvk):
+		if _, ok := res.meta.Annotations[reviewer.AnnotationHubAuth]; !ok {
+			return Result{}, false
+		}
+		result.Namespace = res.meta.Namespace
+		errs = v.validateIngress(res)
+
+	case isIngressRoute(res.gvk):
+		_, hasPolicy := res.meta.Annotations[reviewer.AnnotationHubAuth]
+		_, hasRoutePolicies := res.meta.Annotations[reviewer.AnnotationHubRouteAuth]
+		if !hasPolicy && !hasRoutePolicies {
+			return Result{}, false
+		}
+		result.Namespace = res.meta.Namespace
+		errs = append(v.validatePolicyReference(res), v.validateRoutePolicies(res)...)
+
+	default:
+		return Result{}, false
+	}
+
+	result.Errors = errs
+
+	return result, true
+}
+
+func (v *validator) validatePolicy(res resource) []string {
+	var policy hubv1alpha1.AccessControlPolicy
+	if err := yaml.UnmarshalStrict(res.raw, &policy); err != nil {
+		return []string{fmt.Sprintf("decode: %v", err)}
+	}
+
+	return fieldErrors(acp.ValidateSpec(&policy.Spec))
+}
+
+func (v *validator) validateNamespacedPolicy(res resource) []string {
+	var policy hubv1alpha1.NamespacedAccessControlPolicy
+	if err := yaml.UnmarshalStrict(res.raw, &policy); err != nil {
+		return []string{fmt.Sprintf("decode: %v", err)}
+	}
+
+	return fieldErrors(acp.ValidateNamespacedSpec(&policy.Spec, res.meta.Namespace))
+}
+
+func (v *validator) validateEdgeIngress(res resource) []string {
+	var edgeIng hubv1alpha1.EdgeIngress
+	if err := yaml.UnmarshalStrict(res.raw, &edgeIng); err != nil {
+		return []string{fmt.Sprintf("decode: %v", err)}
+	}
+
+	var errs []string
+
+	if edgeIng.Spec.Service.Name == "" {
+		errs = append(errs, "spec.service.name: is required")
+	}
+	if edgeIng.Spec.Service.Port <= 0 || edgeIng.Spec.Service.Port > 65535 {
+		errs = append(errs, "spec.service.port: must be between 1 and 65535")
+	}
+
+	if edgeIng.Spec.ACP != nil {
+		switch name := edgeIng.Spec.ACP.Name; {
+		case name == "":
+			errs = append(errs, "spec.acp.name: is required")
+		default:
+			if _, ok := v.policies[name]; !ok {
+				errs = append(errs, fmt.Sprintf("spec.acp.name: AccessControlPolicy %q not found", name))
+			}
+		}
+	}
+
+	return errs
+}
+
+func (v *validator) validateIngress(res resource) []string {
+	errs := v.validatePolicyReference(res)
+
+	ar := admv1.AdmissionReview{
+		Request: &admv1.AdmissionRequest{
+			Kind: metav1.GroupVersionKind{
+				Group:   res.gvk.Group,
+				Version: res.gvk.Version,
+				Kind:    res.gvk.Kind,
+			},
+			Operation: admv1.Create,
+			Object:    runtime.RawExtension{Raw: res.raw},
+		},
+	}
+
+	for _, rev := range v.reviewers {
+		ok, err := rev.CanReview(ar)
+		if err != nil {
+			return append(errs, fmt.Sprintf("ingress class: %v", err))
+		}
+		if ok {
+			return errs
+		}
+	}
+
+	return append(errs, "ingress class: the Ingress is not handled by a supported ingress controller")
+}
+
+// validatePolicyReference checks the access control policy the given resource references exists. Policies resolve
+// as they do in the admission webhook: namespaced policies take precedence over cluster ones.
+func (v *validator) validatePolicyReference(res resource) []string {
+	name := res.meta.Annotations[reviewer.AnnotationHubAuth]
+	if name == "" || v.policyExists(name, res.meta.Namespace) {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("metadata.annotations[%s]: access control policy %q not found", reviewer.AnnotationHubAuth, name)}
+}
+
+// validateRoutePolicies checks the route mapping of the given IngressRoute is well-formed and the access control
+// policies it references exist.
+func (v *validator) validateRoutePolicies(res resource) []string {
+	routePols, err := reviewer.ParseRoutePolicies(res.meta.Annotations[reviewer.AnnotationHubRouteAuth])
+	if err != nil {
+		return []string{fmt.Sprintf("metadata.annotations[%s]: %v", reviewer.AnnotationHubRouteAuth, err)}
+	}
+
+	routes := make([]string, 0, len(routePols))
+	for route := range routePols {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	var errs []string
+	for _, route := range routes {
+		name := routePols[route]
+		if name == "" || v.policyExists(name, res.meta.Namespace) {
+			continue
+		}
+
+		errs = append(errs, fmt.Sprintf("metadata.annotations[%s]: route %q: access control policy %q not found", reviewer.AnnotationHubRouteAuth, route, name))
+	}
+
+	return errs
+}
+
+// policyExists reports whether the given policy name resolves from the given namespace.
+func (v *validator) policyExists(name, namespace string) bool {
+	if _, ok := v.namespacedPolicies[acp.CanonicalName(name, namespace)]; ok {
+		return true
+	}
+
+	_, ok := v.policies[name]
+
+	return ok
+}
+
+func fieldErrors(errs []acp.FieldError) []string {
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+
+	return msgs
+}
+
+func isHubKind(gvk schema.GroupVersionKind, kind string) bool {
+	return gvk.Group == hubv1alpha1.SchemeGroupVersion.Group && gvk.Kind == kind
+}
+
+func isIngressClass(gvk schema.GroupVersionKind) bool {
+	return gvk.Kind == "IngressClass" &&
+		(gvk.Group == "networking.k8s.io" || gvk.Group == hubv1alpha1.SchemeGroupVersion.Group)
+}
+
+func isIngress(gvk schema.GroupVersionKind) bool {
+	return gvk.Kind == "Ingress" && (gvk.Group == "networking.k8s.io" || gvk.Group == "extensions")
+}
+
+func isIngressRoute(gvk schema.GroupVersionKind) bool {
+	return gvk.Kind == "IngressRoute" && (gvk.Group == traefikiov1alpha1.GroupName || gvk.Group == traefikv1alpha1.GroupName)
+}
@@ -0,0 +1,170 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	dir := filepath.Join("testdata", "manifests")
+	ingresses := filepath.Join(dir, "apps", "ingresses.yaml")
+	policies := filepath.Join(dir, "policies.yaml")
+
+	results, err := Validate(dir, Options{
+		IngressClasses: []IngressClass{{Name: "nginx", Controller: "k8s.io/ingress-nginx"}},
+	})
+	require.NoError(t, err)
+
+	want := []Result{
+		{File: ingresses, Kind: "Ingress", Namespace: "apps", Name: "whoami"},
+		{
+			File: ingresses, Kind: "Ingress", Namespace: "apps", Name: "unknown-class",
+			Errors: []string{`ingress class: get ingress class controller from ingress class name: IngressClass "haproxy" not found`},
+		},
+		{
+			File: ingresses, Kind: "Ingress", Namespace: "default", Name: "unknown-policy",
+			Errors: []string{`metadata.annotations[hub.traefik.io/access-control-policy]: access control policy "basic" not found`},
+		},
+		{File: ingresses, Kind: "IngressRoute", Namespace: "apps", Name: "whoami"},
+		{
+			File: ingresses, Kind: "IngressRoute", Namespace: "apps", Name: "per-route",
+			Errors: []string{"metadata.annotations[hub.traefik.io/route-access-control-policies]: route \"PathPrefix(`/admin`)\": access control policy \"oidc\" not found"},
+		},
+		{
+			File: ingresses, Kind: "EdgeIngress", Namespace: "apps", Name: "whoami",
+			Errors: []string{
+				"spec.service.port: must be between 1 and 65535",
+				`spec.acp.name: AccessControlPolicy "basic" not found`,
+			},
+		},
+		{File: policies, Kind: "AccessControlPolicy", Name: "jwt"},
+		{
+			File: policies, Kind: "AccessControlPolicy", Name: "broken",
+			Errors: []string{
+				"spec.jwt.publicKey: must be a PEM-encoded public key",
+				"spec.jwt.claims: invalid expression: unable to parse expression: 1:15: missing ',' before newline in argument list",
+			},
+		},
+		{
+			File: policies, Kind: "AccessControlPolicy", Name: "typo",
+			Errors: []string{`decode: error unmarshaling JSON: while decoding JSON: json: unknown field "user"`},
+		},
+		{
+			File: policies, Kind: "NamespacedAccessControlPolicy", Namespace: "apps", Name: "basic",
+			Errors: []string{
+				"spec.basicAuth.users[1]: must be formatted as name:hashed-password",
+				"spec.denyResponse.templateRef.namespace: must be empty or equal to the namespace of the policy",
+			},
+		},
+	}
+
+	assert.Equal(t, want, results)
+	assert.Equal(t, 7, Failures(results))
+}
+
+func TestValidate_unsupportedIngressController(t *testing.T) {
+	dir := filepath.Join("testdata", "manifests")
+
+	results, err := Validate(dir, Options{
+		IngressClasses: []IngressClass{{Name: "haproxy", Controller: "istio.io/ingress-controller"}},
+	})
+	require.NoError(t, err)
+
+	for _, result := range results {
+		if result.Kind == "Ingress" && result.Name == "unknown-class" {
+			assert.Equal(t, []string{"ingress class: the Ingress is not handled by a supported ingress controller"}, result.Errors)
+			return
+		}
+	}
+
+	t.Fatal("Ingress unknown-class not validated")
+}
+
+func TestValidate_haproxyTechIngressController(t *testing.T) {
+	dir := filepath.Join("testdata", "manifests")
+
+	results, err := Validate(dir, Options{
+		IngressClasses: []IngressClass{{Name: "haproxy", Controller: "haproxy.org/ingress-controller/haproxy"}},
+	})
+	require.NoError(t, err)
+
+	for _, result := range results {
+		if result.Kind == "Ingress" && result.Name == "unknown-class" {
+			want := `ingress class: ingress controller "haproxy.org/ingress-controller/haproxy" does not support ACPs, only HAProxy Ingress (haproxy-ingress.github.io/controller) does`
+			assert.Equal(t, []string{want}, result.Errors)
+			return
+		}
+	}
+
+	t.Fatal("Ingress unknown-class not validated")
+}
+
+func TestWriteReport(t *testing.T) {
+	results := []Result{
+		{File: "policies.yaml", Kind: "AccessControlPolicy", Name: "jwt"},
+		{File: "policies.yaml", Kind: "AccessControlPolicy", Name: "broken", Errors: []string{"spec.jwt.claims: invalid expression"}},
+		{File: "broken.yaml", Errors: []string{"decode manifest: invalid YAML"}},
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, WriteReport(&text, FormatText, results))
+	assert.Equal(t, `PASS policies.yaml: AccessControlPolicy jwt
+FAIL policies.yaml: AccessControlPolicy broken
+  - spec.jwt.claims: invalid expression
+FAIL broken.yaml: broken.yaml
+  - decode manifest: invalid YAML
+
+3 resource(s) validated, 2 failure(s)
+`, text.String())
+
+	var jsonReport bytes.Buffer
+	require.NoError(t, WriteReport(&jsonReport, FormatJSON, results))
+
+	var got struct {
+		Results  []Result `json:"results"`
+		Failures int      `json:"failures"`
+	}
+	require.NoError(t, json.Unmarshal(jsonReport.Bytes(), &got))
+	assert.Equal(t, results, got.Results)
+	assert.Equal(t, 2, got.Failures)
+
+	var junit bytes.Buffer
+	require.NoError(t, WriteReport(&junit, FormatJUnit, results))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="hub-manifests" tests="3" failures="2">
+    <testcase classname="policies.yaml" name="AccessControlPolicy jwt"></testcase>
+    <testcase classname="policies.yaml" name="AccessControlPolicy broken">
+      <failure message="1 error(s)">spec.jwt.claims: invalid expression</failure>
+    </testcase>
+    <testcase classname="broken.yaml" name="broken.yaml">
+      <failure message="1 error(s)">decode manifest: invalid YAML</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`, junit.String())
+
+	assert.Error(t, WriteReport(&text, "yaml", results))
+}
@@ -0,0 +1,387 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+// Package webhookcert manages the self-signed TLS certificates of the admission webhook.
+package webhookcert
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	kerror "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// keyCABundle is the key of the Secret data holding the CA bundle, along with the serving certificate stored under
+// the usual tls.crt and tls.key keys. The CA bundle holds the current CA certificate, followed by the previous one while
+// it is still valid, so that API servers trust both the current and the previous serving certificates during
+// rotations. The CA key is not stored: a new CA is generated on each renewal, so it is only needed to sign the serving
+// certificate.
+const keyCABundle = "ca.crt"
+
+// Config holds the Manager configuration.
+type Config struct {
+	// Namespace is the namespace of the webhook Service and of the Secret holding the certificates.
+	Namespace string
+	// SecretName is the name of the Secret holding the certificates.
+	SecretName string
+	// ServiceName is the name of the Service exposing the webhook, which the serving certificate is issued for.
+	// MutatingWebhookConfigurations referencing this Service get the CA bundle injected.
+	ServiceName string
+	// Validity is the validity of the generated certificates.
+	Validity time.Duration
+	// RenewBefore is the time before their expiry certificates are renewed.
+	RenewBefore time.Duration
+	// SyncInterval is the interval at which certificates are checked for renewal and reloaded.
+	SyncInterval time.Duration
+}
+
+// Manager generates a self-signed CA and a serving certificate for the admission webhook, stores them in a Secret and
+// injects the CA bundle into the MutatingWebhookConfigurations of the webhook. Certificates are renewed before they
+// expire and served through GetCertificate, which always returns the latest one.
+// Replicas share the certificates through the Secret: the one which fails to create or update it reloads it instead.
+type Manager struct {
+	client clientset.Interface
+	cfg    Config
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+
+	now func() time.Time
+}
+
+// NewManager returns a new Manager.
+func NewManager(client clientset.Interface, cfg Config) *Manager {
+	return &Manager{
+		client: client,
+		cfg:    cfg,
+		now:    time.Now,
+	}
+}
+
+// Setup makes sure a valid certificate is available and its CA bundle is injected. It must be called before serving
+// requests.
+func (m *Manager) Setup(ctx context.Context) error {
+	return m.sync(ctx)
+}
+
+// Run runs the Manager control loop, renewing and reloading certificates every sync interval.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.sync(ctx); err != nil {
+				log.Error().Err(err).Msg("Unable to sync webhook certificates")
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// GetCertificate returns the current serving certificate. It is meant to be used as tls.Config GetCertificate.
+func (m *Manager) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.cert == nil {
+		return nil, errors.New("no certificate available")
+	}
+
+	return m.cert, nil
+}
+
+func (m *Manager) sync(ctx context.Context) error {
+	secrets := m.client.CoreV1().Secrets(m.cfg.Namespace)
+
+	secret, err := secrets.Get(ctx, m.cfg.SecretName, metav1.GetOptions{})
+	if err != nil && !kerror.IsNotFound(err) {
+		return fmt.Errorf("get secret: %w", err)
+	}
+	if kerror.IsNotFound(err) {
+		secret = nil
+	}
+
+	cert, err := m.parseCertificate(secret)
+	if err != nil {
+		log.Info().Err(err).Str("secret_name", m.cfg.SecretName).Msg("Generating new webhook certificates")
+
+		secret, err = m.renew(ctx, secret)
+		if err != nil {
+			return fmt.Errorf("renew certificates: %w", err)
+		}
+
+		if cert, err = m.parseCertificate(secret); err != nil {
+			return fmt.Errorf("parse renewed certificate: %w", err)
+		}
+	}
+
+	// API servers must trust the certificate before it gets served, otherwise webhook calls fail until the next sync.
+	// The previous certificate keeps being served until then, as the CA bundle still holds its CA.
+	if err = m.injectCABundle(ctx, secret.Data[keyCABundle]); err != nil {
+		return fmt.Errorf("inject CA bundle: %w", err)
+	}
+
+	m.mu.Lock()
+	m.cert = cert
+	m.mu.Unlock()
+
+	return nil
+}
+
+// parseCertificate returns the serving certificate of the given Secret, or an error if it doesn't hold a valid one,
+// signed by its CA bundle.
+func (m *Manager) parseCertificate(secret *corev1.Secret) (*tls.Certificate, error) {
+	if secret == nil {
+		return nil, errors.New("no certificate found")
+	}
+
+	cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse certificate: %w", err)
+	}
+	cert.Leaf = leaf
+
+	if m.now().Add(m.cfg.RenewBefore).After(leaf.NotAfter) {
+		return nil, fmt.Errorf("certificate expires on %s", leaf.NotAfter)
+	}
+
+	if !reflect.DeepEqual(leaf.DNSNames, m.dnsNames()) {
+		return nil, errors.New("certificate issued for another service")
+	}
+
+	// The CA bundle gets injected into the webhook configurations, it must let API servers verify the certificate.
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(secret.Data[keyCABundle]) {
+		return nil, errors.New("no CA bundle found")
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:     leaf.DNSNames[0],
+		Roots:       roots,
+		CurrentTime: m.now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verify certificate: %w", err)
+	}
+
+	return &cert, nil
+}
+
+// renew generates a new CA and serving certificate and stores them in the given Secret, which is created if nil.
+// If another replica created or updated the Secret in the meantime, its latest version is returned instead.
+func (m *Manager) renew(ctx context.Context, secret *corev1.Secret) (*corev1.Secret, error) {
+	var prevCABundle []byte
+	if secret != nil {
+		prevCABundle = secret.Data[keyCABundle]
+	}
+
+	data, err := m.generate(prevCABundle)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := m.client.CoreV1().Secrets(m.cfg.Namespace)
+
+	if secret == nil {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      m.cfg.SecretName,
+				Namespace: m.cfg.Namespace,
+				Labels: map[string]string{
+					"app.kubernetes.io/managed-by": "traefik-hub",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: data,
+		}
+
+		created, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+		if kerror.IsAlreadyExists(err) {
+			return secrets.Get(ctx, m.cfg.SecretName, metav1.GetOptions{})
+		}
+		return created, err
+	}
+
+	secret = secret.DeepCopy()
+	secret.Data = data
+
+	updated, err := secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	if kerror.IsConflict(err) {
+		return secrets.Get(ctx, m.cfg.SecretName, metav1.GetOptions{})
+	}
+	return updated, err
+}
+
+// generate generates a new CA and serving certificate, returning them as Secret data. The first certificate of the
+// given previous CA bundle is kept in the new bundle while it is still valid.
+func (m *Manager) generate(prevCABundle []byte) (map[string][]byte, error) {
+	now := m.now()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate CA key: %w", err)
+	}
+
+	caTmpl := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: m.cfg.ServiceName + "-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(m.cfg.Validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if caTmpl.SerialNumber, err = serialNumber(); err != nil {
+		return nil, err
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("create CA certificate: %w", err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+
+	dnsNames := m.dnsNames()
+	tmpl := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: dnsNames[len(dnsNames)-2]},
+		DNSNames:    dnsNames,
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(m.cfg.Validity),
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if tmpl.SerialNumber, err = serialNumber(); err != nil {
+		return nil, err
+	}
+
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("marshal key: %w", err)
+	}
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})
+	if block, _ := pem.Decode(prevCABundle); block != nil {
+		if prevCA, err := x509.ParseCertificate(block.Bytes); err == nil && now.Before(prevCA.NotAfter) {
+			caBundle = append(caBundle, pem.EncodeToMemory(block)...)
+		}
+	}
+
+	return map[string][]byte{
+		keyCABundle:             caBundle,
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
+
+// injectCABundle sets the given CA bundle on the webhooks of the MutatingWebhookConfigurations calling the webhook
+// Service.
+func (m *Manager) injectCABundle(ctx context.Context, caBundle []byte) error {
+	webhookConfigs := m.client.AdmissionregistrationV1().MutatingWebhookConfigurations()
+
+	configs, err := webhookConfigs.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list MutatingWebhookConfigurations: %w", err)
+	}
+
+	for _, config := range configs.Items {
+		var updated bool
+		for i, webhook := range config.Webhooks {
+			svc := webhook.ClientConfig.Service
+			if svc == nil || svc.Name != m.cfg.ServiceName || svc.Namespace != m.cfg.Namespace {
+				continue
+			}
+			if bytes.Equal(webhook.ClientConfig.CABundle, caBundle) {
+				continue
+			}
+
+			config.Webhooks[i].ClientConfig.CABundle = caBundle
+			updated = true
+		}
+
+		if !updated {
+			continue
+		}
+
+		config := config
+		if _, err = webhookConfigs.Update(ctx, &config, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("update MutatingWebhookConfiguration %q: %w", config.Name, err)
+		}
+
+		log.Info().Str("webhook_config_name", config.Name).Msg("CA bundle injected")
+	}
+
+	return nil
+}
+
+// dnsNames returns the DNS names the webhook Service can be reached on from the API server.
+func (m *Manager) dnsNames() []string {
+	svc, ns := m.cfg.ServiceName, m.cfg.Namespace
+
+	return []string{
+		svc,
+		svc + "." + ns,
+		svc + "." + ns + ".svc",
+		svc + "." + ns + ".svc.cluster.local",
+	}
+}
+
+func serialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number: %w", err)
+	}
+
+	return serial, nil
+}
@@ -0,0 +1,253 @@
+/*
+Copyright (C) 2022 Traefik Labs
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as published
+by the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+*/
+
+package webhookcert
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admregv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func newWebhookConfig(name, svcName string) *admregv1.MutatingWebhookConfiguration {
+	return &admregv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Webhooks: []admregv1.MutatingWebhook{
+			{
+				Name: name + ".hub.traefik.io",
+				ClientConfig: admregv1.WebhookClientConfig{
+					Service: &admregv1.ServiceReference{Name: svcName, Namespace: "hub"},
+				},
+			},
+		},
+	}
+}
+
+func newManager(client *kubefake.Clientset) *Manager {
+	return NewManager(client, Config{
+		Namespace:    "hub",
+		SecretName:   "hub-agent-cert",
+		ServiceName:  "hub-agent",
+		Validity:     365 * 24 * time.Hour,
+		RenewBefore:  30 * 24 * time.Hour,
+		SyncInterval: time.Hour,
+	})
+}
+
+func TestManager_Setup(t *testing.T) {
+	client := kubefake.NewSimpleClientset(
+		newWebhookConfig("hub-acp", "hub-agent"),
+		newWebhookConfig("other", "other-service"),
+	)
+
+	mgr := newManager(client)
+
+	err := mgr.Setup(context.Background())
+	require.NoError(t, err)
+
+	secret, err := client.CoreV1().Secrets("hub").Get(context.Background(), "hub-agent-cert", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	config, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.Background(), "hub-acp", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, secret.Data["ca.crt"], config.Webhooks[0].ClientConfig.CABundle)
+
+	config, err = client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.Background(), "other", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, config.Webhooks[0].ClientConfig.CABundle)
+
+	assertServedCertificate(t, mgr, secret.Data["ca.crt"])
+
+	// Certificates still valid are reused.
+	client.ClearActions()
+
+	err = newManager(client).Setup(context.Background())
+	require.NoError(t, err)
+
+	for _, action := range client.Actions() {
+		assert.Contains(t, []string{"get", "list"}, action.GetVerb())
+	}
+}
+
+func TestManager_Setup_renewsCertificates(t *testing.T) {
+	client := kubefake.NewSimpleClientset(newWebhookConfig("hub-acp", "hub-agent"))
+
+	mgr := newManager(client)
+
+	err := mgr.Setup(context.Background())
+	require.NoError(t, err)
+
+	prevCert, err := mgr.GetCertificate(nil)
+	require.NoError(t, err)
+
+	secret, err := client.CoreV1().Secrets("hub").Get(context.Background(), "hub-agent-cert", metav1.GetOptions{})
+	require.NoError(t, err)
+	prevCABundle := secret.Data["ca.crt"]
+
+	// Move to the renewal window of the certificates.
+	mgr.now = func() time.Time { return time.Now().Add(340 * 24 * time.Hour) }
+
+	client.ClearActions()
+
+	err = mgr.sync(context.Background())
+	require.NoError(t, err)
+
+	var secretUpdated bool
+	for _, action := range client.Actions() {
+		if updateAction, ok := action.(ktesting.UpdateAction); ok && updateAction.GetResource().Resource == "secrets" {
+			secretUpdated = true
+		}
+	}
+	assert.True(t, secretUpdated)
+
+	cert, err := mgr.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, prevCert.Certificate, cert.Certificate)
+
+	secret, err = client.CoreV1().Secrets("hub").Get(context.Background(), "hub-agent-cert", metav1.GetOptions{})
+	require.NoError(t, err)
+
+	// The new CA bundle keeps trusting the previous CA.
+	caBundle := secret.Data["ca.crt"]
+	assert.Contains(t, string(caBundle), string(prevCABundle))
+	assert.NotEqual(t, prevCABundle, caBundle)
+
+	config, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.Background(), "hub-acp", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, caBundle, config.Webhooks[0].ClientConfig.CABundle)
+}
+
+func TestManager_sync_keepsCertificateUntilCABundleInjected(t *testing.T) {
+	client := kubefake.NewSimpleClientset(newWebhookConfig("hub-acp", "hub-agent"))
+
+	mgr := newManager(client)
+
+	err := mgr.Setup(context.Background())
+	require.NoError(t, err)
+
+	prevCert, err := mgr.GetCertificate(nil)
+	require.NoError(t, err)
+
+	failInjection := true
+	client.PrependReactor("update", "mutatingwebhookconfigurations", func(ktesting.Action) (bool, runtime.Object, error) {
+		if failInjection {
+			return true, nil, errors.New("boom")
+		}
+		return false, nil, nil
+	})
+
+	// Move to the renewal window of the certificates.
+	mgr.now = func() time.Time { return time.Now().Add(340 * 24 * time.Hour) }
+
+	err = mgr.sync(context.Background())
+	require.Error(t, err)
+
+	cert, err := mgr.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, prevCert.Certificate, cert.Certificate)
+
+	// The renewed certificate is served once its CA bundle is injected.
+	failInjection = false
+
+	err = mgr.sync(context.Background())
+	require.NoError(t, err)
+
+	cert, err = mgr.GetCertificate(nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, prevCert.Certificate, cert.Certificate)
+}
+
+func TestManager_Setup_renewsCertificatesWithInvalidCABundle(t *testing.T) {
+	tests := []struct {
+		desc     string
+		caBundle func() []byte
+	}{
+		{
+			desc:     "missing CA bundle",
+			caBundle: func() []byte { return nil },
+		},
+		{
+			desc: "CA bundle not matching the certificate",
+			caBundle: func() []byte {
+				data, err := newManager(kubefake.NewSimpleClientset()).generate(nil)
+				require.NoError(t, err)
+
+				return data["ca.crt"]
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			client := kubefake.NewSimpleClientset(newWebhookConfig("hub-acp", "hub-agent"))
+
+			err := newManager(client).Setup(context.Background())
+			require.NoError(t, err)
+
+			secret, err := client.CoreV1().Secrets("hub").Get(context.Background(), "hub-agent-cert", metav1.GetOptions{})
+			require.NoError(t, err)
+
+			secret.Data["ca.crt"] = test.caBundle()
+			_, err = client.CoreV1().Secrets("hub").Update(context.Background(), secret, metav1.UpdateOptions{})
+			require.NoError(t, err)
+
+			mgr := newManager(client)
+			err = mgr.Setup(context.Background())
+			require.NoError(t, err)
+
+			secret, err = client.CoreV1().Secrets("hub").Get(context.Background(), "hub-agent-cert", metav1.GetOptions{})
+			require.NoError(t, err)
+			require.NotEmpty(t, secret.Data["ca.crt"])
+			assert.NotContains(t, secret.Data, "ca.key")
+
+			config, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.Background(), "hub-acp", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, secret.Data["ca.crt"], config.Webhooks[0].ClientConfig.CABundle)
+
+			assertServedCertificate(t, mgr, secret.Data["ca.crt"])
+		})
+	}
+}
+
+func assertServedCertificate(t *testing.T, mgr *Manager, caBundle []byte) {
+	t.Helper()
+
+	cert, err := mgr.GetCertificate(nil)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(caBundle))
+
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{
+		DNSName: "hub-agent.hub.svc",
+		Roots:   roots,
+	})
+	assert.NoError(t, err)
+}
@@ -26,6 +26,28 @@ docker run --rm \
            -w "/go/src/${PROJECT_MODULE}" \
            "${IMAGE_NAME}" $cmd
 
+# The traefik.io group serves the same resources as the traefik.containo.us one, and its API package aliases the
+# traefik.containo.us types. Its clientSet is therefore derived from the traefik.containo.us one.
+echo "Deriving Traefik (traefik.io) clientSet code ..."
+TRAEFIK_IO_CLIENT="pkg/crd/generated/client/traefikio"
+rm -rf "${TRAEFIK_IO_CLIENT}"
+cp -r pkg/crd/generated/client/traefik "${TRAEFIK_IO_CLIENT}"
+mv "${TRAEFIK_IO_CLIENT}/clientset/versioned/typed/traefik" "${TRAEFIK_IO_CLIENT}/clientset/versioned/typed/traefikio"
+mv "${TRAEFIK_IO_CLIENT}/informers/externalversions/traefik" "${TRAEFIK_IO_CLIENT}/informers/externalversions/traefikio"
+mv "${TRAEFIK_IO_CLIENT}/listers/traefik" "${TRAEFIK_IO_CLIENT}/listers/traefikio"
+find "${TRAEFIK_IO_CLIENT}" -name '*.go' -exec sed -i \
+    -e 's#client/traefik/#client/traefikio/#g' \
+    -e 's#/typed/traefik/#/typed/traefikio/#g' \
+    -e 's#/externalversions/traefik"#/externalversions/traefikio"#g' \
+    -e 's#/externalversions/traefik/#/externalversions/traefikio/#g' \
+    -e 's#/listers/traefik/#/listers/traefikio/#g' \
+    -e 's#crd/api/traefik/v1alpha1#crd/api/traefikio/v1alpha1#g' \
+    -e 's#traefik\.containo\.us#traefik.io#g' {} +
+sed -i 's/^package traefik$/package traefikio/' "${TRAEFIK_IO_CLIENT}/informers/externalversions/traefikio/interface.go"
+sed -i -e 's#^\ttraefik "\(.*\)/externalversions/traefikio"#\ttraefikio "\1/externalversions/traefikio"#' \
+    -e 's/traefik\.Interface/traefikio.Interface/g' -e 's/return traefik\.New(/return traefikio.New(/' \
+    "${TRAEFIK_IO_CLIENT}/informers/externalversions/factory.go"
+gofmt -w "${TRAEFIK_IO_CLIENT}"
 
 cmd="controller-gen crd:crdVersions=v1 paths=./pkg/crd/api/hub/v1alpha1/... output:dir=."
 